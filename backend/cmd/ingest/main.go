@@ -4,6 +4,7 @@ import (
 	"context"
 	"kessler/internal/ingest/routes"
 	"kessler/internal/ingest/tasks"
+	"kessler/internal/llm_utils"
 	"kessler/pkg/logger"
 	"net/http"
 	"os"
@@ -45,10 +46,10 @@ const concurrency = 30 // Max concurrent tasks
 var redisAddr = os.Getenv("INTERNAL_REDIS_ADDRESS")
 
 // In main.go add this middleware
-func clientMiddleware(client *asynq.Client) mux.MiddlewareFunc {
+func clientMiddleware(client *asynq.Client, inspector *asynq.Inspector) mux.MiddlewareFunc {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			ctx := tasks.WithClient(r.Context(), client)
+			ctx := tasks.WithInspector(tasks.WithClient(r.Context(), client), inspector)
 			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
@@ -70,13 +71,18 @@ func main() {
 	// Create asynq client
 	client := asynq.NewClient(asynq.RedisClientOpt{Addr: redisAddr})
 	defer client.Close()
+	// The inspector frees the task IDs of files whose processing was archived so they can be requeued
+	inspector := asynq.NewInspector(asynq.RedisClientOpt{Addr: redisAddr})
+	defer inspector.Close()
 	ctx := logger.WithLogger(context.Background())
 	log := logger.FromContext(ctx)
 
 	// Create API subrouter with client middleware
 	api := r.PathPrefix(root).Subrouter()
-	api.Use(clientMiddleware(client))
-	routes.DefineGlobalRouter(api) // Pass the subrouter to routes package
+	api.Use(clientMiddleware(client, inspector))
+	// The model that writes file extras while files are processed
+	extrasLLM := llm_utils.DefaultBigLLMModel
+	routes.DefineGlobalRouter(api, extrasLLM) // Pass the subrouter to routes package
 	// Create asynq client
 
 	// Create and start worker
@@ -92,7 +98,7 @@ func main() {
 
 	// Create mux and register handlers
 	asyncq_mux := asynq.NewServeMux()
-	tasks.AsynqHandler(asyncq_mux)
+	tasks.AsynqHandler(asyncq_mux, extrasLLM)
 	// asyncq_mux.Use(tasksMiddleware(client))
 	// asyncq_mux.HandleFunc(tasks.TypeAddFileScraper, tasks.HandleAddFileScraperTask)
 	// asyncq_mux.HandleFunc(tasks.TypeProcessExistingFile, tasks.HandleProcessFileTask)
@@ -9,19 +9,26 @@ import (
 
 	// "kessler/internal/cards"
 	"kessler/internal/dbstore"
+	"kessler/internal/fugusdk"
 	"kessler/internal/health"
+	"kessler/internal/indexalias"
 	"kessler/internal/jobs"
 	"kessler/internal/objects"
+	"kessler/internal/quickwit"
 	"kessler/internal/search"
+	"kessler/internal/search/filter"
 	"kessler/pkg/database"
 	"kessler/pkg/logger"
+	"kessler/pkg/s3utils"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
 	"time"
 
+	migration "kessler/internal/ingest/fugu"
 	indexing "kessler/internal/ingest/indexing"
 
 	ConversationsHandler "kessler/internal/objects/conversations/handler"
@@ -87,6 +94,13 @@ const (
 type AppDependencies struct {
 	DB    dbstore.DBTX
 	Cache cache.CacheController
+	// Fugu is shared by every handler talking to FuguDB, FuguConfig is what it was built from
+	Fugu       *fugusdk.Client
+	FuguConfig fugusdk.Config
+	// Aliases resolves search index aliases to the version they were switched to
+	Aliases *indexalias.Resolver
+	// Search serves the search routes and runs saved searches in the background
+	Search *search.SearchService
 }
 
 func main() {
@@ -122,6 +136,20 @@ func main() {
 		WriteTimeout: adminTimeout,
 	}
 
+	// Background work stops once the server has stopped taking requests
+	backgroundCtx, stopBackground := context.WithCancel(ctx)
+	defer stopBackground()
+	var background sync.WaitGroup
+	background.Add(2)
+	go func() {
+		defer background.Done()
+		search.NewSavedSearchRunner(deps.Search, search.SavedSearchIntervalFromEnv()).Run(backgroundCtx)
+	}()
+	go func() {
+		defer background.Done()
+		deps.Search.RunAnalytics(backgroundCtx)
+	}()
+
 	// Start server in goroutine
 	serverErrors := make(chan error, 1)
 	go func() {
@@ -151,12 +179,50 @@ func main() {
 		}
 	}
 
+	// Let a saved search run that is in flight stop and buffered analytics flush before exiting
+	stopBackground()
+	backgroundDone := make(chan struct{})
+	go func() {
+		background.Wait()
+		close(backgroundDone)
+	}()
+	select {
+	case <-backgroundDone:
+	case <-time.After(shutdownTimeout):
+		log.WarnContext(ctx, "Background work did not stop in time")
+	}
+
 	log.InfoContext(ctx, "Application stopped")
 }
 
 func initDependencies(ctx context.Context) (*AppDependencies, error) {
 	log := logger.FromContext(ctx)
 
+	// Load and validate the FuguDB configuration before anything else so a bad deploy fails fast
+	fuguConfig, err := fugusdk.LoadConfig()
+	if err != nil {
+		return nil, fmt.Errorf("fugu configuration invalid: %w", err)
+	}
+	fuguClient, err := fugusdk.NewClientFromConfig(ctx, fuguConfig)
+	if err != nil {
+		return nil, fmt.Errorf("fugu client initialization failed: %w", err)
+	}
+	log.InfoContext(ctx, "FuguDB client configured",
+		zap.String("url", fuguConfig.URL),
+		zap.String("namespace", fuguConfig.Namespace),
+		zap.Bool("token_set", fuguConfig.Token != ""))
+	healthCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	if err := fuguClient.Health(healthCtx); err != nil {
+		// Search degrades rather than the server refusing to start
+		log.WarnContext(ctx, "FuguDB health check failed", zap.Error(err))
+	}
+
+	// Raw attachments are read through the shared blob store, a misconfigured one fails the deploy too
+	if err := s3utils.NewKeFileManager().Err(); err != nil {
+		return nil, err
+	}
+
 	// Initialize database
 	log.InfoContext(ctx, "Connecting to database")
 	pool, err := database.Init(30)
@@ -187,9 +253,22 @@ func initDependencies(ctx context.Context) (*AppDependencies, error) {
 		}
 	}
 
+	// Quickwit searches follow index alias switches made by versioned rebuilds
+	aliases := indexalias.NewResolver(pool)
+	quickwit.SetIndexResolver(aliases.QuickwitIndex)
+
+	searchService, err := search.NewSearchService(fuguClient, filter.NewService(fuguClient), pool, aliases, fuguConfig.Namespace)
+	if err != nil {
+		return nil, fmt.Errorf("search service initialization failed: %w", err)
+	}
+
 	return &AppDependencies{
-		DB:    pool,
-		Cache: cacheController,
+		DB:         pool,
+		Cache:      cacheController,
+		Fugu:       fuguClient,
+		FuguConfig: fuguConfig,
+		Aliases:    aliases,
+		Search:     searchService,
 	}, nil
 }
 
@@ -277,6 +356,7 @@ func registerPublicRoutes(router *mux.Router, log *otelzap.Logger, deps *AppDepe
 	)
 	rawattachments.DefineRawAttachmentRoutes(
 		publicSubroute.PathPrefix("/raw_attachments").Subrouter(),
+		deps.DB,
 	)
 	OrganizationsHandler.DefineOrganizationRoutes(
 		publicSubroute.PathPrefix("/organizations").Subrouter(),
@@ -295,11 +375,8 @@ func registerPublicRoutes(router *mux.Router, log *otelzap.Logger, deps *AppDepe
 
 	// Search routes - pass DB to search
 	searchSubroute := router.PathPrefix("/search").Subrouter()
-	if err := search.RegisterSearchRoutes(searchSubroute, deps.DB); err != nil {
-		log.ErrorContext(context.Background(), "Failed to register search routes", zap.Error(err))
-	} else {
-		fmt.Println("   ✅ Search routes registered")
-	}
+	search.RegisterSearchRoutes(searchSubroute, deps.Search)
+	fmt.Println("   ✅ Search routes registered")
 	// LEGACY code that enabled search routing on /v2/ before the stripprefix was handled by traefik
 	// v2SearchSubroute := router.PathPrefix("/v2/search").Subrouter()
 	// if err := search.RegisterSearchRoutes(v2SearchSubroute, deps.DB); err != nil {
@@ -308,11 +385,14 @@ func registerPublicRoutes(router *mux.Router, log *otelzap.Logger, deps *AppDepe
 
 	// Object routes (directly access Fugu objects)
 	objectsSubroute := router.PathPrefix("/objects").Subrouter()
-	objects.RegisterObjectRoutes(objectsSubroute)
+	objects.RegisterObjectRoutes(objectsSubroute, deps.Fugu, deps.Aliases, deps.FuguConfig.Namespace)
 	fmt.Println("   ✅ Object routes registered")
 
 	autocomplete.DefineAutocompleteRoutes(
 		router.PathPrefix("/autocomplete").Subrouter(),
+		deps.Fugu,
+		deps.Aliases,
+		deps.FuguConfig.Namespace,
 	)
 	fmt.Println("   ✅ Autocomplete routes registered")
 
@@ -343,7 +423,10 @@ func registerAdminRoutes(router *mux.Router, log *otelzap.Logger, deps *AppDepen
 	adminRoute.Use(timeoutMiddleware(adminTimeout))
 	admin.DefineAdminRoutes(adminRoute, deps.DB) // Assuming admin.DefineAdminRoutes accepts dbstore.DBTX
 	// Admin indexing endpoints
-	indexing.RegisterIndexingRoutes(adminRoute, deps.DB)
+	indexing.RegisterIndexingRoutes(adminRoute, deps.DB, deps.Fugu, deps.FuguConfig.Namespace, deps.Aliases)
+	migration.RegisterMigrationRoutes(adminRoute, deps.Fugu)
+	// Search analytics reports
+	search.RegisterAnalyticsAdminRoutes(adminRoute, deps.DB)
 	fmt.Println("   ✅ Admin routes registered")
 }
 
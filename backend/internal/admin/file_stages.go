@@ -0,0 +1,145 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"kessler/internal/dbstore"
+	"kessler/internal/objects/files"
+	"kessler/internal/objects/files/crud"
+	FileHandler "kessler/internal/objects/files/handler"
+	"kessler/pkg/database"
+	"kessler/pkg/logger"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"go.uber.org/zap"
+)
+
+// HandleFileStageList godoc
+// @Summary List files by their latest processing stage
+// @Description Lists files whose latest stage log entry is at a processing stage, such as those errored at text extraction or still processing hours later, oldest first.
+// @Tags admin
+// @Produce json
+// @Param stage query string true "Processing stage, such as begin_processing or text_completed"
+// @Param state query string false "pending, processing, completed or errored, any when empty"
+// @Param stuck_for query string false "Only files whose latest stage is at least this old, such as 2h"
+// @Param limit query int false "Maximum files returned, default 100, at most 1000"
+// @Success 200 {array} files.FileStageEntry
+// @Failure 400 {string} string "Invalid filter"
+// @Router /admin/file-stages [get]
+func (h *AdminHandler) HandleFileStageList(w http.ResponseWriter, r *http.Request) {
+	filter, err := files.StageFilterFromQuery(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	entries, err := h.FileStageList(r.Context(), filter, time.Now())
+	if err != nil {
+		logger.Error(r.Context(), "could not list file stages", zap.Error(err))
+		http.Error(w, fmt.Sprintf("Error listing file stages: %v", err), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(entries)
+}
+
+func (h *AdminHandler) FileStageList(ctx context.Context, filter files.StageFilter, now time.Time) ([]files.FileStageEntry, error) {
+	q := database.GetQueries(h.db)
+	rows, err := q.StageLogListLatestByStage(ctx, dbstore.StageLogListLatestByStageParams{
+		DocprocStage:  string(filter.Stage),
+		Status:        string(filter.State),
+		UpdatedBefore: pgtype.Timestamptz{Time: now.Add(-filter.StuckFor), Valid: true},
+		MaxRows:       int32(filter.Limit),
+	})
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]files.FileStageEntry, 0, len(rows))
+	for _, row := range rows {
+		var stage files.DocProcStage
+		if err := json.Unmarshal(row.Log, &stage); err != nil {
+			logger.Warn(ctx, "skipping unreadable stage log", zap.String("file_id", row.FileID.String()), zap.Error(err))
+			continue
+		}
+		entries = append(entries, files.FileStageEntry{FileID: row.FileID, Stage: stage, UpdatedAt: row.CreatedAt.Time})
+	}
+	return entries, nil
+}
+
+// HandleFileStageRecord godoc
+// @Summary Record a processing stage for a file
+// @Description Appends a stage to the file's stage log, the ingest workers call this on every stage transition.
+// @Tags admin
+// @Accept json
+// @Param uuid path string true "File ID"
+// @Param body body files.DocProcStage true "Stage reached"
+// @Success 204
+// @Failure 400 {string} string "Invalid file ID or stage"
+// @Router /admin/file-stages/{uuid} [post]
+func (h *AdminHandler) HandleFileStageRecord(w http.ResponseWriter, r *http.Request) {
+	fileID, err := uuid.Parse(mux.Vars(r)["uuid"])
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid file ID: %v", err), http.StatusBadRequest)
+		return
+	}
+	var stage files.DocProcStage
+	if err := json.NewDecoder(r.Body).Decode(&stage); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+	if stage.DocProcStatus.Index() < 0 {
+		http.Error(w, fmt.Sprintf("unknown processing stage %q", stage.DocProcStatus), http.StatusBadRequest)
+		return
+	}
+	if err := crud.FileStatusInsert(r.Context(), *database.GetQueries(h.db), fileID, stage); err != nil {
+		logger.Error(r.Context(), "could not record file stage", zap.String("file_id", fileID.String()), zap.Error(err))
+		http.Error(w, fmt.Sprintf("Error recording stage: %v", err), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// HandleFileForProcessing godoc
+// @Summary Get a file to resume processing
+// @Description Returns the complete file with its attachments, their texts and the latest stage, unprocessed when no stage was recorded.
+// @Tags admin
+// @Produce json
+// @Param uuid path string true "File ID"
+// @Success 200 {object} files.CompleteFileSchema
+// @Failure 404 {string} string "File not found"
+// @Router /admin/file-stages/{uuid}/file [get]
+func (h *AdminHandler) HandleFileForProcessing(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	fileID, err := uuid.Parse(mux.Vars(r)["uuid"])
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid file ID: %v", err), http.StatusBadRequest)
+		return
+	}
+	q := database.GetQueries(h.db)
+	fh := FileHandler.NewFileHandler(h.db)
+	file, err := fh.SemiCompleteFileGetFromUUID(ctx, q, fileID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	file.Stage, err = fh.FileStageGet(ctx, q, fileID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		file.Stage = files.DocProcStage{PGStage: files.PGStagePending, DocProcStatus: files.DocStatusUnprocessed}
+	} else if err != nil {
+		http.Error(w, fmt.Sprintf("Error getting file stage: %v", err), http.StatusInternalServerError)
+		return
+	}
+	file.Attachments, err = FileHandler.AttachmentsCompleteGet(ctx, *q, fileID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error getting attachments: %v", err), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(file)
+}
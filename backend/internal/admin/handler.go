@@ -58,4 +58,17 @@ func DefineAdminRoutes(admin_subrouter *mux.Router, db dbstore.DBTX) {
 		"/file-metadata-match",
 		handler.HandleCheckDocumentMetadata,
 	).Methods(http.MethodPost)
+
+	admin_subrouter.HandleFunc(
+		"/file-stages",
+		handler.HandleFileStageList,
+	).Methods(http.MethodGet)
+	admin_subrouter.HandleFunc(
+		"/file-stages/{uuid}",
+		handler.HandleFileStageRecord,
+	).Methods(http.MethodPost)
+	admin_subrouter.HandleFunc(
+		"/file-stages/{uuid}/file",
+		handler.HandleFileForProcessing,
+	).Methods(http.MethodGet)
 }
@@ -1,118 +1,115 @@
 package autocomplete
 
 import (
-	"context"
 	"encoding/json"
-	"fmt"
-	"kessler/internal/quickwit"
 	"net/http"
+	"strconv"
+
+	"kessler/internal/fugusdk"
+	"kessler/internal/indexalias"
+	"kessler/pkg/logger"
 
-	"github.com/charmbracelet/log"
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
+	"go.uber.org/zap"
 )
 
-func DefineAutocompleteRoutes(autocomplete_subrouter *mux.Router) {
+// legacyLimit is how many dockets and organizations /files-basic returned each
+const legacyLimit = 10
+
+// DefineAutocompleteRoutes mounts the autocomplete endpoints, suggestions come from namespace in Fugu
+func DefineAutocompleteRoutes(autocomplete_subrouter *mux.Router, client *fugusdk.Client, aliases *indexalias.Resolver, namespace string) {
+	h := &Handler{service: NewService(client, aliases, namespace)}
+	autocomplete_subrouter.HandleFunc(
+		"/",
+		h.Autocomplete,
+	).Methods(http.MethodGet)
 	autocomplete_subrouter.HandleFunc(
 		"/files-basic",
-		AutocompleteFileHandler,
+		h.AutocompleteFileHandler,
 	).Methods(http.MethodGet)
 }
 
-func AutocompleteFileHandler(w http.ResponseWriter, r *http.Request) {
+// Handler serves autocomplete requests
+type Handler struct {
+	service *Service
+}
+
+// Autocomplete godoc
+// @Summary Suggest dockets, organizations and documents
+// @Description Completes a partially typed query with prefix and typo tolerant matching against docket names and IDs, organization names and aliases, and document titles. Suggestions are ranked by match quality and popularity.
+// @Param q query string true "Typed query"
+// @Param types query string false "Comma separated types: docket, organization, document (default all)"
+// @Param limit query int false "Suggestions per type (default 5, max 20)"
+// @Success 200 {object} Response
+// @Failure 400 {string} string "Invalid types or limit"
+// @Router /autocomplete/ [get]
+func (h *Handler) Autocomplete(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
-	query := r.URL.Query().Get("query")
-	autocomplete_hits, err := AutoCompleteFileGetResults(query, ctx)
+
+	types, err := ParseTypes(r.URL.Query().Get("types"))
 	if err != nil {
-		log.Error("Error getting autocomplete results", "err", err)
-		http.Error(w, fmt.Sprintf("Error getting autocomplete results: %v", err), http.StatusInternalServerError)
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-	return_bytes, err := json.Marshal(autocomplete_hits)
+	limit := defaultLimit
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		if limit, err = strconv.Atoi(raw); err != nil || limit <= 0 {
+			http.Error(w, "limit must be a positive integer", http.StatusBadRequest)
+			return
+		}
+	}
+
+	response, err := h.service.Suggest(ctx, Request{Query: r.URL.Query().Get("q"), Types: types, Limit: limit})
 	if err != nil {
-		log.Error("Error marshaling autocomplete results", "err", err)
-		http.Error(w, fmt.Sprintf("Error marshaling autocomplete results: %v", err), http.StatusInternalServerError)
+		logger.Error(ctx, "autocomplete failed", zap.Error(err))
+		http.Error(w, "Error getting autocomplete results", http.StatusBadGateway)
 		return
 	}
-	w.Write(return_bytes)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
 }
 
+// AutoCompleteHit is a suggestion in the shape /files-basic has always returned
 type AutoCompleteHit struct {
 	ID   uuid.UUID `json:"uuid"`
 	Name string    `json:"name"`
 	Type string    `json:"type"`
 }
 
-func AutoCompleteFileGetResults(query string, ctx context.Context) ([]AutoCompleteHit, error) {
-	results_each := 10
-	type AsyncResult struct {
-		Results []AutoCompleteHit
-		Err     error
+// AutocompleteFileHandler keeps /files-basic working for existing clients, it suggests dockets as
+// "conversation" and organizations as "organization"
+func (h *Handler) AutocompleteFileHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	query := r.URL.Query().Get("query")
+	response, err := h.service.Suggest(ctx, Request{
+		Query: query,
+		Types: []string{TypeOrganization, TypeDocket},
+		Limit: legacyLimit,
+	})
+	if err != nil {
+		// This endpoint has always answered an empty list rather than an error
+		logger.Error(ctx, "Error getting autocomplete results", zap.Error(err))
+		response = &Response{}
 	}
 
-	convoChan := make(chan AsyncResult, 1)
-	orgChan := make(chan AsyncResult, 1)
-	go func() {
-		search_convo_results := quickwit.ConvoSearchRequestData{
-			Search: quickwit.ConversationSearchSchema{
-				Query: query,
-			},
-			Limit:  results_each,
-			Offset: 0,
-		}
-		results, err := quickwit.SearchConversations(search_convo_results, ctx)
-		if err != nil {
-			log.Error("Encountered Error while getting quickwit autocomplete", "err", err)
-			convoChan <- AsyncResult{Err: err}
-			return
-		}
-		log.Info("Creating Autocomplete Hits")
-		autocomplete_hits := make([]AutoCompleteHit, len(results))
-		for index, result := range results {
-			autocomplete_hits[index] = AutoCompleteHit{
-				ID:   result.ID,
-				Name: result.Name,
-				Type: "conversation",
-			}
-		}
-		convoChan <- AsyncResult{Results: autocomplete_hits}
-		return
-	}()
-
-	go func() {
-		search_convo_results := quickwit.OrgSearchRequestData{
-			Search: quickwit.OrganizationSearchSchema{
-				Query: query,
-			},
-			Limit:  results_each,
-			Offset: 0,
+	autocomplete_hits := make([]AutoCompleteHit, 0, len(response.Suggestions))
+	for _, suggestion := range response.Suggestions {
+		hitType := suggestion.Type
+		if hitType == TypeDocket {
+			hitType = "conversation"
 		}
-		results, err := quickwit.SearchOrganizations(search_convo_results, ctx)
-		if err != nil {
-			log.Error("Encountered Error while getting quickwit autocomplete", "err", err)
-			orgChan <- AsyncResult{Err: err}
-			return
-		}
-		autocomplete_hits := make([]AutoCompleteHit, len(results))
-		log.Info("Creating Autocomplete Hits")
-		for index, result := range results {
-			autocomplete_hits[index] = AutoCompleteHit{
-				ID:   result.ID,
-				Name: result.Name,
-				Type: "organization",
-			}
-		}
-		orgChan <- AsyncResult{Results: autocomplete_hits}
-		return
-	}()
-	orgResults := <-orgChan
-	if orgResults.Err != nil {
-		return []AutoCompleteHit{}, nil
+		autocomplete_hits = append(autocomplete_hits, AutoCompleteHit{
+			ID:   suggestion.ID,
+			Name: suggestion.Name,
+			Type: hitType,
+		})
 	}
-	convoResults := <-convoChan
-	if convoResults.Err != nil {
-		return []AutoCompleteHit{}, nil
+	return_bytes, err := json.Marshal(autocomplete_hits)
+	if err != nil {
+		logger.Error(ctx, "Error marshaling autocomplete results", zap.Error(err))
+		http.Error(w, "Error marshaling autocomplete results", http.StatusInternalServerError)
+		return
 	}
-	log.Info("Got autocomplete results!")
-	return append(orgResults.Results, convoResults.Results...), nil
+	w.Write(return_bytes)
 }
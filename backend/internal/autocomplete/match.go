@@ -0,0 +1,121 @@
+package autocomplete
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"kessler/pkg/util"
+)
+
+const (
+	// Match scores, a whole-name match ranks above a word prefix which ranks above a typo
+	scoreExact      = 1.0
+	scorePrefix     = 0.9
+	scoreCompact    = 0.85
+	scoreWord       = 0.8
+	scoreWordPrefix = 0.7
+	scoreFuzzy      = 0.5
+	// popularityWeight is how much the most popular candidate of a type gains over the least popular one
+	popularityWeight = 0.15
+)
+
+// normalize lower cases text and replaces punctuation with single spaces, so "18-E-0138" reads "18 e 0138"
+func normalize(text string) string {
+	return strings.Join(tokens(text), " ")
+}
+
+// tokens splits text into lower case words of letters and digits
+func tokens(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// maxEdits is the typo allowance for a query word, short words must match exactly
+func maxEdits(word string) int {
+	switch n := len([]rune(word)); {
+	case n <= 3:
+		return 0
+	case n <= 6:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// fuguQuery asks Fugu for every word, words starting with it and words within its typo allowance.
+// Terms are OR'd so a typo in one word does not hide the candidate, matchScore decides what is kept.
+func fuguQuery(words []string) string {
+	var terms []string
+	for _, word := range words {
+		terms = append(terms, word, word+"*")
+		if edits := maxEdits(word); edits > 0 {
+			terms = append(terms, word+"~"+strconv.Itoa(edits))
+		}
+	}
+	return strings.Join(terms, " OR ")
+}
+
+// matchScore rates how well a typed query matches one name of a candidate, 0 when it does not.
+// Every query word has to match a word of the name, the last one may be a prefix as it is still being typed.
+func matchScore(query, name string) float64 {
+	q, n := normalize(query), normalize(name)
+	switch {
+	case q == "" || n == "":
+		return 0
+	case n == q:
+		return scoreExact
+	case strings.HasPrefix(n, q):
+		return scorePrefix
+	case strings.HasPrefix(strings.ReplaceAll(n, " ", ""), strings.ReplaceAll(q, " ", "")):
+		// Docket IDs are typed with or without their separators
+		return scoreCompact
+	}
+
+	queryWords, nameWords := strings.Fields(q), strings.Fields(n)
+	var total float64
+	for _, qw := range queryWords {
+		best := 0.0
+		for _, nw := range nameWords {
+			best = max(best, wordScore(qw, nw))
+		}
+		if best == 0 {
+			return 0
+		}
+		total += best
+	}
+	return total / float64(len(queryWords)) * scoreWord
+}
+
+// wordScore rates one query word against one name word
+func wordScore(query, word string) float64 {
+	switch {
+	case query == word:
+		return 1
+	case strings.HasPrefix(word, query):
+		return scoreWordPrefix / scoreWord
+	}
+	edits := maxEdits(query)
+	if edits == 0 {
+		return 0
+	}
+	// Compare against the start of the word too, a typo in a half typed word should still complete
+	distance := util.EditDistance(query, word)
+	if prefix := []rune(word); len(prefix) > len([]rune(query)) {
+		distance = min(distance, util.EditDistance(query, string(prefix[:len([]rune(query))])))
+	}
+	if distance > edits {
+		return 0
+	}
+	return scoreFuzzy / scoreWord * (1 - float64(distance-1)/float64(edits+1))
+}
+
+// popularityBoost scales popularity logarithmically against the most popular candidate of the same type
+func popularityBoost(popularity, maxPopularity int64) float64 {
+	if popularity <= 0 || maxPopularity <= 0 {
+		return 0
+	}
+	return popularityWeight * math.Log1p(float64(popularity)) / math.Log1p(float64(maxPopularity))
+}
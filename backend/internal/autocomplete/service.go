@@ -0,0 +1,301 @@
+package autocomplete
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"kessler/internal/fugusdk"
+	"kessler/internal/indexalias"
+	"kessler/pkg/logger"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// Suggestion types accepted by the types filter
+const (
+	TypeDocket       = "docket"
+	TypeOrganization = "organization"
+	TypeDocument     = "document"
+)
+
+const (
+	defaultLimit = 5
+	maxLimit     = 20
+	// overfetch is how many Fugu hits are read per suggestion returned, most are dropped by matchScore
+	overfetch = 5
+	// documentOverfetch is larger since each segment of an attachment is its own hit
+	documentOverfetch = 15
+	lookupTimeout     = 3 * time.Second
+)
+
+// ErrInvalidType is returned for a types filter naming an unknown suggestion type
+var ErrInvalidType = errors.New("invalid suggestion type")
+
+// entityTypes maps suggestion types to the entity_type the indexers write
+var entityTypes = map[string]string{
+	TypeDocket:       fugusdk.EntityConversation,
+	TypeOrganization: fugusdk.EntityOrganization,
+	TypeDocument:     fugusdk.EntityAttachment,
+}
+
+// Suggestion is one completion for a typed query
+type Suggestion struct {
+	ID   uuid.UUID `json:"uuid"`
+	Name string    `json:"name"`
+	Type string    `json:"type"`
+	// Matched is the alias or docket ID the query matched, empty when it matched the name
+	Matched     string `json:"matched,omitempty"`
+	DocketGovID string `json:"docket_gov_id,omitempty"`
+	// Popularity is documents filed in a docket or authored by an organization
+	Popularity int64   `json:"popularity"`
+	Score      float64 `json:"score"`
+}
+
+// Request is a typed query with the suggestion types wanted and how many of each
+type Request struct {
+	Query string
+	Types []string
+	Limit int
+}
+
+// Response holds suggestions of every requested type ordered by score
+type Response struct {
+	Query       string       `json:"query"`
+	Suggestions []Suggestion `json:"suggestions"`
+	// Errors names the types whose lookup failed, suggestions of the other types are still returned
+	Errors map[string]string `json:"errors,omitempty"`
+}
+
+// ParseTypes splits a comma separated types filter, empty means every type
+func ParseTypes(raw string) ([]string, error) {
+	if strings.TrimSpace(raw) == "" {
+		return []string{TypeDocket, TypeOrganization, TypeDocument}, nil
+	}
+	var types []string
+	for _, t := range strings.Split(raw, ",") {
+		t = strings.ToLower(strings.TrimSpace(t))
+		if t == "" || slices.Contains(types, t) {
+			continue
+		}
+		if _, ok := entityTypes[t]; !ok {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidType, t)
+		}
+		types = append(types, t)
+	}
+	return types, nil
+}
+
+// Service suggests dockets, organizations and documents from the Fugu index
+type Service struct {
+	client    *fugusdk.Client
+	aliases   *indexalias.Resolver
+	namespace string
+}
+
+// NewService creates a service searching namespace, following its alias once it has been rebuilt
+func NewService(client *fugusdk.Client, aliases *indexalias.Resolver, namespace string) *Service {
+	return &Service{client: client, aliases: aliases, namespace: namespace}
+}
+
+// candidate is a hit reduced to the names a query can match and its popularity
+type candidate struct {
+	suggestion Suggestion
+	names      []string
+}
+
+// Suggest looks up each requested type concurrently and merges the results by score
+func (s *Service) Suggest(ctx context.Context, req Request) (*Response, error) {
+	response := &Response{Query: req.Query, Suggestions: []Suggestion{}}
+	words := tokens(req.Query)
+	if len(words) == 0 {
+		return response, nil
+	}
+	if req.Limit <= 0 {
+		req.Limit = defaultLimit
+	}
+	req.Limit = min(req.Limit, maxLimit)
+
+	namespace, client := s.aliases.FuguTarget(ctx, s.client, s.namespace)
+	query := fuguQuery(words)
+
+	perType := make([][]Suggestion, len(req.Types))
+	errs := make([]error, len(req.Types))
+	var wg sync.WaitGroup
+	for i, suggestionType := range req.Types {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			lookupCtx, cancel := context.WithTimeout(ctx, lookupTimeout)
+			defer cancel()
+			perType[i], errs[i] = s.suggestType(lookupCtx, client, namespace, query, words, suggestionType, req)
+		}()
+	}
+	wg.Wait()
+
+	failed := 0
+	for i, err := range errs {
+		if err != nil {
+			failed++
+			logger.Warn(ctx, "autocomplete lookup failed",
+				zap.String("type", req.Types[i]),
+				zap.Error(err))
+			if response.Errors == nil {
+				response.Errors = make(map[string]string)
+			}
+			response.Errors[req.Types[i]] = err.Error()
+			continue
+		}
+		response.Suggestions = append(response.Suggestions, perType[i]...)
+	}
+	if failed > 0 && failed == len(req.Types) {
+		return nil, fmt.Errorf("autocomplete failed for every type: %w", errors.Join(errs...))
+	}
+
+	// Stable keeps the requested type order for equal scores
+	sort.SliceStable(response.Suggestions, func(i, j int) bool {
+		return response.Suggestions[i].Score > response.Suggestions[j].Score
+	})
+	return response, nil
+}
+
+// suggestType runs one Fugu lookup and ranks its hits against the query
+func (s *Service) suggestType(ctx context.Context, client *fugusdk.Client, namespace, query string, words []string, suggestionType string, req Request) ([]Suggestion, error) {
+	filters := []string{"metadata/entity_type/" + entityTypes[suggestionType]}
+	if namespace != "" {
+		filters = append(filters, "namespace/"+namespace)
+	}
+	page, perPage := 0, min(req.Limit*overfetch, 100)
+	if suggestionType == TypeDocument {
+		perPage = min(req.Limit*documentOverfetch, 100)
+	}
+	fuguQuery := fugusdk.FuguSearchQuery{
+		Query:   query,
+		Filters: &filters,
+		Page:    &fugusdk.Pagination{Page: &page, PerPage: &perPage},
+	}
+
+	resp, err := client.Search(ctx, fuguQuery)
+	var apiErr *fugusdk.APIError
+	if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusBadRequest {
+		// A Fugu build without prefix or fuzzy terms still completes whole words
+		fuguQuery.Query = strings.Join(words, " OR ")
+		resp, err = client.Search(ctx, fuguQuery)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return rank(candidates(resp.Results, suggestionType), req.Query, req.Limit), nil
+}
+
+// candidates decodes hits of one type, keeping the first hit of each entity
+func candidates(results []fugusdk.FuguSearchResult, suggestionType string) []candidate {
+	seen := make(map[uuid.UUID]bool)
+	var found []candidate
+	add := func(c candidate) {
+		if c.suggestion.ID == uuid.Nil || seen[c.suggestion.ID] {
+			return
+		}
+		seen[c.suggestion.ID] = true
+		c.suggestion.Type = suggestionType
+		found = append(found, c)
+	}
+
+	for _, result := range results {
+		switch suggestionType {
+		case TypeDocket:
+			typed, err := fugusdk.DecodeResult[fugusdk.ConversationMetadata](result)
+			if err != nil {
+				continue
+			}
+			name := typed.Metadata.Name
+			if name == "" {
+				name = result.Text
+			}
+			add(candidate{
+				suggestion: Suggestion{
+					ID:          typed.Metadata.ConversationID,
+					Name:        name,
+					DocketGovID: typed.Metadata.DocketGovID,
+					Popularity:  int64(typed.Metadata.TotalDocuments),
+				},
+				names: []string{name, typed.Metadata.DocketGovID},
+			})
+		case TypeOrganization:
+			typed, err := fugusdk.DecodeResult[fugusdk.OrganizationMetadata](result)
+			if err != nil {
+				continue
+			}
+			add(candidate{
+				suggestion: Suggestion{
+					ID:         typed.Metadata.OrganizationID,
+					Name:       typed.Metadata.OrganizationName,
+					Popularity: int64(typed.Metadata.TotalDocumentsAuthored),
+				},
+				names: append([]string{typed.Metadata.OrganizationName}, typed.Metadata.Aliases...),
+			})
+		case TypeDocument:
+			typed, err := fugusdk.DecodeResult[fugusdk.AttachmentMetadata](result)
+			if err != nil {
+				continue
+			}
+			add(candidate{
+				suggestion: Suggestion{
+					ID:          typed.Metadata.AttachmentID,
+					Name:        typed.Metadata.FileName,
+					DocketGovID: typed.Metadata.DocketGovID,
+				},
+				names: []string{typed.Metadata.FileName},
+			})
+		}
+	}
+	return found
+}
+
+// rank scores candidates by their best matching name plus popularity and keeps the top limit
+func rank(found []candidate, query string, limit int) []Suggestion {
+	var maxPopularity int64
+	for _, c := range found {
+		maxPopularity = max(maxPopularity, c.suggestion.Popularity)
+	}
+
+	var ranked []Suggestion
+	for _, c := range found {
+		best, matched := 0.0, ""
+		for i, name := range c.names {
+			if score := matchScore(query, name); score > best {
+				best = score
+				matched = ""
+				if i > 0 {
+					matched = name
+				}
+			}
+		}
+		if best == 0 {
+			continue
+		}
+		suggestion := c.suggestion
+		suggestion.Matched = matched
+		suggestion.Score = best + popularityBoost(suggestion.Popularity, maxPopularity)
+		ranked = append(ranked, suggestion)
+	}
+
+	sort.SliceStable(ranked, func(i, j int) bool {
+		if ranked[i].Score != ranked[j].Score {
+			return ranked[i].Score > ranked[j].Score
+		}
+		return ranked[i].Name < ranked[j].Name
+	})
+	if len(ranked) > limit {
+		ranked = ranked[:limit]
+	}
+	return ranked
+}
@@ -0,0 +1,162 @@
+package autocomplete
+
+import (
+	"context"
+	"kessler/internal/fugusdk"
+	"kessler/internal/fugusdk/fugutest"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestMatchScore(t *testing.T) {
+	tests := []struct {
+		name, query, candidate string
+		want                   bool
+	}{
+		{"exact", "con edison", "Con Edison", true},
+		{"prefix", "con ed", "Con Edison", true},
+		{"word prefix out of order", "edis con", "Con Edison", true},
+		{"typo", "edisn", "Con Edison", true},
+		{"transposed typo while typing", "conslo", "Consolidated Edison", true},
+		{"docket id", "18-E-01", "18-E-0138", true},
+		{"docket id without separators", "18e0138", "18-E-0138", true},
+		{"short words must be exact", "cen", "Con Edison", false},
+		{"every word must match", "con gas", "Con Edison", false},
+		{"unrelated", "national grid", "Con Edison", false},
+	}
+	for _, tt := range tests {
+		if got := matchScore(tt.query, tt.candidate) > 0; got != tt.want {
+			t.Errorf("%s: matchScore(%q, %q) matched = %v, want %v", tt.name, tt.query, tt.candidate, got, tt.want)
+		}
+	}
+
+	if exact, prefix := matchScore("con edison", "Con Edison"), matchScore("con edi", "Con Edison"); exact <= prefix {
+		t.Errorf("exact %v should outrank prefix %v", exact, prefix)
+	}
+	if prefix, fuzzy := matchScore("edis", "Con Edison"), matchScore("edisn", "Con Edison"); prefix <= fuzzy {
+		t.Errorf("word prefix %v should outrank typo %v", prefix, fuzzy)
+	}
+}
+
+func TestParseTypes(t *testing.T) {
+	types, err := ParseTypes(" Docket,organization,docket ")
+	if err != nil || len(types) != 2 || types[0] != TypeDocket || types[1] != TypeOrganization {
+		t.Errorf("unexpected types %v, %v", types, err)
+	}
+	if types, _ := ParseTypes(""); len(types) != 3 {
+		t.Errorf("empty filter should select every type, got %v", types)
+	}
+	if _, err := ParseTypes("docket,filing"); err == nil {
+		t.Error("unknown type should fail")
+	}
+}
+
+func TestSuggest(t *testing.T) {
+	ctx := context.Background()
+	srv := fugutest.NewServer(t)
+	client, err := srv.Client(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conEd, nationalGrid, smallCo := uuid.New(), uuid.New(), uuid.New()
+	docket, file := uuid.New(), uuid.New()
+	srv.Seed(
+		fugusdk.ObjectRecord{
+			ID: conEd.String(), Text: "Consolidated Edison (Con Edison, ConEd)", Namespace: "NYPUC",
+			Facets: []string{"metadata/entity_type/organization"},
+			Metadata: map[string]interface{}{
+				"entity_type": "organization", "organization_id": conEd.String(), "organization_name": "Consolidated Edison",
+				"organization_aliases": []string{"Con Edison", "ConEd"}, "total_documents_authored": 900,
+			},
+		},
+		fugusdk.ObjectRecord{
+			ID: smallCo.String(), Text: "Edison Solar Cooperative", Namespace: "NYPUC",
+			Facets: []string{"metadata/entity_type/organization"},
+			Metadata: map[string]interface{}{
+				"entity_type": "organization", "organization_id": smallCo.String(), "organization_name": "Edison Solar Cooperative",
+				"total_documents_authored": 2,
+			},
+		},
+		fugusdk.ObjectRecord{
+			ID: nationalGrid.String(), Text: "National Grid", Namespace: "NYPUC",
+			Facets: []string{"metadata/entity_type/organization"},
+			Metadata: map[string]interface{}{
+				"entity_type": "organization", "organization_id": nationalGrid.String(), "organization_name": "National Grid",
+			},
+		},
+		fugusdk.ObjectRecord{
+			ID: docket.String(), Text: "Con Edison Electric Rate Case (24-E-0060)", Namespace: "NYPUC",
+			Facets: []string{"metadata/entity_type/conversation"},
+			Metadata: map[string]interface{}{
+				"entity_type": "conversation", "conversation_id": docket.String(), "name": "Con Edison Electric Rate Case",
+				"docket_gov_id": "24-E-0060", "total_documents": 340,
+			},
+		},
+		fugusdk.ObjectRecord{
+			ID: file.String() + "-0", Text: "Direct testimony of the Edison panel", Namespace: "NYPUC",
+			Facets: []string{"metadata/entity_type/attachment"},
+			Metadata: map[string]interface{}{
+				"entity_type": "attachment", "attachment_id": file.String(), "file_name": "Edison Panel Testimony",
+			},
+		},
+		fugusdk.ObjectRecord{
+			ID: file.String() + "-1", Text: "Edison panel exhibits", Namespace: "NYPUC",
+			Facets: []string{"metadata/entity_type/attachment"},
+			Metadata: map[string]interface{}{
+				"entity_type": "attachment", "attachment_id": file.String(), "file_name": "Edison Panel Testimony",
+			},
+		},
+	)
+	svc := NewService(client, nil, "NYPUC")
+
+	t.Run("alias typo ranks by popularity", func(t *testing.T) {
+		resp, err := svc.Suggest(ctx, Request{Query: "edisn", Types: []string{TypeOrganization}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(resp.Suggestions) != 2 || resp.Suggestions[0].ID != conEd || resp.Suggestions[1].ID != smallCo {
+			t.Fatalf("unexpected suggestions %+v", resp.Suggestions)
+		}
+	})
+
+	t.Run("alias match is reported", func(t *testing.T) {
+		resp, err := svc.Suggest(ctx, Request{Query: "coned", Types: []string{TypeOrganization}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(resp.Suggestions) != 1 || resp.Suggestions[0].Matched != "ConEd" {
+			t.Fatalf("unexpected suggestions %+v", resp.Suggestions)
+		}
+	})
+
+	t.Run("docket by id", func(t *testing.T) {
+		resp, err := svc.Suggest(ctx, Request{Query: "24-E-00", Types: []string{TypeDocket}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(resp.Suggestions) != 1 || resp.Suggestions[0].ID != docket || resp.Suggestions[0].Matched != "24-E-0060" {
+			t.Fatalf("unexpected suggestions %+v", resp.Suggestions)
+		}
+	})
+
+	t.Run("documents are deduplicated and limited per type", func(t *testing.T) {
+		resp, err := svc.Suggest(ctx, Request{Query: "edison", Types: []string{TypeDocket, TypeOrganization, TypeDocument}, Limit: 1})
+		if err != nil {
+			t.Fatal(err)
+		}
+		counts := map[string]int{}
+		for _, s := range resp.Suggestions {
+			counts[s.Type]++
+		}
+		if counts[TypeDocket] != 1 || counts[TypeOrganization] != 1 || counts[TypeDocument] != 1 {
+			t.Fatalf("expected one suggestion per type, got %+v", resp.Suggestions)
+		}
+		for i := 1; i < len(resp.Suggestions); i++ {
+			if resp.Suggestions[i].Score > resp.Suggestions[i-1].Score {
+				t.Fatalf("suggestions not ordered by score: %+v", resp.Suggestions)
+			}
+		}
+	})
+}
@@ -257,3 +257,51 @@ func (q *Queries) AttachmentTextListOriginal(ctx context.Context, attachmentID u
 	}
 	return items, nil
 }
+
+const attachmentTextTermsList = `-- name: AttachmentTextTermsList :many
+SELECT
+    word :: VARCHAR AS word,
+    ndoc :: BIGINT AS documents
+FROM
+    ts_stat(
+        'SELECT to_tsvector(''simple'', left(text, 20000)) FROM public.attachment_text_source WHERE is_original_text ORDER BY created_at DESC LIMIT 5000'
+    )
+WHERE
+    ndoc >= $1 :: BIGINT
+    AND length(word) BETWEEN 4 AND 30
+ORDER BY
+    ndoc DESC
+LIMIT
+    $2 :: INTEGER
+`
+
+type AttachmentTextTermsListParams struct {
+	MinDocuments int64
+	MaxTerms     int32
+}
+
+type AttachmentTextTermsListRow struct {
+	Word      string
+	Documents int64
+}
+
+// Words of the most recent original texts and how many texts use them, for spelling suggestions
+func (q *Queries) AttachmentTextTermsList(ctx context.Context, arg AttachmentTextTermsListParams) ([]AttachmentTextTermsListRow, error) {
+	rows, err := q.db.Query(ctx, attachmentTextTermsList, arg.MinDocuments, arg.MaxTerms)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []AttachmentTextTermsListRow
+	for rows.Next() {
+		var i AttachmentTextTermsListRow
+		if err := rows.Scan(&i.Word, &i.Documents); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
@@ -87,6 +87,41 @@ func (q *Queries) AttachmentGetById(ctx context.Context, id uuid.UUID) (Attachme
 	return i, err
 }
 
+const attachmentHashListByIds = `-- name: AttachmentHashListByIds :many
+SELECT
+    id,
+    hash
+FROM
+    public.attachment
+WHERE
+    id = ANY($1::uuid[])
+`
+
+type AttachmentHashListByIdsRow struct {
+	ID   uuid.UUID
+	Hash string
+}
+
+func (q *Queries) AttachmentHashListByIds(ctx context.Context, ids []uuid.UUID) ([]AttachmentHashListByIdsRow, error) {
+	rows, err := q.db.Query(ctx, attachmentHashListByIds, ids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []AttachmentHashListByIdsRow
+	for rows.Next() {
+		var i AttachmentHashListByIdsRow
+		if err := rows.Scan(&i.ID, &i.Hash); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const attachmentListByFileId = `-- name: AttachmentListByFileId :many
 SELECT
     id, file_id, lang, name, extension, hash, mdata, created_at, updated_at
@@ -223,6 +258,7 @@ SELECT
 	a.id AS id,
   a.file_id as file_id,
 	a.name AS name,
+	a.extension AS extension,
 	a.created_at,
 	fm.mdata,
 	ats.text
@@ -241,6 +277,7 @@ type GetAllSearchAttachmentsRow struct {
 	ID        uuid.UUID
 	FileID    uuid.UUID
 	Name      string
+	Extension string
 	CreatedAt pgtype.Timestamptz
 	Mdata     []byte
 	Text      pgtype.Text
@@ -259,6 +296,7 @@ func (q *Queries) GetAllSearchAttachments(ctx context.Context) ([]GetAllSearchAt
 			&i.ID,
 			&i.FileID,
 			&i.Name,
+			&i.Extension,
 			&i.CreatedAt,
 			&i.Mdata,
 			&i.Text,
@@ -757,6 +795,7 @@ SELECT
 	a.id AS id,
   a.file_id as file_id,
 	a.name AS name,
+	a.extension AS extension,
 	a.created_at,
 	fm.mdata,
 	ats.text
@@ -775,6 +814,7 @@ type GetSearchAttachmentByIdRow struct {
 	ID        uuid.UUID
 	FileID    uuid.UUID
 	Name      string
+	Extension string
 	CreatedAt pgtype.Timestamptz
 	Mdata     []byte
 	Text      pgtype.Text
@@ -787,6 +827,7 @@ func (q *Queries) GetSearchAttachmentById(ctx context.Context, id uuid.UUID) (Ge
 		&i.ID,
 		&i.FileID,
 		&i.Name,
+		&i.Extension,
 		&i.CreatedAt,
 		&i.Mdata,
 		&i.Text,
@@ -130,6 +130,41 @@ func (q *Queries) ExtrasFileFetch(ctx context.Context, id uuid.UUID) (FileExtra,
 	return i, err
 }
 
+const extrasFileListByIds = `-- name: ExtrasFileListByIds :many
+SELECT
+    id, isprivate, created_at, updated_at, extra_obj
+FROM
+    public.file_extras
+WHERE
+    id = ANY($1::uuid[])
+`
+
+func (q *Queries) ExtrasFileListByIds(ctx context.Context, ids []uuid.UUID) ([]FileExtra, error) {
+	rows, err := q.db.Query(ctx, extrasFileListByIds, ids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []FileExtra
+	for rows.Next() {
+		var i FileExtra
+		if err := rows.Scan(
+			&i.ID,
+			&i.Isprivate,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.ExtraObj,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const extrasFileUpdate = `-- name: ExtrasFileUpdate :one
 UPDATE
     public.file_extras
@@ -489,6 +524,75 @@ func (q *Queries) StageLogFileGetLatest(ctx context.Context, fileID uuid.UUID) (
 	return i, err
 }
 
+const stageLogListLatestByStage = `-- name: StageLogListLatestByStage :many
+SELECT
+    latest.id,
+    latest.status,
+    latest.log,
+    latest.created_at,
+    latest.file_id
+FROM
+    (
+        SELECT
+            DISTINCT ON (file_id) id, status, log, created_at, file_id
+        FROM
+            public.stage_log
+        ORDER BY
+            file_id,
+            created_at DESC
+    ) latest
+WHERE
+    latest.log ->> 'docproc_stage' = $1 :: text
+    AND (
+        $2 :: text = ''
+        OR latest.status :: text = $2 :: text
+    )
+    AND latest.created_at < $3
+ORDER BY
+    latest.created_at
+LIMIT
+    $4
+`
+
+type StageLogListLatestByStageParams struct {
+	DocprocStage  string
+	Status        string
+	UpdatedBefore pgtype.Timestamptz
+	MaxRows       int32
+}
+
+// files whose latest stage log is at a processing stage, optionally in one state, and older than a cutoff
+func (q *Queries) StageLogListLatestByStage(ctx context.Context, arg StageLogListLatestByStageParams) ([]StageLog, error) {
+	rows, err := q.db.Query(ctx, stageLogListLatestByStage,
+		arg.DocprocStage,
+		arg.Status,
+		arg.UpdatedBefore,
+		arg.MaxRows,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []StageLog
+	for rows.Next() {
+		var i StageLog
+		if err := rows.Scan(
+			&i.ID,
+			&i.Status,
+			&i.Log,
+			&i.CreatedAt,
+			&i.FileID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const updateFile = `-- name: UpdateFile :exec
 UPDATE
     public.file
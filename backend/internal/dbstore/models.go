@@ -320,6 +320,75 @@ type RelationUsersUsergroup struct {
 	CreatedAt   pgtype.Timestamp
 }
 
+type SavedSearch struct {
+	ID            uuid.UUID
+	Name          string
+	Query         string
+	Namespace     string
+	Filters       []byte
+	SearchRequest []byte
+	RunCount      int32
+	LastRunAt     pgtype.Timestamptz
+	LastRunError  string
+	CreatedAt     pgtype.Timestamptz
+	UpdatedAt     pgtype.Timestamptz
+	BaselineDone  bool
+}
+
+type SavedSearchResult struct {
+	SavedSearchID uuid.UUID
+	DocumentID    uuid.UUID
+	Baseline      bool
+	FirstSeenAt   pgtype.Timestamptz
+}
+
+type SearchClick struct {
+	ID        uuid.UUID
+	SearchID  uuid.UUID
+	CardID    string
+	CardType  string
+	Position  int32
+	CreatedAt pgtype.Timestamptz
+}
+
+type SearchIndexAlias struct {
+	Backend   string
+	Alias     string
+	VersionID uuid.UUID
+	Target    string
+	UpdatedAt pgtype.Timestamptz
+}
+
+type SearchIndexVersion struct {
+	ID             uuid.UUID
+	Backend        string
+	Alias          string
+	Version        int32
+	Target         string
+	Status         string
+	StatusMessage  string
+	ExpectedCounts []byte
+	IndexedCounts  []byte
+	CreatedAt      pgtype.Timestamptz
+	UpdatedAt      pgtype.Timestamptz
+	ActivatedAt    pgtype.Timestamptz
+	RetiredAt      pgtype.Timestamptz
+}
+
+type SearchQueryLog struct {
+	ID              uuid.UUID
+	Query           string
+	NormalizedQuery string
+	Filters         []byte
+	Namespace       string
+	ResultCount     int32
+	ResultOffset    int32
+	ReturnedCount   int32
+	LatencyMs       int32
+	Suggestion      string
+	CreatedAt       pgtype.Timestamptz
+}
+
 type StageLog struct {
 	ID        uuid.UUID
 	Status    NullStageState
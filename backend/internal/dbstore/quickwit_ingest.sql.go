@@ -95,6 +95,22 @@ func (q *Queries) ConversationCompleteQuickwitListGet(ctx context.Context) ([]Co
 	return items, nil
 }
 
+const conversationTotalDocumentsGet = `-- name: ConversationTotalDocumentsGet :one
+SELECT
+    COUNT(public.docket_documents.file_id) AS total_documents
+FROM
+    public.docket_documents
+WHERE
+    public.docket_documents.conversation_uuid = $1
+`
+
+func (q *Queries) ConversationTotalDocumentsGet(ctx context.Context, conversationUuid uuid.UUID) (int64, error) {
+	row := q.db.QueryRow(ctx, conversationTotalDocumentsGet, conversationUuid)
+	var total_documents int64
+	err := row.Scan(&total_documents)
+	return total_documents, err
+}
+
 const organizationCompleteQuickwitListGet = `-- name: OrganizationCompleteQuickwitListGet :many
 SELECT
     public.organization.id,
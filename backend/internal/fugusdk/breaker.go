@@ -0,0 +1,185 @@
+// breaker.go
+package fugusdk
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultBreakerFailureThreshold is how many consecutive failed requests open the breaker
+	DefaultBreakerFailureThreshold = 5
+	// DefaultBreakerOpenTimeout is how long an open breaker fails fast before letting a probe through
+	DefaultBreakerOpenTimeout = 30 * time.Second
+)
+
+// ErrCircuitOpen is returned without contacting Fugu while the circuit breaker is open
+var ErrCircuitOpen = errors.New("fugu circuit breaker is open")
+
+// BreakerState is the state of a circuit breaker
+type BreakerState string
+
+const (
+	// BreakerClosed lets every request through
+	BreakerClosed BreakerState = "closed"
+	// BreakerOpen fails every request fast until the open timeout passes
+	BreakerOpen BreakerState = "open"
+	// BreakerHalfOpen lets a single probe request through to decide whether to close again
+	BreakerHalfOpen BreakerState = "half_open"
+)
+
+// BreakerSnapshot is a point in time view of a circuit breaker, suitable for health responses
+type BreakerSnapshot struct {
+	State               BreakerState `json:"state"`
+	ConsecutiveFailures int          `json:"consecutive_failures"`
+	OpenedAt            *time.Time   `json:"opened_at,omitempty"`
+	RetryAt             *time.Time   `json:"retry_at,omitempty"`
+	LastError           string       `json:"last_error,omitempty"`
+}
+
+// CircuitBreaker stops requests to Fugu after repeated failures so callers fail fast while it is down.
+// A nil breaker lets every request through.
+type CircuitBreaker struct {
+	mu               sync.Mutex
+	failureThreshold int
+	openTimeout      time.Duration
+	now              func() time.Time
+
+	state         BreakerState
+	failures      int
+	openedAt      time.Time
+	lastError     string
+	probeInFlight bool
+}
+
+// NewCircuitBreaker creates a closed breaker that opens after failureThreshold consecutive failures
+func NewCircuitBreaker(failureThreshold int, openTimeout time.Duration) *CircuitBreaker {
+	if failureThreshold <= 0 {
+		failureThreshold = DefaultBreakerFailureThreshold
+	}
+	if openTimeout <= 0 {
+		openTimeout = DefaultBreakerOpenTimeout
+	}
+	return &CircuitBreaker{
+		failureThreshold: failureThreshold,
+		openTimeout:      openTimeout,
+		now:              time.Now,
+		state:            BreakerClosed,
+	}
+}
+
+var (
+	breakersMu sync.Mutex
+	breakers   = make(map[string]*CircuitBreaker)
+)
+
+// BreakerFor returns the breaker shared by every client of the Fugu host a base URL points at.
+// Clients are usually created per request, so breaker state has to outlive a single client, and base
+// URLs differing only in path or trailing slash reach the same server.
+func BreakerFor(baseURL string) *CircuitBreaker {
+	key := baseURL
+	if u, err := url.Parse(baseURL); err == nil && u.Host != "" {
+		key = strings.ToLower(u.Host)
+	}
+
+	breakersMu.Lock()
+	defer breakersMu.Unlock()
+	if b, ok := breakers[key]; ok {
+		return b
+	}
+	b := NewCircuitBreaker(DefaultBreakerFailureThreshold, DefaultBreakerOpenTimeout)
+	breakers[key] = b
+	return b
+}
+
+// allow reports whether a request may be sent, moving an expired open breaker to half open
+func (b *CircuitBreaker) allow() error {
+	if b == nil {
+		return nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case BreakerOpen:
+		if b.now().Sub(b.openedAt) < b.openTimeout {
+			return ErrCircuitOpen
+		}
+		b.state = BreakerHalfOpen
+		b.probeInFlight = true
+		return nil
+	case BreakerHalfOpen:
+		if b.probeInFlight {
+			return ErrCircuitOpen
+		}
+		b.probeInFlight = true
+		return nil
+	default:
+		return nil
+	}
+}
+
+// recordSuccess closes the breaker, Fugu answered
+func (b *CircuitBreaker) recordSuccess() {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.state = BreakerClosed
+	b.failures = 0
+	b.probeInFlight = false
+}
+
+// recordFailure counts a failed request, opening the breaker at the threshold or when a probe fails
+func (b *CircuitBreaker) recordFailure(err error) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures++
+	if err != nil {
+		b.lastError = err.Error()
+	}
+	if b.state == BreakerHalfOpen || b.failures >= b.failureThreshold {
+		b.state = BreakerOpen
+		b.openedAt = b.now()
+	}
+	b.probeInFlight = false
+}
+
+// release ends a request that neither succeeded nor failed, such as one cancelled by the caller
+func (b *CircuitBreaker) release() {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probeInFlight = false
+}
+
+// Snapshot returns the current breaker state
+func (b *CircuitBreaker) Snapshot() BreakerSnapshot {
+	if b == nil {
+		return BreakerSnapshot{State: BreakerClosed}
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	snapshot := BreakerSnapshot{
+		State:               b.state,
+		ConsecutiveFailures: b.failures,
+		LastError:           b.lastError,
+	}
+	if b.state != BreakerClosed {
+		openedAt := b.openedAt
+		retryAt := openedAt.Add(b.openTimeout)
+		snapshot.OpenedAt = &openedAt
+		snapshot.RetryAt = &retryAt
+	}
+	return snapshot
+}
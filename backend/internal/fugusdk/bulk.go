@@ -0,0 +1,279 @@
+// bulk.go
+package fugusdk
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"iter"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultBulkBatchObjects is how many objects a bulk request holds at most
+	DefaultBulkBatchObjects = 100
+	// DefaultBulkBatchBytes keeps bulk request bodies comfortably under MaxRequestBodySize
+	DefaultBulkBatchBytes = 8 * 1024 * 1024
+	// DefaultBulkConcurrency is how many bulk requests are in flight at once
+	DefaultBulkConcurrency = 4
+	// DefaultBulkRetries is how many times a failed batch is resent before it is split or given up on
+	DefaultBulkRetries = 3
+
+	// maxBulkBreakerWaits bounds how many circuit breaker cooldowns a batch waits out
+	maxBulkBreakerWaits = 10
+	// bulkEnvelopeBytes covers the JSON wrapping a batch of objects
+	bulkEnvelopeBytes = 64
+)
+
+// ErrNotUpserted is reported for objects Fugu accepted in a batch but did not count as upserted
+var ErrNotUpserted = errors.New("fugu did not upsert every object in the batch")
+
+// BulkSendFunc sends one batch of objects to Fugu
+type BulkSendFunc func(ctx context.Context, objects []ObjectRecord) (*SanitizedResponse, error)
+
+// BulkResult is the outcome for a single object, Err is nil when it was indexed
+type BulkResult struct {
+	ID  string
+	Err error
+}
+
+// BulkStats totals the outcome of a bulk run
+type BulkStats struct {
+	Succeeded int      `json:"succeeded"`
+	Failed    int      `json:"failed"`
+	FailedIDs []string `json:"failed_ids,omitempty"`
+	Requests  int      `json:"requests"`
+	Retries   int      `json:"retries"`
+}
+
+// BulkIndexerConfig configures a BulkIndexer, zero values use the defaults
+type BulkIndexerConfig struct {
+	// MaxBatchObjects and MaxBatchBytes bound each request, whichever is reached first closes a batch
+	MaxBatchObjects int
+	MaxBatchBytes   int
+	// Concurrency is the number of batches sent in parallel
+	Concurrency int
+	// MaxRetries is how many times a failed batch is resent
+	MaxRetries int
+	// Send delivers a batch, BatchUpsertObjects without the client's own retries is used when nil
+	Send BulkSendFunc
+	// OnResult is called once per object as its batch completes, calls are serialised
+	OnResult func(BulkResult)
+}
+
+// BulkIndexer packs object records into size bounded requests and sends them with bounded concurrency.
+// Objects that fail validation are reported without being sent, batches rejected by Fugu are split
+// so one bad object does not fail its neighbours. An indexer runs one Index call at a time.
+type BulkIndexer struct {
+	client *Client
+	cfg    BulkIndexerConfig
+
+	mu    sync.Mutex
+	stats BulkStats
+	// resultMu serialises OnResult calls without holding mu, so callbacks may take their time
+	resultMu sync.Mutex
+}
+
+// NewBulkIndexer creates a bulk indexer that sends through the client
+func (c *Client) NewBulkIndexer(cfg BulkIndexerConfig) *BulkIndexer {
+	if cfg.MaxBatchObjects <= 0 || cfg.MaxBatchObjects > MaxBatchSize {
+		cfg.MaxBatchObjects = DefaultBulkBatchObjects
+	}
+	if cfg.MaxBatchBytes <= 0 || cfg.MaxBatchBytes > MaxRequestBodySize {
+		cfg.MaxBatchBytes = DefaultBulkBatchBytes
+	}
+	if cfg.Concurrency <= 0 {
+		cfg.Concurrency = DefaultBulkConcurrency
+	}
+	if cfg.MaxRetries < 0 {
+		cfg.MaxRetries = 0
+	} else if cfg.MaxRetries == 0 {
+		cfg.MaxRetries = DefaultBulkRetries
+	}
+	if cfg.Send == nil {
+		// The indexer retries, splits and waits out the breaker itself, client retries would multiply its attempts
+		cfg.Send = c.withoutRetries().BatchUpsertObjects
+	}
+	return &BulkIndexer{client: c, cfg: cfg}
+}
+
+// IndexSlice indexes a slice of objects
+func (b *BulkIndexer) IndexSlice(ctx context.Context, objects []ObjectRecord) (BulkStats, error) {
+	return b.Index(ctx, slices.Values(objects))
+}
+
+// IndexChan indexes objects until the channel is closed
+func (b *BulkIndexer) IndexChan(ctx context.Context, objects <-chan ObjectRecord) (BulkStats, error) {
+	return b.Index(ctx, func(yield func(ObjectRecord) bool) {
+		for obj := range objects {
+			if !yield(obj) {
+				return
+			}
+		}
+	})
+}
+
+// Index packs and sends every object from the iterator, returning totals once all batches finish.
+// Per object failures are reported through OnResult and the stats, the error is only set when ctx ends the run.
+func (b *BulkIndexer) Index(ctx context.Context, objects iter.Seq[ObjectRecord]) (BulkStats, error) {
+	b.mu.Lock()
+	b.stats = BulkStats{}
+	b.mu.Unlock()
+
+	batches := make(chan []ObjectRecord)
+	var wg sync.WaitGroup
+	for range b.cfg.Concurrency {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for batch := range batches {
+				b.sendBatch(ctx, batch)
+			}
+		}()
+	}
+
+	var batch []ObjectRecord
+	batchBytes := bulkEnvelopeBytes
+	flush := func() {
+		if len(batch) > 0 {
+			select {
+			case batches <- batch:
+			case <-ctx.Done():
+				b.report(batch, ctx.Err())
+			}
+		}
+		batch = nil
+		batchBytes = bulkEnvelopeBytes
+	}
+
+	for obj := range objects {
+		if ctx.Err() != nil {
+			b.report([]ObjectRecord{obj}, ctx.Err())
+			continue
+		}
+		if err := obj.Validate(b.client.sanitizer); err != nil {
+			b.report([]ObjectRecord{obj}, fmt.Errorf("validation failed: %w", err))
+			continue
+		}
+		encoded, err := json.Marshal(obj)
+		if err != nil {
+			b.report([]ObjectRecord{obj}, fmt.Errorf("encoding object: %w", err))
+			continue
+		}
+		size := len(encoded) + 1
+		if size+bulkEnvelopeBytes > b.cfg.MaxBatchBytes {
+			b.report([]ObjectRecord{obj}, fmt.Errorf("object is %d bytes, larger than a bulk request allows", size))
+			continue
+		}
+		if len(batch) == b.cfg.MaxBatchObjects || batchBytes+size > b.cfg.MaxBatchBytes {
+			flush()
+		}
+		batch = append(batch, obj)
+		batchBytes += size
+	}
+	flush()
+	close(batches)
+	wg.Wait()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.stats, ctx.Err()
+}
+
+// sendBatch sends a batch with retries, splitting it when Fugu rejects the request itself or upserts
+// fewer objects than it was sent, until the objects at fault are isolated
+func (b *BulkIndexer) sendBatch(ctx context.Context, batch []ObjectRecord) {
+	var err error
+	breakerWaits := 0
+	for attempt := 0; attempt <= b.cfg.MaxRetries; attempt++ {
+		if attempt > 0 {
+			b.count(func(s *BulkStats) { s.Retries++ })
+		}
+		b.count(func(s *BulkStats) { s.Requests++ })
+		var resp *SanitizedResponse
+		if resp, err = b.cfg.Send(ctx, batch); err == nil {
+			if err = upsertShortfall(resp, len(batch)); err == nil {
+				b.report(batch, nil)
+				return
+			}
+		}
+		if ctx.Err() != nil || rejectedRequest(err) || errors.Is(err, ErrNotUpserted) {
+			break
+		}
+
+		wait := b.client.backoff(attempt + 1)
+		// An open breaker means Fugu is down, wait for it to allow a probe without spending an attempt
+		if errors.Is(err, ErrCircuitOpen) && breakerWaits < maxBulkBreakerWaits {
+			breakerWaits++
+			attempt--
+			wait = time.Second
+			if retryAt := b.client.CircuitBreaker().Snapshot().RetryAt; retryAt != nil {
+				wait = max(time.Until(*retryAt), time.Second)
+			}
+		}
+		select {
+		case <-ctx.Done():
+			b.report(batch, ctx.Err())
+			return
+		case <-time.After(wait):
+		}
+	}
+
+	if len(batch) > 1 && (rejectedRequest(err) || errors.Is(err, ErrNotUpserted)) {
+		half := len(batch) / 2
+		b.sendBatch(ctx, batch[:half])
+		b.sendBatch(ctx, batch[half:])
+		return
+	}
+	b.report(batch, err)
+}
+
+// rejectedRequest reports whether Fugu refused the request content, resending it unchanged cannot succeed
+func rejectedRequest(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) &&
+		apiErr.StatusCode >= 400 && apiErr.StatusCode < 500 &&
+		apiErr.StatusCode != http.StatusTooManyRequests
+}
+
+// upsertShortfall returns an error when Fugu reports upserting fewer objects than it was sent.
+// Responses without a count are taken at their word.
+func upsertShortfall(resp *SanitizedResponse, sent int) error {
+	if resp == nil || resp.UpsertedCount == nil || *resp.UpsertedCount >= sent {
+		return nil
+	}
+	return fmt.Errorf("%w: %d of %d upserted", ErrNotUpserted, *resp.UpsertedCount, sent)
+}
+
+// report records the outcome for every object in a batch, then passes each to OnResult
+func (b *BulkIndexer) report(batch []ObjectRecord, err error) {
+	b.mu.Lock()
+	for _, obj := range batch {
+		if err == nil {
+			b.stats.Succeeded++
+		} else {
+			b.stats.Failed++
+			b.stats.FailedIDs = append(b.stats.FailedIDs, obj.ID)
+		}
+	}
+	b.mu.Unlock()
+
+	if b.cfg.OnResult == nil {
+		return
+	}
+	b.resultMu.Lock()
+	defer b.resultMu.Unlock()
+	for _, obj := range batch {
+		b.cfg.OnResult(BulkResult{ID: obj.ID, Err: err})
+	}
+}
+
+func (b *BulkIndexer) count(update func(*BulkStats)) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	update(&b.stats)
+}
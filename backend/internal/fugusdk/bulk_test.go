@@ -0,0 +1,135 @@
+package fugusdk
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+func bulkRecords(n int) []ObjectRecord {
+	records := make([]ObjectRecord, n)
+	for i := range records {
+		records[i] = ObjectRecord{ID: fmt.Sprintf("obj-%02d", i), Text: "filing text"}
+	}
+	return records
+}
+
+func TestBulkIndexer(t *testing.T) {
+	client, err := BuildClient(context.Background(), "http://fugu.invalid", WithRetry(0, time.Millisecond), WithCircuitBreaker(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("packs by object count", func(t *testing.T) {
+		var mu sync.Mutex
+		var sizes []int
+		indexer := client.NewBulkIndexer(BulkIndexerConfig{
+			MaxBatchObjects: 10,
+			Concurrency:     2,
+			Send: func(ctx context.Context, objects []ObjectRecord) (*SanitizedResponse, error) {
+				mu.Lock()
+				defer mu.Unlock()
+				sizes = append(sizes, len(objects))
+				return &SanitizedResponse{}, nil
+			},
+		})
+		stats, err := indexer.IndexSlice(context.Background(), bulkRecords(25))
+		if err != nil {
+			t.Fatal(err)
+		}
+		slices.Sort(sizes)
+		if !slices.Equal(sizes, []int{5, 10, 10}) || stats.Succeeded != 25 || stats.Requests != 3 {
+			t.Errorf("unexpected batches %v, stats %+v", sizes, stats)
+		}
+	})
+
+	t.Run("splits rejected batches to isolate bad objects", func(t *testing.T) {
+		var results []BulkResult
+		records := bulkRecords(8)
+		records[5].ID = "bad"
+		indexer := client.NewBulkIndexer(BulkIndexerConfig{
+			Concurrency: 1,
+			Send: func(ctx context.Context, objects []ObjectRecord) (*SanitizedResponse, error) {
+				for _, obj := range objects {
+					if obj.ID == "bad" {
+						return nil, &APIError{StatusCode: http.StatusUnprocessableEntity, Message: "bad object"}
+					}
+				}
+				return &SanitizedResponse{}, nil
+			},
+			OnResult: func(result BulkResult) { results = append(results, result) },
+		})
+		stats, err := indexer.IndexSlice(context.Background(), records)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stats.Succeeded != 7 || stats.Failed != 1 || !slices.Equal(stats.FailedIDs, []string{"bad"}) {
+			t.Errorf("unexpected stats %+v", stats)
+		}
+		if len(results) != 8 {
+			t.Errorf("expected a result per object, got %d", len(results))
+		}
+	})
+
+	t.Run("retries transient failures", func(t *testing.T) {
+		calls := 0
+		indexer := client.NewBulkIndexer(BulkIndexerConfig{
+			Concurrency: 1,
+			Send: func(ctx context.Context, objects []ObjectRecord) (*SanitizedResponse, error) {
+				calls++
+				if calls == 1 {
+					return nil, &APIError{StatusCode: http.StatusServiceUnavailable, Message: "restarting"}
+				}
+				return &SanitizedResponse{}, nil
+			},
+		})
+		stats, err := indexer.IndexSlice(context.Background(), bulkRecords(3))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stats.Succeeded != 3 || stats.Retries != 1 || stats.Requests != 2 {
+			t.Errorf("unexpected stats %+v", stats)
+		}
+	})
+
+	t.Run("reports invalid objects without sending them", func(t *testing.T) {
+		records := bulkRecords(2)
+		records[1].Text = ""
+		sent := 0
+		indexer := client.NewBulkIndexer(BulkIndexerConfig{
+			Send: func(ctx context.Context, objects []ObjectRecord) (*SanitizedResponse, error) {
+				sent += len(objects)
+				return &SanitizedResponse{}, nil
+			},
+		})
+		stats, err := indexer.IndexSlice(context.Background(), records)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if sent != 1 || stats.Failed != 1 || stats.FailedIDs[0] != "obj-01" {
+			t.Errorf("sent %d, stats %+v", sent, stats)
+		}
+	})
+
+	t.Run("calls OnResult without holding the stats lock", func(t *testing.T) {
+		var indexer *BulkIndexer
+		results := 0
+		indexer = client.NewBulkIndexer(BulkIndexerConfig{
+			Send: func(ctx context.Context, objects []ObjectRecord) (*SanitizedResponse, error) {
+				return &SanitizedResponse{}, nil
+			},
+			// Touching the stats from the callback deadlocks if report still holds the lock
+			OnResult: func(BulkResult) { indexer.count(func(*BulkStats) { results++ }) },
+		})
+		if _, err := indexer.IndexSlice(context.Background(), bulkRecords(3)); err != nil {
+			t.Fatal(err)
+		}
+		if results != 3 {
+			t.Errorf("expected 3 results, got %d", results)
+		}
+	})
+}
@@ -0,0 +1,175 @@
+// capabilities.go
+package fugusdk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ErrUnsupported is returned without searching when a query needs a feature the Fugu server lacks
+var ErrUnsupported = errors.New("not supported by this fugu server")
+
+// capabilityProbeSize is how many hits the plain probe reads while looking for a metadata value to range over
+const capabilityProbeSize = 10
+
+// Capabilities are the optional search features of a Fugu server. Older servers ignore sort and
+// search_after and read a range filter as a literal facet, so their results look valid but are wrong.
+type Capabilities struct {
+	// Sort is support for the sort and search_after query fields
+	Sort bool `json:"sort"`
+	// RangeFilters is support for "{field}:[{from} TO {to}]" filters built by RangeFilter
+	RangeFilters bool `json:"range_filters"`
+}
+
+// capabilityCache holds the detected capabilities, shared by every copy of a client
+type capabilityCache struct {
+	mu    sync.Mutex
+	known *Capabilities
+}
+
+// WithCapabilities skips detection for a server whose capabilities are already known
+func WithCapabilities(capabilities Capabilities) ClientOption {
+	return func(c *Client) error {
+		c.capabilities = &capabilityCache{known: &capabilities}
+		return nil
+	}
+}
+
+// Capabilities reports which optional search features the server supports, probing it on first use.
+// An empty index cannot show whether a query was honoured, so every feature is assumed supported and
+// the server is probed again on the next call.
+func (c *Client) Capabilities(ctx context.Context) (Capabilities, error) {
+	c.capabilities.mu.Lock()
+	defer c.capabilities.mu.Unlock()
+	if c.capabilities.known != nil {
+		return *c.capabilities.known, nil
+	}
+
+	capabilities, determined, err := c.detectCapabilities(ctx)
+	if err != nil {
+		return Capabilities{}, err
+	}
+	if determined {
+		c.capabilities.known = &capabilities
+	}
+	return capabilities, nil
+}
+
+// detectCapabilities sends probe searches whose results differ depending on whether a feature was honoured
+func (c *Client) detectCapabilities(ctx context.Context) (Capabilities, bool, error) {
+	perPage := capabilityProbeSize
+	plain, err := c.probeSearch(ctx, FuguSearchQuery{Query: "*", Page: &Pagination{PerPage: &perPage}})
+	if err != nil {
+		return Capabilities{}, false, err
+	}
+	field, value, found := probeRangeField(plain.Results)
+	if len(plain.Results) == 0 || !found {
+		return Capabilities{Sort: true, RangeFilters: true}, false, nil
+	}
+
+	var capabilities Capabilities
+	// Nothing sorts after the last code point, a server that ignores search_after still returns hits
+	after := []string{"\U0010FFFF"}
+	sorted, err := c.probeSearch(ctx, FuguSearchQuery{
+		Query:       "*",
+		Page:        &Pagination{PerPage: &perPage},
+		Sort:        &[]SortField{{Field: "id", Order: SortAscending}},
+		SearchAfter: &after,
+	})
+	switch {
+	case rejected(err):
+	case err != nil:
+		return Capabilities{}, false, err
+	default:
+		capabilities.Sort = len(sorted.Results) == 0
+	}
+
+	// A range over a value a hit holds matches it, read as a facet it matches nothing
+	filters := []string{RangeFilter(field, value, value)}
+	ranged, err := c.probeSearch(ctx, FuguSearchQuery{Query: "*", Filters: &filters, Page: &Pagination{PerPage: &perPage}})
+	switch {
+	case rejected(err):
+	case err != nil:
+		return Capabilities{}, false, err
+	default:
+		capabilities.RangeFilters = len(ranged.Results) > 0
+	}
+	return capabilities, true, nil
+}
+
+// probeSearch searches without the checks and ID prefixing of Search
+func (c *Client) probeSearch(ctx context.Context, query FuguSearchQuery) (*SanitizedResponse, error) {
+	resp, err := c.makeRequest(ctx, "POST", "/search", query)
+	if err != nil {
+		return nil, err
+	}
+	var result SanitizedResponse
+	if err := c.handleResponse(resp, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// rejected reports whether a probe failed because the server refused the query itself
+func rejected(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) &&
+		apiErr.StatusCode >= 400 && apiErr.StatusCode < 500 && apiErr.StatusCode != http.StatusTooManyRequests
+}
+
+// probeRangeField picks a non-empty string metadata value from the hits, keys are tried in sorted order
+func probeRangeField(hits []FuguSearchResult) (field, value string, found bool) {
+	for _, hit := range hits {
+		keys := make([]string, 0, len(hit.Metadata))
+		for key := range hit.Metadata {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			if v, ok := hit.Metadata[key].(string); ok && v != "" && !strings.ContainsAny(v, "[] ") {
+				return "metadata/" + key, v, true
+			}
+		}
+	}
+	return "", "", false
+}
+
+// checkCapabilities returns ErrUnsupported when a query needs a feature the server lacks
+func (c *Client) checkCapabilities(ctx context.Context, query FuguSearchQuery) error {
+	needsSort := (query.Sort != nil && len(*query.Sort) > 0) || query.SearchAfter != nil
+	needsRange := false
+	if query.Filters != nil {
+		for _, filter := range *query.Filters {
+			if isRangeFilter(filter) {
+				needsRange = true
+				break
+			}
+		}
+	}
+	if !needsSort && !needsRange {
+		return nil
+	}
+
+	capabilities, err := c.Capabilities(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to detect fugu capabilities: %w", err)
+	}
+	if needsSort && !capabilities.Sort {
+		return fmt.Errorf("sort and search_after: %w", ErrUnsupported)
+	}
+	if needsRange && !capabilities.RangeFilters {
+		return fmt.Errorf("range filters: %w", ErrUnsupported)
+	}
+	return nil
+}
+
+// isRangeFilter reports whether a filter has the form built by RangeFilter
+func isRangeFilter(filter string) bool {
+	_, bounds, found := strings.Cut(filter, ":[")
+	return found && strings.HasSuffix(bounds, "]") && strings.Contains(bounds, " TO ")
+}
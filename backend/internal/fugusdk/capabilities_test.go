@@ -0,0 +1,96 @@
+package fugusdk_test
+
+import (
+	"context"
+	"errors"
+	"kessler/internal/fugusdk"
+	"kessler/internal/fugusdk/fugutest"
+	"testing"
+)
+
+// probingClient builds a client for the fake server that detects capabilities itself
+func probingClient(t *testing.T, srv *fugutest.Server) *fugusdk.Client {
+	t.Helper()
+	client, err := fugusdk.BuildClient(context.Background(), srv.URL,
+		fugusdk.WithRetry(0, 0),
+		fugusdk.WithRateLimit(1000, 100),
+		fugusdk.WithCircuitBreaker(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+func TestCapabilitiesDetection(t *testing.T) {
+	tests := map[string]struct {
+		legacy bool
+		want   fugusdk.Capabilities
+	}{
+		"current server": {false, fugusdk.Capabilities{Sort: true, RangeFilters: true}},
+		"legacy server":  {true, fugusdk.Capabilities{}},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			srv := fugutest.NewServer(t)
+			if tt.legacy {
+				srv.Legacy()
+			}
+			srv.Seed(fugusdk.ObjectRecord{ID: "a", Text: "rate case", Metadata: map[string]interface{}{"date_iso": "2024-01-02"}})
+			client := probingClient(t, srv)
+
+			ctx := context.Background()
+			got, err := client.Capabilities(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("Capabilities() = %+v, want %+v", got, tt.want)
+			}
+			probes := len(srv.Searches())
+			if _, err := client.WithIDPrefix("v1.").Capabilities(ctx); err != nil {
+				t.Fatal(err)
+			}
+			if len(srv.Searches()) != probes {
+				t.Error("a copy of the client probed again instead of sharing the detected capabilities")
+			}
+		})
+	}
+}
+
+func TestCapabilitiesEmptyIndexProbedAgain(t *testing.T) {
+	srv := fugutest.NewServer(t)
+	srv.Legacy()
+	client := probingClient(t, srv)
+
+	ctx := context.Background()
+	if got, err := client.Capabilities(ctx); err != nil || !got.Sort || !got.RangeFilters {
+		t.Fatalf("an empty index assumes every feature, got %+v, %v", got, err)
+	}
+	srv.Seed(fugusdk.ObjectRecord{ID: "a", Text: "rate case", Metadata: map[string]interface{}{"date_iso": "2024-01-02"}})
+	if got, err := client.Capabilities(ctx); err != nil || got.Sort || got.RangeFilters {
+		t.Errorf("once documents exist the legacy server is detected, got %+v, %v", got, err)
+	}
+}
+
+func TestSearchRejectsUnsupportedQueries(t *testing.T) {
+	srv := fugutest.NewServer(t)
+	srv.Legacy()
+	srv.Seed(fugusdk.ObjectRecord{ID: "a", Text: "rate case", Metadata: map[string]interface{}{"date_iso": "2024-01-02"}})
+	client := probingClient(t, srv)
+
+	ctx := context.Background()
+	ranged := []string{fugusdk.RangeFilter("metadata/date_iso", "2024-01-01", "")}
+	queries := map[string]fugusdk.FuguSearchQuery{
+		"sort":         {Query: "rate", Sort: &[]fugusdk.SortField{{Field: "id", Order: fugusdk.SortAscending}}},
+		"search_after": {Query: "rate", SearchAfter: &[]string{"1", "a"}},
+		"range filter": {Query: "rate", Filters: &ranged},
+	}
+	for name, query := range queries {
+		if _, err := client.Search(ctx, query); !errors.Is(err, fugusdk.ErrUnsupported) {
+			t.Errorf("%s: expected ErrUnsupported, got %v", name, err)
+		}
+	}
+	if result, err := client.Search(ctx, fugusdk.FuguSearchQuery{Query: "rate"}); err != nil || len(result.Results) != 1 {
+		t.Errorf("a plain search still runs, got %+v, %v", result, err)
+	}
+}
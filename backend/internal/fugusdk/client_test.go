@@ -0,0 +1,176 @@
+package fugusdk_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"kessler/internal/fugusdk"
+	"kessler/internal/fugusdk/fugutest"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// retryClient builds a client for the fake server with fast retries and the given breaker, nil disables it
+func retryClient(t *testing.T, srv *fugutest.Server, breaker *fugusdk.CircuitBreaker, retries int) *fugusdk.Client {
+	t.Helper()
+	client, err := fugusdk.BuildClient(context.Background(), srv.URL,
+		fugusdk.WithRetry(retries, time.Millisecond),
+		fugusdk.WithRateLimit(1000, 100),
+		fugusdk.WithCircuitBreaker(breaker))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+func TestMakeRequestRetriesByStatus(t *testing.T) {
+	tests := []struct {
+		name         string
+		failures     []int
+		wantRequests int
+		wantErr      bool
+	}{
+		{"unavailable then ok", []int{http.StatusServiceUnavailable}, 2, false},
+		{"rate limited then ok", []int{http.StatusTooManyRequests}, 2, false},
+		{"bad gateway until exhausted", []int{http.StatusBadGateway, http.StatusBadGateway, http.StatusBadGateway}, 3, true},
+		{"client error not retried", []int{http.StatusBadRequest}, 1, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := fugutest.NewServer(t)
+			client := retryClient(t, srv, nil, 2)
+			srv.FailNext(tt.failures...)
+
+			err := client.Health(context.Background())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if got := srv.Requests(); got != tt.wantRequests {
+				t.Errorf("expected %d requests, got %d", tt.wantRequests, got)
+			}
+		})
+	}
+}
+
+func TestCircuitBreakerOpensAndRecovers(t *testing.T) {
+	srv := fugutest.NewServer(t)
+	srv.FailNext(http.StatusInternalServerError, http.StatusInternalServerError)
+	breaker := fugusdk.NewCircuitBreaker(2, time.Minute)
+	now := time.Now()
+	breaker.SetNow(func() time.Time { return now })
+	client := retryClient(t, srv, breaker, 0)
+	ctx := context.Background()
+
+	client.Health(ctx)
+	client.Health(ctx)
+	if state := breaker.Snapshot().State; state != fugusdk.BreakerOpen {
+		t.Fatalf("expected open breaker after 2 failures, got %s", state)
+	}
+	if err := client.Health(ctx); !errors.Is(err, fugusdk.ErrCircuitOpen) {
+		t.Fatalf("expected ErrCircuitOpen, got %v", err)
+	}
+	if srv.Requests() != 2 {
+		t.Errorf("open breaker should not contact the server, got %d requests", srv.Requests())
+	}
+
+	now = now.Add(time.Minute)
+	if err := client.Health(ctx); err != nil {
+		t.Fatalf("probe after open timeout failed: %v", err)
+	}
+	if snapshot := breaker.Snapshot(); snapshot.State != fugusdk.BreakerClosed || snapshot.ConsecutiveFailures != 0 {
+		t.Errorf("expected closed breaker after successful probe, got %+v", snapshot)
+	}
+}
+
+func TestCircuitBreakerCountsCalls(t *testing.T) {
+	srv := fugutest.NewServer(t)
+	breaker := fugusdk.NewCircuitBreaker(2, time.Minute)
+	client := retryClient(t, srv, breaker, 2)
+
+	// Every attempt of one call fails, that is still a single failure
+	srv.FailNext(http.StatusBadGateway, http.StatusBadGateway, http.StatusBadGateway)
+	if err := client.Health(context.Background()); err == nil {
+		t.Fatal("expected the call to fail")
+	}
+	if snapshot := breaker.Snapshot(); snapshot.State != fugusdk.BreakerClosed || snapshot.ConsecutiveFailures != 1 {
+		t.Errorf("expected one failure on a closed breaker, got %+v", snapshot)
+	}
+
+	// A call that recovers on retry leaves no failure behind
+	srv.FailNext(http.StatusBadGateway)
+	if err := client.Health(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if snapshot := breaker.Snapshot(); snapshot.ConsecutiveFailures != 0 {
+		t.Errorf("expected the breaker reset by a successful call, got %+v", snapshot)
+	}
+}
+
+func TestMakeRequestDoesNotRetryUnknownHosts(t *testing.T) {
+	client, err := fugusdk.BuildClient(context.Background(), "http://fugu.invalid",
+		fugusdk.WithRetry(3, time.Hour),
+		fugusdk.WithCircuitBreaker(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A retry would wait out the hour long backoff
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := client.Health(ctx); err == nil || errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected the lookup failure without retries, got %v", err)
+	}
+}
+
+func TestBulkIndexerUpserts(t *testing.T) {
+	srv := fugutest.NewServer(t)
+	// Client retries are left to the indexer, every request it counts is one the server saw
+	client := retryClient(t, srv, nil, 3)
+	records := make([]fugusdk.ObjectRecord, 25)
+	for i := range records {
+		records[i] = fugusdk.ObjectRecord{ID: fmt.Sprintf("obj-%02d", i), Text: "filing text"}
+	}
+	// The first batch hits a restarting server and is resent by the indexer
+	srv.FailNext(http.StatusServiceUnavailable)
+
+	stats, err := client.NewBulkIndexer(fugusdk.BulkIndexerConfig{MaxBatchObjects: 10, Concurrency: 1}).IndexSlice(context.Background(), records)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Succeeded != 25 || stats.Failed != 0 || stats.Requests != 4 || stats.Retries != 1 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+	if srv.Len() != 25 || srv.Requests() != stats.Requests {
+		t.Errorf("expected 25 stored objects in %d requests, got %d in %d", stats.Requests, srv.Len(), srv.Requests())
+	}
+}
+
+func TestBulkIndexerReportsUpsertShortfall(t *testing.T) {
+	srv := fugutest.NewServer(t)
+	client := retryClient(t, srv, nil, 0)
+	records := make([]fugusdk.ObjectRecord, 10)
+	for i := range records {
+		records[i] = fugusdk.ObjectRecord{ID: fmt.Sprintf("obj-%02d", i), Text: "filing text"}
+	}
+	srv.SkipOnUpsert("obj-03")
+
+	var failed []fugusdk.BulkResult
+	indexer := client.NewBulkIndexer(fugusdk.BulkIndexerConfig{
+		Concurrency: 1,
+		OnResult: func(result fugusdk.BulkResult) {
+			if result.Err != nil {
+				failed = append(failed, result)
+			}
+		},
+	})
+	stats, err := indexer.IndexSlice(context.Background(), records)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Succeeded != 9 || stats.Failed != 1 || stats.FailedIDs[0] != "obj-03" {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+	if len(failed) != 1 || !errors.Is(failed[0].Err, fugusdk.ErrNotUpserted) {
+		t.Errorf("expected obj-03 reported as not upserted, got %+v", failed)
+	}
+}
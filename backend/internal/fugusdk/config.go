@@ -0,0 +1,186 @@
+// config.go
+package fugusdk
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"time"
+)
+
+const (
+	// DefaultURL is the FuguDB address inside the compose network
+	DefaultURL = "http://fugudb:3301"
+	// DefaultNamespace is the namespace records are indexed into when none is configured
+	DefaultNamespace = "NYPUC"
+)
+
+// Config is the connection configuration for a FuguDB instance
+type Config struct {
+	URL   string `json:"url"`
+	Token string `json:"token,omitempty"`
+	// Timeout bounds a single HTTP request
+	Timeout   Duration `json:"timeout"`
+	RateLimit int      `json:"rate_limit"`
+	Burst     int      `json:"burst"`
+	// MaxRetries, RetryDelay and MaxRetryDelay shape the retry backoff
+	MaxRetries    int      `json:"max_retries"`
+	RetryDelay    Duration `json:"retry_delay"`
+	MaxRetryDelay Duration `json:"max_retry_delay"`
+	// Namespace is the default namespace for indexing
+	Namespace string `json:"namespace"`
+}
+
+// Duration is a time.Duration written as a Go duration string in config files
+type Duration time.Duration
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var raw string
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return fmt.Errorf("duration must be a string such as \"30s\": %w", err)
+	}
+	parsed, err := time.ParseDuration(raw)
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+// DefaultConfig returns the configuration NewClient used before it was configurable, without a token
+func DefaultConfig() Config {
+	return Config{
+		URL:           DefaultURL,
+		Timeout:       Duration(DefaultTimeout),
+		RateLimit:     50,
+		Burst:         10,
+		MaxRetries:    3,
+		RetryDelay:    Duration(time.Second),
+		MaxRetryDelay: Duration(DefaultMaxRetryDelay),
+		Namespace:     DefaultNamespace,
+	}
+}
+
+// LoadConfig builds the configuration from the defaults, then the JSON file named by FUGU_CONFIG_FILE,
+// then the FUGU_* environment variables, and validates the result
+func LoadConfig() (Config, error) {
+	cfg := DefaultConfig()
+
+	if path := os.Getenv("FUGU_CONFIG_FILE"); path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return Config{}, fmt.Errorf("reading fugu config file: %w", err)
+		}
+		if err := json.Unmarshal(data, &cfg); err != nil {
+			return Config{}, fmt.Errorf("parsing fugu config file %s: %w", path, err)
+		}
+	}
+
+	if err := cfg.applyEnv(); err != nil {
+		return Config{}, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return Config{}, err
+	}
+	return cfg, nil
+}
+
+// applyEnv overrides fields with any FUGU_* environment variables that are set
+func (cfg *Config) applyEnv() error {
+	if v := os.Getenv("FUGU_URL"); v != "" {
+		cfg.URL = v
+	}
+	if v := os.Getenv("FUGU_TOKEN"); v != "" {
+		cfg.Token = v
+	}
+	if v := os.Getenv("FUGU_NAMESPACE"); v != "" {
+		cfg.Namespace = v
+	}
+
+	ints := map[string]*int{
+		"FUGU_RATE_LIMIT":  &cfg.RateLimit,
+		"FUGU_BURST":       &cfg.Burst,
+		"FUGU_MAX_RETRIES": &cfg.MaxRetries,
+	}
+	for key, field := range ints {
+		if v := os.Getenv(key); v != "" {
+			parsed, err := strconv.Atoi(v)
+			if err != nil {
+				return fmt.Errorf("invalid %s %q: %w", key, v, err)
+			}
+			*field = parsed
+		}
+	}
+
+	durations := map[string]*Duration{
+		"FUGU_TIMEOUT":         &cfg.Timeout,
+		"FUGU_RETRY_DELAY":     &cfg.RetryDelay,
+		"FUGU_MAX_RETRY_DELAY": &cfg.MaxRetryDelay,
+	}
+	for key, field := range durations {
+		if v := os.Getenv(key); v != "" {
+			parsed, err := time.ParseDuration(v)
+			if err != nil {
+				return fmt.Errorf("invalid %s %q: %w", key, v, err)
+			}
+			*field = Duration(parsed)
+		}
+	}
+	return nil
+}
+
+// Validate reports every invalid field at once
+func (cfg Config) Validate() error {
+	var errs []error
+	if parsed, err := url.Parse(cfg.URL); err != nil || parsed.Scheme == "" || parsed.Host == "" {
+		errs = append(errs, fmt.Errorf("fugu url %q must be an absolute http(s) URL", cfg.URL))
+	} else if parsed.Scheme != "http" && parsed.Scheme != "https" {
+		errs = append(errs, fmt.Errorf("fugu url scheme %q must be http or https", parsed.Scheme))
+	}
+	if cfg.Timeout <= 0 {
+		errs = append(errs, errors.New("fugu timeout must be positive"))
+	}
+	if cfg.RateLimit <= 0 || cfg.Burst <= 0 {
+		errs = append(errs, errors.New("fugu rate limit and burst must be positive"))
+	}
+	if cfg.MaxRetries < 0 {
+		errs = append(errs, errors.New("fugu max retries cannot be negative"))
+	}
+	if cfg.RetryDelay < 0 || cfg.MaxRetryDelay <= 0 {
+		errs = append(errs, errors.New("fugu retry delays must be positive"))
+	}
+	if err := NewInputSanitizer().ValidateNamespace(cfg.Namespace); err != nil {
+		errs = append(errs, fmt.Errorf("fugu namespace: %w", err))
+	}
+	return errors.Join(errs...)
+}
+
+// Options converts the configuration into client options
+func (cfg Config) Options() []ClientOption {
+	options := []ClientOption{
+		WithTimeout(time.Duration(cfg.Timeout)),
+		WithRateLimit(cfg.RateLimit, cfg.Burst),
+		WithRetry(cfg.MaxRetries, time.Duration(cfg.RetryDelay)),
+		WithMaxRetryDelay(time.Duration(cfg.MaxRetryDelay)),
+	}
+	if cfg.Token != "" {
+		options = append(options, WithSecureToken(cfg.Token))
+	}
+	return options
+}
+
+// NewClientFromConfig creates a client for the configured instance
+func NewClientFromConfig(ctx context.Context, cfg Config) (*Client, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid fugu config: %w", err)
+	}
+	return BuildClient(ctx, cfg.URL, cfg.Options()...)
+}
@@ -0,0 +1,54 @@
+package fugusdk
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLoadConfig(t *testing.T) {
+	t.Run("defaults", func(t *testing.T) {
+		cfg, err := LoadConfig()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cfg.URL != DefaultURL || cfg.Namespace != DefaultNamespace || cfg.Token != "" {
+			t.Errorf("unexpected defaults %+v", cfg)
+		}
+	})
+
+	t.Run("env overrides file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "fugu.json")
+		file := `{"url": "https://fugu-staging.internal", "token": "from-file", "timeout": "45s", "namespace": "STAGING"}`
+		if err := os.WriteFile(path, []byte(file), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		t.Setenv("FUGU_CONFIG_FILE", path)
+		t.Setenv("FUGU_TOKEN", "from-env")
+		t.Setenv("FUGU_MAX_RETRIES", "5")
+
+		cfg, err := LoadConfig()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cfg.URL != "https://fugu-staging.internal" || cfg.Token != "from-env" || cfg.Namespace != "STAGING" ||
+			time.Duration(cfg.Timeout) != 45*time.Second || cfg.MaxRetries != 5 {
+			t.Errorf("unexpected config %+v", cfg)
+		}
+	})
+
+	t.Run("invalid values are rejected", func(t *testing.T) {
+		t.Setenv("FUGU_URL", "fugudb:3301")
+		t.Setenv("FUGU_NAMESPACE", "bad namespace")
+		if _, err := LoadConfig(); err == nil {
+			t.Error("expected an error for a relative url and invalid namespace")
+		}
+		t.Setenv("FUGU_URL", "")
+		t.Setenv("FUGU_NAMESPACE", "")
+		t.Setenv("FUGU_TIMEOUT", "soon")
+		if _, err := LoadConfig(); err == nil {
+			t.Error("expected an error for an unparseable timeout")
+		}
+	})
+}
@@ -0,0 +1,8 @@
+package fugusdk
+
+import "time"
+
+// SetNow replaces the breaker's clock so tests outside the package can step past its open timeout
+func (b *CircuitBreaker) SetNow(now func() time.Time) {
+	b.now = now
+}
@@ -9,6 +9,21 @@ import (
 	"strings"
 )
 
+// RangeUnbounded marks an open end of a range filter
+const RangeUnbounded = "*"
+
+// RangeFilter builds an inclusive range filter over a field, empty bounds are left open
+// Format: {field}:[{from} TO {to}]
+func RangeFilter(field, from, to string) string {
+	if from == "" {
+		from = RangeUnbounded
+	}
+	if to == "" {
+		to = RangeUnbounded
+	}
+	return fmt.Sprintf("%s:[%s TO %s]", field, from, to)
+}
+
 // FilterBuilder helps build validated filters for Fugu search with namespace support
 type FilterBuilder struct {
 	filters   map[string]string
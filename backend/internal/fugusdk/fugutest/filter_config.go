@@ -0,0 +1,129 @@
+package fugutest
+
+import (
+	"encoding/json"
+	"fmt"
+	"kessler/internal/fugusdk"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// SetFilterConfiguration sets the filter configuration the server serves and validates and converts
+// filters with. Every namespace shares it.
+func (s *Server) SetFilterConfiguration(config fugusdk.FilterConfiguration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.filterConfig = config
+}
+
+func (s *Server) filterConfiguration() fugusdk.FilterConfiguration {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.filterConfig
+}
+
+// enabledField looks up an enabled field of the configuration by its ID
+func enabledField(config fugusdk.FilterConfiguration, id string) (fugusdk.FilterFieldDefinition, bool) {
+	for _, field := range config.Fields {
+		if field.ID == id && field.Enabled {
+			return field, true
+		}
+	}
+	return fugusdk.FilterFieldDefinition{}, false
+}
+
+// validateFilters checks filter values against the configured fields, namespace facet paths are always valid
+func validateFilters(config fugusdk.FilterConfiguration, filters map[string]string) fugusdk.FilterValidationResult {
+	result := fugusdk.FilterValidationResult{Errors: []fugusdk.ValidationError{}, Warnings: []fugusdk.ValidationWarning{}}
+	fail := func(fieldID, kind, message string) {
+		result.Errors = append(result.Errors, fugusdk.ValidationError{FieldID: fieldID, Type: kind, Message: message})
+	}
+
+	ids := make([]string, 0, len(filters))
+	for id := range filters {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		if strings.HasPrefix(id, "namespace/") {
+			continue
+		}
+		field, ok := enabledField(config, id)
+		if !ok {
+			fail(id, "unknown_field", fmt.Sprintf("unknown filter field %q", id))
+			continue
+		}
+		value, rules := filters[id], field.Validation
+		if rules == nil || value == "" {
+			continue
+		}
+		length := utf8.RuneCountInString(value)
+		if rules.MinLength != nil && length < *rules.MinLength {
+			fail(id, "min_length", fmt.Sprintf("%s must be at least %d characters", field.DisplayName, *rules.MinLength))
+		}
+		if rules.MaxLength != nil && length > *rules.MaxLength {
+			fail(id, "max_length", fmt.Sprintf("%s must be at most %d characters", field.DisplayName, *rules.MaxLength))
+		}
+		if rules.Pattern != "" {
+			if pattern, err := regexp.Compile(rules.Pattern); err != nil || !pattern.MatchString(value) {
+				fail(id, "pattern", fmt.Sprintf("%s does not match %s", field.DisplayName, rules.Pattern))
+			}
+		}
+	}
+	for _, field := range config.Fields {
+		if field.Enabled && field.Required && filters[field.ID] == "" {
+			fail(field.ID, "required", fmt.Sprintf("%s is required", field.DisplayName))
+		}
+	}
+	result.IsValid = len(result.Errors) == 0
+	return result
+}
+
+// convertFilters maps field IDs to their backend keys, unknown fields and empty values are dropped
+func convertFilters(config fugusdk.FilterConfiguration, filters map[string]string) map[string]interface{} {
+	backend := make(map[string]interface{})
+	for id, value := range filters {
+		if value == "" {
+			continue
+		}
+		if strings.HasPrefix(id, "namespace/") {
+			backend[id] = value
+			continue
+		}
+		field, ok := enabledField(config, id)
+		if !ok {
+			continue
+		}
+		key := field.BackendKey
+		if key == "" {
+			key = field.ID
+		}
+		backend[key] = value
+	}
+	return backend
+}
+
+func (s *Server) handleFilterConfiguration(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.filterConfiguration())
+}
+
+func (s *Server) handleValidateFilters(w http.ResponseWriter, r *http.Request) {
+	var req fugusdk.FilterValidateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	writeJSON(w, http.StatusOK, validateFilters(s.filterConfiguration(), req.Filters))
+}
+
+func (s *Server) handleConvertFilters(w http.ResponseWriter, r *http.Request) {
+	var req fugusdk.FilterConvertRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	writeJSON(w, http.StatusOK, fugusdk.FilterConvertResponse{BackendFilters: convertFilters(s.filterConfiguration(), req.Filters)})
+}
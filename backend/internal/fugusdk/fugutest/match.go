@@ -0,0 +1,321 @@
+package fugutest
+
+import (
+	"kessler/internal/fugusdk"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// queryTerms is a query reduced to simple term matching.
+// Terms are required unless the query uses OR, in which case any one term matches.
+// Quoted phrases match as substrings and NOT or a leading "-" excludes a term.
+// A trailing "*" matches words starting with the term and "~N" words within N edits of it.
+type queryTerms struct {
+	include []string
+	exclude []string
+	any     bool
+}
+
+// parseTerms splits a query into lower case terms, "" and "*" match everything
+func parseTerms(query string) queryTerms {
+	var terms queryTerms
+	negate := false
+	for _, token := range tokenize(query) {
+		switch token {
+		case "AND", "*":
+			continue
+		case "OR":
+			terms.any = true
+			continue
+		case "NOT":
+			negate = true
+			continue
+		}
+		if strings.HasPrefix(token, "-") && len(token) > 1 {
+			negate = true
+			token = token[1:]
+		}
+		token = strings.ToLower(strings.Trim(token, "()"))
+		if token == "" {
+			continue
+		}
+		if negate {
+			terms.exclude = append(terms.exclude, token)
+		} else {
+			terms.include = append(terms.include, token)
+		}
+		negate = false
+	}
+	return terms
+}
+
+// tokenize splits on whitespace, keeping quoted phrases together without their quotes
+func tokenize(query string) []string {
+	var tokens []string
+	var current strings.Builder
+	inQuote := false
+	for _, r := range query {
+		switch {
+		case r == '"':
+			inQuote = !inQuote
+		case unicode.IsSpace(r) && !inQuote:
+			if current.Len() > 0 {
+				tokens = append(tokens, current.String())
+				current.Reset()
+			}
+		default:
+			current.WriteRune(r)
+		}
+	}
+	if current.Len() > 0 {
+		tokens = append(tokens, current.String())
+	}
+	return tokens
+}
+
+// score reports whether text matches and scores it by how often the included terms occur
+func (q queryTerms) score(text string) (float32, bool) {
+	lower := strings.ToLower(text)
+	words := strings.FieldsFunc(lower, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	occurrences := func(term string) int {
+		match := func(word string) bool { return word == term }
+		if prefix, ok := strings.CutSuffix(term, "*"); ok && isWord(prefix) {
+			match = func(word string) bool { return strings.HasPrefix(word, prefix) }
+		} else if fuzzy, distance, ok := parseFuzzy(term); ok {
+			match = func(word string) bool { return editDistance(word, fuzzy) <= distance }
+		} else if !isWord(term) {
+			return strings.Count(lower, term)
+		}
+		count := 0
+		for _, word := range words {
+			if match(word) {
+				count++
+			}
+		}
+		return count
+	}
+
+	for _, term := range q.exclude {
+		if occurrences(term) > 0 {
+			return 0, false
+		}
+	}
+	if len(q.include) == 0 {
+		return 1, true
+	}
+
+	var score float32
+	matched := 0
+	for _, term := range q.include {
+		if n := occurrences(term); n > 0 {
+			matched++
+			score += float32(n)
+		}
+	}
+	if matched == 0 || (!q.any && matched < len(q.include)) {
+		return 0, false
+	}
+	return score, true
+}
+
+// isWord reports whether term is a single non-empty word
+func isWord(term string) bool {
+	return term != "" && !strings.ContainsFunc(term, func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) })
+}
+
+// parseFuzzy splits a "term~N" fuzzy term
+func parseFuzzy(term string) (string, int, bool) {
+	word, raw, found := strings.Cut(term, "~")
+	if !found || !isWord(word) {
+		return "", 0, false
+	}
+	distance, err := strconv.Atoi(raw)
+	if err != nil || distance < 0 {
+		return "", 0, false
+	}
+	return word, distance, true
+}
+
+// editDistance is the Levenshtein distance between two words
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur := make([]int, len(rb)+1)
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(rb)]
+}
+
+// matchesFilters reports whether an object satisfies every filter.
+// A facet filter matches the facet itself or any facet below it, range filters compare metadata values as strings
+// when ranges is set and are otherwise read as facets.
+func matchesFilters(obj fugusdk.ObjectRecord, filters []string, ranges bool) bool {
+	for _, filter := range filters {
+		if field, from, to, ok := parseRangeFilter(filter); ranges && ok {
+			value := fieldValue(obj.Metadata, field)
+			if value == "" ||
+				(from != fugusdk.RangeUnbounded && value < from) ||
+				(to != fugusdk.RangeUnbounded && value > to) {
+				return false
+			}
+			continue
+		}
+
+		filter = strings.Trim(filter, "/")
+		matched := false
+		for _, facet := range obj.Facets {
+			facet = strings.Trim(facet, "/")
+			if facet == filter || strings.HasPrefix(facet, filter+"/") {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	return true
+}
+
+// parseRangeFilter splits a filter built by fugusdk.RangeFilter
+func parseRangeFilter(filter string) (field, from, to string, ok bool) {
+	field, bounds, found := strings.Cut(filter, ":[")
+	if !found || !strings.HasSuffix(bounds, "]") {
+		return "", "", "", false
+	}
+	from, to, found = strings.Cut(strings.TrimSuffix(bounds, "]"), " TO ")
+	if !found {
+		return "", "", "", false
+	}
+	return field, from, to, true
+}
+
+// fieldValue reads a "metadata/{key}" field, list values use their first entry
+func fieldValue(metadata map[string]interface{}, field string) string {
+	key, ok := strings.CutPrefix(field, "metadata/")
+	if !ok || metadata == nil {
+		return ""
+	}
+	switch v := metadata[key].(type) {
+	case string:
+		return v
+	case []interface{}:
+		if len(v) > 0 {
+			if s, ok := v[0].(string); ok {
+				return s
+			}
+		}
+	case []string:
+		if len(v) > 0 {
+			return v[0]
+		}
+	}
+	return ""
+}
+
+// sortKey returns the value a hit is ordered by for one sort field, compared case-sensitively as Fugu does
+func sortKey(hit fugusdk.FuguSearchResult, field string) string {
+	if field == "id" {
+		return hit.ID
+	}
+	return fieldValue(hit.Metadata, field)
+}
+
+// compareKeys orders two values in a sort direction, empty values always sort last
+func compareKeys(a, b, order string) int {
+	if a == b {
+		return 0
+	}
+	if a == "" || b == "" {
+		if a == "" {
+			return 1
+		}
+		return -1
+	}
+	cmp := strings.Compare(a, b)
+	if order == fugusdk.SortDescending {
+		return -cmp
+	}
+	return cmp
+}
+
+// compareHit orders a hit against the key values of another position, relevance is score descending then id
+func compareHit(hit fugusdk.FuguSearchResult, fields []fugusdk.SortField, score float32, keys []string) int {
+	if len(fields) == 0 {
+		switch {
+		case hit.Score > score:
+			return -1
+		case hit.Score < score:
+			return 1
+		}
+		if len(keys) > 0 {
+			return strings.Compare(hit.ID, keys[len(keys)-1])
+		}
+		return 0
+	}
+	for i, field := range fields {
+		if i >= len(keys) {
+			break
+		}
+		if cmp := compareKeys(sortKey(hit, field.Field), keys[i], field.Order); cmp != 0 {
+			return cmp
+		}
+	}
+	return 0
+}
+
+// sortHits orders hits by the sort fields, or by relevance when none are given
+func sortHits(hits []fugusdk.FuguSearchResult, fields []fugusdk.SortField) {
+	sort.SliceStable(hits, func(i, j int) bool {
+		keys := make([]string, len(fields))
+		for k, field := range fields {
+			keys[k] = sortKey(hits[j], field.Field)
+		}
+		if len(fields) == 0 {
+			keys = []string{hits[j].ID}
+		}
+		return compareHit(hits[i], fields, hits[j].Score, keys) < 0
+	})
+}
+
+// hitsAfter drops hits at or before a search_after position.
+// Relevance positions are [score, id], sorted positions hold one value per sort field.
+func hitsAfter(hits []fugusdk.FuguSearchResult, fields []fugusdk.SortField, after []string) []fugusdk.FuguSearchResult {
+	var score float32
+	keys := after
+	if len(fields) == 0 {
+		if len(after) == 0 {
+			return hits
+		}
+		parsed, err := strconv.ParseFloat(after[0], 32)
+		if err != nil {
+			return hits
+		}
+		score = float32(parsed)
+		keys = after[1:]
+	}
+
+	kept := make([]fugusdk.FuguSearchResult, 0, len(hits))
+	for _, hit := range hits {
+		if compareHit(hit, fields, score, keys) > 0 {
+			kept = append(kept, hit)
+		}
+	}
+	return kept
+}
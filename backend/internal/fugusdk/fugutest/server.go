@@ -0,0 +1,582 @@
+// Package fugutest provides an in-memory FuguDB server for tests.
+//
+// The server speaks the subset of the Fugu HTTP API that fugusdk calls, so code under test can use a
+// real fugusdk.Client pointed at Server.URL instead of a fugudb container.
+package fugutest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"kessler/internal/fugusdk"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// defaultPerPage is the page size used when a search does not set one
+const defaultPerPage = 10
+
+// Server is an httptest server holding Fugu objects in memory
+type Server struct {
+	*httptest.Server
+
+	mu           sync.RWMutex
+	objects      map[string]fugusdk.ObjectRecord
+	searches     []fugusdk.FuguSearchQuery
+	filterConfig fugusdk.FilterConfiguration
+	// failures are statuses answered, in order, to the next requests instead of handling them
+	failures []int
+	requests int
+	// skipped objects are left out of upserts and their counts, as Fugu does with objects it cannot index
+	skipped map[string]bool
+	// namespaceFaults answers searches filtered on a namespace with a status, 0 stalls them instead
+	namespaceFaults map[string]int
+	// legacy ignores sort and search_after and reads range filters as facets, as older Fugu servers do
+	legacy bool
+}
+
+// NewServer starts an empty fake Fugu server that is closed when the test finishes
+func NewServer(t testing.TB) *Server {
+	t.Helper()
+
+	s := &Server{objects: make(map[string]fugusdk.ObjectRecord)}
+	s.Server = httptest.NewServer(s.intercept(s.routes()))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// Client returns a fugusdk client for the server without retry delays or a tight rate limit.
+// The client knows the server's capabilities up front, so recorded searches hold no probes.
+func (s *Server) Client(ctx context.Context) (*fugusdk.Client, error) {
+	s.mu.RLock()
+	legacy := s.legacy
+	s.mu.RUnlock()
+	return fugusdk.BuildClient(
+		ctx,
+		s.URL,
+		fugusdk.WithRetry(0, 0),
+		fugusdk.WithRateLimit(10000, 1000),
+		fugusdk.WithCapabilities(fugusdk.Capabilities{Sort: !legacy, RangeFilters: !legacy}),
+	)
+}
+
+// Legacy makes the server behave like an older Fugu that ignores sort and search_after and reads
+// range filters as literal facets
+func (s *Server) Legacy() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.legacy = true
+}
+
+// Seed stores objects directly, replacing any with the same ID
+func (s *Server) Seed(objects ...fugusdk.ObjectRecord) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, obj := range objects {
+		s.objects[obj.ID] = storedObject(obj)
+	}
+}
+
+// Object returns a stored object by ID
+func (s *Server) Object(id string) (fugusdk.ObjectRecord, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	obj, ok := s.objects[id]
+	return obj, ok
+}
+
+// Objects returns every stored object ordered by ID
+func (s *Server) Objects() []fugusdk.ObjectRecord {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	objects := make([]fugusdk.ObjectRecord, 0, len(s.objects))
+	for _, obj := range s.objects {
+		objects = append(objects, obj)
+	}
+	sort.Slice(objects, func(i, j int) bool { return objects[i].ID < objects[j].ID })
+	return objects
+}
+
+// Len returns the number of stored objects
+func (s *Server) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.objects)
+}
+
+// Searches returns every search query received, in order
+func (s *Server) Searches() []fugusdk.FuguSearchQuery {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]fugusdk.FuguSearchQuery(nil), s.searches...)
+}
+
+// FailNext makes the next requests fail with the given statuses, one request per status, before requests
+// are handled normally again. Rate limited and unavailable responses ask to be retried immediately.
+func (s *Server) FailNext(statuses ...int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures = append(s.failures, statuses...)
+}
+
+// SkipOnUpsert makes upserts silently leave out objects with these IDs, the upserted count only covers
+// the objects stored
+func (s *Server) SkipOnUpsert(ids ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.skipped == nil {
+		s.skipped = make(map[string]bool)
+	}
+	for _, id := range ids {
+		s.skipped[id] = true
+	}
+}
+
+// FailNamespace makes every search filtered on the namespace fail with status until Reset
+func (s *Server) FailNamespace(namespace string, status int) {
+	s.setNamespaceFault(namespace, status)
+}
+
+// StallNamespace makes every search filtered on the namespace hang until the client gives up, until Reset
+func (s *Server) StallNamespace(namespace string) {
+	s.setNamespaceFault(namespace, 0)
+}
+
+func (s *Server) setNamespaceFault(namespace string, status int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.namespaceFaults == nil {
+		s.namespaceFaults = make(map[string]int)
+	}
+	s.namespaceFaults[namespace] = status
+}
+
+// namespaceFault returns the fault set for a namespace the query filters on
+func (s *Server) namespaceFault(query fugusdk.FuguSearchQuery) (int, bool) {
+	if query.Filters == nil {
+		return 0, false
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, filter := range *query.Filters {
+		if namespace, ok := strings.CutPrefix(strings.Trim(filter, "/"), "namespace/"); ok {
+			if status, ok := s.namespaceFaults[namespace]; ok {
+				return status, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// Requests returns the number of requests received, failed ones included
+func (s *Server) Requests() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.requests
+}
+
+// Reset removes all objects, recorded searches, pending failures, skipped IDs and namespace faults
+func (s *Server) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.objects = make(map[string]fugusdk.ObjectRecord)
+	s.searches = nil
+	s.failures = nil
+	s.requests = 0
+	s.skipped = nil
+	s.namespaceFaults = nil
+}
+
+// intercept counts every request and answers it with the next queued failure, if any
+func (s *Server) intercept(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.requests++
+		status := 0
+		if len(s.failures) > 0 {
+			status, s.failures = s.failures[0], s.failures[1:]
+		}
+		s.mu.Unlock()
+
+		if status == 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if status == http.StatusTooManyRequests || status == http.StatusServiceUnavailable {
+			w.Header().Set("Retry-After", "0")
+		}
+		writeError(w, status, http.StatusText(status))
+	})
+}
+
+// storedObject adds the namespace facets Fugu derives on ingest
+func storedObject(obj fugusdk.ObjectRecord) fugusdk.ObjectRecord {
+	facets := append([]string(nil), obj.Facets...)
+	for _, facet := range obj.GenerateNamespaceFacets() {
+		if !containsString(facets, facet) {
+			facets = append(facets, facet)
+		}
+	}
+	obj.Facets = facets
+	return obj
+}
+
+func (s *Server) routes() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /health", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+	})
+
+	mux.HandleFunc("POST /ingest", s.handleIngest)
+	mux.HandleFunc("POST /ingest/namespace", s.handleIngest)
+	mux.HandleFunc("PUT /objects", s.handleIngest)
+	mux.HandleFunc("POST /batch/upsert", s.handleBatchUpsert)
+	mux.HandleFunc("GET /objects/{id}", s.handleGetObject)
+	mux.HandleFunc("DELETE /objects/{id}", s.handleDeleteObject)
+
+	mux.HandleFunc("POST /search", s.handleSearch)
+	mux.HandleFunc("POST /search/namespace", s.handleSearch)
+	mux.HandleFunc("GET /search", s.handleSearchText)
+
+	mux.HandleFunc("GET /filters", s.handleAllFilters)
+	mux.HandleFunc("GET /filters/all", s.handleAllFilters)
+	mux.HandleFunc("GET /filters/namespace/{namespace}", s.handleNamespaceFilters)
+	mux.HandleFunc("GET /filters/path/{path...}", s.handleFilterValues)
+
+	mux.HandleFunc("GET /search/filters/configuration", s.handleFilterConfiguration)
+	mux.HandleFunc("GET /search/filters/namespace/{namespace}/configuration", s.handleFilterConfiguration)
+	mux.HandleFunc("POST /search/filters/validate", s.handleValidateFilters)
+	mux.HandleFunc("POST /search/filters/namespace/{namespace}/validate", s.handleValidateFilters)
+	mux.HandleFunc("POST /search/filters/convert", s.handleConvertFilters)
+	mux.HandleFunc("POST /search/filters/namespace/{namespace}/convert", s.handleConvertFilters)
+
+	mux.HandleFunc("GET /namespaces", s.handleNamespaces)
+	mux.HandleFunc("GET /namespaces/{namespace}/facets", s.handleNamespaceFacets)
+	mux.HandleFunc("GET /namespaces/{namespace}/organizations", s.handleNamespaceField(func(o fugusdk.ObjectRecord) string { return o.Organization }, "organizations"))
+	mux.HandleFunc("GET /namespaces/{namespace}/conversations", s.handleNamespaceField(func(o fugusdk.ObjectRecord) string { return o.ConversationID }, "conversations"))
+	mux.HandleFunc("GET /namespaces/{namespace}/data", s.handleNamespaceField(func(o fugusdk.ObjectRecord) string { return o.DataType }, "data_types"))
+	return mux
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+// writeError responds in the error shape fugusdk reads messages from
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
+
+// upsert stores objects and responds with the upserted count
+func (s *Server) upsert(w http.ResponseWriter, objects []fugusdk.ObjectRecord) {
+	if len(objects) == 0 {
+		writeError(w, http.StatusBadRequest, "no objects provided")
+		return
+	}
+	for i, obj := range objects {
+		if obj.ID == "" {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("object at index %d has no id", i))
+			return
+		}
+	}
+	s.mu.RLock()
+	stored := make([]fugusdk.ObjectRecord, 0, len(objects))
+	for _, obj := range objects {
+		if !s.skipped[obj.ID] {
+			stored = append(stored, obj)
+		}
+	}
+	s.mu.RUnlock()
+	s.Seed(stored...)
+
+	count := len(stored)
+	writeJSON(w, http.StatusOK, fugusdk.SanitizedResponse{
+		Status:        "success",
+		Message:       fmt.Sprintf("upserted %d objects", count),
+		UpsertedCount: &count,
+	})
+}
+
+func (s *Server) handleIngest(w http.ResponseWriter, r *http.Request) {
+	var req fugusdk.IndexRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	s.upsert(w, req.Data)
+}
+
+func (s *Server) handleBatchUpsert(w http.ResponseWriter, r *http.Request) {
+	var req fugusdk.BatchIndexRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	s.upsert(w, req.Objects)
+}
+
+func (s *Server) handleGetObject(w http.ResponseWriter, r *http.Request) {
+	obj, ok := s.Object(r.PathValue("id"))
+	if !ok {
+		writeError(w, http.StatusNotFound, "object not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, fugusdk.SanitizedResponse{Status: "success", Data: obj})
+}
+
+func (s *Server) handleDeleteObject(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	s.mu.Lock()
+	_, ok := s.objects[id]
+	delete(s.objects, id)
+	s.mu.Unlock()
+	if !ok {
+		writeError(w, http.StatusNotFound, "object not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, fugusdk.SanitizedResponse{Status: "success", Message: fmt.Sprintf("deleted %s", id)})
+}
+
+func (s *Server) handleSearch(w http.ResponseWriter, r *http.Request) {
+	var query fugusdk.FuguSearchQuery
+	if err := json.NewDecoder(r.Body).Decode(&query); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if status, ok := s.namespaceFault(query); ok {
+		if status == 0 {
+			<-r.Context().Done()
+			return
+		}
+		writeError(w, status, http.StatusText(status))
+		return
+	}
+	writeJSON(w, http.StatusOK, s.search(query))
+}
+
+func (s *Server) handleSearchText(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.search(fugusdk.FuguSearchQuery{Query: r.URL.Query().Get("q")}))
+}
+
+// search records the query and returns the requested page of matching objects
+func (s *Server) search(query fugusdk.FuguSearchQuery) fugusdk.SanitizedResponse {
+	s.mu.Lock()
+	s.searches = append(s.searches, query)
+	objects := make([]fugusdk.ObjectRecord, 0, len(s.objects))
+	for _, obj := range s.objects {
+		objects = append(objects, obj)
+	}
+	legacy := s.legacy
+	s.mu.Unlock()
+	if legacy {
+		query.Sort, query.SearchAfter = nil, nil
+	}
+
+	var filters []string
+	if query.Filters != nil {
+		filters = *query.Filters
+	}
+	var sortFields []fugusdk.SortField
+	if query.Sort != nil {
+		sortFields = *query.Sort
+	}
+
+	terms := parseTerms(query.Query)
+	var hits []fugusdk.FuguSearchResult
+	for _, obj := range objects {
+		if !matchesFilters(obj, filters, !legacy) {
+			continue
+		}
+		score, ok := terms.score(obj.Text)
+		if !ok {
+			continue
+		}
+		hits = append(hits, fugusdk.FuguSearchResult{
+			ID:       obj.ID,
+			Score:    score,
+			Text:     obj.Text,
+			Metadata: obj.Metadata,
+			Facets:   obj.Facets,
+		})
+	}
+	sortHits(hits, sortFields)
+	total := len(hits)
+	if query.SearchAfter != nil {
+		hits = hitsAfter(hits, sortFields, *query.SearchAfter)
+	}
+
+	page, perPage := 0, defaultPerPage
+	if query.Page != nil {
+		if query.Page.Page != nil && *query.Page.Page > 0 {
+			page = *query.Page.Page
+		}
+		if query.Page.PerPage != nil && *query.Page.PerPage > 0 {
+			perPage = *query.Page.PerPage
+		}
+	}
+	start := min(page*perPage, len(hits))
+	end := min(start+perPage, len(hits))
+
+	return fugusdk.SanitizedResponse{
+		Results: hits[start:end],
+		Total:   total,
+		Page:    page,
+		PerPage: perPage,
+		Query:   query.Query,
+		Status:  "success",
+	}
+}
+
+// objectsInNamespace returns stored objects, limited to a namespace when one is given
+func (s *Server) objectsInNamespace(namespace string) []fugusdk.ObjectRecord {
+	objects := s.Objects()
+	if namespace == "" {
+		return objects
+	}
+	filter := "namespace/" + namespace
+	kept := objects[:0]
+	for _, obj := range objects {
+		if matchesFilters(obj, []string{filter}, false) {
+			kept = append(kept, obj)
+		}
+	}
+	return kept
+}
+
+// filterPaths groups facet values by their parent path, "metadata/docket_gov_id/18-M-0084" is value
+// "18-M-0084" of path "metadata/docket_gov_id"
+func filterPaths(objects []fugusdk.ObjectRecord) map[string][]string {
+	paths := make(map[string][]string)
+	for _, obj := range objects {
+		for _, facet := range obj.Facets {
+			idx := strings.LastIndex(facet, "/")
+			if idx <= 0 {
+				continue
+			}
+			path, value := facet[:idx], facet[idx+1:]
+			if !containsString(paths[path], value) {
+				paths[path] = append(paths[path], value)
+			}
+		}
+	}
+	for path := range paths {
+		sort.Strings(paths[path])
+	}
+	return paths
+}
+
+func (s *Server) handleAllFilters(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, fugusdk.SanitizedResponse{
+		Status: "success",
+		Data:   map[string]interface{}{"filter_paths": filterPaths(s.Objects())},
+	})
+}
+
+func (s *Server) handleNamespaceFilters(w http.ResponseWriter, r *http.Request) {
+	namespace := r.PathValue("namespace")
+	writeJSON(w, http.StatusOK, fugusdk.SanitizedResponse{
+		Status: "success",
+		Data: map[string]interface{}{
+			"namespace":    namespace,
+			"filter_paths": filterPaths(s.objectsInNamespace(namespace)),
+		},
+	})
+}
+
+func (s *Server) handleFilterValues(w http.ResponseWriter, r *http.Request) {
+	path := strings.Trim(r.PathValue("path"), "/")
+	values := filterPaths(s.Objects())[path]
+	if values == nil {
+		values = []string{}
+	}
+	writeJSON(w, http.StatusOK, fugusdk.SanitizedResponse{
+		Status: "success",
+		Data: map[string]interface{}{
+			"filter_path": path,
+			"values":      values,
+		},
+	})
+}
+
+func (s *Server) handleNamespaces(w http.ResponseWriter, r *http.Request) {
+	namespaces := []string{}
+	for _, obj := range s.Objects() {
+		if obj.Namespace != "" && !containsString(namespaces, obj.Namespace) {
+			namespaces = append(namespaces, obj.Namespace)
+		}
+	}
+	sort.Strings(namespaces)
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"status":     "success",
+		"namespaces": namespaces,
+	})
+}
+
+// handleNamespaceFacets counts objects under every facet path and each of its parents, forming the facet tree
+func (s *Server) handleNamespaceFacets(w http.ResponseWriter, r *http.Request) {
+	namespace := r.PathValue("namespace")
+	counts := make(map[string]int64)
+	for _, obj := range s.objectsInNamespace(namespace) {
+		seen := make(map[string]bool)
+		for _, facet := range obj.Facets {
+			parts := strings.Split(strings.Trim(facet, "/"), "/")
+			for i := range parts {
+				path := strings.Join(parts[:i+1], "/")
+				if !seen[path] {
+					seen[path] = true
+					counts[path]++
+				}
+			}
+		}
+	}
+
+	type facetCount struct {
+		Path  string `json:"path"`
+		Count int64  `json:"count"`
+	}
+	facets := make([]facetCount, 0, len(counts))
+	for path, count := range counts {
+		facets = append(facets, facetCount{Path: path, Count: count})
+	}
+	sort.Slice(facets, func(i, j int) bool { return facets[i].Path < facets[j].Path })
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"status":    "success",
+		"namespace": namespace,
+		"facets":    facets,
+	})
+}
+
+// handleNamespaceField lists the distinct values of one namespace field under the given response key
+func (s *Server) handleNamespaceField(field func(fugusdk.ObjectRecord) string, key string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		namespace := r.PathValue("namespace")
+		values := []string{}
+		for _, obj := range s.objectsInNamespace(namespace) {
+			if value := field(obj); value != "" && !containsString(values, value) {
+				values = append(values, value)
+			}
+		}
+		sort.Strings(values)
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"status":    "success",
+			"namespace": namespace,
+			key:         values,
+		})
+	}
+}
+
+func containsString(values []string, target string) bool {
+	for _, v := range values {
+		if v == target {
+			return true
+		}
+	}
+	return false
+}
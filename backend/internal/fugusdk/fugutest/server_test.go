@@ -0,0 +1,216 @@
+package fugutest
+
+import (
+	"context"
+	"errors"
+	"kessler/internal/fugusdk"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func seedDockets(t *testing.T) (*Server, *fugusdk.Client) {
+	t.Helper()
+	srv := NewServer(t)
+	client, err := srv.Client(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = client.IngestObjects(context.Background(), []fugusdk.ObjectRecord{
+		{
+			ID:        "a1",
+			Text:      "Rate case testimony on solar interconnection",
+			Namespace: "NYPUC",
+			DataType:  "data/attachment",
+			Metadata:  map[string]interface{}{"date_iso": "2024-03-01T00:00:00Z"},
+			Facets:    []string{"metadata/entity_type/attachment", "metadata/docket_gov_id/23-E-0418"},
+		},
+		{
+			ID:        "a2",
+			Text:      "Solar net metering comments, solar tariffs",
+			Namespace: "NYPUC",
+			DataType:  "data/attachment",
+			Metadata:  map[string]interface{}{"date_iso": "2023-01-15T00:00:00Z"},
+			Facets:    []string{"metadata/entity_type/attachment", "metadata/docket_gov_id/18-M-0084"},
+		},
+		{
+			ID:        "c1",
+			Text:      "Proceeding on solar programs",
+			Namespace: "NYPUC",
+			Facets:    []string{"metadata/entity_type/conversation"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return srv, client
+}
+
+func TestSearch(t *testing.T) {
+	srv, client := seedDockets(t)
+	ctx := context.Background()
+
+	tests := []struct {
+		name    string
+		query   fugusdk.FuguSearchQuery
+		wantIDs []string
+	}{
+		{"relevance", fugusdk.FuguSearchQuery{Query: "solar"}, []string{"a2", "a1", "c1"}},
+		{"all terms", fugusdk.FuguSearchQuery{Query: "solar testimony"}, []string{"a1"}},
+		{"any term", fugusdk.FuguSearchQuery{Query: "testimony OR proceeding"}, []string{"a1", "c1"}},
+		{"excluded term", fugusdk.FuguSearchQuery{Query: "solar NOT metering"}, []string{"a1", "c1"}},
+		{"phrase", fugusdk.FuguSearchQuery{Query: `"net metering"`}, []string{"a2"}},
+		{"prefix", fugusdk.FuguSearchQuery{Query: "testim*"}, []string{"a1"}},
+		{"fuzzy", fugusdk.FuguSearchQuery{Query: "procedding~1 OR tarifs~1"}, []string{"a2", "c1"}},
+		{"facet filter", fugusdk.FuguSearchQuery{Query: "solar", Filters: &[]string{"metadata/entity_type/attachment"}}, []string{"a2", "a1"}},
+		{"parent facet", fugusdk.FuguSearchQuery{Query: "", Filters: &[]string{"metadata/docket_gov_id", "namespace/NYPUC"}}, []string{"a1", "a2"}},
+		{"range filter", fugusdk.FuguSearchQuery{Query: "*", Filters: &[]string{fugusdk.RangeFilter("metadata/date_iso", "2024-01-01T00:00:00Z", "")}}, []string{"a1"}},
+		{"sorted", fugusdk.FuguSearchQuery{Query: "solar", Sort: &[]fugusdk.SortField{{Field: "metadata/date_iso", Order: fugusdk.SortAscending}, {Field: "id", Order: fugusdk.SortAscending}}}, []string{"a2", "a1", "c1"}},
+		{"search after", fugusdk.FuguSearchQuery{Query: "solar", Sort: &[]fugusdk.SortField{{Field: "metadata/date_iso", Order: fugusdk.SortAscending}, {Field: "id", Order: fugusdk.SortAscending}}, SearchAfter: &[]string{"2023-01-15T00:00:00Z", "a2"}}, []string{"a1", "c1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := client.Search(ctx, tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, hit := range resp.Results {
+				got = append(got, hit.ID)
+			}
+			if len(got) != len(tt.wantIDs) {
+				t.Fatalf("got %v, want %v", got, tt.wantIDs)
+			}
+			for i := range got {
+				if got[i] != tt.wantIDs[i] {
+					t.Fatalf("got %v, want %v", got, tt.wantIDs)
+				}
+			}
+		})
+	}
+	if len(srv.Searches()) != len(tests) {
+		t.Errorf("expected %d recorded searches, got %d", len(tests), len(srv.Searches()))
+	}
+}
+
+func TestSearchPagination(t *testing.T) {
+	_, client := seedDockets(t)
+
+	resp, err := client.AdvancedSearch(context.Background(), "solar", nil, 1, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Total != 3 || len(resp.Results) != 1 || resp.Results[0].ID != "c1" {
+		t.Errorf("unexpected second page: total %d, results %+v", resp.Total, resp.Results)
+	}
+}
+
+func TestObjectsAndFilters(t *testing.T) {
+	srv, client := seedDockets(t)
+	ctx := context.Background()
+
+	if _, err := client.GetObjectByID(ctx, "a1"); err != nil {
+		t.Fatalf("get object: %v", err)
+	}
+	if _, err := client.DeleteObject(ctx, "a1"); err != nil {
+		t.Fatalf("delete object: %v", err)
+	}
+	if _, ok := srv.Object("a1"); ok {
+		t.Error("object still stored after delete")
+	}
+	if _, err := client.GetObjectByID(ctx, "a1"); err == nil {
+		t.Error("expected an error reading a deleted object")
+	}
+
+	filters, err := client.GetAllFilters(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := false
+	for _, f := range filters {
+		if f.FilterPath == "metadata/docket_gov_id" {
+			found = len(f.Values) == 1 && f.Values[0] == "18-M-0084"
+		}
+	}
+	if !found {
+		t.Errorf("docket filter values missing from %+v", filters)
+	}
+
+	facets, err := client.GetNamespaceFacets(ctx, "NYPUC")
+	if err != nil {
+		t.Fatal(err)
+	}
+	counts := make(map[string]int64)
+	for _, facet := range facets {
+		counts[facet.Path] = facet.Count
+	}
+	if counts["metadata/entity_type"] != 2 || counts["metadata/entity_type/attachment"] != 1 || counts["namespace/NYPUC/data"] != 1 {
+		t.Errorf("unexpected facet tree %v", counts)
+	}
+}
+
+func TestFilterConfiguration(t *testing.T) {
+	srv, client := seedDockets(t)
+	ctx := context.Background()
+	maxLength := 12
+	srv.SetFilterConfiguration(fugusdk.FilterConfiguration{
+		Fields: []fugusdk.FilterFieldDefinition{
+			{ID: "docket", BackendKey: "metadata/docket_gov_id", DisplayName: "Docket", Enabled: true, Required: true, Validation: &fugusdk.FilterValidation{Pattern: `^\d{2}-[A-Z]-\d{4}$`, MaxLength: &maxLength}},
+			{ID: "author", BackendKey: "metadata/author_names", DisplayName: "Author", Enabled: true},
+			{ID: "retired", DisplayName: "Retired", Enabled: false},
+		},
+	})
+
+	config, err := client.GetNamespaceFilterConfiguration(ctx, "NYPUC")
+	if err != nil || len(config.Fields) != 3 {
+		t.Fatalf("expected the configured fields, got %+v, %v", config, err)
+	}
+
+	validation, err := client.NewFilterBuilder().AddMetadataFilter("docket", "23-E-0418").AddMetadataFilter("author", "Staff").Validate(ctx)
+	if err != nil || !validation.IsValid {
+		t.Errorf("expected valid filters, got %+v, %v", validation, err)
+	}
+	validation, err = client.NewFilterBuilder().AddMetadataFilter("author", "Staff").AddMetadataFilter("retired", "x").Validate(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var kinds []string
+	for _, e := range validation.Errors {
+		kinds = append(kinds, e.FieldID+":"+e.Type)
+	}
+	if validation.IsValid || strings.Join(kinds, ",") != "retired:unknown_field,docket:required" {
+		t.Errorf("unexpected validation %+v", validation)
+	}
+	validation, err = client.NewFilterBuilder().AddMetadataFilter("docket", "docket 23-E-0418").Validate(ctx)
+	if err != nil || validation.IsValid || len(validation.Errors) != 2 {
+		t.Errorf("expected pattern and length errors, got %+v, %v", validation, err)
+	}
+
+	filters, err := client.NewFilterBuilder().AddNamespaceFilter("NYPUC").AddMetadataFilter("docket", "23-E-0418").AddMetadataFilter("retired", "x").Build(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"namespace/NYPUC", "metadata/docket_gov_id:23-E-0418"}; !slices.Equal(filters, want) {
+		t.Errorf("Build = %v, want %v", filters, want)
+	}
+}
+
+func TestFailNext(t *testing.T) {
+	srv, client := seedDockets(t)
+	ctx := context.Background()
+	requests := srv.Requests()
+
+	srv.FailNext(http.StatusBadGateway)
+	var apiErr *fugusdk.APIError
+	if err := client.Health(ctx); !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusBadGateway {
+		t.Fatalf("expected a bad gateway error, got %v", err)
+	}
+	if err := client.Health(ctx); err != nil {
+		t.Fatalf("requests after the queued failures must succeed, got %v", err)
+	}
+	if got := srv.Requests() - requests; got != 2 {
+		t.Errorf("expected 2 requests counted, got %d", got)
+	}
+}
@@ -56,7 +56,7 @@ func (o *ObjectRecord) GenerateNamespaceFacets() []string {
 
 // IngestObjectsWithNamespaceFacets ingests objects with enhanced namespace facet support
 func (c *Client) IngestObjectsWithNamespaceFacets(ctx context.Context, objects []ObjectRecord) (*SanitizedResponse, error) {
-	req := IndexRequest{Data: objects}
+	req := IndexRequest{Data: c.prefixObjects(objects)}
 
 	// Validate request
 	if err := req.Validate(c.sanitizer); err != nil {
@@ -120,6 +120,7 @@ func (c *Client) SearchWithNamespaceFacets(ctx context.Context, query string, na
 
 	var result SanitizedResponse
 	err = c.handleResponse(resp, &result)
+	c.stripResultIDs(&result)
 	return &result, err
 }
 
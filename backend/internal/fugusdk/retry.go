@@ -0,0 +1,87 @@
+// retry.go
+package fugusdk
+
+import (
+	"errors"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// DefaultMaxRetryDelay caps a single backoff, including delays asked for by Retry-After
+const DefaultMaxRetryDelay = 30 * time.Second
+
+// retryableStatus reports whether a response status is worth retrying: rate limits and server side failures
+func retryableStatus(code int) bool {
+	switch code {
+	case http.StatusTooManyRequests,
+		http.StatusInternalServerError,
+		http.StatusBadGateway,
+		http.StatusServiceUnavailable,
+		http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// transportError reports whether a request error came from sending it, rather than from building it
+func transportError(err error) bool {
+	var urlErr *url.Error
+	return errors.As(err, &urlErr)
+}
+
+// retryableError reports whether a transport error is worth retrying: a timeout or a refused or reset
+// connection, which a restarting Fugu causes. DNS and TLS failures will not fix themselves within a backoff.
+func retryableError(err error) bool {
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return false
+	}
+	if errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.ECONNRESET) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// retryAfter reads a Retry-After header given in seconds or as an HTTP date
+func retryAfter(resp *http.Response) (time.Duration, bool) {
+	value := resp.Header.Get("Retry-After")
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		return max(time.Until(at), 0), true
+	}
+	return 0, false
+}
+
+// withoutRetries returns a copy of the client that sends each request once, for callers with their own retries.
+// The copy shares the rate limiter and circuit breaker.
+func (c *Client) withoutRetries() *Client {
+	clone := *c
+	clone.maxRetries = 0
+	return &clone
+}
+
+// backoff returns the delay before a retry attempt: exponential from retryDelay, capped, with the
+// upper half jittered so clients retrying together after a Fugu restart spread out
+func (c *Client) backoff(attempt int) time.Duration {
+	if c.retryDelay <= 0 || attempt <= 0 {
+		return 0
+	}
+	delay := c.retryDelay
+	for i := 1; i < attempt && delay < c.maxRetryDelay; i++ {
+		delay *= 2
+	}
+	delay = min(delay, c.maxRetryDelay)
+	half := delay / 2
+	return half + rand.N(half+1)
+}
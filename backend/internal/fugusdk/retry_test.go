@@ -0,0 +1,58 @@
+package fugusdk
+
+import (
+	"crypto/x509"
+	"errors"
+	"net"
+	"net/url"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestRetryableError(t *testing.T) {
+	sent := func(err error) error {
+		return &url.Error{Op: "Post", URL: "http://fugudb:3301/search", Err: err}
+	}
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"refused", sent(&net.OpError{Op: "dial", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}), true},
+		{"reset", sent(&net.OpError{Op: "read", Err: os.NewSyscallError("read", syscall.ECONNRESET)}), true},
+		{"timeout", sent(&net.OpError{Op: "read", Err: os.ErrDeadlineExceeded}), true},
+		{"unknown host", sent(&net.OpError{Op: "dial", Err: &net.DNSError{Err: "no such host", Name: "fugudb", IsNotFound: true}}), false},
+		{"dns timeout", sent(&net.OpError{Op: "dial", Err: &net.DNSError{Err: "i/o timeout", Name: "fugudb", IsTimeout: true}}), false},
+		{"untrusted certificate", sent(x509.UnknownAuthorityError{}), false},
+		{"other transport failure", sent(errors.New("malformed HTTP response")), false},
+	}
+	for _, tt := range tests {
+		if got := retryableError(tt.err); got != tt.want {
+			t.Errorf("%s: retryableError = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+	if transportError(errors.New("failed to marshal request body")) {
+		t.Error("an error building the request was never sent")
+	}
+}
+
+func TestBreakerForSharesHost(t *testing.T) {
+	if BreakerFor("http://fugu-shared:3301") != BreakerFor("http://FUGU-shared:3301/api/") {
+		t.Error("base URLs of one host must share a breaker")
+	}
+	if BreakerFor("http://fugu-shared:3301") == BreakerFor("http://fugu-other:3301") {
+		t.Error("different hosts must not share a breaker")
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	client := &Client{retryDelay: time.Second, maxRetryDelay: 5 * time.Second}
+	for attempt, want := range map[int]time.Duration{1: time.Second, 2: 2 * time.Second, 3: 4 * time.Second, 6: 5 * time.Second} {
+		got := client.backoff(attempt)
+		if got < want/2 || got > want {
+			t.Errorf("attempt %d: backoff %s outside [%s, %s]", attempt, got, want/2, want)
+		}
+	}
+}
@@ -81,6 +81,13 @@ type Client struct {
 	sanitizer   *InputSanitizer
 	maxRetries  int
 	retryDelay  time.Duration
+	// maxRetryDelay caps exponential backoff and Retry-After waits
+	maxRetryDelay time.Duration
+	breaker       *CircuitBreaker
+	// idPrefix is prepended to object IDs on write and stripped from hits, see WithIDPrefix
+	idPrefix string
+	// capabilities caches the server's optional search features, see Capabilities
+	capabilities *capabilityCache
 }
 
 // InputSanitizer handles input validation and sanitization
@@ -213,15 +220,33 @@ func WithRetry(maxRetries int, delay time.Duration) ClientOption {
 	}
 }
 
-// NewClient creates a new default FuguDB API client
+// WithCircuitBreaker replaces the breaker shared by clients of the same base URL, nil disables it
+func WithCircuitBreaker(breaker *CircuitBreaker) ClientOption {
+	return func(c *Client) error {
+		c.breaker = breaker
+		return nil
+	}
+}
+
+// WithMaxRetryDelay caps a single retry wait
+func WithMaxRetryDelay(delay time.Duration) ClientOption {
+	return func(c *Client) error {
+		if delay <= 0 {
+			return fmt.Errorf("max retry delay must be positive")
+		}
+		c.maxRetryDelay = delay
+		return nil
+	}
+}
+
+// NewClient creates a FuguDB API client for baseURL using the defaults and any FUGU_* environment settings
 func NewClient(ctx context.Context, baseURL string) (*Client, error) {
-	return BuildClient(
-		ctx,
-		baseURL,
-		WithSecureToken("your-token"),
-		WithRateLimit(50, 10),
-		WithRetry(3, time.Second),
-	)
+	cfg := DefaultConfig()
+	if err := cfg.applyEnv(); err != nil {
+		return nil, err
+	}
+	cfg.URL = baseURL
+	return NewClientFromConfig(ctx, cfg)
 }
 
 // BuildClient creates a new custom FuguDB API client
@@ -255,12 +280,15 @@ func BuildClient(ctx context.Context, baseURL string, options ...ClientOption) (
 			Timeout:   DefaultTimeout,
 			Transport: transport,
 		},
-		userAgent:   fmt.Sprintf("fugusdk-go/%s", SDKVersion),
-		tracer:      tracer,
-		rateLimiter: rate.NewLimiter(DefaultRateLimit, DefaultBurst),
-		sanitizer:   NewInputSanitizer(),
-		maxRetries:  3,
-		retryDelay:  1 * time.Second,
+		userAgent:     fmt.Sprintf("fugusdk-go/%s", SDKVersion),
+		tracer:        tracer,
+		rateLimiter:   rate.NewLimiter(DefaultRateLimit, DefaultBurst),
+		sanitizer:     NewInputSanitizer(),
+		maxRetries:    3,
+		retryDelay:    1 * time.Second,
+		maxRetryDelay: DefaultMaxRetryDelay,
+		breaker:       BreakerFor(strings.TrimSuffix(parsedURL.String(), "/")),
+		capabilities:  &capabilityCache{},
 	}
 
 	// Apply options
@@ -353,11 +381,27 @@ type Pagination struct {
 	PerPage *int `json:"per_page,omitempty"`
 }
 
-// FuguSearchQuery matches the Rust FuguSearchQuery struct exactly
+// Sort orders accepted by SortField
+const (
+	SortAscending  = "asc"
+	SortDescending = "desc"
+)
+
+// SortField describes a single sort key, results are ordered by the first key then the next
+type SortField struct {
+	Field string `json:"field"`
+	Order string `json:"order"`
+}
+
+// FuguSearchQuery is the body of a POST search. Sort, SearchAfter and range filters are optional server
+// features that older Fugu servers ignore, Search checks them against Capabilities before sending.
 type FuguSearchQuery struct {
-	Query   string      `json:"query"`
-	Filters *[]string   `json:"filters,omitempty"`
-	Page    *Pagination `json:"page,omitempty"`
+	Query   string       `json:"query"`
+	Filters *[]string    `json:"filters,omitempty"`
+	Page    *Pagination  `json:"page,omitempty"`
+	Sort    *[]SortField `json:"sort,omitempty"`
+	// SearchAfter resumes after the hit with these sort values, used instead of page offsets for deep paging
+	SearchAfter *[]string `json:"search_after,omitempty"`
 }
 
 // IndexRequest matches the Rust IndexRequest struct
@@ -441,36 +485,82 @@ func (e *APIError) Error() string {
 	return fmt.Sprintf("API error %d: %s", e.StatusCode, e.Message)
 }
 
-// makeRequest performs a secure HTTP request with retries and rate limiting
+// BaseURL returns the FuguDB address the client sends requests to
+func (c *Client) BaseURL() string {
+	return c.baseURL
+}
+
+// CircuitBreaker returns the breaker guarding this client's requests
+func (c *Client) CircuitBreaker() *CircuitBreaker {
+	return c.breaker
+}
+
+// makeRequest performs a secure HTTP request with retries and rate limiting.
+// Timeouts, refused or reset connections and 429/5xx responses are retried with jittered exponential backoff,
+// or after the Retry-After the server asked for. Once retries run out the last response is returned so
+// handleResponse reports it. The circuit breaker sees one outcome per call, however many attempts it took.
 func (c *Client) makeRequest(ctx context.Context, method, path string, body interface{}) (*http.Response, error) {
 	// Rate limiting
 	if err := c.rateLimiter.Wait(ctx); err != nil {
 		return nil, fmt.Errorf("rate limit exceeded: %w", err)
 	}
+	if err := c.breaker.allow(); err != nil {
+		return nil, err
+	}
 
 	var lastErr error
+	var delay time.Duration
 	for attempt := 0; attempt <= c.maxRetries; attempt++ {
 		if attempt > 0 {
 			select {
 			case <-ctx.Done():
+				c.breaker.release()
 				return nil, ctx.Err()
-			case <-time.After(c.retryDelay * time.Duration(attempt)):
+			case <-time.After(delay):
 			}
 		}
 
 		resp, err := c.doRequest(ctx, method, path, body)
-		if err == nil {
+		if err != nil {
+			// Requests cancelled by the caller or never sent say nothing about Fugu
+			if ctx.Err() != nil || !transportError(err) {
+				c.breaker.release()
+				return nil, err
+			}
+			lastErr = err
+			if !retryableError(err) {
+				break
+			}
+			delay = c.backoff(attempt + 1)
+			continue
+		}
+
+		if !retryableStatus(resp.StatusCode) {
+			c.breaker.recordSuccess()
 			return resp, nil
 		}
 
-		lastErr = err
+		statusErr := &APIError{StatusCode: resp.StatusCode, Message: http.StatusText(resp.StatusCode)}
+		if attempt == c.maxRetries {
+			// A rate limited Fugu is still up, only server errors count against the breaker
+			if resp.StatusCode == http.StatusTooManyRequests {
+				c.breaker.recordSuccess()
+			} else {
+				c.breaker.recordFailure(statusErr)
+			}
+			return resp, nil
+		}
 
-		// Don't retry on client errors (4xx)
-		if apiErr, ok := err.(*APIError); ok && apiErr.StatusCode >= 400 && apiErr.StatusCode < 500 {
-			break
+		delay = c.backoff(attempt + 1)
+		if wait, ok := retryAfter(resp); ok {
+			delay = min(wait, c.maxRetryDelay)
 		}
+		io.Copy(io.Discard, io.LimitReader(resp.Body, MaxResponseBodySize))
+		resp.Body.Close()
+		lastErr = statusErr
 	}
 
+	c.breaker.recordFailure(lastErr)
 	return nil, lastErr
 }
 
@@ -586,7 +676,7 @@ func (c *Client) Health(ctx context.Context) error {
 // IngestObjects ingests multiple objects into the database (now performs upserts)
 // Enhanced to support namespace facets automatically
 func (c *Client) IngestObjects(ctx context.Context, objects []ObjectRecord) (*SanitizedResponse, error) {
-	req := IndexRequest{Data: objects}
+	req := IndexRequest{Data: c.prefixObjects(objects)}
 
 	// Validate request
 	if err := req.Validate(c.sanitizer); err != nil {
@@ -628,7 +718,7 @@ func (c *Client) IngestObjects(ctx context.Context, objects []ObjectRecord) (*Sa
 
 // UpsertObjects explicitly upserts multiple objects - matches Rust PUT /objects endpoint
 func (c *Client) UpsertObjects(ctx context.Context, objects []ObjectRecord) (*SanitizedResponse, error) {
-	req := IndexRequest{Data: objects}
+	req := IndexRequest{Data: c.prefixObjects(objects)}
 
 	// Validate request
 	if err := req.Validate(c.sanitizer); err != nil {
@@ -647,7 +737,7 @@ func (c *Client) UpsertObjects(ctx context.Context, objects []ObjectRecord) (*Sa
 
 // BatchUpsertObjects performs batch upsert with detailed response - matches Rust /batch/upsert endpoint
 func (c *Client) BatchUpsertObjects(ctx context.Context, objects []ObjectRecord) (*SanitizedResponse, error) {
-	req := BatchIndexRequest{Objects: objects}
+	req := BatchIndexRequest{Objects: c.prefixObjects(objects)}
 
 	// Validate request
 	if err := req.Validate(c.sanitizer); err != nil {
@@ -666,6 +756,8 @@ func (c *Client) BatchUpsertObjects(ctx context.Context, objects []ObjectRecord)
 
 // DeleteObject deletes a single object by ID - matches Rust DELETE /objects/{id} endpoint
 func (c *Client) DeleteObject(ctx context.Context, objectID string) (*SanitizedResponse, error) {
+	objectID = c.idPrefix + objectID
+
 	// Validate object ID
 	if err := c.sanitizer.ValidateObjectID(objectID); err != nil {
 		return nil, fmt.Errorf("validation failed: %w", err)
@@ -685,6 +777,11 @@ func (c *Client) DeleteObject(ctx context.Context, objectID string) (*SanitizedR
 
 // Search performs a POST search - enhanced to automatically use namespace endpoints when appropriate
 func (c *Client) Search(ctx context.Context, query FuguSearchQuery) (*SanitizedResponse, error) {
+	if err := c.checkCapabilities(ctx, query); err != nil {
+		return nil, err
+	}
+	query = c.prefixSearchAfter(query)
+
 	// Check if filters contain namespace facets
 	endpoint := "/search"
 	if query.Filters != nil {
@@ -711,6 +808,7 @@ func (c *Client) Search(ctx context.Context, query FuguSearchQuery) (*SanitizedR
 
 	var result SanitizedResponse
 	err = c.handleResponse(resp, &result)
+	c.stripResultIDs(&result)
 	return &result, err
 }
 
@@ -727,11 +825,14 @@ func (c *Client) SearchText(ctx context.Context, query string) (*SanitizedRespon
 
 	var result SanitizedResponse
 	err = c.handleResponse(resp, &result)
+	c.stripResultIDs(&result)
 	return &result, err
 }
 
 // GetObjectByID retrieves a specific object by its ID - matches Rust /objects/{id} endpoint
 func (c *Client) GetObjectByID(ctx context.Context, objectID string) (*SanitizedResponse, error) {
+	objectID = c.idPrefix + objectID
+
 	// Validate object ID
 	if err := c.sanitizer.ValidateObjectID(objectID); err != nil {
 		return nil, fmt.Errorf("validation failed: %w", err)
@@ -746,6 +847,7 @@ func (c *Client) GetObjectByID(ctx context.Context, objectID string) (*Sanitized
 
 	var result SanitizedResponse
 	err = c.handleResponse(resp, &result)
+	c.stripResultIDs(&result)
 	return &result, err
 }
 
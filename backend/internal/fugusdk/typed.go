@@ -0,0 +1,251 @@
+// typed.go
+package fugusdk
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Entity types written to metadata/entity_type by the indexers
+const (
+	EntityAttachment   = "attachment"
+	EntityConversation = "conversation"
+	EntityOrganization = "organization"
+)
+
+// AttachmentMetadata is the metadata the attachment indexer writes for each attachment segment.
+// Keys from the attachment's raw metadata are merged in as well, so unknown keys are expected.
+// Identifiers come first so a bad id is the error reported over a bad descriptive field.
+type AttachmentMetadata struct {
+	EntityType     string      `json:"entity_type"`
+	AttachmentID   uuid.UUID   `json:"attachment_id"`
+	FileID         uuid.UUID   `json:"file_id"`
+	ConversationID uuid.UUID   `json:"conversation_id"`
+	AuthorIDs      []uuid.UUID `json:"author_ids,omitempty"`
+	FileName       string      `json:"file_name,omitempty"`
+	FileExtension  string      `json:"file_extension,omitempty"`
+	DocketGovID    string      `json:"docket_gov_id,omitempty"`
+	AuthorNames    []string    `json:"author_names,omitempty"`
+	Description    string      `json:"description,omitempty"`
+	CaseNumber     string      `json:"case_number,omitempty"`
+	CreatedAt      time.Time   `json:"created_at,omitempty"`
+	// Date is the publish date as filed, DateISO is the same date normalised by the indexer
+	Date          string    `json:"date,omitempty"`
+	DateISO       time.Time `json:"date_iso,omitempty"`
+	DateYear      string    `json:"date_year,omitempty"`
+	IsSegmented   bool      `json:"is_segmented"`
+	SegmentIndex  int       `json:"segment_index"`
+	TotalSegments int       `json:"total_segments"`
+	SegmentOffset int       `json:"segment_offset"`
+}
+
+// ConversationMetadata is the metadata the conversation indexer writes for each docket
+type ConversationMetadata struct {
+	EntityType     string    `json:"entity_type"`
+	ConversationID uuid.UUID `json:"conversation_id"`
+	Name           string    `json:"name,omitempty"`
+	DocketGovID    string    `json:"docket_gov_id,omitempty"`
+	State          string    `json:"state,omitempty"`
+	MatterType     string    `json:"matter_type,omitempty"`
+	IndustryType   string    `json:"industry_type,omitempty"`
+	Description    string    `json:"description,omitempty"`
+	TotalDocuments int       `json:"total_documents,omitempty"`
+}
+
+// OrganizationMetadata is the metadata the organization indexer writes for each organization
+type OrganizationMetadata struct {
+	EntityType             string    `json:"entity_type"`
+	OrganizationID         uuid.UUID `json:"organization_id"`
+	OrganizationName       string    `json:"organization_name"`
+	IsPerson               bool      `json:"is_person"`
+	TotalDocumentsAuthored int       `json:"total_documents_authored,omitempty"`
+	Description            string    `json:"description,omitempty"`
+	Aliases                []string  `json:"organization_aliases,omitempty"`
+}
+
+// DecodeMode controls what happens when result metadata does not match its typed struct
+type DecodeMode int
+
+const (
+	// DecodeLenient keeps every result, those that failed to decode carry the error in SearchResult.Err
+	DecodeLenient DecodeMode = iota
+	// DecodeStrict stops at the first result that fails to decode and returns its DecodeError
+	DecodeStrict
+)
+
+// DecodeError reports a metadata key whose value does not fit the typed field
+type DecodeError struct {
+	ResultID string
+	Field    string
+	Value    interface{}
+	Err      error
+	// More holds the fields after Field that also failed to decode, in T's field order
+	More []*DecodeError
+}
+
+func (e *DecodeError) Error() string {
+	msg := fmt.Sprintf("result %s: metadata field %q (%v): %v", e.ResultID, e.Field, e.Value, e.Err)
+	if len(e.More) > 0 {
+		msg += fmt.Sprintf(" (and %d more fields)", len(e.More))
+	}
+	return msg
+}
+
+// Fields returns every metadata key that failed to decode
+func (e *DecodeError) Fields() []string {
+	fields := []string{e.Field}
+	for _, more := range e.More {
+		fields = append(fields, more.Field)
+	}
+	return fields
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// SearchResult is a search hit with metadata decoded into T
+type SearchResult[T any] struct {
+	ID       string
+	Score    float32
+	Text     string
+	Facets   []string
+	Metadata T
+	// Raw holds the untyped metadata, including keys T does not declare
+	Raw map[string]interface{}
+	// Err is set in lenient mode when a metadata field failed to decode, the other fields are still filled
+	Err *DecodeError
+}
+
+// TypedResponse is a search response with typed results
+type TypedResponse[T any] struct {
+	Results []SearchResult[T]
+	Total   int
+	Page    int
+	PerPage int
+}
+
+// DecodeResult decodes one search hit, fields are decoded independently so one bad value does not
+// hide the rest and the error names the offending key. When several keys fail the first in T's field order is reported
+// and the others are kept in DecodeError.More.
+func DecodeResult[T any](result FuguSearchResult) (SearchResult[T], error) {
+	decoded := SearchResult[T]{
+		ID:     result.ID,
+		Score:  result.Score,
+		Text:   result.Text,
+		Facets: result.Facets,
+		Raw:    result.Metadata,
+	}
+	if err := decodeMetadata(result.ID, result.Metadata, &decoded.Metadata); err != nil {
+		decoded.Err = err
+		return decoded, err
+	}
+	return decoded, nil
+}
+
+// DecodeResults decodes a page of search hits
+func DecodeResults[T any](results []FuguSearchResult, mode DecodeMode) ([]SearchResult[T], error) {
+	decoded := make([]SearchResult[T], 0, len(results))
+	for _, result := range results {
+		typed, err := DecodeResult[T](result)
+		if err != nil && mode == DecodeStrict {
+			return nil, err
+		}
+		decoded = append(decoded, typed)
+	}
+	return decoded, nil
+}
+
+// DecodeResponse decodes every hit of a search response
+func DecodeResponse[T any](resp *SanitizedResponse, mode DecodeMode) (*TypedResponse[T], error) {
+	if resp == nil {
+		return &TypedResponse[T]{}, nil
+	}
+	results, err := DecodeResults[T](resp.Results, mode)
+	if err != nil {
+		return nil, err
+	}
+	return &TypedResponse[T]{
+		Results: results,
+		Total:   resp.Total,
+		Page:    resp.Page,
+		PerPage: resp.PerPage,
+	}, nil
+}
+
+// metadataField is a struct field and the metadata key it decodes from
+type metadataField struct {
+	key   string
+	index int
+}
+
+// metadataFieldCache maps struct types to their fields in declaration order
+var metadataFieldCache sync.Map
+
+func metadataFields(t reflect.Type) []metadataField {
+	if cached, ok := metadataFieldCache.Load(t); ok {
+		return cached.([]metadataField)
+	}
+	var fields []metadataField
+	for i := range t.NumField() {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		key, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if key == "-" {
+			continue
+		}
+		if key == "" {
+			key = field.Name
+		}
+		fields = append(fields, metadataField{key: key, index: i})
+	}
+	metadataFieldCache.Store(t, fields)
+	return fields
+}
+
+// decodeMetadata fills target, a pointer to a struct, from metadata one field at a time.
+// Keys without a matching field are ignored, missing or null values leave the field at its zero value.
+func decodeMetadata(resultID string, metadata map[string]interface{}, target interface{}) *DecodeError {
+	value := reflect.ValueOf(target).Elem()
+	if value.Kind() != reflect.Struct {
+		raw, err := json.Marshal(metadata)
+		if err == nil {
+			err = json.Unmarshal(raw, target)
+		}
+		if err != nil {
+			return &DecodeError{ResultID: resultID, Err: err}
+		}
+		return nil
+	}
+
+	var first *DecodeError
+	for _, field := range metadataFields(value.Type()) {
+		raw, ok := metadata[field.key]
+		if !ok || raw == nil {
+			continue
+		}
+		encoded, err := json.Marshal(raw)
+		if err == nil {
+			err = json.Unmarshal(encoded, value.Field(field.index).Addr().Interface())
+		}
+		if err == nil {
+			continue
+		}
+		// Keep decoding the remaining fields so lenient callers get everything that does fit
+		fieldErr := &DecodeError{ResultID: resultID, Field: field.key, Value: raw, Err: err}
+		if first == nil {
+			first = fieldErr
+		} else {
+			first.More = append(first.More, fieldErr)
+		}
+	}
+	return first
+}
@@ -0,0 +1,51 @@
+package fugusdk
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestDecodeResults(t *testing.T) {
+	good := uuid.New()
+	results := []FuguSearchResult{
+		{ID: "good", Metadata: map[string]interface{}{
+			"file_id":       good.String(),
+			"author_ids":    []interface{}{good.String()},
+			"file_name":     "Order.pdf",
+			"segment_index": float64(2),
+			"unknown_key":   "ignored",
+		}},
+		{ID: "bad", Metadata: map[string]interface{}{
+			"file_id":         "not-a-uuid",
+			"file_name":       "Brief.pdf",
+			"conversation_id": "also-not-a-uuid",
+		}},
+	}
+
+	decoded, err := DecodeResults[AttachmentMetadata](results, DecodeLenient)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded) != 2 {
+		t.Fatalf("lenient decode should keep every result, got %d", len(decoded))
+	}
+	first := decoded[0]
+	if first.Err != nil || first.Metadata.FileID != good || first.Metadata.AuthorIDs[0] != good || first.Metadata.SegmentIndex != 2 {
+		t.Errorf("unexpected decode %+v", first)
+	}
+	second := decoded[1]
+	if second.Err == nil || second.Err.Field != "file_id" || second.Metadata.FileName != "Brief.pdf" {
+		t.Errorf("expected file_id error with other fields kept, got %+v", second)
+	}
+	if fields := second.Err.Fields(); len(fields) != 2 || fields[1] != "conversation_id" {
+		t.Errorf("every failing field must be reported, got %v", fields)
+	}
+
+	_, err = DecodeResults[AttachmentMetadata](results, DecodeStrict)
+	var decodeErr *DecodeError
+	if !errors.As(err, &decodeErr) || decodeErr.ResultID != "bad" || decodeErr.Field != "file_id" {
+		t.Errorf("strict decode should report the offending field, got %v", err)
+	}
+}
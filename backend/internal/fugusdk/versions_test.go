@@ -0,0 +1,54 @@
+package fugusdk
+
+import "testing"
+
+func TestParseVersionedNamespace(t *testing.T) {
+	cases := []struct {
+		name    string
+		base    string
+		version int
+		ok      bool
+	}{
+		{"NYPUC_v3", "NYPUC", 3, true},
+		{"my_ns_v12", "my_ns", 12, true},
+		{"NYPUC", "", 0, false},
+		{"NYPUC_v", "", 0, false},
+		{"NYPUC_v0", "", 0, false},
+		{"NYPUC_v03", "", 0, false},
+		{"_v2", "", 0, false},
+	}
+	for _, tc := range cases {
+		base, version, ok := ParseVersionedNamespace(tc.name)
+		if base != tc.base || version != tc.version || ok != tc.ok {
+			t.Errorf("ParseVersionedNamespace(%q) = %q, %d, %v", tc.name, base, version, ok)
+		}
+	}
+}
+
+func TestIDPrefixRoundTrip(t *testing.T) {
+	c := (&Client{}).WithIDPrefix(VersionIDPrefix("NYPUC_v2"))
+
+	objects := []ObjectRecord{{ID: "a"}, {ID: "b"}}
+	prefixed := c.prefixObjects(objects)
+	if prefixed[0].ID != "NYPUC_v2.a" || prefixed[1].ID != "NYPUC_v2.b" {
+		t.Errorf("unexpected prefixed IDs %q %q", prefixed[0].ID, prefixed[1].ID)
+	}
+	if objects[0].ID != "a" {
+		t.Errorf("caller's objects were modified: %q", objects[0].ID)
+	}
+
+	after := []string{"0.5", "a"}
+	query := c.prefixSearchAfter(FuguSearchQuery{SearchAfter: &after})
+	if got := *query.SearchAfter; got[0] != "0.5" || got[1] != "NYPUC_v2.a" {
+		t.Errorf("unexpected search_after %v", got)
+	}
+	if after[1] != "a" {
+		t.Errorf("caller's search_after was modified: %v", after)
+	}
+
+	resp := &SanitizedResponse{Results: []FuguSearchResult{{ID: "NYPUC_v2.a"}, {ID: "legacy"}}}
+	c.stripResultIDs(resp)
+	if resp.Results[0].ID != "a" || resp.Results[1].ID != "legacy" {
+		t.Errorf("unexpected stripped IDs %q %q", resp.Results[0].ID, resp.Results[1].ID)
+	}
+}
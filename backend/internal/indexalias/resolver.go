@@ -0,0 +1,119 @@
+// Package indexalias resolves search index aliases to the versioned index they were last switched to.
+// Versions are built and switched by the indexing package, search reads through the resolver.
+package indexalias
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"kessler/internal/dbstore"
+	"kessler/internal/fugusdk"
+	"kessler/pkg/database"
+	"kessler/pkg/logger"
+
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+)
+
+// Search backends whose indexes are rebuilt into versions
+const (
+	BackendFugu     = "fugu"
+	BackendQuickwit = "quickwit"
+)
+
+// CacheTTL bounds how long a replica keeps searching the previous version after an alias switch
+const CacheTTL = 30 * time.Second
+
+type aliasKey struct {
+	backend string
+	alias   string
+}
+
+type aliasEntry struct {
+	target  string
+	expires time.Time
+}
+
+// Resolver maps index aliases to the version they were last switched to. Lookups are cached for
+// CacheTTL so searches do not query Postgres on every request.
+type Resolver struct {
+	db      dbstore.DBTX
+	mu      sync.Mutex
+	entries map[aliasKey]aliasEntry
+}
+
+// NewResolver creates a resolver reading the alias table through db
+func NewResolver(db dbstore.DBTX) *Resolver {
+	return &Resolver{
+		db:      db,
+		entries: make(map[aliasKey]aliasEntry),
+	}
+}
+
+// Resolve returns the namespace or index alias currently points at, alias itself when it was never switched.
+// A failed lookup keeps the last known target, or the alias, so search keeps working while Postgres is down.
+func (r *Resolver) Resolve(ctx context.Context, backend, alias string) string {
+	if r == nil || alias == "" {
+		return alias
+	}
+	key := aliasKey{backend: backend, alias: alias}
+
+	r.mu.Lock()
+	entry, cached := r.entries[key]
+	r.mu.Unlock()
+	if cached && time.Now().Before(entry.expires) {
+		return entry.target
+	}
+
+	target := alias
+	row, err := database.GetQueries(r.db).SearchIndexAliasRead(ctx, dbstore.SearchIndexAliasReadParams{
+		Backend: backend,
+		Alias:   alias,
+	})
+	switch {
+	case err == nil:
+		target = row.Target
+	case errors.Is(err, pgx.ErrNoRows):
+	default:
+		logger.Warn(ctx, "index alias lookup failed, using last known target",
+			zap.String("backend", backend),
+			zap.String("alias", alias),
+			zap.Error(err))
+		if cached {
+			target = entry.target
+		}
+	}
+
+	r.mu.Lock()
+	r.entries[key] = aliasEntry{target: target, expires: time.Now().Add(CacheTTL)}
+	r.mu.Unlock()
+	return target
+}
+
+// Invalidate drops the cached target of an alias so the next lookup reads the switch just made
+func (r *Resolver) Invalidate(backend, alias string) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	delete(r.entries, aliasKey{backend: backend, alias: alias})
+	r.mu.Unlock()
+}
+
+// QuickwitIndex resolves a Quickwit index alias, it matches the signature quickwit.SetIndexResolver expects
+func (r *Resolver) QuickwitIndex(name string) string {
+	return r.Resolve(context.Background(), BackendQuickwit, name)
+}
+
+// FuguTarget returns the namespace a search of namespace should filter on and the client to run it with.
+// Once namespace has been switched to a version that is the version, searched through a client that strips
+// the version's ID prefix from hits.
+func (r *Resolver) FuguTarget(ctx context.Context, client *fugusdk.Client, namespace string) (string, *fugusdk.Client) {
+	target := r.Resolve(ctx, BackendFugu, namespace)
+	if target == namespace {
+		return namespace, client
+	}
+	return target, client.WithIDPrefix(fugusdk.VersionIDPrefix(target))
+}
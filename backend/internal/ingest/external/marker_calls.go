@@ -2,125 +2,144 @@ package external
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"kessler/pkg/constants"
 	"kessler/pkg/hashes"
 	"kessler/pkg/s3utils"
 	"net/http"
-	"os"
 	"time"
 
 	"github.com/charmbracelet/log"
 )
 
-func getIntEnv(key string) int {
-	valStr := os.Getenv(key)
-	if valStr == "" {
-		log.Fatal("Environment variable not set", "key", key)
+// MarkerClient submits documents to the Marker transcription server and polls until the markdown is ready
+type MarkerClient struct {
+	BaseURL      string
+	HTTPClient   *http.Client
+	MaxPolls     int
+	PollInterval time.Duration
+}
+
+// NewMarkerClient configures a client from MARKER_ENDPOINT_URL, MARKER_MAX_POLLS and MARKER_SECONDS_PER_POLL
+func NewMarkerClient() *MarkerClient {
+	return &MarkerClient{
+		BaseURL:      constants.MARKER_ENDPOINT_URL,
+		HTTPClient:   &http.Client{Timeout: 30 * time.Second},
+		MaxPolls:     constants.MARKER_MAX_POLLS,
+		PollInterval: time.Duration(constants.MARKER_SECONDS_PER_POLL) * time.Second,
 	}
-	var val int
-	fmt.Sscanf(valStr, "%d", &val)
-	return val
 }
 
-func PollMarkerEndpointForResponse(requestCheckURL string, maxPolls int, pollWait int) (string, error) {
-	for polls := 0; polls < maxPolls; polls++ {
-		time.Sleep(time.Duration(pollWait) * time.Second)
-		resp, err := http.Get(requestCheckURL)
-		if err != nil {
-			return "", err
-		}
-		defer resp.Body.Close()
+type markerStatus struct {
+	Status   string `json:"status"`
+	Markdown string `json:"markdown"`
+	Error    string `json:"error"`
+}
+
+func (c *MarkerClient) do(req *http.Request, out any) error {
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("marker server returned status %d: %s", resp.StatusCode, string(body))
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
 
-		var pollData map[string]interface{}
-		err = json.NewDecoder(resp.Body).Decode(&pollData)
+// Poll checks requestCheckURL until the document is complete, errored or MaxPolls is reached
+func (c *MarkerClient) Poll(ctx context.Context, requestCheckURL string) (string, error) {
+	for polls := 0; polls < c.MaxPolls; polls++ {
+		select {
+		case <-time.After(c.PollInterval):
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, requestCheckURL, nil)
 		if err != nil {
 			return "", err
 		}
-
-		status, ok := pollData["status"].(string)
-		if !ok {
-			return "", fmt.Errorf("status not found in response")
+		var pollData markerStatus
+		if err := c.do(req, &pollData); err != nil {
+			return "", err
 		}
 
-		switch status {
+		switch pollData.Status {
 		case "complete":
-			markdown, ok := pollData["markdown"].(string)
-			if !ok {
-				return "", fmt.Errorf("markdown not found in response")
-			}
-			if markdown == "" {
+			if pollData.Markdown == "" {
 				return "", fmt.Errorf("got empty string from markdown server")
 			}
-			log.Info("Processed document after polls", "polls", polls, "text", markdown[:50])
-			return markdown, nil
+			log.Info("Processed document after polls", "polls", polls, "length", len(pollData.Markdown))
+			return pollData.Markdown, nil
 		case "error":
-			errorMsg, ok := pollData["error"].(string)
-			if !ok {
-				return "", fmt.Errorf("error not found in response")
-			}
-			log.Error("Pdf server encountered an error", "polls", polls, "error", errorMsg)
-			return "", fmt.Errorf("pdf server encountered an error after polls: %s", errorMsg)
+			log.Error("Pdf server encountered an error", "polls", polls, "error", pollData.Error)
+			return "", fmt.Errorf("pdf server encountered an error after polls: %s", pollData.Error)
+		case "processing":
+		case "":
+			return "", fmt.Errorf("status not found in response")
 		default:
-			if status != "processing" {
-				return "", fmt.Errorf("pdf processing failed. status was unrecognized %s after polls %d", status, polls)
-			}
+			return "", fmt.Errorf("pdf processing failed. status was unrecognized %s after polls %d", pollData.Status, polls)
 		}
 	}
 
 	return "", fmt.Errorf("polling for marker API result timed out")
 }
 
-func TranscribePdfS3URI(s3URI string, externalProcess bool, priority bool) (string, error) {
-	baseURL := constants.MARKER_ENDPOINT_URL
-	var queryStr string
-	if priority {
-		queryStr = "?priority=true"
-	} else {
-		queryStr = "?priority=false"
-	}
-	markerURLEndpoint := baseURL + "/api/v1/marker/direct_s3_url_upload" + queryStr
+// TranscribeURI asks Marker to fetch and transcribe the document at fileURI, returning its markdown
+func (c *MarkerClient) TranscribeURI(ctx context.Context, fileURI string, priority bool) (string, error) {
+	markerURLEndpoint := fmt.Sprintf("%s/api/v1/marker/direct_s3_url_upload?priority=%t", c.BaseURL, priority)
 
-	data := map[string]string{"s3_url": s3URI}
-	log.Info("Sending request to marker server", "data", data)
-	body, err := json.Marshal(data)
+	body, err := json.Marshal(map[string]string{"s3_url": fileURI})
 	if err != nil {
 		return "", err
 	}
 	log.Info("Sending request to marker server", "body", string(body))
 
-	resp, err := http.Post(
-		markerURLEndpoint,
-		"application/json",
-		bytes.NewBuffer(body),
-	)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, markerURLEndpoint, bytes.NewReader(body))
 	if err != nil {
 		return "", err
 	}
-	defer resp.Body.Close()
+	req.Header.Set("Content-Type", "application/json")
 
-	var response map[string]interface{}
-	err = json.NewDecoder(resp.Body).Decode(&response)
-	if err != nil {
+	var response struct {
+		RequestCheckURLLeaf string `json:"request_check_url_leaf"`
+	}
+	if err := c.do(req, &response); err != nil {
 		return "", err
 	}
-
-	requestCheckURLLeaf, ok := response["request_check_url_leaf"].(string)
-	if !ok {
+	if response.RequestCheckURLLeaf == "" {
 		return "", fmt.Errorf("request_check_url_leaf not found in response")
 	}
-	requestCheckURL := baseURL + requestCheckURLLeaf
+	requestCheckURL := c.BaseURL + response.RequestCheckURLLeaf
 	log.Info("Got response from marker server, polling to see when file is finished processing", "requestCheckURL", requestCheckURL)
-	return PollMarkerEndpointForResponse(requestCheckURL, constants.MARKER_MAX_POLLS, constants.MARKER_SECONDS_PER_POLL)
+	return c.Poll(ctx, requestCheckURL)
 }
 
-func TranscribePDFFromHash(hash hashes.KesslerHash) (string, error) {
-	s3_client := s3utils.NewKeFileManager()
-
-	s3_uri, err := s3_client.GetURIFromHash(hash)
+// TranscribeHash transcribes an attachment, handing Marker a presigned URL from the blob store
+func (c *MarkerClient) TranscribeHash(ctx context.Context, fileManager *s3utils.KesslerFileManager, hash hashes.KesslerHash) (string, error) {
+	fileURI, err := fileManager.GetURIFromHash(ctx, hash)
 	if err != nil {
 		return "", err
 	}
-	return TranscribePdfS3URI(s3_uri, true, true)
+	return c.TranscribeURI(ctx, fileURI, true)
+}
+
+func PollMarkerEndpointForResponse(requestCheckURL string, maxPolls int, pollWait int) (string, error) {
+	client := NewMarkerClient()
+	client.MaxPolls = maxPolls
+	client.PollInterval = time.Duration(pollWait) * time.Second
+	return client.Poll(context.Background(), requestCheckURL)
+}
+
+func TranscribePdfS3URI(s3URI string, externalProcess bool, priority bool) (string, error) {
+	return NewMarkerClient().TranscribeURI(context.Background(), s3URI, priority)
+}
+
+func TranscribePDFFromHash(ctx context.Context, hash hashes.KesslerHash) (string, error) {
+	return NewMarkerClient().TranscribeHash(ctx, s3utils.NewKeFileManager(), hash)
 }
@@ -0,0 +1,79 @@
+package extract
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// openZipPart opens one part of an OOXML package capped at maxPartSize, nil when the part is missing
+func openZipPart(archive *zip.Reader, name string) (io.ReadCloser, error) {
+	for _, file := range archive.File {
+		if strings.TrimPrefix(file.Name, "/") == name {
+			part, err := file.Open()
+			if err != nil {
+				return nil, fmt.Errorf("opening %s: %w", name, err)
+			}
+			return struct {
+				io.Reader
+				io.Closer
+			}{io.LimitReader(part, maxPartSize), part}, nil
+		}
+	}
+	return nil, nil
+}
+
+// DOCXText reads the main document body one paragraph per line, including the paragraphs in table cells
+func DOCXText(r io.ReaderAt, size int64) (string, error) {
+	archive, err := zip.NewReader(r, size)
+	if err != nil {
+		return "", fmt.Errorf("reading docx: %w", err)
+	}
+	part, err := openZipPart(archive, "word/document.xml")
+	if err != nil {
+		return "", err
+	}
+	if part == nil {
+		return "", errors.New("docx has no word/document.xml")
+	}
+	defer part.Close()
+
+	var out strings.Builder
+	decoder := xml.NewDecoder(part)
+	inText := false
+	for {
+		token, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return "", fmt.Errorf("parsing docx: %w", err)
+		}
+		switch token := token.(type) {
+		case xml.StartElement:
+			switch token.Name.Local {
+			case "t":
+				inText = true
+			case "tab":
+				out.WriteString("\t")
+			case "br", "cr":
+				out.WriteString("\n")
+			}
+		case xml.EndElement:
+			switch token.Name.Local {
+			case "t":
+				inText = false
+			case "p", "tr":
+				out.WriteString("\n")
+			}
+		case xml.CharData:
+			if inText {
+				out.Write(token)
+			}
+		}
+	}
+	return collapseLines(out.String()), nil
+}
@@ -0,0 +1,133 @@
+// Package extract turns raw attachments into the text that is stored, summarized and searched. Each known
+// file extension has an Extractor, PDFs go through the Marker transcription server and the office and web
+// formats are parsed in process.
+package extract
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"kessler/internal/ingest/external"
+	"kessler/internal/objects/files"
+	"kessler/pkg/hashes"
+	"kessler/pkg/s3utils"
+)
+
+var (
+	// ErrUnsupported is returned for extensions without a registered extractor
+	ErrUnsupported = errors.New("no text extractor for extension")
+	// ErrNoText is returned when a document parsed but held no text, such as a scanned page without OCR
+	ErrNoText = errors.New("no text found in document")
+)
+
+// maxPartSize bounds how much of one decompressed part of a DOCX or XLSX is read
+const maxPartSize = 256 << 20
+
+// Document is an attachment to extract text from
+type Document struct {
+	Hash  hashes.KesslerHash
+	Files *s3utils.KesslerFileManager
+}
+
+// Extractor produces the text of a document
+type Extractor interface {
+	Extract(ctx context.Context, doc Document) (string, error)
+}
+
+// NativeExtractor parses a local copy of the attachment in process
+type NativeExtractor func(r io.ReaderAt, size int64) (string, error)
+
+func (extractor NativeExtractor) Extract(ctx context.Context, doc Document) (string, error) {
+	path, err := doc.Files.LocalPath(ctx, doc.Hash)
+	if err != nil {
+		return "", err
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	stat, err := file.Stat()
+	if err != nil {
+		return "", err
+	}
+	return extractor(file, stat.Size())
+}
+
+// MarkerExtractor transcribes documents to markdown with the Marker server
+type MarkerExtractor struct {
+	Client *external.MarkerClient
+}
+
+func (extractor MarkerExtractor) Extract(ctx context.Context, doc Document) (string, error) {
+	return extractor.Client.TranscribeHash(ctx, doc.Files, doc.Hash)
+}
+
+// Registry picks the extractor for a file extension
+type Registry map[files.KnownFileExtension]Extractor
+
+// NewRegistry registers Marker for PDFs and the native extractors for everything else
+func NewRegistry(marker *external.MarkerClient) Registry {
+	return Registry{
+		files.KnownFileExtensionPDF:  MarkerExtractor{Client: marker},
+		files.KnownFileExtensionDOCX: NativeExtractor(DOCXText),
+		files.KnownFileExtensionXLSX: NativeExtractor(XLSXText),
+		files.KnownFileExtensionHTML: NativeExtractor(HTMLText),
+		files.KnownFileExtensionMD:   NativeExtractor(MarkdownText),
+	}
+}
+
+// DefaultRegistry uses the Marker server configured in the environment
+func DefaultRegistry() Registry {
+	return NewRegistry(external.NewMarkerClient())
+}
+
+// Extract runs the extractor registered for extension, trimming the result
+func (registry Registry) Extract(ctx context.Context, extension files.KnownFileExtension, doc Document) (string, error) {
+	extractor, ok := registry[extension]
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrUnsupported, extension)
+	}
+	text, err := extractor.Extract(ctx, doc)
+	if err != nil {
+		return "", fmt.Errorf("extracting %s text from %s: %w", extension, doc.Hash, err)
+	}
+	text = strings.TrimSpace(strings.ToValidUTF8(text, "�"))
+	if text == "" {
+		return "", fmt.Errorf("%w: %s", ErrNoText, doc.Hash)
+	}
+	return text, nil
+}
+
+// MarkdownText returns markdown as is, it is already the format every other extractor aims for
+func MarkdownText(r io.ReaderAt, size int64) (string, error) {
+	content, err := io.ReadAll(io.NewSectionReader(r, 0, size))
+	if err != nil {
+		return "", err
+	}
+	return string(content), nil
+}
+
+// collapseLines trims every line, squeezes runs of spaces and keeps at most one blank line in a row
+func collapseLines(text string) string {
+	var out strings.Builder
+	blank := true
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.Join(strings.Fields(line), " ")
+		if line == "" {
+			if !blank {
+				out.WriteString("\n")
+			}
+			blank = true
+			continue
+		}
+		out.WriteString(line)
+		out.WriteString("\n")
+		blank = false
+	}
+	return strings.TrimSpace(out.String())
+}
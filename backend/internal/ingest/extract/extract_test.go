@@ -0,0 +1,202 @@
+package extract
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"kessler/internal/ingest/external"
+	"kessler/internal/objects/files"
+	"kessler/pkg/blobstore"
+	"kessler/pkg/hashes"
+	"kessler/pkg/s3utils"
+)
+
+func zipOf(t *testing.T, parts map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+	for name, content := range parts {
+		w, err := archive.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(content))
+	}
+	if err := archive.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestDOCXText(t *testing.T) {
+	document := `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"><w:body>
+<w:p><w:r><w:t>Direct Testimony of</w:t></w:r><w:r><w:t xml:space="preserve"> Jane Doe</w:t></w:r></w:p>
+<w:p><w:r><w:t>Q.</w:t><w:tab/><w:t>Please state your name.</w:t></w:r></w:p>
+<w:tbl><w:tr><w:tc><w:p><w:r><w:t>Rate</w:t></w:r></w:p></w:tc><w:tc><w:p><w:r><w:t>4.2%</w:t></w:r></w:p></w:tc></w:tr></w:tbl>
+<w:p><w:r><w:instrText>PAGE</w:instrText></w:r></w:p>
+</w:body></w:document>`
+	content := zipOf(t, map[string]string{"word/document.xml": document})
+	text, err := DOCXText(bytes.NewReader(content), int64(len(content)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "Direct Testimony of Jane Doe\nQ. Please state your name.\nRate\n4.2%"
+	if text != want {
+		t.Errorf("DOCXText = %q, want %q", text, want)
+	}
+
+	notDocx := zipOf(t, map[string]string{"other.xml": "<a/>"})
+	if _, err := DOCXText(bytes.NewReader(notDocx), int64(len(notDocx))); err == nil {
+		t.Error("expected an error without word/document.xml")
+	}
+}
+
+func TestXLSXText(t *testing.T) {
+	content := zipOf(t, map[string]string{
+		"xl/workbook.xml": `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">
+<sheets><sheet name="Rates" sheetId="1" r:id="rId1"/><sheet name="Empty" sheetId="2" r:id="rId2"/></sheets></workbook>`,
+		"xl/_rels/workbook.xml.rels": `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Target="worksheets/sheet1.xml"/><Relationship Id="rId2" Target="/xl/worksheets/sheet2.xml"/></Relationships>`,
+		"xl/sharedStrings.xml": `<sst xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+<si><t>Class</t></si><si><r><t>Rate, </t></r><r><t>per kWh</t></r></si><si><t>Residential</t></si></sst>`,
+		"xl/worksheets/sheet1.xml": `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>
+<row r="1"><c r="A1" t="s"><v>0</v></c><c r="B1" t="s"><v>1</v></c></row>
+<row r="2"><c r="A2" t="s"><v>2</v></c><c r="C2"><v>0.21</v></c></row>
+<row r="3"><c r="B3" t="inlineStr"><is><t>note</t></is></c><c r="C3" t="b"><v>1</v></c></row>
+<row r="4"><c r="A4"/></row>
+</sheetData></worksheet>`,
+		"xl/worksheets/sheet2.xml": `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData/></worksheet>`,
+	})
+	text, err := XLSXText(bytes.NewReader(content), int64(len(content)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "## Rates\n\nClass,\"Rate, per kWh\"\nResidential,,0.21\n,note,TRUE\n\n"
+	if text != want {
+		t.Errorf("XLSXText = %q, want %q", text, want)
+	}
+}
+
+func TestXLSXColumn(t *testing.T) {
+	tests := map[string]int{"A1": 0, "Z9": 25, "AA10": 26, "AB12": 27}
+	for ref, want := range tests {
+		if got, ok := xlsxColumn(ref); !ok || got != want {
+			t.Errorf("xlsxColumn(%q) = %d, %v, want %d", ref, got, ok, want)
+		}
+	}
+	if _, ok := xlsxColumn("12"); ok {
+		t.Error("a reference without letters has no column")
+	}
+}
+
+func TestHTMLText(t *testing.T) {
+	page := `<!DOCTYPE html><html><head><title>Docket</title><style>p { color: red }</style></head>
+<body><h1>Order &amp; Notice</h1><script>var x = "hidden";</script>
+<p>The <b>Commission</b>   finds<br>that rates are just.</p>
+<table><tr><td>Filed</td><td>2024-01-05</td></tr></table></body></html>`
+	text, err := HTMLText(strings.NewReader(page), int64(len(page)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "Order & Notice\n\nThe Commission finds\nthat rates are just.\n\nFiled 2024-01-05"
+	if text != want {
+		t.Errorf("HTMLText = %q, want %q", text, want)
+	}
+}
+
+func newMarkerStub(t *testing.T, finalStatus map[string]string) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var polls atomic.Int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v1/marker/direct_s3_url_upload", func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]string
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body["s3_url"] == "" {
+			http.Error(w, "missing s3_url", http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"request_check_url_leaf": "/api/v1/marker/status/1"})
+	})
+	mux.HandleFunc("/api/v1/marker/status/1", func(w http.ResponseWriter, r *http.Request) {
+		if polls.Add(1) < 2 {
+			json.NewEncoder(w).Encode(map[string]string{"status": "processing"})
+			return
+		}
+		json.NewEncoder(w).Encode(finalStatus)
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server, &polls
+}
+
+func newTestClient(url string) *external.MarkerClient {
+	return &external.MarkerClient{BaseURL: url, HTTPClient: http.DefaultClient, MaxPolls: 5, PollInterval: time.Millisecond}
+}
+
+func TestMarkerClient(t *testing.T) {
+	server, polls := newMarkerStub(t, map[string]string{"status": "complete", "markdown": "# Order\n\nGranted."})
+	text, err := newTestClient(server.URL).TranscribeURI(context.Background(), "https://blobs.example/abc", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text != "# Order\n\nGranted." || polls.Load() != 2 {
+		t.Errorf("got %q after %d polls", text, polls.Load())
+	}
+
+	failing, _ := newMarkerStub(t, map[string]string{"status": "error", "error": "corrupt pdf"})
+	if _, err := newTestClient(failing.URL).TranscribeURI(context.Background(), "https://blobs.example/abc", false); err == nil || !strings.Contains(err.Error(), "corrupt pdf") {
+		t.Errorf("expected the marker error, got %v", err)
+	}
+
+	slow, _ := newMarkerStub(t, map[string]string{"status": "processing"})
+	client := newTestClient(slow.URL)
+	client.MaxPolls = 3
+	if _, err := client.TranscribeURI(context.Background(), "https://blobs.example/abc", false); err == nil {
+		t.Error("expected polling to time out")
+	}
+}
+
+func TestRegistryExtract(t *testing.T) {
+	ctx := context.Background()
+	local, err := blobstore.NewLocalStore(t.TempDir(), "http://blobs.example")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cache, err := blobstore.NewCachedStore(local, t.TempDir(), 1<<20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fileManager := s3utils.NewKeFileManagerFromStore(cache, time.Hour)
+	put := func(content string) Document {
+		hash := hashes.HashFromBytes([]byte(content))
+		if err := local.Put(ctx, hash, strings.NewReader(content)); err != nil {
+			t.Fatal(err)
+		}
+		return Document{Hash: hash, Files: fileManager}
+	}
+
+	server, _ := newMarkerStub(t, map[string]string{"status": "complete", "markdown": "transcribed pdf"})
+	registry := NewRegistry(newTestClient(server.URL))
+
+	if text, err := registry.Extract(ctx, files.KnownFileExtensionMD, put("  # Notes\n\nbody\n")); err != nil || text != "# Notes\n\nbody" {
+		t.Errorf("markdown = %q, %v", text, err)
+	}
+	if text, err := registry.Extract(ctx, files.KnownFileExtensionPDF, put("%PDF-1.7")); err != nil || text != "transcribed pdf" {
+		t.Errorf("pdf = %q, %v", text, err)
+	}
+	if _, err := registry.Extract(ctx, files.KnownFileExtensionHTML, put("<html><script>x()</script></html>")); !errors.Is(err, ErrNoText) {
+		t.Errorf("expected ErrNoText, got %v", err)
+	}
+	if _, err := registry.Extract(ctx, files.KnownFileExtensionUnknown, put("?")); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("expected ErrUnsupported, got %v", err)
+	}
+}
@@ -0,0 +1,75 @@
+package extract
+
+import (
+	"errors"
+	"io"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// htmlSkipped elements hold no readable text
+var htmlSkipped = map[atom.Atom]bool{
+	atom.Script:   true,
+	atom.Style:    true,
+	atom.Noscript: true,
+	atom.Template: true,
+	atom.Svg:      true,
+	atom.Head:     true,
+}
+
+// htmlBreaks elements start a new line
+var htmlBreaks = map[atom.Atom]bool{
+	atom.Address: true, atom.Article: true, atom.Aside: true, atom.Blockquote: true, atom.Br: true,
+	atom.Dd: true, atom.Div: true, atom.Dl: true, atom.Dt: true, atom.Figcaption: true, atom.Footer: true,
+	atom.Form: true, atom.H1: true, atom.H2: true, atom.H3: true, atom.H4: true, atom.H5: true, atom.H6: true,
+	atom.Header: true, atom.Hr: true, atom.Li: true, atom.Main: true, atom.Nav: true, atom.Ol: true,
+	atom.P: true, atom.Pre: true, atom.Section: true, atom.Table: true, atom.Tr: true, atom.Ul: true,
+}
+
+// HTMLText keeps the visible text of a page, one line per block element and table row
+func HTMLText(r io.ReaderAt, size int64) (string, error) {
+	tokenizer := html.NewTokenizer(io.NewSectionReader(r, 0, size))
+	var out strings.Builder
+	skipDepth := 0
+	for {
+		switch tokenizer.Next() {
+		case html.ErrorToken:
+			if err := tokenizer.Err(); !errors.Is(err, io.EOF) {
+				return "", err
+			}
+			return collapseLines(out.String()), nil
+		case html.TextToken:
+			if skipDepth == 0 {
+				out.Write(tokenizer.Text())
+			}
+		case html.StartTagToken:
+			name, _ := tokenizer.TagName()
+			tag := atom.Lookup(name)
+			if htmlSkipped[tag] {
+				skipDepth++
+			}
+			if htmlBreaks[tag] {
+				out.WriteString("\n")
+			}
+			if tag == atom.Td || tag == atom.Th {
+				out.WriteString(" ")
+			}
+		case html.EndTagToken:
+			name, _ := tokenizer.TagName()
+			tag := atom.Lookup(name)
+			if htmlSkipped[tag] && skipDepth > 0 {
+				skipDepth--
+			}
+			if htmlBreaks[tag] {
+				out.WriteString("\n")
+			}
+		case html.SelfClosingTagToken:
+			name, _ := tokenizer.TagName()
+			if htmlBreaks[atom.Lookup(name)] {
+				out.WriteString("\n")
+			}
+		}
+	}
+}
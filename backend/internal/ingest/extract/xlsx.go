@@ -0,0 +1,191 @@
+package extract
+
+import (
+	"archive/zip"
+	"encoding/csv"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+)
+
+type xlsxWorkbook struct {
+	Sheets []struct {
+		Name  string `xml:"name,attr"`
+		RelID string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+	} `xml:"sheets>sheet"`
+}
+
+type xlsxRelationships struct {
+	Relationships []struct {
+		ID     string `xml:"Id,attr"`
+		Target string `xml:"Target,attr"`
+	} `xml:"Relationship"`
+}
+
+// xlsxRichText is a shared or inline string, either plain or made of formatted runs
+type xlsxRichText struct {
+	T    string `xml:"t"`
+	Runs []struct {
+		T string `xml:"t"`
+	} `xml:"r"`
+}
+
+func (text xlsxRichText) String() string {
+	if len(text.Runs) == 0 {
+		return text.T
+	}
+	var out strings.Builder
+	for _, run := range text.Runs {
+		out.WriteString(run.T)
+	}
+	return out.String()
+}
+
+type xlsxSharedStrings struct {
+	Items []xlsxRichText `xml:"si"`
+}
+
+type xlsxWorksheet struct {
+	Rows []struct {
+		Cells []struct {
+			Ref    string       `xml:"r,attr"`
+			Type   string       `xml:"t,attr"`
+			Value  string       `xml:"v"`
+			Inline xlsxRichText `xml:"is"`
+		} `xml:"c"`
+	} `xml:"sheetData>row"`
+}
+
+func decodeZipPart(archive *zip.Reader, name string, out any) (bool, error) {
+	part, err := openZipPart(archive, name)
+	if err != nil || part == nil {
+		return false, err
+	}
+	defer part.Close()
+	if err := xml.NewDecoder(part).Decode(out); err != nil {
+		return false, fmt.Errorf("parsing %s: %w", name, err)
+	}
+	return true, nil
+}
+
+// XLSXText renders every worksheet as a markdown heading with the sheet name followed by its rows as CSV
+func XLSXText(r io.ReaderAt, size int64) (string, error) {
+	archive, err := zip.NewReader(r, size)
+	if err != nil {
+		return "", fmt.Errorf("reading xlsx: %w", err)
+	}
+
+	var workbook xlsxWorkbook
+	found, err := decodeZipPart(archive, "xl/workbook.xml", &workbook)
+	if err != nil {
+		return "", err
+	}
+	if !found {
+		return "", errors.New("xlsx has no xl/workbook.xml")
+	}
+	var rels xlsxRelationships
+	if _, err := decodeZipPart(archive, "xl/_rels/workbook.xml.rels", &rels); err != nil {
+		return "", err
+	}
+	targets := make(map[string]string, len(rels.Relationships))
+	for _, rel := range rels.Relationships {
+		if strings.HasPrefix(rel.Target, "/") {
+			targets[rel.ID] = strings.TrimPrefix(rel.Target, "/")
+		} else {
+			targets[rel.ID] = path.Join("xl", rel.Target)
+		}
+	}
+	// Workbooks with only numbers have no shared strings part
+	var shared xlsxSharedStrings
+	if _, err := decodeZipPart(archive, "xl/sharedStrings.xml", &shared); err != nil {
+		return "", err
+	}
+
+	var out strings.Builder
+	for index, sheet := range workbook.Sheets {
+		target, ok := targets[sheet.RelID]
+		if !ok {
+			// Files written without relationships still name their sheets in order
+			target = fmt.Sprintf("xl/worksheets/sheet%d.xml", index+1)
+		}
+		var worksheet xlsxWorksheet
+		found, err := decodeZipPart(archive, target, &worksheet)
+		if err != nil {
+			return "", err
+		}
+		if !found {
+			continue
+		}
+		rows := xlsxRows(worksheet, shared)
+		if len(rows) == 0 {
+			continue
+		}
+		fmt.Fprintf(&out, "## %s\n\n", sheet.Name)
+		writer := csv.NewWriter(&out)
+		if err := writer.WriteAll(rows); err != nil {
+			return "", err
+		}
+		out.WriteString("\n")
+	}
+	return out.String(), nil
+}
+
+// xlsxRows lays cells out by their reference so skipped columns stay empty, trailing empty cells and rows
+// with no values are dropped
+func xlsxRows(worksheet xlsxWorksheet, shared xlsxSharedStrings) [][]string {
+	var rows [][]string
+	for _, row := range worksheet.Rows {
+		var record []string
+		for _, cell := range row.Cells {
+			column := len(record)
+			if parsed, ok := xlsxColumn(cell.Ref); ok {
+				column = parsed
+			}
+			var value string
+			switch cell.Type {
+			case "s":
+				if index, err := strconv.Atoi(cell.Value); err == nil && index >= 0 && index < len(shared.Items) {
+					value = shared.Items[index].String()
+				}
+			case "inlineStr":
+				value = cell.Inline.String()
+			case "b":
+				value = map[string]string{"0": "FALSE", "1": "TRUE"}[cell.Value]
+			default:
+				value = cell.Value
+			}
+			if value == "" {
+				continue
+			}
+			for len(record) <= column {
+				record = append(record, "")
+			}
+			record[column] = value
+		}
+		if len(record) > 0 {
+			rows = append(rows, record)
+		}
+	}
+	return rows
+}
+
+// xlsxColumn turns the letters of a cell reference such as AB12 into a zero based column index
+func xlsxColumn(ref string) (int, bool) {
+	column := 0
+	letters := 0
+	for _, r := range ref {
+		if r < 'A' || r > 'Z' {
+			break
+		}
+		column = column*26 + int(r-'A'+1)
+		letters++
+	}
+	if letters == 0 || letters > 3 {
+		return 0, false
+	}
+	return column - 1, true
+}
@@ -39,13 +39,13 @@ type IngestResponse struct {
 
 // MigrationService handles the business logic for fugu migration
 type MigrationService struct {
-	fuguServerURL string
+	client *fugusdk.Client
 }
 
-// NewMigrationService creates a new migration service
-func NewMigrationService(fuguServerURL string) *MigrationService {
+// NewMigrationService creates a new migration service on the shared fugu client
+func NewMigrationService(client *fugusdk.Client) *MigrationService {
 	return &MigrationService{
-		fuguServerURL: fuguServerURL,
+		client: client,
 	}
 }
 
@@ -62,9 +62,8 @@ func NewMigrationHandler(service *MigrationService) *MigrationServiceHandler {
 }
 
 // RegisterMigrationRoutes registers migration routes with the router
-func RegisterMigrationRoutes(r *mux.Router) error {
-	fuguServerURL := "http://fugudb"
-	service := NewMigrationService(fuguServerURL)
+func RegisterMigrationRoutes(r *mux.Router, client *fugusdk.Client) {
+	service := NewMigrationService(client)
 	msh := NewMigrationHandler(service)
 
 	migrationRoute := r.PathPrefix("/migration").Subrouter()
@@ -77,8 +76,6 @@ func RegisterMigrationRoutes(r *mux.Router) error {
 		"/health",
 		msh.Health,
 	).Methods(http.MethodGet)
-
-	return nil
 }
 
 // Health handles health check requests
@@ -89,15 +86,7 @@ func (h *MigrationServiceHandler) Health(w http.ResponseWriter, r *http.Request)
 
 	logger.Info(ctx, "migration health check called")
 
-	// Create fugu client and check health
-	client, err := fugusdk.NewClient(ctx, h.service.fuguServerURL)
-	if err != nil {
-		logger.Error(ctx, "failed to create fugu client", zap.Error(err))
-		http.Error(w, "Fugu server unavailable", http.StatusServiceUnavailable)
-		return
-	}
-
-	if err := client.Health(ctx); err != nil {
+	if err := h.service.client.Health(ctx); err != nil {
 		logger.Error(ctx, "fugu server health check failed", zap.Error(err))
 		http.Error(w, "Fugu server unavailable", http.StatusServiceUnavailable)
 		return
@@ -175,11 +164,7 @@ func (s *MigrationService) ProcessBatchIngest(ctx context.Context, records []Rec
 	ctx, span := tracer.Start(ctx, "migration-service:process-batch-ingest")
 	defer span.End()
 
-	// Create fugu client
-	client, err := fugusdk.NewClient(ctx, s.fuguServerURL)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create fugu client: %w", err)
-	}
+	client := s.client
 
 	// Convert records to fugu format
 	fuguObjects, conversionErrors := s.convertToFuguObjects(ctx, records)
@@ -199,25 +184,30 @@ func (s *MigrationService) ProcessBatchIngest(ctx context.Context, records []Rec
 		response.FailedIDs = append(response.FailedIDs, recordID)
 	}
 
-	// Ingest valid objects if any
+	// Ingest valid objects if any, failures are reported per record
 	if len(fuguObjects) > 0 {
-		_, err := client.IngestObjects(ctx, fuguObjects)
+		indexer := client.NewBulkIndexer(fugusdk.BulkIndexerConfig{
+			Send: client.IngestObjects,
+			OnResult: func(result fugusdk.BulkResult) {
+				if result.Err != nil {
+					logger.Error(ctx, "record ingestion failed",
+						zap.String("record_id", result.ID),
+						zap.Error(result.Err))
+				}
+			},
+		})
+		stats, err := indexer.IndexSlice(ctx, fuguObjects)
 		if err != nil {
-			logger.Error(ctx, "fugu ingestion failed", zap.Error(err))
-
-			// Mark all remaining objects as failed
-			for _, obj := range fuguObjects {
-				response.Failed++
-				response.FailedIDs = append(response.FailedIDs, obj.ID)
-			}
-
-			response.Message = "Ingestion failed"
-			return response, nil
+			logger.Error(ctx, "fugu ingestion interrupted", zap.Error(err))
 		}
 
-		// Ingestion successful
-		response.Successful = len(fuguObjects)
-		logger.Info(ctx, "successfully ingested records", zap.Int("count", response.Successful))
+		response.Successful = stats.Succeeded
+		response.Failed += stats.Failed
+		response.FailedIDs = append(response.FailedIDs, stats.FailedIDs...)
+		logger.Info(ctx, "ingested records",
+			zap.Int("successful", stats.Succeeded),
+			zap.Int("failed", stats.Failed),
+			zap.Int("requests", stats.Requests))
 	}
 
 	// Set final message
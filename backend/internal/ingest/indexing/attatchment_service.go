@@ -11,15 +11,21 @@ import (
 	"kessler/pkg/logger"
 	"kessler/pkg/util"
 	"log"
+	"slices"
 	"strings"
 	"sync"
 	"time"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgtype"
 	"go.uber.org/zap"
 )
 
+// firstSegmentFacet is only on the first record of an attachment, so counting it counts attachments
+const firstSegmentFacet = "metadata/segment_index/0"
+
 // AttachmentIndexer handles attachment-specific indexing operations
 type AttachmentIndexer struct {
 	svc *IndexService
@@ -32,17 +38,29 @@ func NewAttachmentIndexer(svc *IndexService) *AttachmentIndexer {
 
 // IndexAllAttachments retrieves all attachments and batch indexes them in chunks as data records.
 func (ai *AttachmentIndexer) IndexAllAttachments(ctx context.Context) (int, error) {
+	records, _, err := ai.indexAll(ctx)
+	return records, err
+}
+
+// indexAll indexes every searchable attachment, returning the records written and the attachments they came from
+func (ai *AttachmentIndexer) indexAll(ctx context.Context) (int, int, error) {
 	q := database.GetQueries(ai.svc.db)
 
 	// Use the generated SQLC method that matches our indexing needs
 	rows, err := q.GetAllSearchAttachments(ctx)
 	if err != nil {
-		return 0, fmt.Errorf("fetch all searchable attachments: %w", err)
+		return 0, 0, fmt.Errorf("fetch all searchable attachments: %w", err)
 	}
 
 	if len(rows) == 0 {
 		log.Printf("No attachments to index")
-		return 0, nil
+		return 0, 0, nil
+	}
+
+	// Docket numbers and author names are read once for the whole batch rather than per attachment
+	names, err := loadSortNames(ctx, q)
+	if err != nil {
+		return 0, 0, err
 	}
 
 	// Process attachments in parallel with worker pool
@@ -60,7 +78,7 @@ func (ai *AttachmentIndexer) IndexAllAttachments(ctx context.Context) (int, erro
 	var wg sync.WaitGroup
 	for i := 0; i < workers; i++ {
 		wg.Add(1)
-		go ai.attachmentWorker(ctx, q, attachmentChan, resultChan, &wg)
+		go ai.attachmentWorker(ctx, q, names, attachmentChan, resultChan, &wg)
 	}
 
 	// Send work to workers
@@ -98,18 +116,17 @@ func (ai *AttachmentIndexer) IndexAllAttachments(ctx context.Context) (int, erro
 	}
 	if len(allRecords) == 0 {
 		log.Printf("No valid attachments to index")
-		return 0, nil
+		return 0, 0, nil
 	}
 
+	attachments := len(rows) - skippedCount
 	log.Printf("Successfully processed %d attachments into %d records using %d workers",
-		len(rows)-skippedCount, len(allRecords), workers)
+		attachments, len(allRecords), workers)
 
-	client, err := ai.svc.createFuguClient(ctx)
-	if err != nil {
-		return 0, fmt.Errorf("new fugu client: %w", err)
-	}
+	client := ai.svc.client
 
-	return ai.svc.processBatchInChunks(ctx, client, allRecords, "attachments")
+	records, err := ai.svc.processBatchInChunks(ctx, client, allRecords, "attachments")
+	return records, attachments, err
 }
 
 // attachmentProcessingResult holds the result of processing a single attachment
@@ -124,6 +141,7 @@ type attachmentProcessingResult struct {
 func (ai *AttachmentIndexer) attachmentWorker(
 	ctx context.Context,
 	q *dbstore.Queries,
+	names *sortNames,
 	attachmentChan <-chan dbstore.GetAllSearchAttachmentsRow,
 	resultChan chan<- attachmentProcessingResult,
 	wg *sync.WaitGroup,
@@ -141,9 +159,11 @@ func (ai *AttachmentIndexer) attachmentWorker(
 			id:        row.ID,
 			fileID:    row.FileID,
 			name:      row.Name,
+			extension: row.Extension,
 			createdAt: createdAt,
 			mdata:     row.Mdata,
 			rawText:   row.Text.String,
+			names:     names,
 		})
 
 		resultChan <- attachmentProcessingResult{
@@ -178,6 +198,7 @@ func (ai *AttachmentIndexer) IndexAttachmentByID(ctx context.Context, idStr stri
 		id:        row.ID,
 		fileID:    row.FileID,
 		name:      row.Name,
+		extension: row.Extension,
 		createdAt: createdAt,
 		mdata:     row.Mdata,
 		rawText:   row.Text.String,
@@ -186,10 +207,7 @@ func (ai *AttachmentIndexer) IndexAttachmentByID(ctx context.Context, idStr stri
 		return 0, fmt.Errorf("prepare attachment %s: %w", idStr, err)
 	}
 
-	client, err := ai.svc.createFuguClient(ctx)
-	if err != nil {
-		return 0, fmt.Errorf("new fugu client: %w", err)
-	}
+	client := ai.svc.client
 
 	totalIndexed := 0
 	for _, rec := range records {
@@ -206,10 +224,7 @@ func (ai *AttachmentIndexer) IndexAttachmentByID(ctx context.Context, idStr stri
 
 // DeleteAttachmentFromIndex removes an attachment from the search index.
 func (ai *AttachmentIndexer) DeleteAttachmentFromIndex(ctx context.Context, idStr string) error {
-	client, err := ai.svc.createFuguClient(ctx)
-	if err != nil {
-		return fmt.Errorf("new fugu client: %w", err)
-	}
+	client := ai.svc.client
 
 	response, err := client.DeleteObject(ctx, idStr)
 	if err != nil {
@@ -392,9 +407,68 @@ type attachmentRecordParams struct {
 	id        uuid.UUID
 	fileID    uuid.UUID
 	name      string
+	extension string
 	createdAt *time.Time
 	mdata     []byte
 	rawText   string
+	// names is preloaded for batches, nil reads the docket and authors of this attachment alone
+	names *sortNames
+}
+
+// sortNames maps conversations to their docket numbers and organizations to their names
+type sortNames struct {
+	dockets map[uuid.UUID]string
+	orgs    map[uuid.UUID]string
+}
+
+func loadSortNames(ctx context.Context, q *dbstore.Queries) (*sortNames, error) {
+	convos, err := q.DocketConversationList(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list conversations for sort names: %w", err)
+	}
+	orgs, err := q.OrganizationList(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list organizations for sort names: %w", err)
+	}
+	names := &sortNames{
+		dockets: make(map[uuid.UUID]string, len(convos)),
+		orgs:    make(map[uuid.UUID]string, len(orgs)),
+	}
+	for _, convo := range convos {
+		names.dockets[convo.ID] = strings.TrimSpace(convo.DocketGovID)
+	}
+	for _, org := range orgs {
+		names.orgs[org.ID] = strings.TrimSpace(org.Name)
+	}
+	return names, nil
+}
+
+// docket returns the docket number of a conversation, reading it when it was not preloaded
+func (n *sortNames) docket(ctx context.Context, q *dbstore.Queries, convoID uuid.UUID) (string, error) {
+	if n != nil {
+		if docket, ok := n.dockets[convoID]; ok {
+			return docket, nil
+		}
+	}
+	convo, err := q.DocketConversationRead(ctx, convoID)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(convo.DocketGovID), nil
+}
+
+// org returns the name of an organization, reading it when it was not preloaded
+func (n *sortNames) org(ctx context.Context, q *dbstore.Queries, orgID uuid.UUID) (string, error) {
+	if n != nil {
+		if name, ok := n.orgs[orgID]; ok {
+			return name, nil
+		}
+	}
+	org, err := q.OrganizationRead(ctx, orgID)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(org.Name), nil
 }
 
 func (ai *AttachmentIndexer) prepareAttachmentRecords(ctx context.Context, q *dbstore.Queries, params attachmentRecordParams) ([]fugusdk.ObjectRecord, bool, error) {
@@ -411,6 +485,8 @@ func (ai *AttachmentIndexer) prepareAttachmentRecords(ctx context.Context, q *db
 
 	// Trim and validate text content
 	text := strings.TrimSpace(rawText)
+	// Segment offsets are reported relative to the untrimmed attachment text
+	leadingTrim := utf8.RuneCountInString(rawText[:len(rawText)-len(strings.TrimLeftFunc(rawText, unicode.IsSpace))])
 	if text == "" {
 		return nil, false, fmt.Errorf("attachment %s has no valid text content", id.String())
 	}
@@ -440,14 +516,32 @@ func (ai *AttachmentIndexer) prepareAttachmentRecords(ctx context.Context, q *db
 	}
 	convo_id := convo_rows[0].ConversationUuid
 
+	// Docket number and author names are stored so fugu can sort on them
+	docketGovID, err := params.names.docket(ctx, q, convo_id)
+	if err != nil {
+		log.Warn("Failed docket lookup for file ingest", zap.String("conversation_id", convo_id.String()), zap.Error(err))
+	}
+	author_names := make([]string, 0, len(author_ids))
+	for _, orgID := range author_ids {
+		name, err := params.names.org(ctx, q, orgID)
+		if err != nil {
+			log.Warn("Failed author name lookup for file ingest", zap.String("org_id", orgID.String()), zap.Error(err))
+			continue
+		}
+		author_names = append(author_names, name)
+	}
+
 	metaParams := attachmentMetadataParams{
-		id:        id,
-		fileID:    fileID,
-		convoID:   convo_id,
-		authorIDs: author_ids,
-		name:      name,
-		createdAt: createdAt,
-		mdata:     mdata,
+		id:          id,
+		fileID:      fileID,
+		convoID:     convo_id,
+		docketGovID: docketGovID,
+		authorIDs:   author_ids,
+		authorNames: author_names,
+		name:        name,
+		extension:   params.extension,
+		createdAt:   createdAt,
+		mdata:       mdata,
 	}
 	baseMetadata, facets := ai.buildAttachmentMetadataAndFacets(metaParams)
 
@@ -455,6 +549,9 @@ func (ai *AttachmentIndexer) prepareAttachmentRecords(ctx context.Context, q *db
 	if dateStr, ok := baseMetadata["date"].(string); ok {
 		if parsedTime, err := ai.parseDate(dateStr); err == nil {
 			baseMetadata["date_iso"] = parsedTime.Format(time.RFC3339)
+			// Year facet backs the year refinement in search facet counts
+			baseMetadata["date_year"] = parsedTime.Format("2006")
+			facets = append(facets, fmt.Sprintf("metadata/date_year/%s", parsedTime.Format("2006")))
 		} else {
 			logger.Warn(ctx, "could not parse date from metadata",
 				zap.String("attachment_id", id.String()),
@@ -479,7 +576,8 @@ func (ai *AttachmentIndexer) prepareAttachmentRecords(ctx context.Context, q *db
 
 	for i, segment := range segments {
 		wg.Add(1)
-		go func(segmentIndex int, segmentText string) {
+		go func(segmentIndex int, segment textSegment) {
+			segmentText := segment.text
 			defer wg.Done()
 
 			// Copy base metadata for this goroutine
@@ -500,6 +598,8 @@ func (ai *AttachmentIndexer) prepareAttachmentRecords(ctx context.Context, q *db
 				metadata["segment_index"] = 0
 				metadata["total_segments"] = 1
 			}
+			// Character offset of this segment in the full attachment text, used for highlights
+			metadata["segment_offset"] = leadingTrim + segment.offset
 
 			// Unique ID per segment
 			recID := id.String()
@@ -507,12 +607,18 @@ func (ai *AttachmentIndexer) prepareAttachmentRecords(ctx context.Context, q *db
 				recID = fmt.Sprintf("%s-segment-%d", id.String(), segmentIndex)
 			}
 
+			// The first segment stands for the attachment when counting them
+			recordFacets := facets // Facets are read-only, safe to share
+			if segmentIndex == 0 {
+				recordFacets = append(slices.Clip(facets), firstSegmentFacet)
+			}
+
 			// Store record at the correct index (no mutex needed since each goroutine writes to a unique index)
 			records[segmentIndex] = fugusdk.ObjectRecord{
 				ID:        recID,
 				Text:      segmentText,
 				Metadata:  metadata,
-				Facets:    facets, // Facets are read-only, safe to share
+				Facets:    recordFacets,
 				Namespace: ai.svc.defaultNamespace,
 				DataType:  "data/attachment",
 			}
@@ -528,14 +634,16 @@ func (ai *AttachmentIndexer) prepareAttachmentRecords(ctx context.Context, q *db
 // Helper methods
 
 type attachmentMetadataParams struct {
-	id        uuid.UUID
-	fileID    uuid.UUID
-	authorIDs []uuid.UUID
-	convoID   uuid.UUID
-	name      string
-	extension string
-	createdAt *time.Time
-	mdata     []byte
+	id          uuid.UUID
+	fileID      uuid.UUID
+	authorIDs   []uuid.UUID
+	authorNames []string
+	convoID     uuid.UUID
+	docketGovID string
+	name        string
+	extension   string
+	createdAt   *time.Time
+	mdata       []byte
 }
 
 // buildAttachmentMetadataAndFacets creates both metadata and facets for an attachment record
@@ -571,6 +679,20 @@ func (ai *AttachmentIndexer) buildAttachmentMetadataAndFacets(params attachmentM
 	facets = append(facets, fmt.Sprintf("metadata/conversation_id/%s", params.convoID.String()))
 	facets = append(facets, fmt.Sprintf("metadata/entity_type/%s", "attachment"))
 
+	if params.docketGovID != "" {
+		metadata["docket_gov_id"] = params.docketGovID
+		facets = append(facets, fmt.Sprintf("metadata/docket_gov_id/%s", params.docketGovID))
+	}
+	if len(params.authorNames) > 0 {
+		metadata["author_names"] = params.authorNames
+		for _, authorName := range params.authorNames {
+			facets = append(facets, fmt.Sprintf("metadata/author_names/%s", authorName))
+		}
+	}
+	if params.extension != "" {
+		facets = append(facets, fmt.Sprintf("metadata/file_extension/%s", params.extension))
+	}
+
 	// Author IDs
 	if len(params.authorIDs) > 0 {
 		transform_into_string := func(id uuid.UUID) string {
@@ -632,16 +754,23 @@ func (ai *AttachmentIndexer) parseDate(dateStr string) (time.Time, error) {
 	return time.Time{}, fmt.Errorf("unable to parse date: %s", dateStr)
 }
 
+// textSegment is a piece of attachment text along with its character offset in the text it was cut from
+type textSegment struct {
+	text   string
+	offset int
+}
+
 // splitTextIntoSegments splits long text into smaller segments that fit within the character limit
-func (ai *AttachmentIndexer) splitTextIntoSegments(text string, maxLength int) []string {
+func (ai *AttachmentIndexer) splitTextIntoSegments(text string, maxLength int) []textSegment {
 	if len(text) <= maxLength {
-		return []string{text}
+		return []textSegment{{text: text, offset: 0}}
 	}
 
-	var segments []string
+	var segments []textSegment
 
 	// Work with the original string to ensure we're counting bytes correctly
 	for i := 0; i < len(text); {
+		start := i
 		end := i + maxLength
 		if end > len(text) {
 			end = len(text)
@@ -670,6 +799,7 @@ func (ai *AttachmentIndexer) splitTextIntoSegments(text string, maxLength int) [
 
 		trimmed := strings.TrimSpace(segment)
 		if len(trimmed) > 0 {
+			trimmedStart := start + len(segment) - len(strings.TrimLeftFunc(segment, unicode.IsSpace))
 			// Double-check the length before adding
 			if len(trimmed) > maxLength {
 				logger.Error(nil, "segment still too long after splitting",
@@ -678,7 +808,10 @@ func (ai *AttachmentIndexer) splitTextIntoSegments(text string, maxLength int) [
 				// Force truncate as emergency fallback
 				trimmed = trimmed[:maxLength-3] + "..."
 			}
-			segments = append(segments, trimmed)
+			segments = append(segments, textSegment{
+				text:   trimmed,
+				offset: utf8.RuneCountInString(text[:trimmedStart]),
+			})
 		}
 	}
 
@@ -28,7 +28,7 @@ func NewConversationIndexer(svc *IndexService) *ConversationIndexer {
 // IndexAllConversations retrieves all conversations and batch indexes them in chunks.
 func (ci *ConversationIndexer) IndexAllConversations(ctx context.Context) (int, error) {
 	q := database.GetQueries(ci.svc.db)
-	rows, err := q.DocketConversationList(ctx)
+	rows, err := q.ConversationCompleteQuickwitListGet(ctx)
 	if err != nil {
 		return 0, fmt.Errorf("fetch all conversations: %w", err)
 	}
@@ -47,12 +47,14 @@ func (ci *ConversationIndexer) IndexAllConversations(ctx context.Context) (int,
 		}
 
 		metadata, facets := ci.buildConversationMetadataAndFacets(conversationParams{
-			id:           c.ID,
-			docketGovID:  c.DocketGovID,
-			state:        c.State,
-			matterType:   c.MatterType,
-			industryType: c.IndustryType,
-			description:  c.Description,
+			id:             c.ID,
+			name:           c.Name,
+			docketGovID:    c.DocketGovID,
+			state:          c.State,
+			matterType:     c.MatterType,
+			industryType:   c.IndustryType,
+			description:    c.Description,
+			totalDocuments: c.TotalDocuments,
 		})
 
 		recs = append(recs, fugusdk.ObjectRecord{
@@ -74,10 +76,7 @@ func (ci *ConversationIndexer) IndexAllConversations(ctx context.Context) (int,
 		return 0, nil
 	}
 
-	client, err := ci.svc.createFuguClient(ctx)
-	if err != nil {
-		return 0, fmt.Errorf("new fugu client: %w", err)
-	}
+	client := ci.svc.client
 
 	return ci.svc.processBatchInChunks(ctx, client, recs, "conversations")
 }
@@ -101,13 +100,20 @@ func (ci *ConversationIndexer) IndexConversationByID(ctx context.Context, idStr
 		return 0, fmt.Errorf("conversation %s has no valid text content and cannot be indexed", idStr)
 	}
 
+	totalDocuments, err := q.ConversationTotalDocumentsGet(ctx, c.ID)
+	if err != nil {
+		return 0, fmt.Errorf("count conversation documents: %w", err)
+	}
+
 	metadata, facets := ci.buildConversationMetadataAndFacets(conversationParams{
-		id:           c.ID,
-		docketGovID:  c.DocketGovID,
-		state:        c.State,
-		matterType:   c.MatterType,
-		industryType: c.IndustryType,
-		description:  c.Description,
+		id:             c.ID,
+		name:           c.Name,
+		docketGovID:    c.DocketGovID,
+		state:          c.State,
+		matterType:     c.MatterType,
+		industryType:   c.IndustryType,
+		description:    c.Description,
+		totalDocuments: totalDocuments,
 	})
 
 	rec := fugusdk.ObjectRecord{
@@ -119,10 +125,7 @@ func (ci *ConversationIndexer) IndexConversationByID(ctx context.Context, idStr
 		DataType:  "data/conversation",
 	}
 
-	client, err := ci.svc.createFuguClient(ctx)
-	if err != nil {
-		return 0, fmt.Errorf("new fugu client: %w", err)
-	}
+	client := ci.svc.client
 
 	response, err := client.AddOrUpdateObject(ctx, rec)
 	if err != nil {
@@ -135,10 +138,7 @@ func (ci *ConversationIndexer) IndexConversationByID(ctx context.Context, idStr
 
 // DeleteConversationFromIndex removes a conversation from the search index.
 func (ci *ConversationIndexer) DeleteConversationFromIndex(ctx context.Context, idStr string) error {
-	client, err := ci.svc.createFuguClient(ctx)
-	if err != nil {
-		return fmt.Errorf("new fugu client: %w", err)
-	}
+	client := ci.svc.client
 
 	response, err := client.DeleteObject(ctx, idStr)
 	if err != nil {
@@ -152,11 +152,14 @@ func (ci *ConversationIndexer) DeleteConversationFromIndex(ctx context.Context,
 // conversationParams holds the parameters for building conversation metadata and facets
 type conversationParams struct {
 	id           uuid.UUID
+	name         string
 	docketGovID  string
 	state        string
 	matterType   string
 	industryType string
 	description  string
+	// totalDocuments ranks dockets in autocomplete
+	totalDocuments int64
 }
 
 // buildConversationMetadataAndFacets creates both metadata and facets for a conversation record
@@ -176,6 +179,14 @@ func (ci *ConversationIndexer) buildConversationMetadataAndFacets(params convers
 	facets = append(facets, "metadata/entity_type/conversation")
 	facets = append(facets, fmt.Sprintf("metadata/conversation_id/%s", params.id.String()))
 
+	if params.name != "" {
+		metadata["name"] = params.name
+	}
+
+	if params.totalDocuments > 0 {
+		metadata["total_documents"] = params.totalDocuments
+	}
+
 	// Add metadata and facets for each field if not empty
 	if params.docketGovID != "" {
 		metadata["docket_gov_id"] = params.docketGovID
@@ -206,21 +217,30 @@ func (ci *ConversationIndexer) buildConversationMetadataAndFacets(params convers
 }
 
 // createConversationText creates a meaningful text field for conversation indexing
-// with multiple fallback options to ensure we always have searchable content
+// with multiple fallback options to ensure we always have searchable content.
+// The docket ID is always part of the text so dockets can be found by it.
 func (ci *ConversationIndexer) createConversationText(name, description, docketGovID, id string) string {
+	govID := strings.TrimSpace(docketGovID)
+	withGovID := func(text string) string {
+		if govID == "" || strings.Contains(text, govID) {
+			return text
+		}
+		return fmt.Sprintf("%s (%s)", text, govID)
+	}
+
 	// Try name first (most common case)
 	if text := strings.TrimSpace(name); text != "" {
-		return text
+		return withGovID(text)
 	}
 
 	// Fall back to description
 	if text := strings.TrimSpace(description); text != "" {
-		return text
+		return withGovID(text)
 	}
 
 	// Fall back to docket gov ID with meaningful prefix
-	if text := strings.TrimSpace(docketGovID); text != "" {
-		return fmt.Sprintf("Docket %s", text)
+	if govID != "" {
+		return fmt.Sprintf("Docket %s", govID)
 	}
 
 	// Last resort: use UUID with prefix
@@ -278,10 +298,7 @@ func (ci *ConversationIndexer) GetConversationStats(ctx context.Context) (map[st
 
 // BulkUpdateConversationMetadata updates metadata for multiple conversations
 func (ci *ConversationIndexer) BulkUpdateConversationMetadata(ctx context.Context, updates map[string]map[string]interface{}) error {
-	client, err := ci.svc.createFuguClient(ctx)
-	if err != nil {
-		return fmt.Errorf("new fugu client: %w", err)
-	}
+	client := ci.svc.client
 
 	for conversationID, metadata := range updates {
 		// Validate conversation ID
@@ -311,14 +328,25 @@ func (ci *ConversationIndexer) BulkUpdateConversationMetadata(ctx context.Contex
 			continue
 		}
 
+		// Without the count the update would drop the docket's popularity
+		totalDocuments, err := q.ConversationTotalDocumentsGet(ctx, c.ID)
+		if err != nil {
+			logger.Error(ctx, "failed to count conversation documents for metadata update",
+				zap.String("conversation_id", conversationID),
+				zap.Error(err))
+			continue
+		}
+
 		// Build metadata and facets
 		baseMetadata, facets := ci.buildConversationMetadataAndFacets(conversationParams{
-			id:           c.ID,
-			docketGovID:  c.DocketGovID,
-			state:        c.State,
-			matterType:   c.MatterType,
-			industryType: c.IndustryType,
-			description:  c.Description,
+			id:             c.ID,
+			name:           c.Name,
+			docketGovID:    c.DocketGovID,
+			state:          c.State,
+			matterType:     c.MatterType,
+			industryType:   c.IndustryType,
+			description:    c.Description,
+			totalDocuments: totalDocuments,
 		})
 
 		// Add custom metadata updates
@@ -9,6 +9,8 @@ import (
 	"time"
 
 	"kessler/internal/dbstore"
+	"kessler/internal/fugusdk"
+	"kessler/internal/indexalias"
 	"kessler/pkg/logger"
 
 	"github.com/gorilla/mux"
@@ -33,11 +35,12 @@ func NewIndexHandler(svc *IndexService) *IndexHandler {
 }
 
 // RegisterAdminIndexingRoutes mounts indexing endpoints under /admin/indexing.
-func RegisterIndexingRoutes(r *mux.Router, db dbstore.DBTX) {
+func RegisterIndexingRoutes(r *mux.Router, db dbstore.DBTX, client *fugusdk.Client, namespace string, aliases *indexalias.Resolver) {
 	sr := r.PathPrefix("/indexing").Subrouter()
 
-	// TODO: have this be configurable
-	h := NewIndexHandler(NewIndexService("http://fugudb:3301", db))
+	svc := NewIndexService(client, namespace, db)
+	svc.aliases = aliases
+	h := NewIndexHandler(svc)
 
 	// Conversation endpoints
 	sr.HandleFunc("/conversations", h.IndexAllConversations).Methods(http.MethodPost)
@@ -64,6 +67,12 @@ func RegisterIndexingRoutes(r *mux.Router, db dbstore.DBTX) {
 	// Bulk operations
 	sr.HandleFunc("/all", h.IndexAllData).Methods(http.MethodPost)
 	sr.HandleFunc("/complete", h.IndexCompleteData).Methods(http.MethodPost) // NEW: includes attachments
+
+	// Versioned rebuilds and alias switches
+	sr.HandleFunc("/versions", h.ListIndexVersions).Methods(http.MethodGet)
+	sr.HandleFunc("/versions", h.RebuildIndexVersion).Methods(http.MethodPost)
+	sr.HandleFunc("/versions/collect", h.CollectIndexVersions).Methods(http.MethodPost)
+	sr.HandleFunc("/versions/{id}/activate", h.ActivateIndexVersion).Methods(http.MethodPost)
 }
 
 // IndexAllConversations godoc
@@ -311,8 +320,10 @@ func (h *IndexHandler) DeleteAttachment(w http.ResponseWriter, r *http.Request)
 
 // IndexAllData godoc
 // @Summary Index all conversations, organizations, and attachments
-// @Description Bulk operation to index all conversations, organizations, and attachments from the database with proper namespace facets
+// @Description Rebuilds the default namespace into a new version and switches to it once its counts validate. Pass in_place=true to write into the live namespace instead.
+// @Param in_place query bool false "Index into the live namespace without versioning"
 // @Success 200 {object} map[string]interface{}
+// @Failure 422 {object} map[string]interface{} "The rebuilt version failed validation"
 // @Failure 500 {object} map[string]string{"error":string}
 // @Router /admin/indexing/all [post]
 func (h *IndexHandler) IndexAllData(w http.ResponseWriter, r *http.Request) {
@@ -324,33 +335,15 @@ func (h *IndexHandler) IndexAllData(w http.ResponseWriter, r *http.Request) {
 	defer span.End()
 
 	logger.Info(ctx, "indexing all data requested")
-
-	convCount, orgCount, attachCount, err := h.svc.IndexCompleteData(ctx)
-	if err != nil {
-		logger.Error(ctx, "index all data failed", zap.Error(err))
-		h.respondError(w, http.StatusInternalServerError, err.Error())
-		return
-	}
-
-	response := map[string]interface{}{
-		"conversations_indexed": convCount,
-		"organizations_indexed": orgCount,
-		"attachments_indexed":   attachCount,
-		"total_indexed":         convCount + orgCount + attachCount,
-		"message":               "Successfully indexed all data with namespace facets",
-	}
-
-	logger.Info(ctx, "successfully indexed all data",
-		zap.Int("conversations", convCount),
-		zap.Int("organizations", orgCount),
-		zap.Int("attachments", attachCount))
-	h.respondJSON(w, http.StatusOK, response)
+	h.indexEverything(ctx, w, r, "Successfully indexed all data with namespace facets")
 }
 
 // IndexCompleteData godoc
 // @Summary Index all conversations, organizations, and attachments
-// @Description Bulk operation to index all conversations, organizations, and attachments from the database with proper namespace facets
+// @Description Rebuilds the default namespace into a new version and switches to it once its counts validate. Pass in_place=true to write into the live namespace instead.
+// @Param in_place query bool false "Index into the live namespace without versioning"
 // @Success 200 {object} map[string]interface{}
+// @Failure 422 {object} map[string]interface{} "The rebuilt version failed validation"
 // @Failure 500 {object} map[string]string{"error":string}
 // @Router /admin/indexing/complete [post]
 func (h *IndexHandler) IndexCompleteData(w http.ResponseWriter, r *http.Request) {
@@ -362,27 +355,7 @@ func (h *IndexHandler) IndexCompleteData(w http.ResponseWriter, r *http.Request)
 	defer span.End()
 
 	logger.Info(ctx, "indexing complete data requested")
-
-	convCount, orgCount, attachCount, err := h.svc.IndexCompleteData(ctx)
-	if err != nil {
-		logger.Error(ctx, "index complete data failed", zap.Error(err))
-		h.respondError(w, http.StatusInternalServerError, err.Error())
-		return
-	}
-
-	response := map[string]interface{}{
-		"conversations_indexed": convCount,
-		"organizations_indexed": orgCount,
-		"attachments_indexed":   attachCount,
-		"total_indexed":         convCount + orgCount + attachCount,
-		"message":               "Successfully indexed all complete data with namespace facets",
-	}
-
-	logger.Info(ctx, "successfully indexed complete data",
-		zap.Int("conversations", convCount),
-		zap.Int("organizations", orgCount),
-		zap.Int("attachments", attachCount))
-	h.respondJSON(w, http.StatusOK, response)
+	h.indexEverything(ctx, w, r, "Successfully indexed all complete data with namespace facets")
 }
 
 // BatchIngestData godoc
@@ -539,14 +512,7 @@ func (h *IndexHandler) DataHealthCheck(w http.ResponseWriter, r *http.Request) {
 	logger.Info(ctx, "data health check requested")
 
 	// Check FuguDB health through the service
-	client, err := h.svc.createFuguClient(ctx)
-	if err != nil {
-		logger.Error(ctx, "failed to create fugu client for health check", zap.Error(err))
-		h.respondError(w, http.StatusServiceUnavailable, "FuguDB unavailable")
-		return
-	}
-
-	if err := client.Health(ctx); err != nil {
+	if err := h.svc.client.Health(ctx); err != nil {
 		logger.Error(ctx, "fugu health check failed", zap.Error(err))
 		h.respondError(w, http.StatusServiceUnavailable, "FuguDB health check failed")
 		return
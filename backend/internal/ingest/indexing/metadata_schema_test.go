@@ -0,0 +1,89 @@
+package indexing
+
+import (
+	"encoding/json"
+	"kessler/internal/fugusdk"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// asSearchResult sends metadata through JSON the way Fugu returns it
+func asSearchResult(t *testing.T, metadata map[string]interface{}) fugusdk.FuguSearchResult {
+	t.Helper()
+	encoded, err := json.Marshal(metadata)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := fugusdk.FuguSearchResult{ID: "result"}
+	if err := json.Unmarshal(encoded, &result.Metadata); err != nil {
+		t.Fatal(err)
+	}
+	return result
+}
+
+// The typed metadata in fugusdk must keep decoding what the indexers write
+func TestIndexedMetadataMatchesTypedSchema(t *testing.T) {
+	svc := &IndexService{defaultNamespace: "NYPUC"}
+
+	t.Run("attachment", func(t *testing.T) {
+		createdAt := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+		params := attachmentMetadataParams{
+			id:          uuid.New(),
+			fileID:      uuid.New(),
+			authorIDs:   []uuid.UUID{uuid.New()},
+			authorNames: []string{"Con Edison"},
+			convoID:     uuid.New(),
+			docketGovID: "24-E-0001",
+			name:        "Rate Filing",
+			extension:   "pdf",
+			createdAt:   &createdAt,
+			mdata:       []byte(`{"date":"03/01/2024","description":"Initial filing","case_number":"24-E-0001"}`),
+		}
+		metadata, _ := (&AttachmentIndexer{svc: svc}).buildAttachmentMetadataAndFacets(params)
+		metadata["is_segmented"] = true
+		metadata["segment_index"] = 1
+		metadata["total_segments"] = 2
+		metadata["segment_offset"] = 9500
+
+		typed, err := fugusdk.DecodeResult[fugusdk.AttachmentMetadata](asSearchResult(t, metadata))
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := typed.Metadata
+		if got.AttachmentID != params.id || got.FileID != params.fileID || got.ConversationID != params.convoID ||
+			len(got.AuthorIDs) != 1 || got.AuthorIDs[0] != params.authorIDs[0] {
+			t.Errorf("identifiers did not round trip: %+v", got)
+		}
+		if got.FileName != params.name || got.CaseNumber != "24-E-0001" || !got.CreatedAt.Equal(createdAt) || got.SegmentOffset != 9500 {
+			t.Errorf("fields did not round trip: %+v", got)
+		}
+	})
+
+	t.Run("conversation", func(t *testing.T) {
+		params := conversationParams{id: uuid.New(), name: "Rate Case", docketGovID: "24-E-0001", state: "NY", matterType: "Tariff", totalDocuments: 7}
+		metadata, _ := (&ConversationIndexer{svc: svc}).buildConversationMetadataAndFacets(params)
+		typed, err := fugusdk.DecodeResult[fugusdk.ConversationMetadata](asSearchResult(t, metadata))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if typed.Metadata.ConversationID != params.id || typed.Metadata.MatterType != params.matterType ||
+			typed.Metadata.Name != params.name || typed.Metadata.TotalDocuments != 7 {
+			t.Errorf("fields did not round trip: %+v", typed.Metadata)
+		}
+	})
+
+	t.Run("organization", func(t *testing.T) {
+		params := organizationParams{id: uuid.New(), name: "Con Edison", totalDocumentsAuthored: 42, aliases: []string{"ConEd"}}
+		metadata, _ := (&OrganizationIndexer{svc: svc}).buildOrganizationMetadataAndFacets(params)
+		typed, err := fugusdk.DecodeResult[fugusdk.OrganizationMetadata](asSearchResult(t, metadata))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if typed.Metadata.OrganizationID != params.id || typed.Metadata.TotalDocumentsAuthored != 42 ||
+			len(typed.Metadata.Aliases) != 1 || typed.Metadata.Aliases[0] != "ConEd" {
+			t.Errorf("fields did not round trip: %+v", typed.Metadata)
+		}
+	})
+}
@@ -46,13 +46,16 @@ func (oi *OrganizationIndexer) IndexAllOrganizations(ctx context.Context) (int,
 			continue
 		}
 
+		aliases := distinctAliases(o.Name, o.OrganizationAliases)
 		metadata, facets := oi.buildOrganizationMetadataAndFacets(organizationParams{
 			id:                     o.ID,
 			name:                   o.Name,
 			description:            o.Description,
 			isPerson:               o.IsPerson.Bool,
 			totalDocumentsAuthored: o.TotalDocumentsAuthored,
+			aliases:                aliases,
 		})
+		text = withAliases(text, aliases)
 
 		recs = append(recs, fugusdk.ObjectRecord{
 			ID:        o.ID.String(),
@@ -73,10 +76,7 @@ func (oi *OrganizationIndexer) IndexAllOrganizations(ctx context.Context) (int,
 		return 0, nil
 	}
 
-	client, err := oi.svc.createFuguClient(ctx)
-	if err != nil {
-		return 0, fmt.Errorf("new fugu client: %w", err)
-	}
+	client := oi.svc.client
 
 	return oi.svc.processBatchInChunks(ctx, client, recs, "organizations")
 }
@@ -100,14 +100,17 @@ func (oi *OrganizationIndexer) IndexOrganizationByID(ctx context.Context, idStr
 		return 0, fmt.Errorf("organization %s has no valid text content and cannot be indexed", idStr)
 	}
 
+	aliases := oi.readAliases(ctx, o.ID, o.Name)
 	metadata, facets := oi.buildOrganizationMetadataAndFacets(organizationParams{
 		id:          o.ID,
 		name:        o.Name,
 		description: o.Description,
 		isPerson:    o.IsPerson.Bool,
+		aliases:     aliases,
 		// Note: OrganizationRead might not have TotalDocumentsAuthored field
 		// totalDocumentsAuthored: 0,
 	})
+	text = withAliases(text, aliases)
 
 	rec := fugusdk.ObjectRecord{
 		ID:        o.ID.String(),
@@ -118,10 +121,7 @@ func (oi *OrganizationIndexer) IndexOrganizationByID(ctx context.Context, idStr
 		DataType:  "data/organization",
 	}
 
-	client, err := oi.svc.createFuguClient(ctx)
-	if err != nil {
-		return 0, fmt.Errorf("new fugu client: %w", err)
-	}
+	client := oi.svc.client
 
 	response, err := client.AddOrUpdateObject(ctx, rec)
 	if err != nil {
@@ -134,10 +134,7 @@ func (oi *OrganizationIndexer) IndexOrganizationByID(ctx context.Context, idStr
 
 // DeleteOrganizationFromIndex removes an organization from the search index.
 func (oi *OrganizationIndexer) DeleteOrganizationFromIndex(ctx context.Context, idStr string) error {
-	client, err := oi.svc.createFuguClient(ctx)
-	if err != nil {
-		return fmt.Errorf("new fugu client: %w", err)
-	}
+	client := oi.svc.client
 
 	response, err := client.DeleteObject(ctx, idStr)
 	if err != nil {
@@ -155,6 +152,8 @@ type organizationParams struct {
 	description            string
 	isPerson               bool
 	totalDocumentsAuthored int64
+	// aliases are the organization's other names, without its name
+	aliases []string
 }
 
 // buildOrganizationMetadataAndFacets creates both metadata and facets for an organization record
@@ -174,6 +173,10 @@ func (oi *OrganizationIndexer) buildOrganizationMetadataAndFacets(params organiz
 		metadata["description"] = params.description
 	}
 
+	if len(params.aliases) > 0 {
+		metadata["organization_aliases"] = params.aliases
+	}
+
 	var facets []string
 
 	// Add namespace facets
@@ -216,6 +219,46 @@ func (oi *OrganizationIndexer) createOrganizationText(name, description, id stri
 	return fmt.Sprintf("Organization %s", id)
 }
 
+// readAliases loads an organization's aliases, indexing goes ahead without them when they cannot be read
+func (oi *OrganizationIndexer) readAliases(ctx context.Context, id uuid.UUID, name string) []string {
+	rows, err := database.GetQueries(oi.svc.db).OrganizationGetAllAliases(ctx, id)
+	if err != nil {
+		logger.Warn(ctx, "failed to read organization aliases, indexing without them",
+			zap.String("organization_id", id.String()),
+			zap.Error(err))
+		return nil
+	}
+	aliases := make([]string, len(rows))
+	for i, row := range rows {
+		aliases[i] = row.OrganizationAlias
+	}
+	return distinctAliases(name, aliases)
+}
+
+// distinctAliases drops blank aliases, repeats and those equal to the name, ignoring case
+func distinctAliases(name string, aliases []string) []string {
+	seen := map[string]bool{strings.ToLower(strings.TrimSpace(name)): true}
+	var distinct []string
+	for _, alias := range aliases {
+		alias = strings.TrimSpace(alias)
+		key := strings.ToLower(alias)
+		if alias == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		distinct = append(distinct, alias)
+	}
+	return distinct
+}
+
+// withAliases appends aliases to an organization's text so it can be found by any of its names
+func withAliases(text string, aliases []string) string {
+	if len(aliases) == 0 {
+		return text
+	}
+	return fmt.Sprintf("%s (%s)", text, strings.Join(aliases, ", "))
+}
+
 // ValidateOrganizationData validates organization data before indexing
 func (oi *OrganizationIndexer) ValidateOrganizationData(organizationID string) error {
 	if organizationID == "" {
@@ -297,10 +340,7 @@ func (oi *OrganizationIndexer) GetOrganizationStats(ctx context.Context) (map[st
 
 // BulkUpdateOrganizationMetadata updates metadata for multiple organizations
 func (oi *OrganizationIndexer) BulkUpdateOrganizationMetadata(ctx context.Context, updates map[string]map[string]interface{}) error {
-	client, err := oi.svc.createFuguClient(ctx)
-	if err != nil {
-		return fmt.Errorf("new fugu client: %w", err)
-	}
+	client := oi.svc.client
 
 	for organizationID, metadata := range updates {
 		// Validate organization ID
@@ -331,14 +371,17 @@ func (oi *OrganizationIndexer) BulkUpdateOrganizationMetadata(ctx context.Contex
 		}
 
 		// Build metadata and facets
+		aliases := oi.readAliases(ctx, o.ID, o.Name)
 		baseMetadata, facets := oi.buildOrganizationMetadataAndFacets(organizationParams{
 			id:          o.ID,
 			name:        o.Name,
 			description: o.Description,
 			isPerson:    o.IsPerson.Bool,
+			aliases:     aliases,
 			// Note: OrganizationRead might not have TotalDocumentsAuthored field
 			// totalDocumentsAuthored: 0,
 		})
+		text = withAliases(text, aliases)
 
 		// Add custom metadata updates
 		for key, value := range metadata {
@@ -12,14 +12,17 @@ import (
 
 	"kessler/internal/dbstore"
 	"kessler/internal/fugusdk"
+	"kessler/internal/indexalias"
 	"kessler/pkg/logger"
 )
 
 // IndexService is the main service that coordinates indexing operations across all entity types
 type IndexService struct {
-	fuguURL          string
+	client           *fugusdk.Client
 	db               dbstore.DBTX
 	defaultNamespace string // e.g., "NYPUC"
+	// aliases is told about alias switches so this replica's searches follow them immediately
+	aliases *indexalias.Resolver
 
 	// Entity-specific indexers
 	conversationIndexer *ConversationIndexer
@@ -27,11 +30,11 @@ type IndexService struct {
 	attachmentIndexer   *AttachmentIndexer
 }
 
-// NewIndexService constructs an IndexService pointing at fuguURL.
-func NewIndexService(fuguURL string, db dbstore.DBTX) *IndexService {
+// NewIndexService constructs an IndexService writing into namespace through the shared fugu client.
+func NewIndexService(client *fugusdk.Client, namespace string, db dbstore.DBTX) *IndexService {
 	svc := &IndexService{
-		fuguURL:          fuguURL,
-		defaultNamespace: "NYPUC", // Configure this based on your organization
+		client:           client,
+		defaultNamespace: namespace,
 		db:               db,
 	}
 
@@ -72,41 +75,83 @@ type DataIngestResponse struct {
 
 // Conversation-related methods (delegate to ConversationIndexer)
 func (s *IndexService) IndexAllConversations(ctx context.Context) (int, error) {
-	return s.conversationIndexer.IndexAllConversations(ctx)
+	var count int
+	err := s.eachWriteTarget(ctx, func(target *IndexService) error {
+		var err error
+		count, err = target.conversationIndexer.IndexAllConversations(ctx)
+		return err
+	})
+	return count, err
 }
 
 func (s *IndexService) IndexConversationByID(ctx context.Context, idStr string) (int, error) {
-	return s.conversationIndexer.IndexConversationByID(ctx, idStr)
+	var count int
+	err := s.eachWriteTarget(ctx, func(target *IndexService) error {
+		var err error
+		count, err = target.conversationIndexer.IndexConversationByID(ctx, idStr)
+		return err
+	})
+	return count, err
 }
 
 func (s *IndexService) DeleteConversationFromIndex(ctx context.Context, idStr string) error {
-	return s.conversationIndexer.DeleteConversationFromIndex(ctx, idStr)
+	return s.eachWriteTarget(ctx, func(target *IndexService) error {
+		return target.conversationIndexer.DeleteConversationFromIndex(ctx, idStr)
+	})
 }
 
 // Organization-related methods (delegate to OrganizationIndexer)
 func (s *IndexService) IndexAllOrganizations(ctx context.Context) (int, error) {
-	return s.organizationIndexer.IndexAllOrganizations(ctx)
+	var count int
+	err := s.eachWriteTarget(ctx, func(target *IndexService) error {
+		var err error
+		count, err = target.organizationIndexer.IndexAllOrganizations(ctx)
+		return err
+	})
+	return count, err
 }
 
 func (s *IndexService) IndexOrganizationByID(ctx context.Context, idStr string) (int, error) {
-	return s.organizationIndexer.IndexOrganizationByID(ctx, idStr)
+	var count int
+	err := s.eachWriteTarget(ctx, func(target *IndexService) error {
+		var err error
+		count, err = target.organizationIndexer.IndexOrganizationByID(ctx, idStr)
+		return err
+	})
+	return count, err
 }
 
 func (s *IndexService) DeleteOrganizationFromIndex(ctx context.Context, idStr string) error {
-	return s.organizationIndexer.DeleteOrganizationFromIndex(ctx, idStr)
+	return s.eachWriteTarget(ctx, func(target *IndexService) error {
+		return target.organizationIndexer.DeleteOrganizationFromIndex(ctx, idStr)
+	})
 }
 
 // Attachment-related methods (delegate to AttachmentIndexer)
 func (s *IndexService) IndexAllAttachments(ctx context.Context) (int, error) {
-	return s.attachmentIndexer.IndexAllAttachments(ctx)
+	var count int
+	err := s.eachWriteTarget(ctx, func(target *IndexService) error {
+		var err error
+		count, err = target.attachmentIndexer.IndexAllAttachments(ctx)
+		return err
+	})
+	return count, err
 }
 
 func (s *IndexService) IndexAttachmentByID(ctx context.Context, idStr string) (int, error) {
-	return s.attachmentIndexer.IndexAttachmentByID(ctx, idStr)
+	var count int
+	err := s.eachWriteTarget(ctx, func(target *IndexService) error {
+		var err error
+		count, err = target.attachmentIndexer.IndexAttachmentByID(ctx, idStr)
+		return err
+	})
+	return count, err
 }
 
 func (s *IndexService) DeleteAttachmentFromIndex(ctx context.Context, idStr string) error {
-	return s.attachmentIndexer.DeleteAttachmentFromIndex(ctx, idStr)
+	return s.eachWriteTarget(ctx, func(target *IndexService) error {
+		return target.attachmentIndexer.DeleteAttachmentFromIndex(ctx, idStr)
+	})
 }
 
 // Bulk operations that coordinate across entity types
@@ -155,10 +200,7 @@ func (s *IndexService) IndexCompleteData(ctx context.Context) (int, int, int, er
 // ProcessBatchDataIngest handles the business logic for batch data ingestion
 func (s *IndexService) ProcessBatchDataIngest(ctx context.Context, records []DataRecord) (*DataIngestResponse, error) {
 	// Create fugu client
-	client, err := s.createFuguClient(ctx)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create fugu client: %w", err)
-	}
+	client := s.client
 
 	// Convert records to fugu format
 	fuguObjects, conversionErrors := s.convertToFuguObjects(ctx, records)
@@ -180,24 +222,17 @@ func (s *IndexService) ProcessBatchDataIngest(ctx context.Context, records []Dat
 
 	// Ingest valid objects if any
 	if len(fuguObjects) > 0 {
-		// Use the same chunked processing as other batch operations
-		processed, err := s.processBatchInChunks(ctx, client, fuguObjects, "data")
+		// Use the same bulk indexing as other batch operations, failures are reported per record
+		stats, err := s.bulkIndex(ctx, client, fuguObjects, "data")
 		if err != nil {
-			logger.Error(ctx, "fugu data ingestion failed", zap.Error(err))
-
-			// Mark all remaining objects as failed
-			for _, obj := range fuguObjects {
-				response.Failed++
-				response.FailedIDs = append(response.FailedIDs, obj.ID)
-			}
-
-			response.Message = "Data ingestion failed"
-			return response, nil
+			logger.Error(ctx, "fugu data ingestion interrupted", zap.Error(err))
 		}
-
-		// Ingestion successful
-		response.Successful = processed
-		logger.Info(ctx, "successfully ingested data records", zap.Int("count", response.Successful))
+		response.Successful = stats.Succeeded
+		response.Failed += stats.Failed
+		response.FailedIDs = append(response.FailedIDs, stats.FailedIDs...)
+		logger.Info(ctx, "ingested data records",
+			zap.Int("successful", stats.Succeeded),
+			zap.Int("failed", stats.Failed))
 	}
 
 	// Set final message
@@ -224,10 +259,7 @@ func (s *IndexService) IndexDataRecordByID(ctx context.Context, record DataRecor
 	}
 
 	// Create fugu client
-	client, err := s.createFuguClient(ctx)
-	if err != nil {
-		return 0, fmt.Errorf("failed to create fugu client: %w", err)
-	}
+	client := s.client
 
 	// Create fugu object with proper namespace facets
 	fuguObj := fugusdk.ObjectRecord{
@@ -279,10 +311,7 @@ func (s *IndexService) IndexDataRecordByID(ctx context.Context, record DataRecor
 }
 
 func (s *IndexService) DeleteDataRecordFromIndex(ctx context.Context, idStr string) error {
-	client, err := s.createFuguClient(ctx)
-	if err != nil {
-		return fmt.Errorf("new fugu client: %w", err)
-	}
+	client := s.client
 
 	response, err := client.DeleteObject(ctx, idStr)
 	if err != nil {
@@ -322,7 +351,7 @@ func (s *IndexService) GetSystemStats(ctx context.Context) (map[string]interface
 	// Add system-wide metadata
 	stats["namespace"] = s.defaultNamespace
 	stats["generated_at"] = time.Now().Format(time.RFC3339)
-	stats["fugu_url"] = s.fuguURL
+	stats["fugu_url"] = s.client.BaseURL()
 
 	return stats, nil
 }
@@ -449,44 +478,43 @@ func (s *IndexService) parseDate(dateStr string) (time.Time, error) {
 	return time.Time{}, fmt.Errorf("unable to parse date: %s", dateStr)
 }
 
-// processBatchInChunks handles large batches by splitting them into smaller chunks
-func (s *IndexService) processBatchInChunks(ctx context.Context, client *fugusdk.Client, recs []fugusdk.ObjectRecord, entityType string) (int, error) {
-	const chunkSize = 10 // Use 500 to stay well under the 1000 limit
-	totalProcessed := 0
+// indexConcurrency is how many bulk requests an indexing run keeps in flight
+const indexConcurrency = 2
 
-	log.Printf("Processing %d %s in chunks of %d", len(recs), entityType, chunkSize)
-
-	for i := 0; i < len(recs); i += chunkSize {
-		end := i + chunkSize
-		if end > len(recs) {
-			end = len(recs)
-		}
-
-		chunk := recs[i:end]
-		log.Printf("Processing chunk %d-%d of %d %s", i+1, end, len(recs), entityType)
+// bulkIndex indexes records through a fugusdk.BulkIndexer, which bounds each request by object count
+// and size and retries failed sub-batches. Records that still fail are logged and listed in the stats.
+func (s *IndexService) bulkIndex(ctx context.Context, client *fugusdk.Client, recs []fugusdk.ObjectRecord, entityType string) (fugusdk.BulkStats, error) {
+	log.Printf("Bulk indexing %d %s", len(recs), entityType)
 
+	indexer := client.NewBulkIndexer(fugusdk.BulkIndexerConfig{
+		Concurrency: indexConcurrency,
 		// Use IngestObjectsWithNamespaceFacets for proper namespace facet handling
-		response, err := client.IngestObjectsWithNamespaceFacets(ctx, chunk)
-		if err != nil {
-			return totalProcessed, fmt.Errorf("batch index %s chunk %d-%d: %w", entityType, i+1, end, err)
-		}
-
-		chunkProcessed := len(chunk)
-		if response.UpsertedCount != nil {
-			chunkProcessed = *response.UpsertedCount
-		}
-
-		totalProcessed += chunkProcessed
-		log.Printf("Successfully processed chunk %d-%d: %s (processed: %d)", i+1, end, response.Message, chunkProcessed)
+		Send: client.IngestObjectsWithNamespaceFacets,
+		OnResult: func(result fugusdk.BulkResult) {
+			if result.Err != nil {
+				log.Printf("Failed to index %s record %s: %v", entityType, result.ID, result.Err)
+			}
+		},
+	})
+	stats, err := indexer.IndexSlice(ctx, recs)
+	log.Printf("Indexed %d %s in %d requests (%d retries, %d failed)",
+		stats.Succeeded, entityType, stats.Requests, stats.Retries, stats.Failed)
+	if err != nil {
+		return stats, fmt.Errorf("bulk index %s: %w", entityType, err)
 	}
-
-	log.Printf("Successfully indexed %d %s in total", totalProcessed, entityType)
-	return totalProcessed, nil
+	return stats, nil
 }
 
-// createFuguClient creates a new FuguDB client for health checks and other operations
-func (s *IndexService) createFuguClient(ctx context.Context) (*fugusdk.Client, error) {
-	return fugusdk.NewClient(ctx, s.fuguURL)
+// processBatchInChunks bulk indexes records, returning an error when any of them failed
+func (s *IndexService) processBatchInChunks(ctx context.Context, client *fugusdk.Client, recs []fugusdk.ObjectRecord, entityType string) (int, error) {
+	stats, err := s.bulkIndex(ctx, client, recs, entityType)
+	if err != nil {
+		return stats.Succeeded, err
+	}
+	if stats.Failed > 0 {
+		return stats.Succeeded, fmt.Errorf("bulk index %s: %d of %d records failed", entityType, stats.Failed, len(recs))
+	}
+	return stats.Succeeded, nil
 }
 
 // Health check and maintenance methods
@@ -499,10 +527,7 @@ func (s *IndexService) HealthCheck(ctx context.Context) error {
 	}
 
 	// Test FuguDB connection
-	client, err := s.createFuguClient(ctx)
-	if err != nil {
-		return fmt.Errorf("failed to create fugu client: %w", err)
-	}
+	client := s.client
 
 	if err := client.Health(ctx); err != nil {
 		return fmt.Errorf("fugu health check failed: %w", err)
@@ -513,8 +538,8 @@ func (s *IndexService) HealthCheck(ctx context.Context) error {
 
 // ValidateConfiguration checks that the service is properly configured
 func (s *IndexService) ValidateConfiguration() error {
-	if s.fuguURL == "" {
-		return fmt.Errorf("fugu URL cannot be empty")
+	if s.client == nil {
+		return fmt.Errorf("fugu client cannot be nil")
 	}
 
 	if s.defaultNamespace == "" {
@@ -0,0 +1,623 @@
+// indexing/versions.go
+package indexing
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strconv"
+	"time"
+
+	"kessler/internal/dbstore"
+	"kessler/internal/fugusdk"
+	"kessler/internal/indexalias"
+	"kessler/internal/quickwit"
+	"kessler/pkg/database"
+	"kessler/pkg/logger"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+)
+
+// A full rebuild is written into a new version of the index, alias_v<N>, while searches keep reading the
+// version the alias points at. Once the build's document counts match Postgres the alias is switched in a
+// single statement and the previous version is garbage collected after retiredGracePeriod. The unversioned
+// index searched before the first switch is collected the same way once the first switch is that old.
+
+// Search backends whose indexes are rebuilt into versions
+const (
+	BackendFugu     = indexalias.BackendFugu
+	BackendQuickwit = indexalias.BackendQuickwit
+)
+
+// Lifecycle of an index version
+const (
+	VersionBuilding = "building"
+	VersionReady    = "ready"
+	VersionFailed   = "failed"
+	VersionLive     = "live"
+	VersionRetired  = "retired"
+	VersionDeleted  = "deleted"
+)
+
+var (
+	// ErrVersionValidation is returned when a build indexed fewer documents than Postgres holds
+	ErrVersionValidation = errors.New("index version failed validation")
+	// ErrVersionNotFound is returned for an unknown version ID
+	ErrVersionNotFound = errors.New("index version not found")
+	// ErrVersionNotSwitchable is returned when switching to a version that is not ready or retired
+	ErrVersionNotSwitchable = errors.New("index version cannot be switched to")
+	// ErrUnknownQuickwitIndex is returned for a Quickwit alias without a versioned build
+	ErrUnknownQuickwitIndex = errors.New("quickwit index has no versioned build")
+)
+
+// retiredGracePeriod is how long a retired version is kept, long enough for every replica's alias cache
+// to expire and for searches already running against it to finish
+const retiredGracePeriod = 2 * indexalias.CacheTTL
+
+// defaultMinCoverage is the share of Postgres rows each entity must reach when REINDEX_MIN_COVERAGE is unset.
+// Attachments whose text cannot be indexed are skipped, so an exact match is too strict for real data.
+const defaultMinCoverage = 0.99
+
+// IndexVersion is the API view of a versioned build
+type IndexVersion struct {
+	ID            uuid.UUID        `json:"id"`
+	Backend       string           `json:"backend"`
+	Alias         string           `json:"alias"`
+	Version       int              `json:"version"`
+	Target        string           `json:"target"`
+	Status        string           `json:"status"`
+	StatusMessage string           `json:"status_message,omitempty"`
+	Expected      map[string]int64 `json:"expected_counts,omitempty"`
+	Indexed       map[string]int64 `json:"indexed_counts,omitempty"`
+	CreatedAt     time.Time        `json:"created_at"`
+	ActivatedAt   *time.Time       `json:"activated_at,omitempty"`
+	RetiredAt     *time.Time       `json:"retired_at,omitempty"`
+}
+
+func indexVersionFromRow(row dbstore.SearchIndexVersion) IndexVersion {
+	version := IndexVersion{
+		ID:            row.ID,
+		Backend:       row.Backend,
+		Alias:         row.Alias,
+		Version:       int(row.Version),
+		Target:        row.Target,
+		Status:        row.Status,
+		StatusMessage: row.StatusMessage,
+		CreatedAt:     row.CreatedAt.Time,
+	}
+	// Counts are written by this package, a row that fails to decode just shows none
+	_ = json.Unmarshal(row.ExpectedCounts, &version.Expected)
+	_ = json.Unmarshal(row.IndexedCounts, &version.Indexed)
+	if row.ActivatedAt.Valid {
+		version.ActivatedAt = &row.ActivatedAt.Time
+	}
+	if row.RetiredAt.Valid {
+		version.RetiredAt = &row.RetiredAt.Time
+	}
+	return version
+}
+
+// RebuildOptions controls a versioned rebuild
+type RebuildOptions struct {
+	// Activate switches the alias to the new version once it validates
+	Activate bool
+	// MinCoverage is the share of Postgres rows every entity must reach, zero reads REINDEX_MIN_COVERAGE
+	MinCoverage float64
+}
+
+// versionBuilder writes one version of an alias and reports what it indexed
+type versionBuilder interface {
+	// build indexes everything into target and returns the document count per entity
+	build(ctx context.Context, target string) (map[string]int64, error)
+	// expected returns the Postgres row count per entity build should reach
+	expected(ctx context.Context) (map[string]int64, error)
+	// drop deletes every document of target, target is the alias itself for the unversioned index
+	drop(ctx context.Context, target string) error
+}
+
+// RebuildFuguVersion rebuilds the default namespace into a new version
+func (s *IndexService) RebuildFuguVersion(ctx context.Context, opts RebuildOptions) (*IndexVersion, error) {
+	return s.rebuild(ctx, BackendFugu, s.defaultNamespace, fuguVersions{svc: s}, opts)
+}
+
+// RebuildQuickwitVersion rebuilds a legacy Quickwit index into a new version
+func (s *IndexService) RebuildQuickwitVersion(ctx context.Context, alias string, opts RebuildOptions) (*IndexVersion, error) {
+	spec, ok := quickwitVersionedIndexes[alias]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownQuickwitIndex, alias)
+	}
+	return s.rebuild(ctx, BackendQuickwit, alias, quickwitVersions{db: s.db, spec: spec}, opts)
+}
+
+func (s *IndexService) rebuild(ctx context.Context, backend, alias string, builder versionBuilder, opts RebuildOptions) (*IndexVersion, error) {
+	q := database.GetQueries(s.db)
+	row, err := q.SearchIndexVersionCreate(ctx, dbstore.SearchIndexVersionCreateParams{
+		Backend: backend,
+		Alias:   alias,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("allocate %s version of %s: %w", backend, alias, err)
+	}
+	logger.Info(ctx, "building index version",
+		zap.String("backend", backend),
+		zap.String("alias", alias),
+		zap.String("target", row.Target))
+	startTime := time.Now()
+
+	indexed, err := builder.build(ctx, row.Target)
+	if err != nil {
+		return s.failVersion(ctx, row.ID, fmt.Errorf("build %s: %w", row.Target, err))
+	}
+	expected, err := builder.expected(ctx)
+	if err != nil {
+		return s.failVersion(ctx, row.ID, fmt.Errorf("count %s source rows: %w", alias, err))
+	}
+
+	expectedJSON, _ := json.Marshal(expected)
+	indexedJSON, _ := json.Marshal(indexed)
+	if err := q.SearchIndexVersionSetCounts(ctx, dbstore.SearchIndexVersionSetCountsParams{
+		ID:             row.ID,
+		ExpectedCounts: expectedJSON,
+		IndexedCounts:  indexedJSON,
+	}); err != nil {
+		return s.failVersion(ctx, row.ID, fmt.Errorf("record counts: %w", err))
+	}
+
+	minCoverage := opts.MinCoverage
+	if minCoverage <= 0 {
+		minCoverage = minCoverageFromEnv()
+	}
+	if err := validateCounts(expected, indexed, minCoverage); err != nil {
+		return s.failVersion(ctx, row.ID, fmt.Errorf("%w: %w", ErrVersionValidation, err))
+	}
+
+	if err := q.SearchIndexVersionSetStatus(ctx, dbstore.SearchIndexVersionSetStatusParams{
+		ID:     row.ID,
+		Status: VersionReady,
+	}); err != nil {
+		return nil, fmt.Errorf("mark %s ready: %w", row.Target, err)
+	}
+	logger.Info(ctx, "index version built",
+		zap.String("target", row.Target),
+		zap.Any("indexed", indexed),
+		zap.Duration("duration", time.Since(startTime)))
+
+	if opts.Activate {
+		return s.ActivateVersion(ctx, row.ID)
+	}
+	return s.readVersion(ctx, row.ID)
+}
+
+// failVersion records why a build failed and returns its version alongside the error
+func (s *IndexService) failVersion(ctx context.Context, id uuid.UUID, cause error) (*IndexVersion, error) {
+	logger.Error(ctx, "index version build failed", zap.String("version_id", id.String()), zap.Error(cause))
+	if err := database.GetQueries(s.db).SearchIndexVersionSetStatus(ctx, dbstore.SearchIndexVersionSetStatusParams{
+		ID:            id,
+		Status:        VersionFailed,
+		StatusMessage: cause.Error(),
+	}); err != nil {
+		logger.Error(ctx, "failed to record index version failure", zap.String("version_id", id.String()), zap.Error(err))
+	}
+	version, err := s.readVersion(ctx, id)
+	if err != nil {
+		return nil, cause
+	}
+	// The partial build is collected like a retired version
+	s.scheduleCollect(version.Backend, version.Alias)
+	return version, cause
+}
+
+// ActivateVersion switches the version's alias to it and retires the version it replaces. A retired
+// version that has not been collected yet can be switched back to, which is how a rebuild is rolled back.
+func (s *IndexService) ActivateVersion(ctx context.Context, id uuid.UUID) (*IndexVersion, error) {
+	version, err := s.readVersion(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if version.Status != VersionReady && version.Status != VersionRetired {
+		return version, fmt.Errorf("%w: %s is %s", ErrVersionNotSwitchable, version.Target, version.Status)
+	}
+
+	alias, err := database.GetQueries(s.db).SearchIndexAliasSwitch(ctx, id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		// The version changed state between the read and the switch, e.g. it was just collected
+		return version, fmt.Errorf("%w: %s", ErrVersionNotSwitchable, version.Target)
+	}
+	if err != nil {
+		return version, fmt.Errorf("switch %s to %s: %w", version.Alias, version.Target, err)
+	}
+	s.aliases.Invalidate(alias.Backend, alias.Alias)
+	s.scheduleCollect(alias.Backend, alias.Alias)
+
+	logger.Info(ctx, "index alias switched",
+		zap.String("backend", alias.Backend),
+		zap.String("alias", alias.Alias),
+		zap.String("target", alias.Target))
+	return s.readVersion(ctx, id)
+}
+
+// ListVersions returns every version of an alias, newest first
+func (s *IndexService) ListVersions(ctx context.Context, backend, alias string) ([]IndexVersion, error) {
+	rows, err := database.GetQueries(s.db).SearchIndexVersionList(ctx, dbstore.SearchIndexVersionListParams{
+		Backend: backend,
+		Alias:   alias,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("list %s versions of %s: %w", backend, alias, err)
+	}
+	versions := make([]IndexVersion, len(rows))
+	for i, row := range rows {
+		versions[i] = indexVersionFromRow(row)
+	}
+	return versions, nil
+}
+
+// CollectVersions deletes the documents of failed versions and of versions retired for longer than
+// retiredGracePeriod, returning the versions it deleted
+func (s *IndexService) CollectVersions(ctx context.Context, backend, alias string) ([]IndexVersion, error) {
+	var builder versionBuilder
+	switch backend {
+	case BackendFugu:
+		builder = fuguVersions{svc: s}
+	case BackendQuickwit:
+		spec, ok := quickwitVersionedIndexes[alias]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownQuickwitIndex, alias)
+		}
+		builder = quickwitVersions{db: s.db, spec: spec}
+	default:
+		return nil, fmt.Errorf("unknown index backend %q", backend)
+	}
+
+	versions, err := s.ListVersions(ctx, backend, alias)
+	if err != nil {
+		return nil, err
+	}
+
+	q := database.GetQueries(s.db)
+	var collected []IndexVersion
+	var errs []error
+	for _, version := range versions {
+		if !collectable(version, time.Now()) {
+			continue
+		}
+		// Mark the version deleted first so it cannot be switched back to while its documents go
+		if err := q.SearchIndexVersionSetStatus(ctx, dbstore.SearchIndexVersionSetStatusParams{
+			ID:            version.ID,
+			Status:        VersionDeleted,
+			StatusMessage: version.StatusMessage,
+		}); err != nil {
+			errs = append(errs, fmt.Errorf("mark %s deleted: %w", version.Target, err))
+			continue
+		}
+		if err := builder.drop(ctx, version.Target); err != nil {
+			// Put it back so the next collection retries
+			_ = q.SearchIndexVersionSetStatus(ctx, dbstore.SearchIndexVersionSetStatusParams{
+				ID:            version.ID,
+				Status:        version.Status,
+				StatusMessage: fmt.Sprintf("garbage collection failed: %v", err),
+			})
+			errs = append(errs, fmt.Errorf("drop %s: %w", version.Target, err))
+			continue
+		}
+		logger.Info(ctx, "index version collected",
+			zap.String("backend", backend),
+			zap.String("target", version.Target))
+		version.Status = VersionDeleted
+		collected = append(collected, version)
+	}
+
+	// Dropping the unversioned index again once it is gone finds nothing to delete
+	if legacyCollectable(versions, time.Now()) {
+		if err := builder.drop(ctx, alias); err != nil {
+			errs = append(errs, fmt.Errorf("drop unversioned %s: %w", alias, err))
+		} else {
+			logger.Info(ctx, "unversioned index collected",
+				zap.String("backend", backend),
+				zap.String("alias", alias))
+		}
+	}
+	return collected, errors.Join(errs...)
+}
+
+// legacyCollectable reports whether the unversioned index can be deleted: the alias is switched to a version
+// and the first switch away from the unversioned index is past the grace period, it cannot be switched back to
+func legacyCollectable(versions []IndexVersion, now time.Time) bool {
+	live := false
+	var firstActivated *time.Time
+	for _, version := range versions {
+		if version.Status == VersionLive {
+			live = true
+		}
+		if version.ActivatedAt != nil && (firstActivated == nil || version.ActivatedAt.Before(*firstActivated)) {
+			firstActivated = version.ActivatedAt
+		}
+	}
+	return live && firstActivated != nil && now.Sub(*firstActivated) >= retiredGracePeriod
+}
+
+// collectable reports whether a version's documents can be deleted
+func collectable(version IndexVersion, now time.Time) bool {
+	switch version.Status {
+	case VersionFailed:
+		return true
+	case VersionRetired:
+		return version.RetiredAt != nil && now.Sub(*version.RetiredAt) >= retiredGracePeriod
+	default:
+		return false
+	}
+}
+
+// scheduleCollect garbage collects the alias once a version retired now has passed its grace period
+func (s *IndexService) scheduleCollect(backend, alias string) {
+	time.AfterFunc(retiredGracePeriod+time.Second, func() {
+		ctx, cancel := context.WithTimeout(logger.WithLogger(context.Background()), 30*time.Minute)
+		defer cancel()
+		if _, err := s.CollectVersions(ctx, backend, alias); err != nil {
+			logger.Warn(ctx, "index version garbage collection failed",
+				zap.String("backend", backend),
+				zap.String("alias", alias),
+				zap.Error(err))
+		}
+	})
+}
+
+func (s *IndexService) readVersion(ctx context.Context, id uuid.UUID) (*IndexVersion, error) {
+	row, err := database.GetQueries(s.db).SearchIndexVersionRead(ctx, id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrVersionNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read index version: %w", err)
+	}
+	version := indexVersionFromRow(row)
+	return &version, nil
+}
+
+// validateCounts checks every entity reached minCoverage of its Postgres row count. Documents written
+// after the source rows were counted can push a count past 100%, which is not an error.
+func validateCounts(expected, indexed map[string]int64, minCoverage float64) error {
+	var errs []error
+	for _, entity := range slices.Sorted(maps.Keys(expected)) {
+		want, got := expected[entity], indexed[entity]
+		if want > 0 && float64(got) < minCoverage*float64(want) {
+			errs = append(errs, fmt.Errorf("%s: indexed %d of %d, below %.4g%% coverage", entity, got, want, minCoverage*100))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// minCoverageFromEnv reads REINDEX_MIN_COVERAGE, a share between 0 and 1
+func minCoverageFromEnv() float64 {
+	raw := os.Getenv("REINDEX_MIN_COVERAGE")
+	if raw == "" {
+		return defaultMinCoverage
+	}
+	coverage, err := strconv.ParseFloat(raw, 64)
+	if err != nil || coverage <= 0 || coverage > 1 {
+		logger.Warn(context.Background(), "invalid REINDEX_MIN_COVERAGE, using default",
+			zap.String("value", raw),
+			zap.Float64("default", defaultMinCoverage))
+		return defaultMinCoverage
+	}
+	return coverage
+}
+
+// forTarget returns a copy of the service writing into a versioned namespace
+func (s *IndexService) forTarget(target string) *IndexService {
+	if target == s.defaultNamespace {
+		return s
+	}
+	version := NewIndexService(s.client.WithIDPrefix(fugusdk.VersionIDPrefix(target)), target, s.db)
+	version.aliases = s.aliases
+	return version
+}
+
+// writeTargets returns where single-record writes go: the live version of the default namespace, or the
+// namespace itself before the first switch, plus any version still building so the rebuild does not miss them
+func (s *IndexService) writeTargets(ctx context.Context) []*IndexService {
+	versions, err := s.ListVersions(ctx, BackendFugu, s.defaultNamespace)
+	if err != nil {
+		logger.Warn(ctx, "could not list index versions, writing to the unversioned namespace", zap.Error(err))
+		return []*IndexService{s}
+	}
+
+	var targets []*IndexService
+	live := false
+	for _, version := range versions {
+		switch version.Status {
+		case VersionLive:
+			live = true
+			targets = append(targets, s.forTarget(version.Target))
+		case VersionBuilding:
+			targets = append(targets, s.forTarget(version.Target))
+		}
+	}
+	if !live {
+		targets = append(targets, s)
+	}
+	return targets
+}
+
+// eachWriteTarget applies a single-record write to every write target
+func (s *IndexService) eachWriteTarget(ctx context.Context, write func(target *IndexService) error) error {
+	targets := s.writeTargets(ctx)
+	if len(targets) == 1 {
+		return write(targets[0])
+	}
+	var errs []error
+	for _, target := range targets {
+		if err := write(target); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", target.defaultNamespace, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// fuguVersions builds versions of the default FuguDB namespace with the regular indexers
+type fuguVersions struct {
+	svc *IndexService
+}
+
+// fuguEntityFilters selects one record per indexed entity. Long attachments are split into several records,
+// only the first segment of each carries the segment_index facet, so validation counts the attachments themselves.
+var fuguEntityFilters = map[string][]string{
+	"conversations": {"metadata/entity_type/conversation"},
+	"organizations": {"metadata/entity_type/organization"},
+	"attachments":   {"metadata/entity_type/attachment", firstSegmentFacet},
+}
+
+func (f fuguVersions) build(ctx context.Context, target string) (map[string]int64, error) {
+	version := f.svc.forTarget(target)
+
+	if _, err := version.IndexAllConversations(ctx); err != nil {
+		return nil, fmt.Errorf("index conversations: %w", err)
+	}
+	if _, err := version.IndexAllOrganizations(ctx); err != nil {
+		return nil, fmt.Errorf("index organizations: %w", err)
+	}
+	if _, _, err := version.attachmentIndexer.indexAll(ctx); err != nil {
+		return nil, fmt.Errorf("index attachments: %w", err)
+	}
+	// The indexers' success counts include upserts Fugu acknowledged without storing, count what is searchable
+	return f.count(ctx, target)
+}
+
+// count returns the number of documents per entity searchable in the namespace
+func (f fuguVersions) count(ctx context.Context, target string) (map[string]int64, error) {
+	counts := make(map[string]int64, len(fuguEntityFilters))
+	page, perPage := 0, 1
+	for entity, entityFilters := range fuguEntityFilters {
+		filters := append([]string{"namespace/" + target}, entityFilters...)
+		resp, err := f.svc.client.Search(ctx, fugusdk.FuguSearchQuery{
+			Query:   "*",
+			Filters: &filters,
+			Page:    &fugusdk.Pagination{Page: &page, PerPage: &perPage},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("count %s in %s: %w", entity, target, err)
+		}
+		counts[entity] = int64(resp.Total)
+	}
+	return counts, nil
+}
+
+func (f fuguVersions) expected(ctx context.Context) (map[string]int64, error) {
+	stats, err := database.GetQueries(f.svc.db).GetSearchIndexSourceStats(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]int64{
+		"conversations": stats.ConversationCount,
+		"organizations": stats.OrganizationCount,
+		"attachments":   stats.AttachmentCount,
+	}, nil
+}
+
+// drop deletes every object in the namespace. FuguDB cannot delete by filter, so it deletes the
+// first page of the namespace until a search comes back empty.
+func (f fuguVersions) drop(ctx context.Context, target string) error {
+	client := f.svc.forTarget(target).client
+	filters := []string{"namespace/" + target}
+	page, perPage := 0, 100
+
+	deleted := 0
+	for {
+		resp, err := client.Search(ctx, fugusdk.FuguSearchQuery{
+			Query:   "*",
+			Filters: &filters,
+			Page:    &fugusdk.Pagination{Page: &page, PerPage: &perPage},
+		})
+		if err != nil {
+			return fmt.Errorf("list objects after deleting %d: %w", deleted, err)
+		}
+		if len(resp.Results) == 0 {
+			return nil
+		}
+
+		removed := 0
+		for _, hit := range resp.Results {
+			if _, err := client.DeleteObject(ctx, hit.ID); err != nil {
+				logger.Warn(ctx, "failed to delete object of collected version",
+					zap.String("target", target),
+					zap.String("object_id", hit.ID),
+					zap.Error(err))
+				continue
+			}
+			removed++
+		}
+		// Only objects that refuse to be deleted are left, searching again would return them forever
+		if removed == 0 {
+			return fmt.Errorf("%d objects could not be deleted", len(resp.Results))
+		}
+		deleted += removed
+	}
+}
+
+// quickwitIndexSpec creates and fills one legacy Quickwit index under any name
+type quickwitIndexSpec struct {
+	entity   string
+	create   func(indexName string) error
+	populate func(ctx context.Context, q *dbstore.Queries, indexName string) error
+}
+
+// quickwitVersionedIndexes are the Quickwit indexes that can be rebuilt into versions, keyed by alias
+var quickwitVersionedIndexes = map[string]quickwitIndexSpec{
+	quickwit.NYConversationIndex: {
+		entity: "conversations",
+		create: quickwit.CreateQuickwitProceedingIndex,
+		populate: func(ctx context.Context, q *dbstore.Queries, indexName string) error {
+			return quickwit.IndexAllConversations(*q, ctx, indexName)
+		},
+	},
+	quickwit.NYOrganizationIndex: {
+		entity: "organizations",
+		create: quickwit.CreateQuickwitOrganizationsIndex,
+		populate: func(ctx context.Context, q *dbstore.Queries, indexName string) error {
+			return quickwit.ReindexAllOrganizations(ctx, *q, indexName)
+		},
+	},
+}
+
+// quickwitVersions builds versions of a legacy Quickwit index, each version is its own index
+type quickwitVersions struct {
+	db   dbstore.DBTX
+	spec quickwitIndexSpec
+}
+
+func (v quickwitVersions) build(ctx context.Context, target string) (map[string]int64, error) {
+	if err := v.spec.create(target); err != nil {
+		return nil, fmt.Errorf("create index: %w", err)
+	}
+	if err := v.spec.populate(ctx, database.GetQueries(v.db), target); err != nil {
+		return nil, fmt.Errorf("index %s: %w", v.spec.entity, err)
+	}
+	// Ingest commits synchronously, so the count includes everything written above
+	count, err := quickwit.CountDocuments(target)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]int64{v.spec.entity: int64(count)}, nil
+}
+
+func (v quickwitVersions) expected(ctx context.Context) (map[string]int64, error) {
+	stats, err := database.GetQueries(v.db).GetSearchIndexSourceStats(ctx)
+	if err != nil {
+		return nil, err
+	}
+	counts := map[string]int64{
+		"conversations": stats.ConversationCount,
+		"organizations": stats.OrganizationCount,
+	}
+	return map[string]int64{v.spec.entity: counts[v.spec.entity]}, nil
+}
+
+func (v quickwitVersions) drop(ctx context.Context, target string) error {
+	return quickwit.ClearIndex(target, true)
+}
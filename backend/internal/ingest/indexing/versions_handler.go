@@ -0,0 +1,240 @@
+// indexing/versions_handler.go
+package indexing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"kessler/pkg/logger"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+)
+
+// versionAlias reads the backend and alias query parameters. The backend defaults to fugu and a fugu alias
+// to the default namespace, Quickwit aliases must be named.
+func (h *IndexHandler) versionAlias(r *http.Request) (string, string, error) {
+	backend := r.URL.Query().Get("backend")
+	if backend == "" {
+		backend = BackendFugu
+	}
+	alias := r.URL.Query().Get("alias")
+	switch backend {
+	case BackendFugu:
+		if alias != "" && alias != h.svc.defaultNamespace {
+			return "", "", fmt.Errorf("only the default namespace %s is versioned", h.svc.defaultNamespace)
+		}
+		return backend, h.svc.defaultNamespace, nil
+	case BackendQuickwit:
+		if _, ok := quickwitVersionedIndexes[alias]; !ok {
+			return "", "", fmt.Errorf("%w: %q", ErrUnknownQuickwitIndex, alias)
+		}
+		return backend, alias, nil
+	default:
+		return "", "", fmt.Errorf("backend must be %s or %s", BackendFugu, BackendQuickwit)
+	}
+}
+
+// respondVersionError maps a versioning error to a status code, including the version when there is one
+func (h *IndexHandler) respondVersionError(w http.ResponseWriter, version *IndexVersion, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, ErrVersionNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, ErrVersionNotSwitchable):
+		status = http.StatusConflict
+	case errors.Is(err, ErrVersionValidation):
+		status = http.StatusUnprocessableEntity
+	case errors.Is(err, ErrUnknownQuickwitIndex):
+		status = http.StatusBadRequest
+	}
+	body := map[string]interface{}{"error": err.Error()}
+	if version != nil {
+		body["version"] = version
+	}
+	h.respondJSON(w, status, body)
+}
+
+// ListIndexVersions godoc
+// @Summary List the versions of a search index
+// @Description Lists every versioned build of a FuguDB namespace or Quickwit index with its status and document counts
+// @Param backend query string false "fugu (default) or quickwit"
+// @Param alias query string false "Index alias, the default namespace for fugu"
+// @Success 200 {array} IndexVersion
+// @Failure 400 {object} map[string]string{"error":string}
+// @Router /admin/indexing/versions [get]
+func (h *IndexHandler) ListIndexVersions(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	ctx, span := tracer.Start(ctx, "indexing:ListIndexVersions")
+	defer span.End()
+
+	backend, alias, err := h.versionAlias(r)
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	versions, err := h.svc.ListVersions(ctx, backend, alias)
+	if err != nil {
+		logger.Error(ctx, "list index versions failed", zap.Error(err))
+		h.respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	h.respondJSON(w, http.StatusOK, versions)
+}
+
+// RebuildIndexVersion godoc
+// @Summary Rebuild a search index into a new version
+// @Description Builds alias_v<N> next to the live index, validates its document counts against Postgres and, unless activate=false, switches the alias to it
+// @Param backend query string false "fugu (default) or quickwit"
+// @Param alias query string false "Index alias, the default namespace for fugu"
+// @Param activate query bool false "Switch the alias once the version validates (default true)"
+// @Success 200 {object} IndexVersion
+// @Failure 422 {object} map[string]interface{} "Validation failed, the version is left unused"
+// @Router /admin/indexing/versions [post]
+func (h *IndexHandler) RebuildIndexVersion(w http.ResponseWriter, r *http.Request) {
+	// Create a context with extended timeout for long-running operations
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Minute)
+	defer cancel()
+
+	ctx, span := tracer.Start(ctx, "indexing:RebuildIndexVersion")
+	defer span.End()
+
+	backend, alias, err := h.versionAlias(r)
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	opts := RebuildOptions{Activate: true}
+	if raw := r.URL.Query().Get("activate"); raw != "" {
+		if opts.Activate, err = strconv.ParseBool(raw); err != nil {
+			h.respondError(w, http.StatusBadRequest, "activate must be true or false")
+			return
+		}
+	}
+
+	logger.Info(ctx, "versioned rebuild requested",
+		zap.String("backend", backend),
+		zap.String("alias", alias),
+		zap.Bool("activate", opts.Activate))
+
+	var version *IndexVersion
+	if backend == BackendQuickwit {
+		version, err = h.svc.RebuildQuickwitVersion(ctx, alias, opts)
+	} else {
+		version, err = h.svc.RebuildFuguVersion(ctx, opts)
+	}
+	if err != nil {
+		h.respondVersionError(w, version, err)
+		return
+	}
+	h.respondJSON(w, http.StatusOK, version)
+}
+
+// ActivateIndexVersion godoc
+// @Summary Switch an alias to an index version
+// @Description Points the version's alias at it and retires the previous version. A retired version that has not been garbage collected can be switched back to.
+// @Param id path string true "Version UUID"
+// @Success 200 {object} IndexVersion
+// @Failure 404 {object} map[string]string{"error":string}
+// @Failure 409 {object} map[string]interface{} "The version is not ready or retired"
+// @Router /admin/indexing/versions/{id}/activate [post]
+func (h *IndexHandler) ActivateIndexVersion(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	ctx, span := tracer.Start(ctx, "indexing:ActivateIndexVersion")
+	defer span.End()
+
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, "version id must be a UUID")
+		return
+	}
+
+	version, err := h.svc.ActivateVersion(ctx, id)
+	if err != nil {
+		logger.Error(ctx, "activate index version failed", zap.String("version_id", id.String()), zap.Error(err))
+		h.respondVersionError(w, version, err)
+		return
+	}
+	h.respondJSON(w, http.StatusOK, version)
+}
+
+// CollectIndexVersions godoc
+// @Summary Garbage collect old index versions
+// @Description Deletes the documents of failed versions and of versions retired longer than the grace period
+// @Param backend query string false "fugu (default) or quickwit"
+// @Param alias query string false "Index alias, the default namespace for fugu"
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/indexing/versions/collect [post]
+func (h *IndexHandler) CollectIndexVersions(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+	defer cancel()
+
+	ctx, span := tracer.Start(ctx, "indexing:CollectIndexVersions")
+	defer span.End()
+
+	backend, alias, err := h.versionAlias(r)
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	collected, err := h.svc.CollectVersions(ctx, backend, alias)
+	response := map[string]interface{}{"collected": collected}
+	if err != nil {
+		logger.Error(ctx, "index version garbage collection failed", zap.Error(err))
+		response["error"] = err.Error()
+		h.respondJSON(w, http.StatusInternalServerError, response)
+		return
+	}
+	h.respondJSON(w, http.StatusOK, response)
+}
+
+// indexEverything runs a complete index for /all and /complete. By default it is a versioned rebuild
+// that only replaces what searches see once it validates, in_place=true writes into the live version.
+func (h *IndexHandler) indexEverything(ctx context.Context, w http.ResponseWriter, r *http.Request, message string) {
+	if inPlace, _ := strconv.ParseBool(r.URL.Query().Get("in_place")); inPlace {
+		convCount, orgCount, attachCount, err := h.svc.IndexCompleteData(ctx)
+		if err != nil {
+			logger.Error(ctx, "in-place index failed", zap.Error(err))
+			h.respondError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		logger.Info(ctx, "successfully indexed data in place",
+			zap.Int("conversations", convCount),
+			zap.Int("organizations", orgCount),
+			zap.Int("attachments", attachCount))
+		h.respondJSON(w, http.StatusOK, map[string]interface{}{
+			"conversations_indexed": convCount,
+			"organizations_indexed": orgCount,
+			"attachments_indexed":   attachCount,
+			"total_indexed":         convCount + orgCount + attachCount,
+			"message":               message,
+		})
+		return
+	}
+
+	version, err := h.svc.RebuildFuguVersion(ctx, RebuildOptions{Activate: true})
+	if err != nil {
+		logger.Error(ctx, "versioned rebuild failed", zap.Error(err))
+		h.respondVersionError(w, version, err)
+		return
+	}
+
+	logger.Info(ctx, "successfully rebuilt index version",
+		zap.String("target", version.Target),
+		zap.Any("indexed", version.Indexed))
+	h.respondJSON(w, http.StatusOK, map[string]interface{}{
+		"conversations_indexed": version.Indexed["conversations"],
+		"organizations_indexed": version.Indexed["organizations"],
+		"attachments_indexed":   version.Indexed["attachments"],
+		"total_indexed":         version.Indexed["conversations"] + version.Indexed["organizations"] + version.Indexed["attachments"],
+		"version":               version,
+		"message":               fmt.Sprintf("%s, now serving %s", message, version.Target),
+	})
+}
@@ -0,0 +1,126 @@
+package indexing
+
+import (
+	"context"
+	"fmt"
+	"kessler/internal/fugusdk"
+	"kessler/internal/fugusdk/fugutest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidateCounts(t *testing.T) {
+	expected := map[string]int64{"conversations": 1000, "organizations": 50, "attachments": 0}
+
+	if err := validateCounts(expected, map[string]int64{"conversations": 995, "organizations": 50}, 0.99); err != nil {
+		t.Errorf("counts within coverage should pass: %v", err)
+	}
+	if err := validateCounts(expected, map[string]int64{"conversations": 1200, "organizations": 60}, 0.99); err != nil {
+		t.Errorf("indexing more than expected should pass: %v", err)
+	}
+
+	err := validateCounts(expected, map[string]int64{"conversations": 900, "organizations": 10}, 0.99)
+	if err == nil {
+		t.Fatal("under-coverage should fail")
+	}
+	if msg := err.Error(); !strings.Contains(msg, "conversations: indexed 900 of 1000") || !strings.Contains(msg, "organizations: indexed 10 of 50") {
+		t.Errorf("error should name each short entity, got %q", msg)
+	}
+}
+
+func TestCollectable(t *testing.T) {
+	now := time.Now()
+	recent := now.Add(-retiredGracePeriod / 2)
+	old := now.Add(-retiredGracePeriod - time.Second)
+
+	cases := []struct {
+		name    string
+		version IndexVersion
+		want    bool
+	}{
+		{"failed", IndexVersion{Status: VersionFailed}, true},
+		{"live", IndexVersion{Status: VersionLive}, false},
+		{"building", IndexVersion{Status: VersionBuilding}, false},
+		{"ready", IndexVersion{Status: VersionReady}, false},
+		{"recently retired", IndexVersion{Status: VersionRetired, RetiredAt: &recent}, false},
+		{"retired past grace", IndexVersion{Status: VersionRetired, RetiredAt: &old}, true},
+		{"deleted", IndexVersion{Status: VersionDeleted}, false},
+	}
+	for _, tc := range cases {
+		if got := collectable(tc.version, now); got != tc.want {
+			t.Errorf("%s: collectable = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestLegacyCollectable(t *testing.T) {
+	now := time.Now()
+	recent := now.Add(-retiredGracePeriod / 2)
+	old := now.Add(-retiredGracePeriod - time.Second)
+
+	cases := []struct {
+		name     string
+		versions []IndexVersion
+		want     bool
+	}{
+		{"never built", nil, false},
+		{"built but not switched", []IndexVersion{{Status: VersionReady}}, false},
+		{"switched recently", []IndexVersion{{Status: VersionLive, ActivatedAt: &recent}}, false},
+		{"switched past grace", []IndexVersion{{Status: VersionLive, ActivatedAt: &old}}, true},
+		{"first switch past grace", []IndexVersion{{Status: VersionLive, ActivatedAt: &recent}, {Status: VersionRetired, ActivatedAt: &old}}, true},
+		{"no live version", []IndexVersion{{Status: VersionRetired, ActivatedAt: &old}}, false},
+	}
+	for _, tc := range cases {
+		if got := legacyCollectable(tc.versions, now); got != tc.want {
+			t.Errorf("%s: legacyCollectable = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestFuguVersionsCountAndDrop(t *testing.T) {
+	ctx := context.Background()
+	server := fugutest.NewServer(t)
+	client, err := server.Client(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	versions := fuguVersions{svc: NewIndexService(client, "NYPUC", nil)}
+	target := fugusdk.VersionedNamespace("NYPUC", 2)
+
+	seed := func(namespace, id string, facets ...string) {
+		if namespace != "NYPUC" {
+			id = fugusdk.VersionIDPrefix(namespace) + id
+		}
+		server.Seed(fugusdk.ObjectRecord{ID: id, Text: "filing", Namespace: namespace, Facets: facets})
+	}
+	for i := 0; i < 3; i++ {
+		seed(target, fmt.Sprintf("convo-%d", i), "metadata/entity_type/conversation")
+		// Each attachment is split in two segments, only the first is counted
+		seed(target, fmt.Sprintf("att-%d-segment-0", i), "metadata/entity_type/attachment", firstSegmentFacet)
+		seed(target, fmt.Sprintf("att-%d-segment-1", i), "metadata/entity_type/attachment")
+		seed("NYPUC", fmt.Sprintf("legacy-%d", i), "metadata/entity_type/conversation")
+	}
+
+	counts, err := versions.count(ctx, target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if counts["conversations"] != 3 || counts["attachments"] != 3 || counts["organizations"] != 0 {
+		t.Errorf("unexpected counts %v", counts)
+	}
+
+	// Dropping the unversioned namespace leaves the version alone
+	if err := versions.drop(ctx, "NYPUC"); err != nil {
+		t.Fatal(err)
+	}
+	if server.Len() != 9 {
+		t.Errorf("expected only the 3 legacy objects dropped, %d objects left", server.Len())
+	}
+	if err := versions.drop(ctx, target); err != nil {
+		t.Fatal(err)
+	}
+	if server.Len() != 0 {
+		t.Errorf("expected every object dropped, %d left", server.Len())
+	}
+}
@@ -0,0 +1,75 @@
+package logic
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"kessler/internal/objects/files"
+	"kessler/pkg/constants"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// callInternalAPI sends body as JSON to the Kessler API and decodes the response into out when it is not nil
+func callInternalAPI(ctx context.Context, method string, url string, body any, out any) error {
+	var reader io.Reader
+	if body != nil {
+		jsonData, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to marshal object: %w", err)
+		}
+		reader = bytes.NewReader(jsonData)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, url, reader)
+	if err != nil {
+		return fmt.Errorf("request creation failed: %w", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("server returned status %d: %s", resp.StatusCode, string(respBody))
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to parse response: %w", err)
+	}
+	return nil
+}
+
+// recordFileStage appends a stage to the file's stage log
+func recordFileStage(ctx context.Context, fileID uuid.UUID, stage files.DocProcStage) error {
+	url := fmt.Sprintf("%s/v2/admin/file-stages/%s", constants.INTERNAL_KESSLER_API_URL, fileID)
+	return callInternalAPI(ctx, http.MethodPost, url, stage, nil)
+}
+
+// fetchFileForProcessing gets a stored file with its attachments and latest stage
+func fetchFileForProcessing(ctx context.Context, fileID uuid.UUID) (files.CompleteFileSchema, error) {
+	url := fmt.Sprintf("%s/v2/admin/file-stages/%s/file", constants.INTERNAL_KESSLER_API_URL, fileID)
+	var file files.CompleteFileSchema
+	err := callInternalAPI(ctx, http.MethodGet, url, nil, &file)
+	return file, err
+}
+
+// ListFilesAtStage lists the files whose latest recorded stage matches the filter
+func ListFilesAtStage(ctx context.Context, filter files.StageFilter) ([]files.FileStageEntry, error) {
+	url := fmt.Sprintf("%s/v2/admin/file-stages?%s", constants.INTERNAL_KESSLER_API_URL, filter.Query().Encode())
+	var entries []files.FileStageEntry
+	err := callInternalAPI(ctx, http.MethodGet, url, nil, &entries)
+	return entries, err
+}
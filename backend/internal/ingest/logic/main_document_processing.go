@@ -4,49 +4,98 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"kessler/internal/ingest/extract"
+	"kessler/internal/ingest/summarize"
 	"kessler/internal/ingest/validators"
+	"kessler/internal/llm_utils"
 	"kessler/internal/objects/files"
 	"kessler/pkg/logger"
 	"kessler/pkg/s3utils"
 	"log/slog"
 	"os"
 
+	"github.com/google/uuid"
 	"go.uber.org/zap"
-	// Assume these are implemented in other packages
 )
 
 var OS_HASH_FILEDIR = os.Getenv("OS_HASH_FILEDIR")
 
-func ProcessFile(ctx context.Context, complete_file files.CompleteFileSchema) error {
-	log := logger.Named("process_file")
-	_, err := ProcessFileRaw(ctx, &complete_file, files.DocStatusCompleted)
+// StageRecorder persists a stage the file reached so processing can be audited and resumed from it
+type StageRecorder func(ctx context.Context, stage files.DocProcStage) error
+
+// ProcessFile inserts a newly scraped file, then processes it recording every stage against the inserted file
+// as it is reached, so a file interrupted part way can be resumed, and saves the result. llm writes the file's extras.
+func ProcessFile(ctx context.Context, complete_file files.CompleteFileSchema, llm llm_utils.LLM) error {
+	saved, err := upsertFullFileToDB(ctx, complete_file, DatabaseInteractionInsert)
 	if err != nil {
-		log.Warn("Encountered error processing file", zap.String("name", complete_file.Name), zap.Error(err))
+		return fmt.Errorf("could not insert file %q: %w", complete_file.Name, err)
+	}
+	complete_file.ID = saved.ID
+
+	record := func(ctx context.Context, stage files.DocProcStage) error {
+		return recordFileStage(ctx, saved.ID, stage)
+	}
+	_, processErr := ProcessFileRaw(ctx, &complete_file, files.DocStatusCompleted, llm, record)
+	if processErr != nil {
+		logger.Warn(ctx, "encountered error processing file", zap.String("name", complete_file.Name), zap.Error(processErr))
 	}
-	fmt.Println("thing directly before upsert line")
-	_, err = upsertFullFileToDB(ctx, complete_file, DatabaseInteractionInsert)
-	fmt.Println("thing directly after upsert line")
+	if _, err := upsertFullFileToDB(ctx, complete_file, DatabaseInteractionUpdate); err != nil {
+		return errors.Join(processErr, fmt.Errorf("could not update file %s: %w", saved.ID, err))
+	}
+	return processErr
+}
+
+// ResumeFile continues processing a stored file from the latest stage in its stage log, recording every
+// stage as it is reached, and saves the result. llm writes the file's extras.
+func ResumeFile(ctx context.Context, fileID uuid.UUID, llm llm_utils.LLM) error {
+	complete_file, err := fetchFileForProcessing(ctx, fileID)
 	if err != nil {
-		fmt.Print("thing in error block with no return")
-		// log.Error("Could not upload file to database", zap.String("name", file.Name), zap.Error(err))
+		return fmt.Errorf("could not fetch file %s: %w", fileID, err)
+	}
+	resumeFrom := complete_file.Stage.DocProcStatus
+	if complete_file.Stage.PGStage == files.PGStageCompleted && resumeFrom == files.DocStatusCompleted {
+		logger.Info(ctx, "file already processed", zap.String("file_id", fileID.String()))
+		return nil
 	}
-	fmt.Println("thing after error block with no return")
-	log.Info("Successfully processed file and reached probelmatic return statement.")
-	var err_nil error
-	return err_nil
+	logger.Info(ctx, "resuming file processing", zap.String("file_id", fileID.String()), zap.String("stage", string(resumeFrom)))
+	complete_file.Stage = files.DocProcStage{PGStage: files.PGStageProcessing, DocProcStatus: resumeFrom}
+
+	record := func(ctx context.Context, stage files.DocProcStage) error {
+		return recordFileStage(ctx, fileID, stage)
+	}
+	_, processErr := ProcessFileRaw(ctx, &complete_file, files.DocStatusCompleted, llm, record)
+	if _, err := upsertFullFileToDB(ctx, complete_file, DatabaseInteractionUpdate); err != nil {
+		return errors.Join(processErr, fmt.Errorf("could not update file %s: %w", fileID, err))
+	}
+	return processErr
 }
 
-func ProcessFileRaw(ctx context.Context, obj *files.CompleteFileSchema, stopAt files.DocProcStatus) (files.CompleteFileSchema, error) {
+// ProcessFileRaw moves the file through the processing stages from its current one until stopAt. Every
+// stage reached, including the one a failure happened at, is passed to record when it is not nil. llm writes
+// the file's extras.
+func ProcessFileRaw(ctx context.Context, obj *files.CompleteFileSchema, stopAt files.DocProcStatus, llm llm_utils.LLM, record StageRecorder) (files.CompleteFileSchema, error) {
 	if obj == nil {
 		return files.CompleteFileSchema{}, nil
 	}
 	logger := slog.Default()
+	recordStage := func(stage files.DocProcStage) {
+		if record == nil {
+			return
+		}
+		// Losing a log entry should not throw away the work done, the next stage records again
+		if err := record(ctx, stage); err != nil {
+			logger.Warn("could not record processing stage", "error", err, "stage", stage.DocProcStatus)
+		}
+	}
 
 	// if obj.Lang == "" {
 	// 	return *obj, errors.New("language is required")
 	// }
 
 	currentStage := obj.Stage.DocProcStatus
+	if currentStage == "" {
+		currentStage = files.DocStatusUnprocessed
+	}
 
 	texts := make(map[string]string)
 
@@ -57,6 +106,7 @@ func ProcessFileRaw(ctx context.Context, obj *files.CompleteFileSchema, stopAt f
 				IsCompleted:   true,
 				DocProcStatus: currentStage,
 			}
+			recordStage(obj.Stage)
 			return *obj, nil
 		}
 
@@ -71,7 +121,7 @@ func ProcessFileRaw(ctx context.Context, obj *files.CompleteFileSchema, stopAt f
 		case files.DocStatusRawTextCompleted:
 			nextStage, err = processTranslateRawText(ctx, obj, texts)
 		case files.DocStatusTextCompleted:
-			nextStage, err = createLLMExtras(ctx, obj)
+			nextStage, err = createLLMExtras(ctx, obj, llm)
 		case files.DocStatusSummarizationCompleted:
 			nextStage, err = processEmbeddings(ctx, obj)
 		case files.DocStatusEmbeddingsCompleted:
@@ -84,15 +134,25 @@ func ProcessFileRaw(ctx context.Context, obj *files.CompleteFileSchema, stopAt f
 			logger.Error("processing error", "error", err, "stage", currentStage)
 			obj.Stage = files.DocProcStage{
 				PGStage:            files.PGStageErrored,
+				SkipProcessing:     obj.Stage.SkipProcessing,
 				IsErrored:          true,
 				IsCompleted:        true,
 				ProcessingErrorMsg: fmt.Sprintf("Encountered Processing Error: %v", err),
 				IngestErrorMsg:     obj.Stage.IngestErrorMsg,
 				DocProcStatus:      currentStage,
 			}
-			return *obj, fmt.Errorf("processing error at stage %d: %w", currentStage, err)
+			recordStage(obj.Stage)
+			return *obj, fmt.Errorf("processing error at stage %s: %w", currentStage, err)
 		}
 		currentStage = nextStage
+		if currentStage.Index() < stopAt.Index() {
+			obj.Stage = files.DocProcStage{
+				PGStage:        files.PGStageProcessing,
+				DocProcStatus:  currentStage,
+				IngestErrorMsg: obj.Stage.IngestErrorMsg,
+			}
+			recordStage(obj.Stage)
+		}
 	}
 
 	return files.CompleteFileSchema{}, errors.New("exceeded maximum processing iterations")
@@ -104,30 +164,50 @@ func processStageHandleExtension(ctx context.Context, obj *files.CompleteFileSch
 		if err != nil {
 			return files.DocStatusUnprocessed, fmt.Errorf("invalid file extension: %w", err)
 		}
-		s3_obj := s3utils.NewKeFileManager()
+		fileManager := s3utils.NewKeFileManager()
 
-		err = validators.ValidateExtensionFromHash(*s3_obj, attachment.Hash, validExtension)
+		err = validators.ValidateExtensionFromHash(ctx, fileManager, attachment.Hash, validExtension)
 		if err != nil {
 			obj.Stage.SkipProcessing = true
 			return files.DocStatusUnprocessed, fmt.Errorf("file validation failed: %v", err)
 		}
 
 		obj.Attachments[index].Extension = string(validExtension)
-		if validExtension == files.KnownFileExtensionXLSX {
-			return files.DocStatusUnprocessed, nil
-		}
-
 	}
 	return files.DocStatusBeginProcessing, nil
 }
 
+// processGenerateRawText extracts the original text of every attachment the scraper sent without one. The
+// texts are stored with the attachments when the file is upserted, through crud.UpsertFileAttachmentTexts.
 func processGenerateRawText(ctx context.Context, obj *files.CompleteFileSchema, texts map[string]string) (files.DocProcStatus, error) {
-	for _, attachment := range obj.Attachments {
-		doesnt_have_text := len(attachment.Texts) == 0
-		if doesnt_have_text {
-			return files.DocStatusBeginProcessing, fmt.Errorf("PDF transcription via crimson not implemented yet")
+	extractors := extract.DefaultRegistry()
+	fileManager := s3utils.NewKeFileManager()
+	for index, attachment := range obj.Attachments {
+		if len(attachment.Texts) != 0 {
+			continue
 		}
-
+		extension, err := files.FileExtensionFromString(attachment.Extension)
+		if err != nil {
+			return files.DocStatusBeginProcessing, err
+		}
+		text, err := extractors.Extract(ctx, extension, extract.Document{Hash: attachment.Hash, Files: fileManager})
+		if errors.Is(err, extract.ErrNoText) {
+			// Scans without OCR text are kept without a text, the extras stage then skips them
+			logger.Info(ctx, "attachment has no text", zap.String("hash", attachment.Hash.String()), zap.String("extension", string(extension)))
+			continue
+		}
+		if err != nil {
+			return files.DocStatusBeginProcessing, err
+		}
+		language := attachment.Lang
+		if language == "" {
+			language = "en"
+		}
+		obj.Attachments[index].Texts = []files.AttachmentChildTextSource{
+			{IsOriginalText: true, Text: text, Language: language},
+		}
+		texts[attachment.Hash.String()] = text
+		logger.Info(ctx, "extracted attachment text", zap.String("hash", attachment.Hash.String()), zap.String("extension", string(extension)), zap.Int("length", len(text)))
 	}
 	// Only english text is supported for now so we jump straight past translation
 	return files.DocStatusTextCompleted, nil
@@ -137,10 +217,25 @@ func processTranslateRawText(ctx context.Context, obj *files.CompleteFileSchema,
 	return files.DocStatusRawTextCompleted, errors.New("not implemented")
 }
 
-func createLLMExtras(ctx context.Context, obj *files.CompleteFileSchema) (files.DocProcStatus, error) {
-	return files.DocStatusTextCompleted, errors.New("not implemented")
+// createLLMExtras summarizes the attachment texts into the file's extras, which are stored with the file
+// through crud.UpsertFileExtras. A file without text, such as a scanned PDF, skips the stage with empty extras.
+func createLLMExtras(ctx context.Context, obj *files.CompleteFileSchema, llm llm_utils.LLM) (files.DocProcStatus, error) {
+	text := summarize.FileText(*obj)
+	if text == "" {
+		logger.Info(ctx, "file has no text to summarize, skipping extras", zap.String("name", obj.Name))
+		obj.Extra = files.FileGeneratedExtras{}
+		return files.DocStatusSummarizationCompleted, nil
+	}
+	extras, err := summarize.NewSummarizer(llm).Extras(ctx, obj.Name, text)
+	if err != nil {
+		return files.DocStatusTextCompleted, err
+	}
+	obj.Extra = extras
+	return files.DocStatusSummarizationCompleted, nil
 }
 
+// processEmbeddings is skipped until embeddings are generated, failing it would mark every finished file errored
 func processEmbeddings(ctx context.Context, obj *files.CompleteFileSchema) (files.DocProcStatus, error) {
-	return files.DocStatusSummarizationCompleted, errors.New("not implemented")
+	logger.Info(ctx, "embeddings are not generated yet, skipping the stage", zap.String("name", obj.Name))
+	return files.DocStatusEmbeddingsCompleted, nil
 }
@@ -0,0 +1,145 @@
+package logic
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"kessler/internal/llm_utils"
+	"kessler/internal/objects/files"
+	"kessler/pkg/constants"
+
+	"github.com/google/uuid"
+)
+
+func TestProcessFileRawRecordsStages(t *testing.T) {
+	var recorded []files.DocProcStage
+	record := func(ctx context.Context, stage files.DocProcStage) error {
+		recorded = append(recorded, stage)
+		return nil
+	}
+
+	file := files.CompleteFileSchema{Stage: files.DocProcStage{DocProcStatus: files.DocStatusEmbeddingsCompleted}}
+	if _, err := ProcessFileRaw(context.Background(), &file, files.DocStatusCompleted, nil, record); err != nil {
+		t.Fatal(err)
+	}
+	if len(recorded) != 1 || recorded[0].PGStage != files.PGStageCompleted || recorded[0].DocProcStatus != files.DocStatusCompleted {
+		t.Errorf("recorded %+v, want a single completed stage", recorded)
+	}
+
+	// Embeddings are skipped, a summarized file finishes as completed
+	recorded = nil
+	file = files.CompleteFileSchema{Stage: files.DocProcStage{DocProcStatus: files.DocStatusSummarizationCompleted}}
+	if _, err := ProcessFileRaw(context.Background(), &file, files.DocStatusCompleted, nil, record); err != nil {
+		t.Fatal(err)
+	}
+	if last := recorded[len(recorded)-1]; last.IsErrored || last.PGStage != files.PGStageCompleted || last.DocProcStatus != files.DocStatusCompleted {
+		t.Errorf("recorded %+v, want the file completed", recorded)
+	}
+
+	// A failing stage is recorded as errored at the stage it failed in, so it can be resumed from there
+	recorded = nil
+	file = files.CompleteFileSchema{Stage: files.DocProcStage{DocProcStatus: files.DocStatusRawTextCompleted}}
+	if _, err := ProcessFileRaw(context.Background(), &file, files.DocStatusCompleted, nil, record); err == nil {
+		t.Fatal("expected the unimplemented translation stage to fail")
+	}
+	if len(recorded) != 1 || !recorded[0].IsErrored || recorded[0].PGStage != files.PGStageErrored || recorded[0].DocProcStatus != files.DocStatusRawTextCompleted {
+		t.Errorf("recorded %+v, want an errored raw_text_completed stage", recorded)
+	}
+	if file.Stage != recorded[0] {
+		t.Errorf("file stage %+v differs from the recorded one", file.Stage)
+	}
+}
+
+func TestProcessFileRawIgnoresRecorderFailures(t *testing.T) {
+	failing := func(ctx context.Context, stage files.DocProcStage) error {
+		return errors.New("api unavailable")
+	}
+	file := files.CompleteFileSchema{Stage: files.DocProcStage{DocProcStatus: files.DocStatusEmbeddingsCompleted}}
+	if _, err := ProcessFileRaw(context.Background(), &file, files.DocStatusCompleted, nil, failing); err != nil {
+		t.Fatalf("a lost stage log entry should not fail processing: %v", err)
+	}
+	if !file.Stage.IsCompleted {
+		t.Errorf("stage = %+v", file.Stage)
+	}
+}
+
+func TestProcessFileRecordsStagesAgainstInsertedFile(t *testing.T) {
+	fileID := uuid.New()
+	var calls []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, r.URL.Path)
+		w.Header().Set("Content-Type", "application/json")
+		if strings.HasPrefix(r.URL.Path, "/v2/public/files/") {
+			fmt.Fprintf(w, `{"id": %q}`, fileID)
+		}
+	}))
+	defer server.Close()
+	previous := constants.INTERNAL_KESSLER_API_URL
+	constants.INTERNAL_KESSLER_API_URL = server.URL
+	defer func() { constants.INTERNAL_KESSLER_API_URL = previous }()
+
+	file := files.CompleteFileSchema{Name: "Order", Stage: files.DocProcStage{DocProcStatus: files.DocStatusEmbeddingsCompleted}}
+	if err := ProcessFile(context.Background(), file, nil); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"/v2/public/files/insert",
+		"/v2/admin/file-stages/" + fileID.String(),
+		"/v2/public/files/" + fileID.String() + "/update",
+	}
+	if !slices.Equal(calls, want) {
+		t.Errorf("calls %v, want %v", calls, want)
+	}
+}
+
+// cannedLLM answers every prompt with the same reply
+type cannedLLM struct {
+	reply string
+	calls int
+}
+
+func (c *cannedLLM) Chat(ctx context.Context, history []llm_utils.ChatMessage) (llm_utils.ChatMessage, error) {
+	c.calls++
+	return llm_utils.ChatMessage{Role: "assistant", Content: c.reply}, nil
+}
+
+func TestCreateLLMExtras(t *testing.T) {
+	llm := &cannedLLM{reply: "No"}
+	file := files.CompleteFileSchema{
+		Name: "Rate Case Order",
+		Attachments: []files.CompleteAttachmentSchema{
+			{Texts: []files.AttachmentChildTextSource{{IsOriginalText: true, Text: "The commission approves the rate increase.", Language: "en"}}},
+		},
+		Stage: files.DocProcStage{DocProcStatus: files.DocStatusTextCompleted},
+	}
+	if _, err := ProcessFileRaw(context.Background(), &file, files.DocStatusSummarizationCompleted, llm, nil); err != nil {
+		t.Fatal(err)
+	}
+	if llm.calls == 0 || file.Extra.ShortSummary == "" || file.Extra.Summary == "" {
+		t.Errorf("extras %+v after %d calls to the injected LLM", file.Extra, llm.calls)
+	}
+	if file.Stage.IsErrored {
+		t.Errorf("stage = %+v", file.Stage)
+	}
+}
+
+func TestCreateLLMExtrasSkipsFilesWithoutText(t *testing.T) {
+	llm := &cannedLLM{reply: "No"}
+	file := files.CompleteFileSchema{
+		Name:        "Scanned Exhibit",
+		Attachments: []files.CompleteAttachmentSchema{{Name: "exhibit.pdf"}},
+	}
+	next, err := createLLMExtras(context.Background(), &file, llm)
+	if err != nil || next != files.DocStatusSummarizationCompleted {
+		t.Fatalf("createLLMExtras = %s, %v, want the stage skipped", next, err)
+	}
+	if llm.calls != 0 || file.Extra != (files.FileGeneratedExtras{}) {
+		t.Errorf("extras %+v after %d calls, want empty extras and no calls", file.Extra, llm.calls)
+	}
+}
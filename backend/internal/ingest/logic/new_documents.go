@@ -210,7 +210,7 @@ func fetchAttachmentFromUrl(ctx context.Context, attachment files.CompleteAttach
 	}
 
 	fileManager := s3utils.NewKeFileManager()
-	hashResult, err := fileManager.UploadFileToS3(tmpFilePath)
+	hashResult, err := fileManager.UploadFile(ctx, tmpFilePath)
 	if err != nil {
 		return files.CompleteAttachmentSchema{}, err
 	}
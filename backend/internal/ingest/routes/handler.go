@@ -4,6 +4,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"kessler/internal/ingest/tasks"
+	"kessler/internal/llm_utils"
+	"kessler/internal/objects/files"
 	"kessler/pkg/logger"
 	"net/http"
 	"os"
@@ -14,16 +16,19 @@ import (
 
 var log = logger.Named("ingest handler")
 
-func DefineGlobalRouter(router *mux.Router) {
+// DefineGlobalRouter registers the ingest routes, llm writes the extras of files ingested from cases
+func DefineGlobalRouter(router *mux.Router, llm llm_utils.LLM) {
 	// Version endpoint
 	router.HandleFunc("/version_hash", HandleVersionHash).Methods("GET")
 
 	// Task endpoints
 	router.HandleFunc("/add-task/ingest", HandleDefaultIngestAddTask).Methods("POST")
 	router.HandleFunc("/add-task/ingest/nypuc", HandleNYPUCIngestAddTask).Methods("POST")
-	router.HandleFunc("/add-task/ingest/openscrapers-case", HandleCaseIngestAddTask).Methods("POST")
+	router.HandleFunc("/add-task/ingest/openscrapers-case", HandleCaseIngestAddTask(llm)).Methods("POST")
 
-	router.HandleFunc("/add-task/ingest/openscrapers-caselist", HandleCaseListIngestAddTask).Methods("POST")
+	router.HandleFunc("/add-task/ingest/openscrapers-caselist", HandleCaseListIngestAddTask(llm)).Methods("POST")
+
+	router.HandleFunc("/add-task/requeue-stage", HandleRequeueStageAddTask).Methods("POST")
 
 	// Task status endpoint
 	router.HandleFunc("/task/{id}", HandleGetTaskInfo).Methods("GET")
@@ -78,25 +83,27 @@ func HandleNYPUCIngestAddTask(w http.ResponseWriter, r *http.Request) {
 // @Failure	400	{string}	string	"Error decoding request body"
 // @Failure	500	{string}	string	"Error adding task"
 // @Router	/add-task/ingest/openscrapsers-case [post]
-func HandleCaseIngestAddTask(w http.ResponseWriter, r *http.Request) {
-	var caseInfo tasks.OpenscrapersCaseInfoPayload
-	if err := json.NewDecoder(r.Body).Decode(&caseInfo); err != nil {
-		log.Info("User Gave Bad Request for CaseIngest", zap.Error(err))
-		errorString := fmt.Sprintf("Error decoding request body: %v", err)
-		http.Error(w, errorString, http.StatusBadRequest)
-		return
-	}
+func HandleCaseIngestAddTask(llm llm_utils.LLM) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var caseInfo tasks.OpenscrapersCaseInfoPayload
+		if err := json.NewDecoder(r.Body).Decode(&caseInfo); err != nil {
+			log.Info("User Gave Bad Request for CaseIngest", zap.Error(err))
+			errorString := fmt.Sprintf("Error decoding request body: %v", err)
+			http.Error(w, errorString, http.StatusBadRequest)
+			return
+		}
 
-	ctx := r.Context()
-	kesslerInfo, err := tasks.AddCaseTaskCastable(ctx, caseInfo)
-	if err != nil {
-		log.Error("Encountered Error Adding Case Task", zap.Error(err))
-		http.Error(w, fmt.Sprintf("Error adding task: %v", err), http.StatusInternalServerError)
-		return
-	}
+		ctx := r.Context()
+		kesslerInfo, err := tasks.AddCaseTaskCastable(ctx, caseInfo, llm)
+		if err != nil {
+			log.Error("Encountered Error Adding Case Task", zap.Error(err))
+			http.Error(w, fmt.Sprintf("Error adding task: %v", err), http.StatusInternalServerError)
+			return
+		}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(kesslerInfo)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(kesslerInfo)
+	}
 }
 
 // @Summary	Add Openscrapers CaseList Ingest Task
@@ -109,33 +116,66 @@ func HandleCaseIngestAddTask(w http.ResponseWriter, r *http.Request) {
 // @Failure	400	{string}	string	"Error decoding request body"
 // @Failure	500	{string}	string	"Error adding task"
 // @Router	/add-task/ingest/openscrapers-caselist [post]
-func HandleCaseListIngestAddTask(w http.ResponseWriter, r *http.Request) {
-	var caseListInfo []tasks.OpenscrapersCaseListEntry
-	if err := json.NewDecoder(r.Body).Decode(&caseListInfo); err != nil {
-		log.Info("User Gave Bad Request for CaseList", zap.Error(err))
-		errorString := fmt.Sprintf("Error decoding request body: %v", err)
-		http.Error(w, errorString, http.StatusBadRequest)
+func HandleCaseListIngestAddTask(llm llm_utils.LLM) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var caseListInfo []tasks.OpenscrapersCaseListEntry
+		if err := json.NewDecoder(r.Body).Decode(&caseListInfo); err != nil {
+			log.Info("User Gave Bad Request for CaseList", zap.Error(err))
+			errorString := fmt.Sprintf("Error decoding request body: %v", err)
+			http.Error(w, errorString, http.StatusBadRequest)
+			return
+		}
+
+		ctx := r.Context()
+		for _, caseListEntry := range caseListInfo {
+			caseInfo, err := caseListEntry.FetchInfoCaseInfo()
+			if err != nil {
+				log.Error("Error fetching info from openscrapers", zap.Error(err), zap.String("case_id", caseListEntry.CaseID))
+			}
+			_, err = tasks.AddCaseTaskCastable(ctx, caseInfo, llm)
+			if err != nil {
+				log.Error("Encountered Error Adding Case Task", zap.Error(err), zap.String("case_id", caseListEntry.CaseID))
+				// http.Error(w, fmt.Sprintf("Error adding task: %v", err), http.StatusInternalServerError)
+				// return
+			}
+			log.Info("Successfuly finished ingest task for case", zap.String("case_id", caseListEntry.CaseID))
+
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode("It was successful !!!!")
+	}
+}
+
+// @Summary	Requeue Files At A Stage
+// @Description	Queues processing again for every file whose latest stage matches, such as those errored at text_completed or stuck processing. Each file resumes from its latest stage.
+// @Tags		tasks
+// @Produce	json
+// @Param	stage		query	string	true	"Processing stage, such as begin_processing or text_completed"
+// @Param	state		query	string	false	"pending, processing, completed or errored, any when empty"
+// @Param	stuck_for	query	string	false	"Only files whose latest stage is at least this old, such as 2h"
+// @Param	limit		query	int		false	"Maximum files requeued, default 100, at most 1000"
+// @Success	200	{object}	tasks.RequeueResult
+// @Failure	400	{string}	string	"Invalid filter"
+// @Failure	500	{string}	string	"Error requeueing files"
+// @Router	/add-task/requeue-stage [post]
+func HandleRequeueStageAddTask(w http.ResponseWriter, r *http.Request) {
+	filter, err := files.StageFilterFromQuery(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
 	ctx := r.Context()
-	for _, caseListEntry := range caseListInfo {
-		caseInfo, err := caseListEntry.FetchInfoCaseInfo()
-		if err != nil {
-			log.Error("Error fetching info from openscrapers", zap.Error(err), zap.String("case_id", caseListEntry.CaseID))
-		}
-		_, err = tasks.AddCaseTaskCastable(ctx, caseInfo)
-		if err != nil {
-			log.Error("Encountered Error Adding Case Task", zap.Error(err), zap.String("case_id", caseListEntry.CaseID))
-			// http.Error(w, fmt.Sprintf("Error adding task: %v", err), http.StatusInternalServerError)
-			// return
-		}
-		log.Info("Successfuly finished ingest task for case", zap.String("case_id", caseListEntry.CaseID))
-
+	result, err := tasks.RequeueFilesAtStage(ctx, filter)
+	if err != nil {
+		log.Error("Encountered Error Requeueing Files", zap.Error(err), zap.String("stage", string(filter.Stage)))
+		http.Error(w, fmt.Sprintf("Error requeueing files: %v", err), http.StatusInternalServerError)
+		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode("It was successful !!!!")
+	json.NewEncoder(w).Encode(result)
 }
 
 // @Summary	Get Task Information
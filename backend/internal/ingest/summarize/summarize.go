@@ -0,0 +1,246 @@
+// Package summarize generates the LLM extras of a file, its summaries, purpose and impressiveness, from
+// the text of its attachments. Long text is split into chunks that are summarized separately and then
+// summarized together, so documents of any length fit in the model's context.
+package summarize
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"kessler/internal/llm_utils"
+	"kessler/internal/objects/files"
+)
+
+const (
+	// DefaultChunkSize is roughly 3000 tokens of English, leaving the model room to answer
+	DefaultChunkSize   = 12000
+	defaultConcurrency = 4
+	// maxReduceRounds stops a model whose summaries are not getting shorter from looping forever
+	maxReduceRounds = 4
+	// maxShortSummary keeps the short summary to the length a search card shows
+	maxShortSummary = 300
+)
+
+// ErrNoText is returned when a file has no attachment text to summarize
+var ErrNoText = errors.New("no text to summarize")
+
+const (
+	shortSummaryPrompt = "Write a single sentence of at most 30 words describing the document below for a search result. Reply with the sentence only."
+	purposePrompt      = "In one short phrase, state the purpose of the document below, such as \"requests approval of a rate increase\" or \"responds to staff interrogatories\". Reply with the phrase only."
+)
+
+// impressivenessRubric is asked of every summary, impressiveness is the share of questions answered yes
+var impressivenessRubric = []string{
+	"Does the document present original analysis, evidence or data rather than only procedural information?",
+	"Does the document propose, argue for or decide a substantive outcome, such as a rate change, a ruling or a policy?",
+	"Is the document more than a cover letter, notice, certificate of service or other routine filing?",
+	"Would the document matter to someone following the proceeding who did not write it?",
+}
+
+// Summarizer fills FileGeneratedExtras from document text with an LLM
+type Summarizer struct {
+	LLM llm_utils.LLM
+	// ChunkSize is the most characters of document text sent to the LLM in one request
+	ChunkSize int
+	// Concurrency bounds how many chunks are summarized at once
+	Concurrency int
+}
+
+func NewSummarizer(llm llm_utils.LLM) *Summarizer {
+	return &Summarizer{LLM: llm, ChunkSize: DefaultChunkSize, Concurrency: defaultConcurrency}
+}
+
+// Extras summarizes the text of the document named name and fills every generated field
+func (s *Summarizer) Extras(ctx context.Context, name string, text string) (files.FileGeneratedExtras, error) {
+	summary, err := s.Summarize(ctx, text)
+	if err != nil {
+		return files.FileGeneratedExtras{}, fmt.Errorf("summarizing: %w", err)
+	}
+	document := fmt.Sprintf("Title: %s\n\nSummary:\n%s", name, summary)
+
+	shortSummary, err := llm_utils.SimpleInstruct(ctx, s.LLM, shortSummaryPrompt+"\n\n"+document)
+	if err != nil {
+		return files.FileGeneratedExtras{}, fmt.Errorf("writing short summary: %w", err)
+	}
+	purpose, err := llm_utils.SimpleInstruct(ctx, s.LLM, purposePrompt+"\n\n"+document)
+	if err != nil {
+		return files.FileGeneratedExtras{}, fmt.Errorf("finding purpose: %w", err)
+	}
+	impressiveness, err := s.impressiveness(ctx, document)
+	if err != nil {
+		return files.FileGeneratedExtras{}, fmt.Errorf("rating impressiveness: %w", err)
+	}
+
+	return files.FileGeneratedExtras{
+		Summary:        summary,
+		ShortSummary:   truncateRunes(cleanAnswer(shortSummary), maxShortSummary),
+		Purpose:        cleanAnswer(purpose),
+		Impressiveness: impressiveness,
+	}, nil
+}
+
+// Summarize map-reduces the text: every chunk is summarized, and the joined summaries are chunked and
+// summarized again until a single chunk remains
+func (s *Summarizer) Summarize(ctx context.Context, text string) (string, error) {
+	chunks := ChunkText(text, s.ChunkSize)
+	if len(chunks) == 0 {
+		return "", ErrNoText
+	}
+	for round := 0; len(chunks) > 1; round++ {
+		summaries, err := s.summarizeChunks(ctx, chunks)
+		if err != nil {
+			return "", err
+		}
+		combined := strings.Join(summaries, "\n\n")
+		if round == maxReduceRounds {
+			// The last round sends as much of the combined summaries as fits
+			chunks = []string{combined}
+			break
+		}
+		chunks = ChunkText(combined, s.ChunkSize)
+	}
+	summary, err := llm_utils.SimpleSummaryTruncate(ctx, s.LLM, chunks[0], s.ChunkSize)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(summary), nil
+}
+
+func (s *Summarizer) summarizeChunks(ctx context.Context, chunks []string) ([]string, error) {
+	summaries := make([]string, len(chunks))
+	errs := make([]error, len(chunks))
+	slots := make(chan struct{}, max(s.Concurrency, 1))
+	var wg sync.WaitGroup
+	for i, chunk := range chunks {
+		wg.Add(1)
+		go func(i int, chunk string) {
+			defer wg.Done()
+			slots <- struct{}{}
+			defer func() { <-slots }()
+			if err := ctx.Err(); err != nil {
+				errs[i] = err
+				return
+			}
+			summary, err := llm_utils.SimpleSummaryTruncate(ctx, s.LLM, chunk, s.ChunkSize)
+			summaries[i] = strings.TrimSpace(summary)
+			if err != nil {
+				errs[i] = fmt.Errorf("chunk %d of %d: %w", i+1, len(chunks), err)
+			}
+		}(i, chunk)
+	}
+	wg.Wait()
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return summaries, nil
+}
+
+func (s *Summarizer) impressiveness(ctx context.Context, document string) (float64, error) {
+	yes := 0
+	for _, question := range impressivenessRubric {
+		answer, err := llm_utils.BooleanTwoStep(ctx, s.LLM, document, question)
+		if err != nil {
+			return 0, err
+		}
+		if answer {
+			yes++
+		}
+	}
+	return float64(yes) / float64(len(impressivenessRubric)), nil
+}
+
+// ChunkText splits text into chunks of at most size bytes, breaking between paragraphs where it can, then
+// between lines or words, and only cutting a word when it is longer than a chunk
+func ChunkText(text string, size int) []string {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return nil
+	}
+	if size <= 0 {
+		size = DefaultChunkSize
+	}
+	var chunks []string
+	for len(text) > size {
+		cut := lastBreak(text[:size+1])
+		if cut <= 0 {
+			// No break at all, cut on a rune boundary
+			cut = size
+			for cut > 1 && !utf8.RuneStart(text[cut]) {
+				cut--
+			}
+		}
+		if chunk := strings.TrimSpace(text[:cut]); chunk != "" {
+			chunks = append(chunks, chunk)
+		}
+		text = strings.TrimSpace(text[cut:])
+	}
+	if text != "" {
+		chunks = append(chunks, text)
+	}
+	return chunks
+}
+
+// lastBreak finds the best place to end a chunk within window, 0 when there is none. A paragraph break
+// early in the window would leave a tiny chunk, so breaks in the second half are preferred.
+func lastBreak(window string) int {
+	separators := []string{"\n\n", "\n", ". ", " "}
+	for _, separator := range separators {
+		if index := strings.LastIndex(window, separator); index >= len(window)/2 {
+			return index + len(separator)
+		}
+	}
+	for _, separator := range separators {
+		if index := strings.LastIndex(window, separator); index > 0 {
+			return index + len(separator)
+		}
+	}
+	return 0
+}
+
+// FileText joins the original text of every attachment, headed by the attachment name when there are several
+func FileText(file files.CompleteFileSchema) string {
+	var parts []string
+	for _, attachment := range file.Attachments {
+		text := attachmentText(attachment)
+		if text == "" {
+			continue
+		}
+		if len(file.Attachments) > 1 && attachment.Name != "" {
+			text = "# " + attachment.Name + "\n\n" + text
+		}
+		parts = append(parts, text)
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+// attachmentText prefers the original text over translations
+func attachmentText(attachment files.CompleteAttachmentSchema) string {
+	for _, text := range attachment.Texts {
+		if text.IsOriginalText && strings.TrimSpace(text.Text) != "" {
+			return strings.TrimSpace(text.Text)
+		}
+	}
+	for _, text := range attachment.Texts {
+		if strings.TrimSpace(text.Text) != "" {
+			return strings.TrimSpace(text.Text)
+		}
+	}
+	return ""
+}
+
+// cleanAnswer strips the quotes and whitespace models tend to wrap short answers in
+func cleanAnswer(answer string) string {
+	return strings.Trim(strings.TrimSpace(answer), "\"'` ")
+}
+
+func truncateRunes(text string, maxRunes int) string {
+	if utf8.RuneCountInString(text) <= maxRunes {
+		return text
+	}
+	runes := []rune(text)
+	return strings.TrimSpace(string(runes[:maxRunes-1])) + "…"
+}
@@ -0,0 +1,157 @@
+package summarize
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"unicode/utf8"
+
+	"kessler/internal/llm_utils"
+	"kessler/internal/objects/files"
+)
+
+// fakeLLM answers every prompt the summarizer sends with a fixed reply so the tests run offline
+type fakeLLM struct {
+	summaries atomic.Int32
+	questions atomic.Int32
+	// yes lists the words of rubric questions answered yes
+	yes  []string
+	fail bool
+}
+
+func (f *fakeLLM) Chat(ctx context.Context, history []llm_utils.ChatMessage) (llm_utils.ChatMessage, error) {
+	if f.fail {
+		return llm_utils.ChatMessage{}, errors.New("model unavailable")
+	}
+	last := history[len(history)-1].Content
+	reply := func(content string) (llm_utils.ChatMessage, error) {
+		return llm_utils.ChatMessage{Role: "assistant", Content: content}, nil
+	}
+	switch {
+	case strings.HasPrefix(last, "Now please summarize"):
+		f.summaries.Add(1)
+		return reply(fmt.Sprintf("summary of %d characters", len(history[1].Content)))
+	case strings.HasPrefix(last, "First, think through"):
+		return reply("Let me think about it.")
+	case strings.HasPrefix(last, "Answer only"):
+		f.questions.Add(1)
+		question := history[2].Content
+		for _, word := range f.yes {
+			if strings.Contains(question, word) {
+				return reply("Yes.")
+			}
+		}
+		return reply("no")
+	case strings.HasPrefix(last, shortSummaryPrompt):
+		return reply(" \"An order granting the rate increase.\"\n")
+	case strings.HasPrefix(last, purposePrompt):
+		return reply("grants a rate increase")
+	}
+	return llm_utils.ChatMessage{}, fmt.Errorf("unexpected prompt %q", last)
+}
+
+func TestChunkText(t *testing.T) {
+	text := strings.Repeat("word ", 10) + "\n\n" + strings.Repeat("more ", 10)
+	chunks := ChunkText(text, 60)
+	if len(chunks) != 2 || chunks[0] != strings.TrimSpace(strings.Repeat("word ", 10)) {
+		t.Fatalf("expected a break between paragraphs, got %q", chunks)
+	}
+
+	if got := ChunkText("  \n ", 10); got != nil {
+		t.Errorf("blank text has no chunks, got %q", got)
+	}
+
+	long := strings.Repeat("é", 25)
+	chunks = ChunkText(long, 9)
+	if strings.Join(chunks, "") != long {
+		t.Errorf("a word longer than a chunk must be cut without losing text, got %q", chunks)
+	}
+	for _, chunk := range chunks {
+		if len(chunk) > 9 || !utf8.ValidString(chunk) {
+			t.Errorf("chunk %q is too long or cuts a rune", chunk)
+		}
+	}
+}
+
+func TestSummarizeMapReduce(t *testing.T) {
+	llm := &fakeLLM{}
+	s := NewSummarizer(llm)
+	s.ChunkSize = 100
+
+	summary, err := s.Summarize(context.Background(), "A short filing.")
+	if err != nil || summary != "summary of 15 characters" || llm.summaries.Load() != 1 {
+		t.Errorf("short text is summarized once, got %q, %v after %d calls", summary, err, llm.summaries.Load())
+	}
+
+	llm.summaries.Store(0)
+	text := strings.Repeat(strings.Repeat("x", 79)+"\n\n", 10)
+	summary, err = s.Summarize(context.Background(), text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Ten chunks are summarized, their joined summaries take four chunks, then two, then one final summary
+	if calls := llm.summaries.Load(); calls != 17 {
+		t.Errorf("expected 17 summaries, got %d", calls)
+	}
+	if !strings.HasPrefix(summary, "summary of") {
+		t.Errorf("unexpected summary %q", summary)
+	}
+
+	if _, err := s.Summarize(context.Background(), " "); !errors.Is(err, ErrNoText) {
+		t.Errorf("expected ErrNoText, got %v", err)
+	}
+	if _, err := NewSummarizer(&fakeLLM{fail: true}).Summarize(context.Background(), "text"); err == nil {
+		t.Error("expected the model error")
+	}
+}
+
+func TestExtras(t *testing.T) {
+	llm := &fakeLLM{yes: []string{"original analysis", "substantive outcome"}}
+	extras, err := NewSummarizer(llm).Extras(context.Background(), "Order 24-01", "The Commission grants the increase.")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := files.FileGeneratedExtras{
+		Summary:        "summary of 35 characters",
+		ShortSummary:   "An order granting the rate increase.",
+		Purpose:        "grants a rate increase",
+		Impressiveness: 0.5,
+	}
+	if extras != want {
+		t.Errorf("Extras = %+v, want %+v", extras, want)
+	}
+	if llm.questions.Load() != int32(len(impressivenessRubric)) {
+		t.Errorf("expected every rubric question asked, got %d", llm.questions.Load())
+	}
+}
+
+func TestFileText(t *testing.T) {
+	file := files.CompleteFileSchema{Attachments: []files.CompleteAttachmentSchema{
+		{Name: "order.pdf", Texts: []files.AttachmentChildTextSource{
+			{Text: "translated", IsOriginalText: false},
+			{Text: " original ", IsOriginalText: true},
+		}},
+		{Name: "blank.pdf"},
+		{Name: "exhibit.xlsx", Texts: []files.AttachmentChildTextSource{{Text: "rates"}}},
+	}}
+	if got, want := FileText(file), "# order.pdf\n\noriginal\n\n# exhibit.xlsx\n\nrates"; got != want {
+		t.Errorf("FileText = %q, want %q", got, want)
+	}
+
+	file.Attachments = file.Attachments[:1]
+	if got := FileText(file); got != "original" {
+		t.Errorf("a single attachment has no heading, got %q", got)
+	}
+}
+
+func TestTruncateRunes(t *testing.T) {
+	if got := truncateRunes("ééééé", 3); got != "éé…" {
+		t.Errorf("truncateRunes = %q", got)
+	}
+	if got := truncateRunes("short", 10); got != "short" {
+		t.Errorf("truncateRunes = %q", got)
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"io"
 	"kessler/internal/ingest/logic"
+	"kessler/internal/llm_utils"
 	"kessler/internal/objects/conversations"
 	"kessler/internal/objects/files/validation"
 	"kessler/pkg/constants"
@@ -26,7 +27,7 @@ var (
 
 // IngestOpenscrapersCase processes a case and its associated filings.
 // TODO: Implement persistence logic for cases and filings.
-func IngestOpenscrapersCase(ctx context.Context, caseInfo OpenscrapersCaseInfoPayload) error {
+func IngestOpenscrapersCase(ctx context.Context, caseInfo OpenscrapersCaseInfoPayload, llm llm_utils.LLM) error {
 	// Example: Log the received case info. Replace with real DB/API calls.
 	log.Info("Ingesting case: %s\n", zap.String("case number", caseInfo.CaseNumber))
 	log.Info("Case details: %+v\n", zap.Int("filings length", len(caseInfo.Filings)))
@@ -68,7 +69,7 @@ func IngestOpenscrapersCase(ctx context.Context, caseInfo OpenscrapersCaseInfoPa
 			return err
 		}
 		log.Info("Successfully completed conversion into complete file", zap.String("name", complete_filing.Name))
-		err = logic.ProcessFile(ctx, complete_filing)
+		err = logic.ProcessFile(ctx, complete_filing, llm)
 		log.Warn("Made it past the line??")
 		if err != nil {
 			logger.Error(ctx, "Encountered error processing file", zap.Error(err), zap.String("name", complete_filing.Name))
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"kessler/internal/llm_utils"
 	"kessler/internal/objects/conversations"
 	"kessler/pkg/constants"
 	"kessler/pkg/hashes"
@@ -124,12 +125,12 @@ func (c OpenscrapersCaseInfoPayload) IntoCaseInfo() (OpenscrapersCaseInfoPayload
 }
 
 // AddCaseTaskCastable enqueues a case ingestion task.
-func AddCaseTaskCastable(ctx context.Context, castable CastableIntoCaseInfo) (KesslerTaskInfo, error) {
+func AddCaseTaskCastable(ctx context.Context, castable CastableIntoCaseInfo, llm llm_utils.LLM) (KesslerTaskInfo, error) {
 	caseInfo, err := castable.IntoCaseInfo()
 	if err != nil {
 		return KesslerTaskInfo{}, fmt.Errorf("error casting to CaseInfoPayload: %w", err)
 	}
-	err = IngestOpenscrapersCase(ctx, caseInfo)
+	err = IngestOpenscrapersCase(ctx, caseInfo, llm)
 	if err != nil {
 		return KesslerTaskInfo{}, err
 	}
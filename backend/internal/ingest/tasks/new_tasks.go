@@ -44,7 +44,10 @@ func NewProcessFileTask(payload ProcessFilePayload) (*asynq.Task, error) {
 
 type contextKey string
 
-const clientKey = contextKey("asynqClient")
+const (
+	clientKey    = contextKey("asynqClient")
+	inspectorKey = contextKey("asynqInspector")
+)
 
 func WithClient(ctx context.Context, client *asynq.Client) context.Context {
 	return context.WithValue(ctx, clientKey, client)
@@ -53,3 +56,13 @@ func WithClient(ctx context.Context, client *asynq.Client) context.Context {
 func GetClient(ctx context.Context) *asynq.Client {
 	return ctx.Value(clientKey).(*asynq.Client)
 }
+
+func WithInspector(ctx context.Context, inspector *asynq.Inspector) context.Context {
+	return context.WithValue(ctx, inspectorKey, inspector)
+}
+
+// GetInspector returns the queue inspector, nil when none was set
+func GetInspector(ctx context.Context) *asynq.Inspector {
+	inspector, _ := ctx.Value(inspectorKey).(*asynq.Inspector)
+	return inspector
+}
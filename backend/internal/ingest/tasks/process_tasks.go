@@ -5,16 +5,24 @@ import (
 	"encoding/json"
 	"fmt"
 	"kessler/internal/ingest/logic"
+	"kessler/internal/llm_utils"
 
 	"github.com/hibiken/asynq"
 	"go.uber.org/zap"
 )
 
-func AsynqHandler(mux *asynq.ServeMux) {
+// AsynqHandler registers the task handlers, llm writes the extras of the files they process
+func AsynqHandler(mux *asynq.ServeMux, llm llm_utils.LLM) {
 	// existing file ingestion
 	mux.HandleFunc("ingest:file", HandleIngestNewFileTask)
 	// new case ingestion
-	mux.HandleFunc(TypeIngestCase, HandleIngestCaseTask)
+	mux.HandleFunc(TypeIngestCase, func(ctx context.Context, task *asynq.Task) error {
+		return HandleIngestCaseTask(ctx, task, llm)
+	})
+	// resuming stored files from their latest stage
+	mux.HandleFunc(TypeProcessExistingFile, func(ctx context.Context, task *asynq.Task) error {
+		return HandleProcessExistingFileTask(ctx, task, llm)
+	})
 }
 
 func HandleIngestNewFileTask(ctx context.Context, task *asynq.Task) error {
@@ -33,16 +41,29 @@ func HandleIngestNewFileTask(ctx context.Context, task *asynq.Task) error {
 }
 
 // HandleIngestCaseTask processes a case ingestion task
-func HandleIngestCaseTask(ctx context.Context, task *asynq.Task) error {
+func HandleIngestCaseTask(ctx context.Context, task *asynq.Task, llm llm_utils.LLM) error {
 	var caseInfo OpenscrapersCaseInfoPayload
 	if err := json.Unmarshal(task.Payload(), &caseInfo); err != nil {
 		return fmt.Errorf("failed to unmarshal case payload: %w", err)
 	}
 	// invoke business logic to persist case and filings
-	err := IngestOpenscrapersCase(ctx, caseInfo)
+	err := IngestOpenscrapersCase(ctx, caseInfo, llm)
 	if err != nil {
 		return fmt.Errorf("error ingesting case: %w", err)
 	}
 	log.Info("Case ingested successfully", zap.String("case_number", caseInfo.CaseNumber))
 	return nil
 }
+
+// HandleProcessExistingFileTask resumes processing a stored file from its latest recorded stage
+func HandleProcessExistingFileTask(ctx context.Context, task *asynq.Task, llm llm_utils.LLM) error {
+	var payload ProcessFilePayload
+	if err := json.Unmarshal(task.Payload(), &payload); err != nil {
+		return fmt.Errorf("failed to unmarshal process file payload: %w", err)
+	}
+	if err := logic.ResumeFile(ctx, payload.FileID, llm); err != nil {
+		return fmt.Errorf("error processing file %s: %w", payload.FileID, err)
+	}
+	log.Info("File processed", zap.String("file_id", payload.FileID.String()))
+	return nil
+}
@@ -0,0 +1,89 @@
+package tasks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"kessler/internal/ingest/logic"
+	"kessler/internal/objects/files"
+
+	"github.com/google/uuid"
+	"github.com/hibiken/asynq"
+)
+
+// RequeueResult reports which of the matching files were queued for processing again
+type RequeueResult struct {
+	Matched  int               `json:"matched"`
+	Requeued []KesslerTaskInfo `json:"requeued"`
+	// AlreadyQueued lists files with a processing task still waiting or running
+	AlreadyQueued []uuid.UUID `json:"already_queued"`
+}
+
+// processQueue is the queue process tasks are enqueued on
+const processQueue = "default"
+
+// processTaskID lets asynq reject a second task for a file while the first is still queued
+func processTaskID(fileID uuid.UUID) string {
+	return "process-file:" + fileID.String()
+}
+
+// releaseFinishedTask deletes the task holding id when it is archived or completed, asynq keeps the ID
+// reserved for those so a file that errored out could never be queued again. It reports whether the ID is free.
+func releaseFinishedTask(inspector *asynq.Inspector, id string) (bool, error) {
+	if inspector == nil {
+		return false, nil
+	}
+	info, err := inspector.GetTaskInfo(processQueue, id)
+	if errors.Is(err, asynq.ErrTaskNotFound) {
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if info.State != asynq.TaskStateArchived && info.State != asynq.TaskStateCompleted {
+		return false, nil
+	}
+	if err := inspector.DeleteTask(processQueue, id); err != nil && !errors.Is(err, asynq.ErrTaskNotFound) {
+		return false, err
+	}
+	return true, nil
+}
+
+// RequeueFilesAtStage enqueues a processing task for every file whose latest stage matches the filter, each
+// file resumes from that stage
+func RequeueFilesAtStage(ctx context.Context, filter files.StageFilter) (RequeueResult, error) {
+	entries, err := logic.ListFilesAtStage(ctx, filter)
+	if err != nil {
+		return RequeueResult{}, fmt.Errorf("error listing files at stage %s: %w", filter.Stage, err)
+	}
+	client := GetClient(ctx)
+	inspector := GetInspector(ctx)
+	result := RequeueResult{Matched: len(entries), Requeued: []KesslerTaskInfo{}, AlreadyQueued: []uuid.UUID{}}
+	for _, entry := range entries {
+		task, err := NewProcessFileTask(ProcessFilePayload{
+			FileID:         entry.FileID,
+			DocumentStatus: DocumentStatus{SkipProcessing: entry.Stage.SkipProcessing},
+		})
+		if err != nil {
+			return result, fmt.Errorf("error creating process task: %w", err)
+		}
+		taskID := processTaskID(entry.FileID)
+		info, err := client.EnqueueContext(ctx, task, asynq.Queue(processQueue), asynq.TaskID(taskID))
+		if errors.Is(err, asynq.ErrTaskIDConflict) {
+			released, releaseErr := releaseFinishedTask(inspector, taskID)
+			if releaseErr != nil {
+				return result, fmt.Errorf("error inspecting task for file %s: %w", entry.FileID, releaseErr)
+			}
+			if !released {
+				result.AlreadyQueued = append(result.AlreadyQueued, entry.FileID)
+				continue
+			}
+			info, err = client.EnqueueContext(ctx, task, asynq.Queue(processQueue), asynq.TaskID(taskID))
+		}
+		if err != nil {
+			return result, fmt.Errorf("error enqueueing task for file %s: %w", entry.FileID, err)
+		}
+		result.Requeued = append(result.Requeued, GenerateTaskInfoFromInfo(*info))
+	}
+	return result, nil
+}
@@ -1,13 +1,15 @@
 package validators
 
 import (
-	"kessler/pkg/hashes"
+	"context"
 	"kessler/internal/objects/files"
+	"kessler/pkg/hashes"
 	"kessler/pkg/s3utils"
 )
 
-func ValidateExtensionFromHash(fileManager s3utils.KesslerFileManager, hash hashes.KesslerHash, extension files.KnownFileExtension) error {
-	filepath, err := fileManager.DownloadFileFromS3(hash)
+// ValidateExtensionFromHash checks the stored attachment with this hash is a valid file of the extension
+func ValidateExtensionFromHash(ctx context.Context, fileManager *s3utils.KesslerFileManager, hash hashes.KesslerHash, extension files.KnownFileExtension) error {
+	filepath, err := fileManager.LocalPath(ctx, hash)
 	if err != nil {
 		return err
 	}
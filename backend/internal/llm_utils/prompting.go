@@ -3,6 +3,7 @@ package llm_utils
 import (
 	"context"
 	"fmt"
+	"strings"
 	"time"
 )
 
@@ -85,7 +86,9 @@ func BooleanTwoStep(ctx context.Context, llm LLM, content, instruction string) (
 		return false, err
 	}
 
-	switch finalRes.Content {
+	// Models often answer "Yes." or "No," rather than the bare word
+	answer := strings.ToLower(strings.Trim(strings.TrimSpace(finalRes.Content), ".,!'\"` "))
+	switch answer {
 	case "yes":
 		return true, nil
 	case "no":
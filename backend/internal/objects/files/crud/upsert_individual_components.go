@@ -17,7 +17,26 @@ import (
 
 var log = logger.Named("files crud")
 
+// attachmentStore holds the queries attachments and their texts are upserted with
+type attachmentStore interface {
+	AttachmentListByFileId(ctx context.Context, fileID uuid.UUID) ([]dbstore.Attachment, error)
+	AttachmentCreate(ctx context.Context, arg dbstore.AttachmentCreateParams) (dbstore.Attachment, error)
+	AttachmentUpdate(ctx context.Context, arg dbstore.AttachmentUpdateParams) (dbstore.Attachment, error)
+	AttachmentTextCreate(ctx context.Context, arg dbstore.AttachmentTextCreateParams) (uuid.UUID, error)
+	AttachmentTextDelete(ctx context.Context, attachmentID uuid.UUID) error
+}
+
 func UpsertFileAttachmentTexts(ctx context.Context, q dbstore.Queries, attachment_uuid uuid.UUID, texts []files.AttachmentChildTextSource, insert bool) error {
+	return upsertAttachmentTexts(ctx, &q, attachment_uuid, texts, insert)
+}
+
+// upsertAttachmentTexts adds the texts of an attachment, on update they replace the stored ones when any are sent
+func upsertAttachmentTexts(ctx context.Context, q attachmentStore, attachment_uuid uuid.UUID, texts []files.AttachmentChildTextSource, insert bool) error {
+	if !insert && len(texts) > 0 {
+		if err := q.AttachmentTextDelete(ctx, attachment_uuid); err != nil {
+			return err
+		}
+	}
 	error_list := []error{}
 	for _, text := range texts {
 		textRaw := dbstore.AttachmentTextCreateParams{
@@ -39,24 +58,48 @@ func UpsertFileAttachmentTexts(ctx context.Context, q dbstore.Queries, attachmen
 }
 
 func UpsertFileAttachments(ctx context.Context, q dbstore.Queries, doc_uuid uuid.UUID, attachments []files.CompleteAttachmentSchema, insert bool) error {
+	return upsertFileAttachments(ctx, &q, doc_uuid, attachments, insert)
+}
+
+// upsertFileAttachments creates the attachments of a file. On update an attachment is matched to a stored one
+// by its ID, or by its hash when it has none, and updated in place so reprocessing a file never duplicates it.
+func upsertFileAttachments(ctx context.Context, q attachmentStore, doc_uuid uuid.UUID, attachments []files.CompleteAttachmentSchema, insert bool) error {
+	var existing []dbstore.Attachment
 	if !insert {
-		// Delete previous file attachments
+		var err error
+		existing, err = q.AttachmentListByFileId(ctx, doc_uuid)
+		if err != nil {
+			return err
+		}
 	}
-	log.Info("Trying to insert attachments", zap.Int("num_attachments", len(attachments)))
+	claimed := make(map[uuid.UUID]bool)
+	log.Info("Trying to upsert attachments", zap.Int("num_attachments", len(attachments)), zap.Bool("insert", insert))
 	for _, attachment := range attachments {
-		attachment_insert_args := dbstore.AttachmentCreateParams{
-			FileID:    doc_uuid,
-			Name:      attachment.Name,
-			Extension: attachment.Extension,
-			Hash:      attachment.Hash.String(),
-			Lang:      attachment.Lang,
-			Mdata:     []byte("{}"),
-		}
-		pg_attachment, err := q.AttachmentCreate(ctx, attachment_insert_args)
+		var pg_attachment dbstore.Attachment
+		var err error
+		if stored, ok := matchStoredAttachment(existing, claimed, attachment); ok {
+			claimed[stored.ID] = true
+			pg_attachment, err = q.AttachmentUpdate(ctx, dbstore.AttachmentUpdateParams{
+				ID:        stored.ID,
+				Lang:      attachment.Lang,
+				Name:      attachment.Name,
+				Extension: attachment.Extension,
+				Hash:      attachment.Hash.String(),
+			})
+		} else {
+			pg_attachment, err = q.AttachmentCreate(ctx, dbstore.AttachmentCreateParams{
+				FileID:    doc_uuid,
+				Name:      attachment.Name,
+				Extension: attachment.Extension,
+				Hash:      attachment.Hash.String(),
+				Lang:      attachment.Lang,
+				Mdata:     []byte("{}"),
+			})
+		}
 		if err != nil {
 			return err
 		}
-		err = UpsertFileAttachmentTexts(ctx, q, pg_attachment.ID, attachment.Texts, insert)
+		err = upsertAttachmentTexts(ctx, q, pg_attachment.ID, attachment.Texts, insert)
 		if err != nil {
 			return err
 		}
@@ -64,6 +107,24 @@ func UpsertFileAttachments(ctx context.Context, q dbstore.Queries, doc_uuid uuid
 	return nil
 }
 
+// matchStoredAttachment finds the stored attachment an incoming one updates, skipping those already matched
+func matchStoredAttachment(existing []dbstore.Attachment, claimed map[uuid.UUID]bool, attachment files.CompleteAttachmentSchema) (dbstore.Attachment, bool) {
+	for _, stored := range existing {
+		if !claimed[stored.ID] && attachment.ID != uuid.Nil && stored.ID == attachment.ID {
+			return stored, true
+		}
+	}
+	if attachment.ID != uuid.Nil {
+		return dbstore.Attachment{}, false
+	}
+	for _, stored := range existing {
+		if !claimed[stored.ID] && stored.Hash == attachment.Hash.String() {
+			return stored, true
+		}
+	}
+	return dbstore.Attachment{}, false
+}
+
 func UpsertFileMetadata(ctx context.Context, q dbstore.Queries, doc_uuid uuid.UUID, metadata files.FileMetadataSchema, insert bool) error {
 	// Sometimes this is getting called with an insert when the metadata already exists in the table, this causes a PGERROR, since it violates uniqueness. However, setting it up so it tries to update will fall back to insert if the file doesnt exist. Its probably a good idea to remove this and debug what is causing the new file thing at some point.
 	// UPDATE: I am pretty sure I solved it this should be safe to take out soon - nic
@@ -139,7 +200,7 @@ func FileStatusInsert(ctx context.Context, q dbstore.Queries, doc_uuid uuid.UUID
 	}
 	params := dbstore.StageLogAddParams{
 		FileID: doc_uuid,
-		Status: dbstore.NullStageState{StageState: dbstore.StageState(stage.PGStage)},
+		Status: dbstore.NullStageState{StageState: dbstore.StageState(stage.PGStage), Valid: stage.PGStage != ""},
 		Log:    stage_json,
 	}
 	_, err = q.StageLogAdd(ctx, params)
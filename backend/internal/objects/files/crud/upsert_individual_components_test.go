@@ -0,0 +1,87 @@
+package crud
+
+import (
+	"context"
+	"testing"
+
+	"kessler/internal/dbstore"
+	"kessler/internal/objects/files"
+	"kessler/pkg/hashes"
+
+	"github.com/google/uuid"
+)
+
+// memoryAttachments keeps attachments and their texts in memory
+type memoryAttachments struct {
+	attachments []dbstore.Attachment
+	texts       map[uuid.UUID][]dbstore.AttachmentTextCreateParams
+}
+
+func (m *memoryAttachments) AttachmentListByFileId(ctx context.Context, fileID uuid.UUID) ([]dbstore.Attachment, error) {
+	var listed []dbstore.Attachment
+	for _, attachment := range m.attachments {
+		if attachment.FileID == fileID {
+			listed = append(listed, attachment)
+		}
+	}
+	return listed, nil
+}
+
+func (m *memoryAttachments) AttachmentCreate(ctx context.Context, arg dbstore.AttachmentCreateParams) (dbstore.Attachment, error) {
+	attachment := dbstore.Attachment{ID: uuid.New(), FileID: arg.FileID, Lang: arg.Lang, Name: arg.Name, Extension: arg.Extension, Hash: arg.Hash, Mdata: arg.Mdata}
+	m.attachments = append(m.attachments, attachment)
+	return attachment, nil
+}
+
+func (m *memoryAttachments) AttachmentUpdate(ctx context.Context, arg dbstore.AttachmentUpdateParams) (dbstore.Attachment, error) {
+	for i, attachment := range m.attachments {
+		if attachment.ID == arg.ID {
+			m.attachments[i].Name, m.attachments[i].Extension, m.attachments[i].Hash = arg.Name, arg.Extension, arg.Hash
+			return m.attachments[i], nil
+		}
+	}
+	return dbstore.Attachment{}, context.Canceled
+}
+
+func (m *memoryAttachments) AttachmentTextCreate(ctx context.Context, arg dbstore.AttachmentTextCreateParams) (uuid.UUID, error) {
+	if m.texts == nil {
+		m.texts = make(map[uuid.UUID][]dbstore.AttachmentTextCreateParams)
+	}
+	m.texts[arg.AttachmentID] = append(m.texts[arg.AttachmentID], arg)
+	return uuid.New(), nil
+}
+
+func (m *memoryAttachments) AttachmentTextDelete(ctx context.Context, attachmentID uuid.UUID) error {
+	delete(m.texts, attachmentID)
+	return nil
+}
+
+func TestUpsertFileAttachmentsUpdatesInPlace(t *testing.T) {
+	ctx := context.Background()
+	store := &memoryAttachments{}
+	fileID := uuid.New()
+	scraped := []files.CompleteAttachmentSchema{
+		{Name: "Order", Extension: "pdf", Hash: hashes.HashFromBytes([]byte("order"))},
+		{Name: "Exhibit", Extension: "pdf", Hash: hashes.HashFromBytes([]byte("exhibit"))},
+	}
+	if err := upsertFileAttachments(ctx, store, fileID, scraped, true); err != nil {
+		t.Fatal(err)
+	}
+
+	// Processing saves the file twice through the update path, the attachments carry no IDs
+	processed := []files.CompleteAttachmentSchema{scraped[0], scraped[1]}
+	processed[0].Texts = []files.AttachmentChildTextSource{{IsOriginalText: true, Language: "en", Text: "order text"}}
+	for range 2 {
+		if err := upsertFileAttachments(ctx, store, fileID, processed, false); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if len(store.attachments) != 2 {
+		t.Fatalf("got %d attachments after processing twice, want 2", len(store.attachments))
+	}
+	orderID := store.attachments[0].ID
+	if texts := store.texts[orderID]; len(texts) != 1 || texts[0].Text != "order text" {
+		t.Errorf("texts of the original attachment = %+v, want the single extracted text", texts)
+	}
+}
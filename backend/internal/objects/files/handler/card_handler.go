@@ -150,7 +150,7 @@ func FileSchemaToDocCard(fileSchema files.CompleteFileSchema) search.DocumentCar
 	// Build the document card data
 	return search.DocumentCardData{
 		Name:           fileSchema.Name,
-		Description:    fileSchema.Extra.Summary,
+		Description:    fileSchema.Extra.CardDescription(),
 		Timestamp:      time.Time(fileSchema.DatePublished),
 		ExtraInfo:      fileSchema.Extra.ShortSummary,
 		Index:          0,
@@ -60,6 +60,15 @@ type FileGeneratedExtras struct {
 	Impressiveness float64 `json:"impressiveness"`
 }
 
+// CardDescription is the summary shown on a document card, files summarized before the short summary
+// existed only have the long one
+func (extras FileGeneratedExtras) CardDescription() string {
+	if extras.ShortSummary != "" {
+		return extras.ShortSummary
+	}
+	return extras.Summary
+}
+
 // To heavy to include in a default file schema unless the user specifies they want a smaller version
 type CompleteFileSchema struct {
 	ID            uuid.UUID                             `json:"id"`
@@ -1,5 +1,14 @@
 package files
 
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+)
+
 type PGStage string
 
 const (
@@ -61,3 +70,73 @@ type DocProcStage struct {
 	ProcessingErrorMsg string        `json:"processing_error_msg"`
 	DatabaseErrorMsg   string        `json:"database_error_msg"`
 }
+
+// StageFilter selects files by the latest stage recorded for them in the stage log
+type StageFilter struct {
+	Stage DocProcStatus
+	// State is empty for any state, errored for failures, processing for files a worker never finished
+	State PGStage
+	// StuckFor only matches files whose latest stage is at least this old
+	StuckFor time.Duration
+	Limit    int
+}
+
+const (
+	DefaultStageFilterLimit = 100
+	MaxStageFilterLimit     = 1000
+)
+
+// StageFilterFromQuery reads stage, state, stuck_for and limit query parameters
+func StageFilterFromQuery(values url.Values) (StageFilter, error) {
+	filter := StageFilter{
+		Stage: DocProcStatus(values.Get("stage")),
+		State: PGStage(values.Get("state")),
+		Limit: DefaultStageFilterLimit,
+	}
+	if filter.Stage.Index() < 0 {
+		return StageFilter{}, fmt.Errorf("unknown processing stage %q", filter.Stage)
+	}
+	switch filter.State {
+	case "", PGStagePending, PGStageProcessing, PGStageCompleted, PGStageErrored:
+	default:
+		return StageFilter{}, fmt.Errorf("unknown stage state %q", filter.State)
+	}
+	if raw := values.Get("stuck_for"); raw != "" {
+		stuckFor, err := time.ParseDuration(raw)
+		if err != nil || stuckFor < 0 {
+			return StageFilter{}, fmt.Errorf("invalid stuck_for %q, use a duration such as 30m or 2h", raw)
+		}
+		filter.StuckFor = stuckFor
+	}
+	if raw := values.Get("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit < 1 {
+			return StageFilter{}, fmt.Errorf("invalid limit %q", raw)
+		}
+		filter.Limit = min(limit, MaxStageFilterLimit)
+	}
+	return filter, nil
+}
+
+// Query encodes the filter as the parameters StageFilterFromQuery reads
+func (filter StageFilter) Query() url.Values {
+	values := url.Values{}
+	values.Set("stage", string(filter.Stage))
+	if filter.State != "" {
+		values.Set("state", string(filter.State))
+	}
+	if filter.StuckFor > 0 {
+		values.Set("stuck_for", filter.StuckFor.String())
+	}
+	if filter.Limit > 0 {
+		values.Set("limit", strconv.Itoa(filter.Limit))
+	}
+	return values
+}
+
+// FileStageEntry is the latest stage recorded for a file
+type FileStageEntry struct {
+	FileID    uuid.UUID    `json:"file_id"`
+	Stage     DocProcStage `json:"stage"`
+	UpdatedAt time.Time    `json:"updated_at"`
+}
@@ -0,0 +1,44 @@
+package files
+
+import (
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestStageFilterFromQuery(t *testing.T) {
+	filter, err := StageFilterFromQuery(url.Values{
+		"stage":     {"begin_processing"},
+		"state":     {"processing"},
+		"stuck_for": {"2h"},
+		"limit":     {"5000"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := StageFilter{Stage: DocStatusBeginProcessing, State: PGStageProcessing, StuckFor: 2 * time.Hour, Limit: MaxStageFilterLimit}
+	if filter != want {
+		t.Errorf("got %+v, want %+v", filter, want)
+	}
+	roundTrip, err := StageFilterFromQuery(filter.Query())
+	if err != nil || roundTrip != filter {
+		t.Errorf("round trip = %+v, %v", roundTrip, err)
+	}
+
+	defaults, err := StageFilterFromQuery(url.Values{"stage": {"text_completed"}})
+	if err != nil || defaults.Limit != DefaultStageFilterLimit || defaults.State != "" || defaults.StuckFor != 0 {
+		t.Errorf("defaults = %+v, %v", defaults, err)
+	}
+
+	for _, bad := range []url.Values{
+		{},
+		{"stage": {"nonsense"}},
+		{"stage": {"text_completed"}, "state": {"lost"}},
+		{"stage": {"text_completed"}, "stuck_for": {"soon"}},
+		{"stage": {"text_completed"}, "limit": {"0"}},
+	} {
+		if _, err := StageFilterFromQuery(bad); err == nil {
+			t.Errorf("expected an error for %v", bad)
+		}
+	}
+}
@@ -1,8 +1,10 @@
 package objects
 
 import (
+	"context"
 	"encoding/json"
 	"kessler/internal/fugusdk"
+	"kessler/internal/indexalias"
 	"kessler/pkg/logger"
 	"net/http"
 
@@ -24,16 +26,27 @@ type ObjectResponse struct {
 
 // ObjectService handles business logic for objects
 type ObjectService struct {
-	fuguServerURL string
+	client *fugusdk.Client
+	// aliases and namespace find the version objects are read from, as search does
+	aliases   *indexalias.Resolver
+	namespace string
 }
 
-// NewObjectService creates a new object service
-func NewObjectService(fuguServerURL string) *ObjectService {
+// NewObjectService creates a new object service using the shared fugu client
+func NewObjectService(client *fugusdk.Client, aliases *indexalias.Resolver, namespace string) *ObjectService {
 	return &ObjectService{
-		fuguServerURL: fuguServerURL,
+		client:    client,
+		aliases:   aliases,
+		namespace: namespace,
 	}
 }
 
+// GetObjectByID reads an object from the version the default namespace is switched to
+func (s *ObjectService) GetObjectByID(ctx context.Context, objectID string) (*fugusdk.SanitizedResponse, error) {
+	_, client := s.aliases.FuguTarget(ctx, s.client, s.namespace)
+	return client.GetObjectByID(ctx, objectID)
+}
+
 // ObjectHandler handles HTTP requests for objects
 type ObjectHandler struct {
 	service *ObjectService
@@ -47,9 +60,8 @@ func NewObjectHandler(service *ObjectService) *ObjectHandler {
 }
 
 // RegisterObjectRoutes registers object routes with the router
-func RegisterObjectRoutes(r *mux.Router) error {
-	fuguServerURL := "http://fugudb:3301"
-	service := NewObjectService(fuguServerURL)
+func RegisterObjectRoutes(r *mux.Router, client *fugusdk.Client, aliases *indexalias.Resolver, namespace string) error {
+	service := NewObjectService(client, aliases, namespace)
 	handler := NewObjectHandler(service)
 
 	// Create objects subrouter
@@ -87,16 +99,8 @@ func (h *ObjectHandler) GetObjectByID(w http.ResponseWriter, r *http.Request) {
 
 	logger.Info(ctx, "getting object by ID", zap.String("object_id", objectID))
 
-	// Create fugu client
-	client, err := fugusdk.NewClient(ctx, h.service.fuguServerURL)
-	if err != nil {
-		logger.Error(ctx, "failed to create fugu client", zap.Error(err))
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
-		return
-	}
-
 	// Get object from fugu
-	response, err := client.GetObjectByID(ctx, objectID)
+	response, err := h.service.GetObjectByID(ctx, objectID)
 	if err != nil {
 		logger.Error(ctx, "failed to get object from fugu", zap.Error(err))
 		http.Error(w, "Object not found or server error", http.StatusNotFound)
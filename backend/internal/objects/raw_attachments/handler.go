@@ -1,34 +1,60 @@
 package rawattachments
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"kessler/internal/dbstore"
+	"kessler/pkg/blobstore"
+	"kessler/pkg/database"
 	"kessler/pkg/hashes"
 	"kessler/pkg/logger"
 	"kessler/pkg/s3utils"
+	"mime"
 	"net/http"
-	"os"
+	"strconv"
+	"strings"
 
 	"github.com/gorilla/mux"
 	"go.uber.org/zap"
 )
 
-func DefineRawAttachmentRoutes(r *mux.Router) {
-	// Create handler instance with database
+// RawAttachmentHandler serves attachment bytes from the blob store
+type RawAttachmentHandler struct {
+	db    dbstore.DBTX
+	files *s3utils.KesslerFileManager
+}
+
+func NewRawAttachmentHandler(db dbstore.DBTX, files *s3utils.KesslerFileManager) *RawAttachmentHandler {
+	return &RawAttachmentHandler{db: db, files: files}
+}
 
-	// Insert endpoint
+func DefineRawAttachmentRoutes(r *mux.Router, db dbstore.DBTX) {
+	handler := NewRawAttachmentHandler(db, s3utils.NewKeFileManager())
 
 	r.HandleFunc(
 		"/{hash}/raw",
-		RawAttachmentRawBytesGet,
-	).Methods(http.MethodGet)
+		handler.RawAttachmentRawBytesGet,
+	).Methods(http.MethodGet, http.MethodHead)
 }
 
-// ReadFileHandler creates a handler for reading files in various formats
-func RawAttachmentRawBytesGet(w http.ResponseWriter, r *http.Request) {
+// RawAttachmentRawBytesGet godoc
+// @Summary Download an attachment by hash
+// @Description Streams the attachment with Range and If-None-Match support, the ETag is the Kessler hash. The file is named after the stored attachment.
+// @Tags Objects
+// @Param hash path string true "Kessler hash of the attachment"
+// @Param download query bool false "Send as an attachment rather than inline"
+// @Param redirect query bool false "Redirect to a presigned URL when the blob store supports one"
+// @Success 200 {file} binary
+// @Success 206 {file} binary "Requested byte range"
+// @Success 304 "Unchanged since the ETag sent in If-None-Match"
+// @Failure 400 {string} string "Invalid hash"
+// @Failure 404 {string} string "No file with this hash"
+// @Router /public/raw_attachments/{hash}/raw [get]
+func (h *RawAttachmentHandler) RawAttachmentRawBytesGet(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	log := logger.FromContext(ctx)
 
-	// token := r.Header.Get("Authorization")
 	params := mux.Vars(r)
 	rawHash := params["hash"]
 	hash, err := hashes.HashFromString(rawHash)
@@ -37,25 +63,142 @@ func RawAttachmentRawBytesGet(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Invalid Hash format", http.StatusBadRequest)
 		return
 	}
-	kefiles := s3utils.NewKeFileManager()
-	file_path, err := kefiles.DownloadFileFromS3(hash)
-	if err != nil {
-		log.Error("Could not get file from s3", zap.Error(err), zap.String("hash", hash.String()))
-		http.Error(w, fmt.Sprintf("Error encountered when getting file with hash %v from s3:%v", hash, err), http.StatusInternalServerError)
+
+	if r.URL.Query().Get("redirect") == "true" {
+		presigned, err := h.files.GetURIFromHash(ctx, hash)
+		if err == nil && (strings.HasPrefix(presigned, "https://") || strings.HasPrefix(presigned, "http://")) {
+			http.Redirect(w, r, presigned, http.StatusFound)
+			return
+		}
+		// Stores without reachable URLs, such as the local one, are streamed instead
+		log.Info("Not redirecting raw attachment, streaming it instead", zap.String("hash", hash.String()), zap.Error(err))
+	}
+
+	// The validators are only sent for a blob that exists, so a 404 is never cached as immutable
+	if _, err := h.files.Stat(ctx, hash); err != nil {
+		h.blobError(w, r, hash, err)
 		return
 	}
-	content, err := os.ReadFile(file_path)
+	// Content never changes for a hash, a matching ETag needs no read of the blob
+	etag := `"` + hash.String() + `"`
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	if etagMatches(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	content, info, err := h.files.OpenSeeker(ctx, hash)
 	if err != nil {
-		log.Error("Encountered error reading downloaded file from s3", zap.Error(err), zap.String("hash", hash.String()))
-		http.Error(w, fmt.Sprintf("Error reading file: %v", err), http.StatusInternalServerError)
+		// The blob may have been deleted since the stat, the validators no longer apply
+		w.Header().Del("ETag")
+		w.Header().Del("Cache-Control")
+		h.blobError(w, r, hash, err)
 		return
 	}
+	defer content.Close()
+	if bounded, ok := content.(blobstore.Bounded); ok {
+		if end, ok := singleRangeEnd(r.Header.Get("Range")); ok {
+			bounded.Bound(end)
+		}
+	}
 
-	mimeType := http.DetectContentType(content)
-	// if mimeType == "application/octet-stream" {
-	// 	mimeType = "application/pdf" // Default to PDF if mime type can't be determined
-	// }
+	filename, extension := h.attachmentName(ctx, hash)
+	if mimeType := mime.TypeByExtension("." + extension); extension != "" && mimeType != "" {
+		w.Header().Set("Content-Type", mimeType)
+	}
+	if filename != "" {
+		w.Header().Set("Content-Disposition", contentDisposition(filename, r.URL.Query().Get("download") == "true"))
+	}
+
+	// ServeContent answers Range, If-Range and conditional requests, and sniffs the type when unknown
+	http.ServeContent(w, r, "", info.ModTime, content)
+}
+
+// blobError answers a failed blob store lookup, a missing blob is a 404
+func (h *RawAttachmentHandler) blobError(w http.ResponseWriter, r *http.Request, hash hashes.KesslerHash, err error) {
+	if errors.Is(err, blobstore.ErrNotFound) {
+		http.Error(w, fmt.Sprintf("No file with hash %v", hash), http.StatusNotFound)
+		return
+	}
+	logger.FromContext(r.Context()).Error("Could not get file from blob store", zap.Error(err), zap.String("hash", hash.String()))
+	http.Error(w, fmt.Sprintf("Error encountered when getting file with hash %v from blob store:%v", hash, err), http.StatusInternalServerError)
+}
 
-	w.Header().Set("Content-Type", mimeType)
-	w.Write(content)
+// singleRangeEnd returns the exclusive end of a request for one closed byte range. Open, suffix and
+// multiple ranges report false, they are read to the end of the blob anyway.
+func singleRangeEnd(header string) (int64, bool) {
+	spec, ok := strings.CutPrefix(strings.TrimSpace(header), "bytes=")
+	if !ok || strings.Contains(spec, ",") {
+		return 0, false
+	}
+	start, last, ok := strings.Cut(strings.TrimSpace(spec), "-")
+	if !ok || strings.TrimSpace(start) == "" {
+		return 0, false
+	}
+	end, err := strconv.ParseInt(strings.TrimSpace(last), 10, 64)
+	if err != nil || end < 0 {
+		return 0, false
+	}
+	return end + 1, true
+}
+
+// attachmentName looks up the stored name and extension of an attachment, empty when unknown
+func (h *RawAttachmentHandler) attachmentName(ctx context.Context, hash hashes.KesslerHash) (string, string) {
+	if h.db == nil {
+		return "", ""
+	}
+	attachments, err := database.GetQueries(h.db).AttachmentListByHash(ctx, hash.String())
+	if err != nil {
+		logger.Warn(ctx, "could not look up attachment name", zap.String("hash", hash.String()), zap.Error(err))
+		return "", ""
+	}
+	return attachmentFilename(attachments)
+}
+
+// attachmentFilename names a download after the first attachment with a name, adding its extension
+func attachmentFilename(attachments []dbstore.Attachment) (string, string) {
+	for _, attachment := range attachments {
+		name := strings.TrimSpace(attachment.Name)
+		extension := strings.TrimPrefix(strings.ToLower(strings.TrimSpace(attachment.Extension)), ".")
+		if name == "" {
+			continue
+		}
+		if extension != "" && !strings.HasSuffix(strings.ToLower(name), "."+extension) {
+			name += "." + extension
+		}
+		return name, extension
+	}
+	return "", ""
+}
+
+// contentDisposition builds the header, non-ASCII names are encoded per RFC 2231
+func contentDisposition(filename string, download bool) string {
+	disposition := "inline"
+	if download {
+		disposition = "attachment"
+	}
+	// Path separators and quotes would let a stored name escape the filename parameter
+	filename = strings.Map(func(r rune) rune {
+		switch r {
+		case '/', '\\', '"', '\r', '\n':
+			return '_'
+		}
+		return r
+	}, filename)
+	if header := mime.FormatMediaType(disposition, map[string]string{"filename": filename}); header != "" {
+		return header
+	}
+	return disposition
+}
+
+// etagMatches reports whether an If-None-Match header names etag, weak or strong, or is *
+func etagMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
 }
@@ -0,0 +1,162 @@
+package rawattachments
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"kessler/internal/dbstore"
+	"kessler/pkg/blobstore"
+	"kessler/pkg/hashes"
+	"kessler/pkg/s3utils"
+
+	"github.com/gorilla/mux"
+)
+
+func newTestRouter(t *testing.T, content string) (*mux.Router, hashes.KesslerHash) {
+	t.Helper()
+	local, err := blobstore.NewLocalStore(t.TempDir(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	hash := hashes.HashFromBytes([]byte(content))
+	if err := local.Put(context.Background(), hash, strings.NewReader(content)); err != nil {
+		t.Fatal(err)
+	}
+	cache, err := blobstore.NewCachedStore(local, t.TempDir(), 1<<20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler := NewRawAttachmentHandler(nil, s3utils.NewKeFileManagerFromStore(cache, time.Hour))
+	router := mux.NewRouter()
+	router.HandleFunc("/{hash}/raw", handler.RawAttachmentRawBytesGet).Methods(http.MethodGet, http.MethodHead)
+	return router, hash
+}
+
+func serve(router http.Handler, method, target string, header map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, nil)
+	for key, value := range header {
+		req.Header.Set(key, value)
+	}
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestRawAttachmentStreaming(t *testing.T) {
+	content := "%PDF-1.7 " + strings.Repeat("exhibit page ", 100)
+	router, hash := newTestRouter(t, content)
+	target := "/" + hash.String() + "/raw"
+	etag := `"` + hash.String() + `"`
+
+	t.Run("full download", func(t *testing.T) {
+		rec := serve(router, http.MethodGet, target, nil)
+		if rec.Code != http.StatusOK || rec.Body.String() != content {
+			t.Fatalf("status %d, body length %d", rec.Code, rec.Body.Len())
+		}
+		if rec.Header().Get("ETag") != etag || rec.Header().Get("Accept-Ranges") != "bytes" {
+			t.Errorf("unexpected headers %v", rec.Header())
+		}
+		if rec.Header().Get("Content-Type") != "application/pdf" {
+			t.Errorf("content type %q should be sniffed", rec.Header().Get("Content-Type"))
+		}
+	})
+
+	t.Run("byte range", func(t *testing.T) {
+		rec := serve(router, http.MethodGet, target, map[string]string{"Range": "bytes=9-20"})
+		if rec.Code != http.StatusPartialContent || rec.Body.String() != content[9:21] {
+			t.Fatalf("status %d, body %q", rec.Code, rec.Body.String())
+		}
+		if got := rec.Header().Get("Content-Range"); got != "bytes 9-20/"+strconv.Itoa(len(content)) {
+			t.Errorf("Content-Range = %q", got)
+		}
+	})
+
+	t.Run("suffix range", func(t *testing.T) {
+		rec := serve(router, http.MethodGet, target, map[string]string{"Range": "bytes=-5"})
+		if rec.Code != http.StatusPartialContent || rec.Body.String() != content[len(content)-5:] {
+			t.Fatalf("status %d, body %q", rec.Code, rec.Body.String())
+		}
+	})
+
+	t.Run("unchanged", func(t *testing.T) {
+		rec := serve(router, http.MethodGet, target, map[string]string{"If-None-Match": `W/"other", ` + etag})
+		if rec.Code != http.StatusNotModified || rec.Body.Len() != 0 {
+			t.Fatalf("status %d, body length %d", rec.Code, rec.Body.Len())
+		}
+	})
+
+	t.Run("missing", func(t *testing.T) {
+		missing := hashes.HashFromBytes([]byte("nothing"))
+		rec := serve(router, http.MethodGet, "/"+missing.String()+"/raw", map[string]string{"If-None-Match": `"` + missing.String() + `"`})
+		if rec.Code != http.StatusNotFound {
+			t.Fatalf("status %d", rec.Code)
+		}
+		if rec.Header().Get("ETag") != "" || rec.Header().Get("Cache-Control") != "" {
+			t.Errorf("a missing blob should not be cacheable, headers %v", rec.Header())
+		}
+	})
+
+	t.Run("redirect falls back to streaming for local blobs", func(t *testing.T) {
+		rec := serve(router, http.MethodGet, target+"?redirect=true", nil)
+		body, _ := io.ReadAll(rec.Body)
+		if rec.Code != http.StatusOK || string(body) != content {
+			t.Fatalf("status %d", rec.Code)
+		}
+	})
+}
+
+func TestSingleRangeEnd(t *testing.T) {
+	tests := []struct {
+		header string
+		end    int64
+		ok     bool
+	}{
+		{"bytes=9-20", 21, true},
+		{"bytes= 0-0", 1, true},
+		{"bytes=9-", 0, false},
+		{"bytes=-5", 0, false},
+		{"bytes=0-1,4-5", 0, false},
+		{"items=0-1", 0, false},
+		{"", 0, false},
+	}
+	for _, tt := range tests {
+		if end, ok := singleRangeEnd(tt.header); end != tt.end || ok != tt.ok {
+			t.Errorf("singleRangeEnd(%q) = %d, %v, want %d, %v", tt.header, end, ok, tt.end, tt.ok)
+		}
+	}
+}
+
+func TestAttachmentFilename(t *testing.T) {
+	tests := []struct {
+		attachments []dbstore.Attachment
+		name, ext   string
+	}{
+		{[]dbstore.Attachment{{Name: "Direct Testimony", Extension: "pdf"}}, "Direct Testimony.pdf", "pdf"},
+		{[]dbstore.Attachment{{Name: "Exhibit A.PDF", Extension: ".pdf"}}, "Exhibit A.PDF", "pdf"},
+		{[]dbstore.Attachment{{Name: " ", Extension: "pdf"}, {Name: "Order", Extension: "docx"}}, "Order.docx", "docx"},
+		{nil, "", ""},
+	}
+	for _, tt := range tests {
+		if name, ext := attachmentFilename(tt.attachments); name != tt.name || ext != tt.ext {
+			t.Errorf("attachmentFilename(%v) = %q, %q, want %q, %q", tt.attachments, name, ext, tt.name, tt.ext)
+		}
+	}
+}
+
+func TestContentDisposition(t *testing.T) {
+	if got := contentDisposition("Rate Case Order.pdf", false); got != `inline; filename="Rate Case Order.pdf"` {
+		t.Errorf("inline = %q", got)
+	}
+	if got := contentDisposition(`a/b"c.pdf`, true); got != `attachment; filename=a_b_c.pdf` {
+		t.Errorf("sanitized = %q", got)
+	}
+	if got := contentDisposition("Résumé.pdf", false); !strings.HasPrefix(got, "inline; filename*=utf-8''") {
+		t.Errorf("non-ASCII = %q", got)
+	}
+}
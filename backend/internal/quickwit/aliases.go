@@ -0,0 +1,33 @@
+package quickwit
+
+import (
+	"fmt"
+	"sync/atomic"
+)
+
+// Quickwit has no index aliases. Versioned rebuilds record which index each alias points at and install a
+// resolver at startup, searches go through ResolveIndex so they follow a switch without a redeploy.
+var indexResolver atomic.Pointer[func(string) string]
+
+// SetIndexResolver installs the function mapping an index alias to the index searches should hit
+func SetIndexResolver(resolve func(name string) string) {
+	indexResolver.Store(&resolve)
+}
+
+// ResolveIndex returns the index to search for name, name itself when no resolver is installed
+func ResolveIndex(name string) string {
+	if resolve := indexResolver.Load(); resolve != nil {
+		return (*resolve)(name)
+	}
+	return name
+}
+
+// CountDocuments returns the number of documents searchable in an index
+func CountDocuments(indexName string) (int, error) {
+	maxHits := 0
+	resp, err := Client.Search(indexName, SearchParams{Query: "*", MaxHits: &maxHits})
+	if err != nil {
+		return 0, fmt.Errorf("counting documents in %s: %w", indexName, err)
+	}
+	return resp.NumHits, nil
+}
@@ -91,7 +91,7 @@ func SearchConversations(search_data ConvoSearchRequestData, ctx context.Context
 		StartOffset: search_data.Offset,
 		SortBy:      "documents_count",
 	}
-	search_index := NYConversationIndex
+	search_index := ResolveIndex(NYConversationIndex)
 	var search_results []conversations.ConversationInformation
 
 	err := SearchHitsQuickwitGeneric(&search_results, search_request, search_index)
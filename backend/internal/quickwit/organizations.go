@@ -76,7 +76,7 @@ func SearchOrganizations(searchData OrgSearchRequestData, ctx context.Context) (
 	}
 
 	var searchResults []organizations.OrganizationQuickwitSchema
-	err := SearchHitsQuickwitGeneric(&searchResults, searchRequest, NYOrganizationIndex)
+	err := SearchHitsQuickwitGeneric(&searchResults, searchRequest, ResolveIndex(NYOrganizationIndex))
 	return searchResults, err
 }
 
@@ -0,0 +1,209 @@
+package search
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"strings"
+	"time"
+
+	"kessler/internal/dbstore"
+	"kessler/pkg/logger"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"go.uber.org/zap"
+)
+
+const (
+	// analyticsBuffer is how many events may wait for Postgres before new ones are dropped
+	analyticsBuffer = 1024
+	// analyticsWriteTimeout bounds a single insert so a slow database cannot stall the writer
+	analyticsWriteTimeout = 5 * time.Second
+	// analyticsFlushTimeout bounds writing the events still buffered when the server stops
+	analyticsFlushTimeout = 10 * time.Second
+)
+
+// analyticsStore is the part of dbstore.Queries the recorder writes through
+type analyticsStore interface {
+	SearchQueryLogCreate(ctx context.Context, arg dbstore.SearchQueryLogCreateParams) error
+	SearchClickCreate(ctx context.Context, arg dbstore.SearchClickCreateParams) error
+}
+
+// searchRecord is what is logged about one answered search
+type searchRecord struct {
+	Query     string
+	Filters   map[string]string
+	Namespace string
+	// ResultCount is every hit matching the search, Returned the cards on this page starting at rank Offset+1
+	ResultCount int
+	Offset      int
+	Returned    int
+	Latency     time.Duration
+	Suggestion  string
+}
+
+// SearchClick is a card opened from a search result page
+type SearchClick struct {
+	SearchID uuid.UUID `json:"search_id"`
+	CardID   string    `json:"card_id"`
+	CardType string    `json:"card_type,omitempty"`
+	// Position is the 1-based rank of the card across pages
+	Position int `json:"position"`
+}
+
+// analyticsEvent holds exactly one of a search or a click
+type analyticsEvent struct {
+	search *dbstore.SearchQueryLogCreateParams
+	click  *dbstore.SearchClickCreateParams
+}
+
+// AnalyticsRecorder writes searches and clicks to Postgres from a background goroutine so requests never
+// wait on the insert. When the buffer is full events are dropped rather than slowing searches down.
+type AnalyticsRecorder struct {
+	store  analyticsStore
+	events chan analyticsEvent
+}
+
+// newAnalyticsRecorder creates a recorder writing to store once Run is started
+func newAnalyticsRecorder(store analyticsStore, buffer int) *AnalyticsRecorder {
+	return &AnalyticsRecorder{store: store, events: make(chan analyticsEvent, buffer)}
+}
+
+// Run writes events until ctx is cancelled, then writes those still buffered within analyticsFlushTimeout
+func (r *AnalyticsRecorder) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			r.flush(ctx)
+			return
+		case event := <-r.events:
+			r.write(ctx, event)
+		}
+	}
+}
+
+// RunAnalytics writes search analytics until ctx is cancelled, it returns at once when analytics are off
+func (s *SearchService) RunAnalytics(ctx context.Context) {
+	if s.analytics != nil {
+		s.analytics.Run(ctx)
+	}
+}
+
+// flush writes the buffered events, events queued by requests still finishing are written too
+func (r *AnalyticsRecorder) flush(ctx context.Context) {
+	flushCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), analyticsFlushTimeout)
+	defer cancel()
+	for flushCtx.Err() == nil {
+		select {
+		case event := <-r.events:
+			r.write(flushCtx, event)
+		default:
+			return
+		}
+	}
+	logger.Warn(ctx, "search analytics flush timed out", zap.Int("dropped", len(r.events)))
+}
+
+func (r *AnalyticsRecorder) write(ctx context.Context, event analyticsEvent) {
+	writeCtx, cancel := context.WithTimeout(ctx, analyticsWriteTimeout)
+	defer cancel()
+
+	var err error
+	switch {
+	case event.search != nil:
+		err = r.store.SearchQueryLogCreate(writeCtx, *event.search)
+	case event.click != nil:
+		err = r.store.SearchClickCreate(writeCtx, *event.click)
+	}
+	if err != nil {
+		logger.Warn(ctx, "failed to write search analytics", zap.Error(err))
+	}
+}
+
+// enqueue hands an event to the writer, reporting false when it was dropped
+func (r *AnalyticsRecorder) enqueue(ctx context.Context, event analyticsEvent) bool {
+	select {
+	case r.events <- event:
+		return true
+	default:
+		logger.Warn(ctx, "search analytics buffer full, dropping event")
+		return false
+	}
+}
+
+// recordSearch queues a search for logging and returns the ID clicks refer to it by, uuid.Nil when
+// analytics are off
+func (r *AnalyticsRecorder) recordSearch(ctx context.Context, rec searchRecord) uuid.UUID {
+	if r == nil {
+		return uuid.Nil
+	}
+	filters := rec.Filters
+	if filters == nil {
+		filters = map[string]string{}
+	}
+	filtersJSON, err := json.Marshal(filters)
+	if err != nil {
+		filtersJSON = []byte("{}")
+	}
+
+	id := uuid.New()
+	r.enqueue(ctx, analyticsEvent{search: &dbstore.SearchQueryLogCreateParams{
+		ID:              id,
+		Query:           rec.Query,
+		NormalizedQuery: normalizeQuery(rec.Query),
+		Filters:         filtersJSON,
+		Namespace:       rec.Namespace,
+		ResultCount:     clampInt32(int64(rec.ResultCount)),
+		ResultOffset:    clampInt32(int64(rec.Offset)),
+		ReturnedCount:   clampInt32(int64(rec.Returned)),
+		LatencyMs:       clampInt32(rec.Latency.Milliseconds()),
+		Suggestion:      rec.Suggestion,
+		CreatedAt:       pgtype.Timestamptz{Time: time.Now(), Valid: true},
+	}})
+	return id
+}
+
+// recordClick queues a click on a search result
+func (r *AnalyticsRecorder) recordClick(ctx context.Context, click SearchClick) {
+	if r == nil {
+		return
+	}
+	r.enqueue(ctx, analyticsEvent{click: &dbstore.SearchClickCreateParams{
+		SearchID:  click.SearchID,
+		CardID:    click.CardID,
+		CardType:  click.CardType,
+		Position:  clampInt32(int64(click.Position)),
+		CreatedAt: pgtype.Timestamptz{Time: time.Now(), Valid: true},
+	}})
+}
+
+// clampInt32 fits a count into the int32 columns, saturating instead of wrapping around
+func clampInt32(n int64) int32 {
+	return int32(min(max(n, math.MinInt32), math.MaxInt32))
+}
+
+// normalizeQuery lower cases a query and collapses its whitespace so reports group the same search together
+func normalizeQuery(query string) string {
+	return strings.Join(strings.Fields(strings.ToLower(query)), " ")
+}
+
+// searchRecordFor describes a response for the query log. Cursor pages do not know the rank they start at,
+// so they are logged without the cards they showed and do not count towards click-through by position.
+func searchRecordFor(response *SearchResponse, query string, filters map[string]string, namespace string, pagination PaginationParams, opts SearchOptions, latency time.Duration) searchRecord {
+	rec := searchRecord{
+		Query:       query,
+		Filters:     filters,
+		Namespace:   namespace,
+		ResultCount: max(response.Total, len(response.Data)),
+		Latency:     latency,
+	}
+	if opts.Cursor == nil {
+		rec.Offset = pagination.Page * pagination.Limit
+		rec.Returned = len(response.Data)
+	}
+	if response.Suggestion != nil {
+		rec.Suggestion = response.Suggestion.Query
+	}
+	return rec
+}
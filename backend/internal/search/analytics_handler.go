@@ -0,0 +1,350 @@
+package search
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"kessler/internal/dbstore"
+	"kessler/pkg/database"
+	"kessler/pkg/logger"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/jackc/pgx/v5/pgtype"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultAnalyticsDays     = 7
+	maxAnalyticsDays         = 365
+	defaultAnalyticsLimit    = 50
+	maxAnalyticsLimit        = 500
+	defaultAnalyticsPosition = 20
+	maxAnalyticsPosition     = 100
+	// maxClickPosition is the deepest rank a click is accepted for, no result page reaches past it
+	maxClickPosition = 10000
+)
+
+// ErrInvalidClick is returned for a click missing its search, card or a plausible position
+var ErrInvalidClick = errors.New("invalid search click")
+
+// recordSearch logs an answered search and stamps the response with the ID clicks refer to it by
+func (h *SearchServiceHandler) recordSearch(ctx context.Context, response *SearchResponse, rec searchRecord) {
+	if id := h.service.analytics.recordSearch(ctx, rec); id != uuid.Nil {
+		response.SearchID = id.String()
+	}
+}
+
+// validateClick checks a click names the search it came from, the card and a rank
+func validateClick(click SearchClick) error {
+	switch {
+	case click.SearchID == uuid.Nil:
+		return fmt.Errorf("%w: search_id is required", ErrInvalidClick)
+	case click.CardID == "":
+		return fmt.Errorf("%w: card_id is required", ErrInvalidClick)
+	case click.Position < 1 || click.Position > maxClickPosition:
+		return fmt.Errorf("%w: position must be between 1 and %d", ErrInvalidClick, maxClickPosition)
+	}
+	return nil
+}
+
+// RecordClick handles POST /search/click, logging which card of a search was opened.
+// The write happens in the background, so the response is 202 Accepted.
+func (h *SearchServiceHandler) RecordClick(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	ctx, span := tracer.Start(ctx, "search-api:record-click")
+	defer span.End()
+
+	var click SearchClick
+	if err := json.NewDecoder(r.Body).Decode(&click); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := validateClick(click); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	h.service.analytics.recordClick(ctx, click)
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// AnalyticsHandler serves search analytics reports
+type AnalyticsHandler struct {
+	queries *dbstore.Queries
+}
+
+// RegisterAnalyticsAdminRoutes mounts the search analytics reports under /search-analytics
+func RegisterAnalyticsAdminRoutes(router *mux.Router, db dbstore.DBTX) {
+	h := &AnalyticsHandler{queries: database.GetQueries(db)}
+	analyticsRoute := router.PathPrefix("/search-analytics").Subrouter()
+	analyticsRoute.HandleFunc("/top-queries", h.TopQueries).Methods(http.MethodGet)
+	analyticsRoute.HandleFunc("/zero-results", h.ZeroResultQueries).Methods(http.MethodGet)
+	analyticsRoute.HandleFunc("/slowest", h.SlowestQueries).Methods(http.MethodGet)
+	analyticsRoute.HandleFunc("/click-through", h.ClickThroughByPosition).Methods(http.MethodGet)
+}
+
+// analyticsWindow is the period and row count a report covers
+type analyticsWindow struct {
+	Since time.Time
+	Limit int
+}
+
+// parseAnalyticsWindow reads ?since= (RFC 3339 or YYYY-MM-DD) or ?days= and ?limit=
+func parseAnalyticsWindow(query map[string][]string, now time.Time) (analyticsWindow, error) {
+	get := func(key string) string {
+		if values := query[key]; len(values) > 0 {
+			return values[0]
+		}
+		return ""
+	}
+	window := analyticsWindow{Since: now.AddDate(0, 0, -defaultAnalyticsDays), Limit: defaultAnalyticsLimit}
+
+	if raw := get("since"); raw != "" {
+		since, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			if since, err = time.Parse(time.DateOnly, raw); err != nil {
+				return window, fmt.Errorf("since must be an RFC 3339 time or YYYY-MM-DD date")
+			}
+		}
+		window.Since = since
+	} else if raw := get("days"); raw != "" {
+		days, err := strconv.Atoi(raw)
+		if err != nil || days < 1 || days > maxAnalyticsDays {
+			return window, fmt.Errorf("days must be between 1 and %d", maxAnalyticsDays)
+		}
+		window.Since = now.AddDate(0, 0, -days)
+	}
+
+	if raw := get("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit < 1 || limit > maxAnalyticsLimit {
+			return window, fmt.Errorf("limit must be between 1 and %d", maxAnalyticsLimit)
+		}
+		window.Limit = limit
+	}
+	return window, nil
+}
+
+// clickThroughRate is clicks per impression, zero when nothing was shown
+func clickThroughRate(clicks, impressions int64) float64 {
+	if impressions == 0 {
+		return 0
+	}
+	return float64(clicks) / float64(impressions)
+}
+
+func timestamptz(t time.Time) pgtype.Timestamptz {
+	return pgtype.Timestamptz{Time: t, Valid: true}
+}
+
+// TopQuery is a frequent query with how often it found nothing and how often a result was opened
+type TopQuery struct {
+	Query              string  `json:"query"`
+	Searches           int64   `json:"searches"`
+	ZeroResultSearches int64   `json:"zero_result_searches"`
+	AvgResults         float64 `json:"avg_results"`
+	AvgLatencyMs       float64 `json:"avg_latency_ms"`
+	ClickThroughRate   float64 `json:"click_through_rate"`
+}
+
+// ZeroResultQuery is a query that found nothing, with the correction offered for it if any
+type ZeroResultQuery struct {
+	Query          string    `json:"query"`
+	Searches       int64     `json:"searches"`
+	LastSearchedAt time.Time `json:"last_searched_at"`
+	Suggestion     string    `json:"suggestion,omitempty"`
+}
+
+// SlowQuery is a query with its latency percentiles
+type SlowQuery struct {
+	Query        string  `json:"query"`
+	Searches     int64   `json:"searches"`
+	AvgLatencyMs float64 `json:"avg_latency_ms"`
+	P95LatencyMs float64 `json:"p95_latency_ms"`
+	MaxLatencyMs int32   `json:"max_latency_ms"`
+}
+
+// PositionClickThrough is how often a result rank was shown and opened
+type PositionClickThrough struct {
+	Position         int32   `json:"position"`
+	Impressions      int64   `json:"impressions"`
+	Clicks           int64   `json:"clicks"`
+	ClickThroughRate float64 `json:"click_through_rate"`
+}
+
+// AnalyticsReport wraps a report's rows with the period it covers
+type AnalyticsReport[T any] struct {
+	Since time.Time `json:"since"`
+	Rows  []T       `json:"rows"`
+}
+
+// TopQueries godoc
+// @Summary Most frequent search queries
+// @Param days query int false "Days to cover (default 7)"
+// @Param since query string false "Start of the period, RFC 3339 or YYYY-MM-DD"
+// @Param limit query int false "Queries to return (default 50, max 500)"
+// @Success 200 {object} AnalyticsReport[TopQuery]
+// @Router /admin/search-analytics/top-queries [get]
+func (h *AnalyticsHandler) TopQueries(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	window, ok := h.window(w, r)
+	if !ok {
+		return
+	}
+	rows, err := h.queries.SearchAnalyticsTopQueries(ctx, dbstore.SearchAnalyticsTopQueriesParams{
+		Since:   timestamptz(window.Since),
+		MaxRows: int32(window.Limit),
+	})
+	if err != nil {
+		h.respondError(ctx, w, "top queries", err)
+		return
+	}
+	report := AnalyticsReport[TopQuery]{Since: window.Since, Rows: make([]TopQuery, 0, len(rows))}
+	for _, row := range rows {
+		report.Rows = append(report.Rows, TopQuery{
+			Query:              row.NormalizedQuery,
+			Searches:           row.Searches,
+			ZeroResultSearches: row.ZeroResultSearches,
+			AvgResults:         row.AvgResults,
+			AvgLatencyMs:       row.AvgLatencyMs,
+			ClickThroughRate:   clickThroughRate(row.ClickedSearches, row.Searches),
+		})
+	}
+	h.respondJSON(ctx, w, report)
+}
+
+// ZeroResultQueries godoc
+// @Summary Search queries that found nothing
+// @Param days query int false "Days to cover (default 7)"
+// @Param since query string false "Start of the period, RFC 3339 or YYYY-MM-DD"
+// @Param limit query int false "Queries to return (default 50, max 500)"
+// @Success 200 {object} AnalyticsReport[ZeroResultQuery]
+// @Router /admin/search-analytics/zero-results [get]
+func (h *AnalyticsHandler) ZeroResultQueries(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	window, ok := h.window(w, r)
+	if !ok {
+		return
+	}
+	rows, err := h.queries.SearchAnalyticsZeroResultQueries(ctx, dbstore.SearchAnalyticsZeroResultQueriesParams{
+		Since:   timestamptz(window.Since),
+		MaxRows: int32(window.Limit),
+	})
+	if err != nil {
+		h.respondError(ctx, w, "zero result queries", err)
+		return
+	}
+	report := AnalyticsReport[ZeroResultQuery]{Since: window.Since, Rows: make([]ZeroResultQuery, 0, len(rows))}
+	for _, row := range rows {
+		report.Rows = append(report.Rows, ZeroResultQuery{
+			Query:          row.NormalizedQuery,
+			Searches:       row.Searches,
+			LastSearchedAt: row.LastSearchedAt.Time,
+			Suggestion:     row.Suggestion,
+		})
+	}
+	h.respondJSON(ctx, w, report)
+}
+
+// SlowestQueries godoc
+// @Summary Search queries by 95th percentile latency
+// @Param days query int false "Days to cover (default 7)"
+// @Param since query string false "Start of the period, RFC 3339 or YYYY-MM-DD"
+// @Param limit query int false "Queries to return (default 50, max 500)"
+// @Success 200 {object} AnalyticsReport[SlowQuery]
+// @Router /admin/search-analytics/slowest [get]
+func (h *AnalyticsHandler) SlowestQueries(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	window, ok := h.window(w, r)
+	if !ok {
+		return
+	}
+	rows, err := h.queries.SearchAnalyticsSlowestQueries(ctx, dbstore.SearchAnalyticsSlowestQueriesParams{
+		Since:   timestamptz(window.Since),
+		MaxRows: int32(window.Limit),
+	})
+	if err != nil {
+		h.respondError(ctx, w, "slowest queries", err)
+		return
+	}
+	report := AnalyticsReport[SlowQuery]{Since: window.Since, Rows: make([]SlowQuery, 0, len(rows))}
+	for _, row := range rows {
+		report.Rows = append(report.Rows, SlowQuery{
+			Query:        row.NormalizedQuery,
+			Searches:     row.Searches,
+			AvgLatencyMs: row.AvgLatencyMs,
+			P95LatencyMs: row.P95LatencyMs,
+			MaxLatencyMs: row.MaxLatencyMs,
+		})
+	}
+	h.respondJSON(ctx, w, report)
+}
+
+// ClickThroughByPosition godoc
+// @Summary Click-through rate by result rank
+// @Description Impressions count each rank a search page showed, cursor pages are left out as their ranks are unknown
+// @Param days query int false "Days to cover (default 7)"
+// @Param since query string false "Start of the period, RFC 3339 or YYYY-MM-DD"
+// @Param max_position query int false "Deepest rank to report (default 20, max 100)"
+// @Success 200 {object} AnalyticsReport[PositionClickThrough]
+// @Router /admin/search-analytics/click-through [get]
+func (h *AnalyticsHandler) ClickThroughByPosition(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	window, ok := h.window(w, r)
+	if !ok {
+		return
+	}
+	maxPosition := defaultAnalyticsPosition
+	if raw := r.URL.Query().Get("max_position"); raw != "" {
+		var err error
+		if maxPosition, err = strconv.Atoi(raw); err != nil || maxPosition < 1 || maxPosition > maxAnalyticsPosition {
+			http.Error(w, fmt.Sprintf("max_position must be between 1 and %d", maxAnalyticsPosition), http.StatusBadRequest)
+			return
+		}
+	}
+	rows, err := h.queries.SearchAnalyticsClicksByPosition(ctx, dbstore.SearchAnalyticsClicksByPositionParams{
+		MaxPosition: int32(maxPosition),
+		Since:       timestamptz(window.Since),
+	})
+	if err != nil {
+		h.respondError(ctx, w, "click-through by position", err)
+		return
+	}
+	report := AnalyticsReport[PositionClickThrough]{Since: window.Since, Rows: make([]PositionClickThrough, 0, len(rows))}
+	for _, row := range rows {
+		report.Rows = append(report.Rows, PositionClickThrough{
+			Position:         row.Position,
+			Impressions:      row.Impressions,
+			Clicks:           row.Clicks,
+			ClickThroughRate: clickThroughRate(row.Clicks, row.Impressions),
+		})
+	}
+	h.respondJSON(ctx, w, report)
+}
+
+func (h *AnalyticsHandler) window(w http.ResponseWriter, r *http.Request) (analyticsWindow, bool) {
+	window, err := parseAnalyticsWindow(r.URL.Query(), time.Now())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return window, false
+	}
+	return window, true
+}
+
+func (h *AnalyticsHandler) respondError(ctx context.Context, w http.ResponseWriter, report string, err error) {
+	logger.Error(ctx, "failed to build search analytics report", zap.String("report", report), zap.Error(err))
+	http.Error(w, "Internal server error", http.StatusInternalServerError)
+}
+
+func (h *AnalyticsHandler) respondJSON(ctx context.Context, w http.ResponseWriter, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		logger.Error(ctx, "failed to encode search analytics report", zap.Error(err))
+	}
+}
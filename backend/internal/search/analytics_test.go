@@ -0,0 +1,211 @@
+package search
+
+import (
+	"context"
+	"math"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"kessler/internal/dbstore"
+
+	"github.com/google/uuid"
+)
+
+type fakeAnalyticsStore struct {
+	mu       sync.Mutex
+	searches []dbstore.SearchQueryLogCreateParams
+	clicks   []dbstore.SearchClickCreateParams
+	written  chan struct{}
+	block    chan struct{}
+}
+
+func (f *fakeAnalyticsStore) SearchQueryLogCreate(ctx context.Context, arg dbstore.SearchQueryLogCreateParams) error {
+	if f.block != nil {
+		<-f.block
+	}
+	f.mu.Lock()
+	f.searches = append(f.searches, arg)
+	f.mu.Unlock()
+	f.written <- struct{}{}
+	return nil
+}
+
+func (f *fakeAnalyticsStore) SearchClickCreate(ctx context.Context, arg dbstore.SearchClickCreateParams) error {
+	f.mu.Lock()
+	f.clicks = append(f.clicks, arg)
+	f.mu.Unlock()
+	f.written <- struct{}{}
+	return nil
+}
+
+func waitWritten(t *testing.T, store *fakeAnalyticsStore) {
+	t.Helper()
+	select {
+	case <-store.written:
+	case <-time.After(time.Second):
+		t.Fatal("analytics event was not written")
+	}
+}
+
+func TestAnalyticsRecorder(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	store := &fakeAnalyticsStore{written: make(chan struct{}, 10)}
+	recorder := newAnalyticsRecorder(store, 10)
+	go recorder.Run(ctx)
+
+	id := recorder.recordSearch(ctx, searchRecord{
+		Query:       "  Rate  Case ",
+		ResultCount: 42,
+		Offset:      20,
+		Returned:    10,
+		Latency:     150 * time.Millisecond,
+	})
+	waitWritten(t, store)
+	recorder.recordClick(ctx, SearchClick{SearchID: id, CardID: "abc", CardType: "document", Position: 23})
+	waitWritten(t, store)
+
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	search := store.searches[0]
+	if search.ID != id || search.NormalizedQuery != "rate case" || search.ResultCount != 42 || search.ResultOffset != 20 ||
+		search.ReturnedCount != 10 || search.LatencyMs != 150 || string(search.Filters) != "{}" {
+		t.Errorf("unexpected search row %+v", search)
+	}
+	if click := store.clicks[0]; click.SearchID != id || click.Position != 23 || click.CardID != "abc" {
+		t.Errorf("unexpected click row %+v", click)
+	}
+}
+
+func TestAnalyticsRecorderDropsWhenFull(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	store := &fakeAnalyticsStore{written: make(chan struct{}, 10), block: make(chan struct{})}
+	recorder := newAnalyticsRecorder(store, 1)
+	go recorder.Run(ctx)
+
+	// The writer holds the first event while blocked, the second fills the buffer
+	recorder.recordSearch(ctx, searchRecord{Query: "one"})
+	deadline := time.Now().Add(time.Second)
+	for len(recorder.events) > 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if !recorder.enqueue(ctx, analyticsEvent{search: &dbstore.SearchQueryLogCreateParams{Query: "two"}}) {
+		t.Fatal("buffered event should be accepted")
+	}
+	if recorder.enqueue(ctx, analyticsEvent{search: &dbstore.SearchQueryLogCreateParams{Query: "three"}}) {
+		t.Fatal("event beyond the buffer should be dropped")
+	}
+	close(store.block)
+	waitWritten(t, store)
+	waitWritten(t, store)
+}
+
+func TestAnalyticsRecorderFlushesOnStop(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	store := &fakeAnalyticsStore{written: make(chan struct{}, 10)}
+	recorder := newAnalyticsRecorder(store, 10)
+	for _, query := range []string{"one", "two", "three"} {
+		recorder.recordSearch(ctx, searchRecord{Query: query})
+	}
+	// Stopped before it wrote anything, the buffered events are still written
+	cancel()
+	recorder.Run(ctx)
+
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	if len(store.searches) != 3 {
+		t.Errorf("expected 3 searches flushed, got %d", len(store.searches))
+	}
+}
+
+func TestClampInt32(t *testing.T) {
+	if got := clampInt32(math.MaxInt32 + 1); got != math.MaxInt32 {
+		t.Errorf("clampInt32 overflowed to %d", got)
+	}
+	if got := clampInt32(-1 << 40); got != math.MinInt32 {
+		t.Errorf("clampInt32 underflowed to %d", got)
+	}
+	if got := clampInt32(42); got != 42 {
+		t.Errorf("clampInt32(42) = %d", got)
+	}
+}
+
+func TestNilAnalyticsRecorder(t *testing.T) {
+	var recorder *AnalyticsRecorder
+	if id := recorder.recordSearch(context.Background(), searchRecord{Query: "x"}); id != uuid.Nil {
+		t.Errorf("disabled recorder returned search id %v", id)
+	}
+	recorder.recordClick(context.Background(), SearchClick{SearchID: uuid.New(), CardID: "x", Position: 1})
+}
+
+func TestSearchRecordFor(t *testing.T) {
+	response := &SearchResponse{Data: make([]CardData, 5), Total: 25, Suggestion: &SpellingSuggestion{Query: "tariff"}}
+	rec := searchRecordFor(response, "tarif", nil, "NYPUC", PaginationParams{Page: 2, Limit: 10}, SearchOptions{}, time.Second)
+	if rec.Offset != 20 || rec.Returned != 5 || rec.ResultCount != 25 || rec.Suggestion != "tariff" {
+		t.Errorf("unexpected record %+v", rec)
+	}
+
+	rec = searchRecordFor(response, "tarif", nil, "NYPUC", PaginationParams{Page: 0, Limit: 10}, SearchOptions{Cursor: &searchCursor{ID: "x"}}, time.Second)
+	if rec.Offset != 0 || rec.Returned != 0 {
+		t.Errorf("cursor page should not record impressions, got %+v", rec)
+	}
+}
+
+func TestValidateClick(t *testing.T) {
+	valid := SearchClick{SearchID: uuid.New(), CardID: "abc", Position: 1}
+	if err := validateClick(valid); err != nil {
+		t.Errorf("valid click rejected: %v", err)
+	}
+	for _, click := range []SearchClick{
+		{CardID: "abc", Position: 1},
+		{SearchID: uuid.New(), Position: 1},
+		{SearchID: uuid.New(), CardID: "abc"},
+		{SearchID: uuid.New(), CardID: "abc", Position: -3},
+		{SearchID: uuid.New(), CardID: "abc", Position: maxClickPosition + 1},
+	} {
+		if err := validateClick(click); err == nil {
+			t.Errorf("invalid click %+v accepted", click)
+		}
+	}
+}
+
+func TestParseAnalyticsWindow(t *testing.T) {
+	now := time.Date(2024, 6, 15, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		query     string
+		wantSince time.Time
+		wantLimit int
+		wantErr   bool
+	}{
+		{"", now.AddDate(0, 0, -7), 50, false},
+		{"days=30&limit=10", now.AddDate(0, 0, -30), 10, false},
+		{"since=2024-06-01", time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC), 50, false},
+		{"since=2024-06-01T08:00:00Z&days=3", time.Date(2024, 6, 1, 8, 0, 0, 0, time.UTC), 50, false},
+		{"days=0", time.Time{}, 0, true},
+		{"limit=1000", time.Time{}, 0, true},
+		{"since=yesterday", time.Time{}, 0, true},
+	}
+	for _, tt := range tests {
+		values, _ := url.ParseQuery(tt.query)
+		window, err := parseAnalyticsWindow(values, now)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseAnalyticsWindow(%q) error = %v, want error %v", tt.query, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && (!window.Since.Equal(tt.wantSince) || window.Limit != tt.wantLimit) {
+			t.Errorf("parseAnalyticsWindow(%q) = %+v, want since %v limit %d", tt.query, window, tt.wantSince, tt.wantLimit)
+		}
+	}
+}
+
+func TestClickThroughRate(t *testing.T) {
+	if got := clickThroughRate(3, 12); got != 0.25 {
+		t.Errorf("clickThroughRate(3, 12) = %v", got)
+	}
+	if got := clickThroughRate(0, 0); got != 0 {
+		t.Errorf("clickThroughRate(0, 0) = %v", got)
+	}
+}
@@ -69,8 +69,11 @@ type DocumentCardData struct {
 	FileUUID       uuid.UUID            `json:"file_uuid"`
 	AttachmentUUID uuid.UUID            `json:"attachment_uuid"`
 	FragmentID     string               `json:"fragment_id"`
+	DatePublished  time.Time            `json:"date_published,omitzero"`
 	Authors        []DocumentAuthor     `json:"authors"`
 	Conversation   DocumentConversation `json:"conversation"`
+	Highlights     []Highlight          `json:"highlights,omitempty"`
+	CollapsedCount int                  `json:"collapsed_count,omitempty"` // other matching hits folded into this card
 }
 
 func (d DocumentCardData) GetType() string {
@@ -0,0 +1,215 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"kessler/internal/fugusdk"
+	"kessler/pkg/logger"
+	"strings"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// CollapseMode selects how segment hits of the same document are grouped
+type CollapseMode string
+
+const (
+	CollapseNone       CollapseMode = "none"
+	CollapseAttachment CollapseMode = "attachment"
+	CollapseFile       CollapseMode = "file"
+)
+
+const (
+	// scanPageSize is the fugu page size used while scanning hits to collapse or count
+	scanPageSize = 200
+	// collapseScanLimit bounds how many raw hits are scanned for a collapsed search, the scan usually
+	// stops earlier once the requested page and one more group are filled
+	collapseScanLimit = 1000
+)
+
+// ParseCollapseMode validates a collapse parameter, an empty value means none
+func ParseCollapseMode(raw string) (CollapseMode, error) {
+	switch mode := CollapseMode(strings.ToLower(strings.TrimSpace(raw))); mode {
+	case "":
+		return CollapseNone, nil
+	case CollapseNone, CollapseAttachment, CollapseFile:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("unsupported collapse %q, expected attachment, file or none", raw)
+	}
+}
+
+// collapsedResults is a page of collapsed hits along with the counts needed for the response
+type collapsedResults struct {
+	page        []fugusdk.FuguSearchResult
+	hits        []fugusdk.FuguSearchResult // every scanned hit, reused for facet counts
+	otherCounts map[string]int             // group key -> other hits folded into the kept hit
+	groups      int
+	rawTotal    int
+	truncated   bool
+}
+
+// collapseKey returns the grouping key for a hit, hits that are not attachments are never grouped
+func collapseKey(result fugusdk.FuguSearchResult, mode CollapseMode) string {
+	if mode == CollapseNone {
+		return result.ID
+	}
+	typed, _ := fugusdk.DecodeResult[fugusdk.AttachmentMetadata](result)
+	switch mode {
+	case CollapseAttachment:
+		if typed.Metadata.AttachmentID != uuid.Nil {
+			return typed.Metadata.AttachmentID.String()
+		}
+		if idx := strings.Index(result.ID, "-segment-"); idx != -1 {
+			return result.ID[:idx]
+		}
+	case CollapseFile:
+		if typed.Metadata.FileID != uuid.Nil {
+			return typed.Metadata.FileID.String()
+		}
+	}
+	return result.ID
+}
+
+// cardCollapseKey returns the grouping key for a hydrated card
+func cardCollapseKey(card DocumentCardData, mode CollapseMode) string {
+	switch mode {
+	case CollapseAttachment:
+		if card.AttachmentUUID != uuid.Nil {
+			return card.AttachmentUUID.String()
+		}
+	case CollapseFile:
+		if card.FileUUID != uuid.Nil {
+			return card.FileUUID.String()
+		}
+	}
+	return ""
+}
+
+// collapseHits groups hits by key, keeping the best scoring hit of each group in first seen order
+func collapseHits(hits []fugusdk.FuguSearchResult, mode CollapseMode) ([]fugusdk.FuguSearchResult, map[string]int) {
+	groupIndex := make(map[string]int)
+	otherCounts := make(map[string]int)
+	var kept []fugusdk.FuguSearchResult
+
+	for _, hit := range hits {
+		key := collapseKey(hit, mode)
+		idx, seen := groupIndex[key]
+		if !seen {
+			groupIndex[key] = len(kept)
+			kept = append(kept, hit)
+			continue
+		}
+		otherCounts[key]++
+		if hit.Score > kept[idx].Score {
+			kept[idx] = hit
+		}
+	}
+	return kept, otherCounts
+}
+
+// scanHits pages through fugu hits for a query until limit hits are read or enough reports the hits
+// read so far suffice, enough may be nil. The returned bool is true when the scan stopped before reaching
+// the raw total.
+func (s *SearchService) scanHits(ctx context.Context, client *fugusdk.Client, query fugusdk.FuguSearchQuery, limit int, enough func([]fugusdk.FuguSearchResult) bool) ([]fugusdk.FuguSearchResult, int, bool, error) {
+	var hits []fugusdk.FuguSearchResult
+	rawTotal := 0
+
+	for page := 0; ; page++ {
+		// Offsets are page * per_page, so every page has the same size and the last one is trimmed
+		pageNum, perPage := page, scanPageSize
+		query.Page = &fugusdk.Pagination{Page: &pageNum, PerPage: &perPage}
+
+		var response *fugusdk.SanitizedResponse
+		var err error
+		if page == 0 {
+			response, err = s.executeSearch(ctx, client, query)
+		} else {
+			// The first page checked fugu's health, the rest of the scan goes straight to search
+			response, err = client.Search(ctx, query)
+		}
+		if err != nil {
+			return nil, 0, false, err
+		}
+		hits = append(hits, response.Results...)
+		rawTotal = response.Total
+
+		if len(response.Results) < perPage || len(hits) >= rawTotal {
+			return hits, rawTotal, false, nil
+		}
+		if enough != nil && enough(hits) {
+			return hits, rawTotal, true, nil
+		}
+		if len(hits) >= limit {
+			hits = hits[:limit]
+			logger.Warn(ctx, "search hit scan limit reached, counts are a lower bound",
+				zap.Int("scanned", len(hits)),
+				zap.Int("raw_total", rawTotal))
+			return hits, rawTotal, true, nil
+		}
+	}
+}
+
+// groupsAtLeast returns a scan stop condition met once hits hold at least n groups
+func groupsAtLeast(n int, mode CollapseMode) func([]fugusdk.FuguSearchResult) bool {
+	groups := make(map[string]bool)
+	counted := 0
+	return func(hits []fugusdk.FuguSearchResult) bool {
+		for _, hit := range hits[counted:] {
+			groups[collapseKey(hit, mode)] = true
+		}
+		counted = len(hits)
+		return len(groups) >= n
+	}
+}
+
+// executeCollapsedSearch scans fugu hits, collapses them and slices out the requested page.
+// Totals are counted in groups so pagination matches the collapsed results. The scan stops once one group
+// past the requested page is found, the group total is then a lower bound and marked approximate.
+func (s *SearchService) executeCollapsedSearch(ctx context.Context, client *fugusdk.Client, query fugusdk.FuguSearchQuery, pagination PaginationParams, mode CollapseMode) (*collapsedResults, error) {
+	ctx, span := serviceTracer.Start(ctx, "search-service:execute-collapsed-search")
+	defer span.End()
+
+	needed := (pagination.Page+1)*pagination.Limit + 1
+	hits, rawTotal, truncated, err := s.scanHits(ctx, client, query, collapseScanLimit, groupsAtLeast(needed, mode))
+	if err != nil {
+		return nil, err
+	}
+
+	kept, otherCounts := collapseHits(hits, mode)
+
+	start := pagination.Page * pagination.Limit
+	end := start + pagination.Limit
+	if start > len(kept) {
+		start = len(kept)
+	}
+	if end > len(kept) {
+		end = len(kept)
+	}
+
+	return &collapsedResults{
+		page:        kept[start:end],
+		hits:        hits,
+		otherCounts: otherCounts,
+		groups:      len(kept),
+		rawTotal:    rawTotal,
+		truncated:   truncated,
+	}, nil
+}
+
+// applyCollapseCounts records how many other hits each card absorbed and the raw hit total
+func applyCollapseCounts(response *SearchResponse, collapsed *collapsedResults, mode CollapseMode) {
+	response.Collapse = mode
+	response.TotalHits = collapsed.rawTotal
+	response.TotalApproximate = collapsed.truncated
+
+	for i, card := range response.Data {
+		doc, ok := card.(DocumentCardData)
+		if !ok {
+			continue
+		}
+		doc.CollapsedCount = collapsed.otherCounts[cardCollapseKey(doc, mode)]
+		response.Data[i] = doc
+	}
+}
@@ -0,0 +1,130 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"kessler/internal/fugusdk"
+	"kessler/internal/fugusdk/fugutest"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+// fileUUID derives a stable file id from a readable name
+func fileUUID(name string) uuid.UUID {
+	return uuid.NewSHA1(uuid.NameSpaceOID, []byte(name))
+}
+
+func TestParseCollapseMode(t *testing.T) {
+	tests := map[string]CollapseMode{"": CollapseNone, " File ": CollapseFile, "attachment": CollapseAttachment}
+	for raw, want := range tests {
+		if got, err := ParseCollapseMode(raw); err != nil || got != want {
+			t.Errorf("ParseCollapseMode(%q) = %q, %v, want %q", raw, got, err, want)
+		}
+	}
+	if _, err := ParseCollapseMode("docket"); err == nil {
+		t.Error("expected an error for an unsupported collapse")
+	}
+}
+
+func TestCollapseKey(t *testing.T) {
+	const attachmentID = "11111111-1111-1111-1111-111111111111"
+	const otherID = "22222222-2222-2222-2222-222222222222"
+	tests := []struct {
+		name string
+		hit  fugusdk.FuguSearchResult
+		mode CollapseMode
+		want string
+	}{
+		{"attachment from metadata", fugusdk.FuguSearchResult{ID: "x-segment-1", Metadata: map[string]interface{}{"attachment_id": otherID}}, CollapseAttachment, otherID},
+		{"malformed attachment id", fugusdk.FuguSearchResult{ID: attachmentID + "-segment-1", Metadata: map[string]interface{}{"attachment_id": "a"}}, CollapseAttachment, attachmentID},
+		{"attachment from segment id", fugusdk.FuguSearchResult{ID: attachmentID + "-segment-3"}, CollapseAttachment, attachmentID},
+		{"unsegmented attachment", fugusdk.FuguSearchResult{ID: attachmentID}, CollapseAttachment, attachmentID},
+		{"file from metadata", fugusdk.FuguSearchResult{ID: "x", Metadata: map[string]interface{}{"file_id": otherID}}, CollapseFile, otherID},
+		{"file without metadata", fugusdk.FuguSearchResult{ID: "x-segment-1"}, CollapseFile, "x-segment-1"},
+		{"empty file id", fugusdk.FuguSearchResult{ID: "x", Metadata: map[string]interface{}{"file_id": ""}}, CollapseFile, "x"},
+		{"none", fugusdk.FuguSearchResult{ID: "x-segment-1", Metadata: map[string]interface{}{"attachment_id": otherID}}, CollapseNone, "x-segment-1"},
+	}
+	for _, test := range tests {
+		if got := collapseKey(test.hit, test.mode); got != test.want {
+			t.Errorf("%s: collapseKey = %q, want %q", test.name, got, test.want)
+		}
+	}
+}
+
+func TestCollapseHits(t *testing.T) {
+	hit := func(id, file string, score float32) fugusdk.FuguSearchResult {
+		return fugusdk.FuguSearchResult{ID: id, Score: score, Metadata: map[string]interface{}{"file_id": fileUUID(file).String()}}
+	}
+	hits := []fugusdk.FuguSearchResult{
+		hit("a1", "a", 3),
+		hit("b1", "b", 2.5),
+		hit("a2", "a", 4),
+		hit("c1", "c", 1),
+		hit("a3", "a", 1),
+	}
+	kept, others := collapseHits(hits, CollapseFile)
+
+	want := []string{"a2", "b1", "c1"}
+	if len(kept) != len(want) {
+		t.Fatalf("expected %d groups, got %+v", len(want), kept)
+	}
+	for i, id := range want {
+		if kept[i].ID != id {
+			t.Errorf("group %d kept %s, want %s", i, kept[i].ID, id)
+		}
+	}
+	if others[fileUUID("a").String()] != 2 || len(others) != 1 {
+		t.Errorf("unexpected folded counts %v", others)
+	}
+
+	if kept, _ := collapseHits(hits, CollapseNone); len(kept) != len(hits) {
+		t.Errorf("collapse none must keep every hit, got %d", len(kept))
+	}
+}
+
+func TestExecuteCollapsedSearchStopsEarly(t *testing.T) {
+	ctx := context.Background()
+	server := fugutest.NewServer(t)
+	client, err := server.Client(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 300 files with 3 segments each, more hits than one scan page
+	for file := 0; file < 300; file++ {
+		for segment := 0; segment < 3; segment++ {
+			server.Seed(fugusdk.ObjectRecord{
+				ID:       fmt.Sprintf("file-%03d-segment-%d", file, segment),
+				Text:     "rate case",
+				Metadata: map[string]interface{}{"file_id": fileUUID(fmt.Sprintf("file-%03d", file)).String()},
+			})
+		}
+	}
+	s := &SearchService{client: client}
+
+	collapsed, err := s.executeCollapsedSearch(ctx, client, fugusdk.FuguSearchQuery{Query: "rate"}, PaginationParams{Page: 0, Limit: 10}, CollapseFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(collapsed.page) != 10 || collapsed.rawTotal != 900 {
+		t.Errorf("expected a page of 10 out of 900 hits, got %d of %d", len(collapsed.page), collapsed.rawTotal)
+	}
+	if searches := len(server.Searches()); searches != 1 {
+		t.Errorf("the first page fits in one scan page, got %d searches", searches)
+	}
+	if !collapsed.truncated || collapsed.groups <= 10 {
+		t.Errorf("a stopped scan must be approximate and show a next page, got %d groups, truncated %v", collapsed.groups, collapsed.truncated)
+	}
+
+	server.Reset()
+	for i := 0; i < 5; i++ {
+		server.Seed(fugusdk.ObjectRecord{ID: fmt.Sprintf("only-%d", i), Text: "rate", Metadata: map[string]interface{}{"file_id": fileUUID("only").String()}})
+	}
+	collapsed, err = s.executeCollapsedSearch(ctx, client, fugusdk.FuguSearchQuery{Query: "rate"}, PaginationParams{Page: 0, Limit: 10}, CollapseFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if collapsed.truncated || collapsed.groups != 1 || collapsed.otherCounts[fileUUID("only").String()] != 4 {
+		t.Errorf("a complete scan is exact, got %d groups, truncated %v, counts %v", collapsed.groups, collapsed.truncated, collapsed.otherCounts)
+	}
+}
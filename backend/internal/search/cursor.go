@@ -0,0 +1,175 @@
+package search
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"kessler/internal/fugusdk"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidCursor is returned when a cursor does not belong to the search it was sent with
+var ErrInvalidCursor = errors.New("invalid search cursor")
+
+// searchCursor marks the last hit of a page, the next page starts strictly after it.
+// Hits are ordered by the sort key and then by ID so the position is stable while indexing runs.
+type searchCursor struct {
+	Sort        SortOption `json:"s"`
+	Key         string     `json:"k,omitempty"`
+	Score       float32    `json:"sc,omitempty"`
+	ID          string     `json:"id"`
+	Fingerprint string     `json:"f"`
+}
+
+// encode returns the opaque token handed to clients
+func (c searchCursor) encode() string {
+	raw, err := json.Marshal(c)
+	if err != nil {
+		return ""
+	}
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+// parseCursor decodes a cursor token, an empty token means the first page
+func parseCursor(token string) (*searchCursor, error) {
+	token = strings.TrimSpace(token)
+	if token == "" {
+		return nil, nil
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCursor, err)
+	}
+	var cursor searchCursor
+	if err := json.Unmarshal(raw, &cursor); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCursor, err)
+	}
+	if cursor.ID == "" || cursor.Fingerprint == "" {
+		return nil, fmt.Errorf("%w: missing position", ErrInvalidCursor)
+	}
+	return &cursor, nil
+}
+
+// searchFingerprint identifies a query, its filters and its order so a cursor cannot be replayed against another search
+func searchFingerprint(query, namespace string, filters map[string]string, opts SearchOptions) string {
+	keys := make([]string, 0, len(filters))
+	for key := range filters {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	h := fnv.New64a()
+	fmt.Fprintf(h, "%s\x00%s\x00%s\x00", query, namespace, opts.Sort)
+	for _, key := range keys {
+		fmt.Fprintf(h, "%s=%s\x00", key, filters[key])
+	}
+	fmt.Fprint(h, dateRangeFilter(opts.DateFrom, opts.DateTo))
+	return strconv.FormatUint(h.Sum64(), 16)
+}
+
+// sortKeyMetadata holds the metadata a hit is sorted by. The date is kept as the stored string rather than
+// a time so the key matches what Fugu compares.
+type sortKeyMetadata struct {
+	DateISO     string   `json:"date_iso"`
+	DocketGovID string   `json:"docket_gov_id"`
+	AuthorNames []string `json:"author_names"`
+}
+
+// hitSortKey returns the value a hit is ordered by for a sort option, relevance orders by score instead.
+// Fugu compares the stored value byte for byte, so the key is neither trimmed nor case folded.
+func hitSortKey(result fugusdk.FuguSearchResult, option SortOption) string {
+	if option == SortRelevance {
+		return ""
+	}
+	typed, _ := fugusdk.DecodeResult[sortKeyMetadata](result)
+	switch option {
+	case SortDatePublishedDesc, SortDatePublishedAsc:
+		return typed.Metadata.DateISO
+	case SortDocketNumber:
+		return typed.Metadata.DocketGovID
+	case SortAuthorName:
+		if len(typed.Metadata.AuthorNames) > 0 {
+			return typed.Metadata.AuthorNames[0]
+		}
+	}
+	return ""
+}
+
+// cursorFor returns the cursor positioned on a hit
+func cursorFor(result fugusdk.FuguSearchResult, option SortOption, fingerprint string) searchCursor {
+	cursor := searchCursor{
+		Sort:        option,
+		ID:          result.ID,
+		Fingerprint: fingerprint,
+	}
+	if option == SortRelevance || option == "" {
+		cursor.Score = result.Score
+	} else {
+		cursor.Key = hitSortKey(result, option)
+	}
+	return cursor
+}
+
+// searchAfter returns the position sent to Fugu so it can resume after the cursor
+func (c searchCursor) searchAfter() []string {
+	if c.Sort == SortRelevance || c.Sort == "" {
+		return []string{strconv.FormatFloat(float64(c.Score), 'g', -1, 32), c.ID}
+	}
+	return []string{c.Key, c.ID}
+}
+
+// isAfter reports whether a hit sorts strictly after the cursor position, comparing keys the way Fugu does
+func (c searchCursor) isAfter(result fugusdk.FuguSearchResult) bool {
+	switch c.Sort {
+	case SortRelevance, "":
+		if result.Score != c.Score {
+			return result.Score < c.Score
+		}
+	case SortDatePublishedDesc:
+		// Missing dates compare as empty strings and so already sort last
+		if key := hitSortKey(result, c.Sort); key != c.Key {
+			return key < c.Key
+		}
+	default:
+		// Ascending sorts put documents without a value last
+		if key := hitSortKey(result, c.Sort); key != c.Key {
+			if key == "" || c.Key == "" {
+				return key == ""
+			}
+			return key > c.Key
+		}
+	}
+	return result.ID > c.ID
+}
+
+// nextCursor returns the cursor for the page after results, the raw Fugu page before seen hits are dropped.
+// A short page is the last one. A full page whose last hit does not move past the cursor means Fugu ignored
+// search_after, and handing the same position back would loop forever, so the walk ends there too.
+func nextCursor(results []fugusdk.FuguSearchResult, limit int, cursor *searchCursor, option SortOption, fingerprint string) string {
+	if len(results) == 0 || len(results) < limit {
+		return ""
+	}
+	last := results[len(results)-1]
+	if cursor != nil && !cursor.isAfter(last) {
+		return ""
+	}
+	return cursorFor(last, option, fingerprint).encode()
+}
+
+// dropSeenHits removes hits at or before the cursor, guarding against duplicates when Fugu ignores search_after
+func dropSeenHits(results []fugusdk.FuguSearchResult, cursor *searchCursor) []fugusdk.FuguSearchResult {
+	if cursor == nil {
+		return results
+	}
+	kept := results[:0]
+	for _, result := range results {
+		if cursor.isAfter(result) {
+			kept = append(kept, result)
+		}
+	}
+	return kept
+}
@@ -0,0 +1,155 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"kessler/internal/fugusdk"
+	"kessler/internal/fugusdk/fugutest"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	hit := fugusdk.FuguSearchResult{
+		ID:       "b",
+		Metadata: map[string]interface{}{"date_iso": "2024-03-01T00:00:00Z"},
+	}
+	cursor := cursorFor(hit, SortDatePublishedDesc, "abc")
+
+	parsed, err := parseCursor(cursor.encode())
+	if err != nil {
+		t.Fatalf("parse cursor: %v", err)
+	}
+	if *parsed != cursor {
+		t.Errorf("expected %+v, got %+v", cursor, *parsed)
+	}
+
+	if _, err := parseCursor("not a cursor!"); !errors.Is(err, ErrInvalidCursor) {
+		t.Errorf("expected ErrInvalidCursor, got %v", err)
+	}
+}
+
+func TestDropSeenHits(t *testing.T) {
+	dated := func(id, date string) fugusdk.FuguSearchResult {
+		return fugusdk.FuguSearchResult{ID: id, Metadata: map[string]interface{}{"date_iso": date}}
+	}
+	cursor := cursorFor(dated("b", "2024-03-01T00:00:00Z"), SortDatePublishedDesc, "abc")
+
+	results := []fugusdk.FuguSearchResult{
+		dated("c", "2024-04-01T00:00:00Z"), // newer, already returned
+		dated("a", "2024-03-01T00:00:00Z"), // same date, lower id, already returned
+		dated("b", "2024-03-01T00:00:00Z"), // the cursor itself
+		dated("d", "2024-03-01T00:00:00Z"),
+		dated("e", "2024-02-01T00:00:00Z"),
+		{ID: "f"}, // no date sorts last
+	}
+	kept := dropSeenHits(results, &cursor)
+
+	want := []string{"d", "e", "f"}
+	if len(kept) != len(want) {
+		t.Fatalf("expected %v, got %v", want, kept)
+	}
+	for i, id := range want {
+		if kept[i].ID != id {
+			t.Errorf("result %d: expected %s, got %s", i, id, kept[i].ID)
+		}
+	}
+}
+
+func TestNextCursor(t *testing.T) {
+	hits := []fugusdk.FuguSearchResult{{ID: "a", Score: 2}, {ID: "b", Score: 1}}
+	if got := nextCursor(hits[:1], 2, nil, SortRelevance, "f"); got != "" {
+		t.Errorf("a short page is the last one, got %q", got)
+	}
+	token := nextCursor(hits, 2, nil, SortRelevance, "f")
+	cursor, err := parseCursor(token)
+	if err != nil || cursor.ID != "b" {
+		t.Fatalf("expected a cursor on the last hit, got %+v, %v", cursor, err)
+	}
+	// Every hit was already seen, so Fugu ignored search_after and the walk must end
+	if got := nextCursor(hits, 2, cursor, SortRelevance, "f"); got != "" {
+		t.Errorf("a page that does not advance must not return a cursor, got %q", got)
+	}
+	// A page whose first hits were already seen still continues from its last hit
+	more := []fugusdk.FuguSearchResult{{ID: "b", Score: 1}, {ID: "c", Score: 0.5}}
+	if next, err := parseCursor(nextCursor(more, 2, cursor, SortRelevance, "f")); err != nil || next.ID != "c" {
+		t.Errorf("expected a cursor on c, got %+v, %v", next, err)
+	}
+}
+
+// TestCursorWalk pages through a sorted search whose keys tie and differ only in case, every document must
+// come back exactly once and in the order Fugu sorts them
+func TestCursorWalk(t *testing.T) {
+	ctx := context.Background()
+	server := fugutest.NewServer(t)
+	client, err := server.Client(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dockets := []string{"22-E-0001", "22-e-0001", "22-E-0001", "A-1", "a-1", "B-2", "22-E-0001", "b-2", "", "a-1", "Z", "z", "22-e-0001"}
+	var want []string
+	for i, docket := range dockets {
+		id := uuid.NewSHA1(uuid.NameSpaceOID, []byte{byte(i)}).String()
+		metadata := map[string]interface{}{}
+		if docket != "" {
+			metadata["docket_gov_id"] = docket
+		}
+		server.Seed(fugusdk.ObjectRecord{ID: id, Text: "rate case", Metadata: metadata})
+		want = append(want, id)
+	}
+	s := &SearchService{client: client}
+
+	for _, option := range []SortOption{SortDocketNumber, SortRelevance} {
+		opts := SearchOptions{Sort: option}
+		seen := make(map[uuid.UUID]int)
+		var walked []DocumentCardData
+		for pages := 0; ; pages++ {
+			if pages > len(dockets) {
+				t.Fatalf("%s: the walk did not end", option)
+			}
+			response, err := s.ProcessSearch(ctx, "rate", nil, PaginationParams{Limit: 3}, "", opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, card := range response.Data {
+				doc := card.(DocumentCardData)
+				seen[doc.AttachmentUUID]++
+				walked = append(walked, doc)
+			}
+			if response.NextCursor == "" {
+				break
+			}
+			if opts.Cursor, err = parseCursor(response.NextCursor); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		if len(seen) != len(want) {
+			t.Errorf("%s: walked %d distinct documents, want %d", option, len(seen), len(want))
+		}
+		for id, count := range seen {
+			if count != 1 {
+				t.Errorf("%s: document %s returned %d times", option, id, count)
+			}
+		}
+		if option == SortDocketNumber {
+			for i := 1; i < len(walked); i++ {
+				prev, cur := walked[i-1], walked[i]
+				prevKey, curKey := dockets[indexOf(want, prev.AttachmentUUID.String())], dockets[indexOf(want, cur.AttachmentUUID.String())]
+				if compareEmptyLast(curKey, prevKey) || (curKey == prevKey && cur.AttachmentUUID.String() < prev.AttachmentUUID.String()) {
+					t.Errorf("%s: %q (%s) came after %q (%s)", option, curKey, cur.AttachmentUUID, prevKey, prev.AttachmentUUID)
+				}
+			}
+		}
+	}
+}
+
+func indexOf(values []string, target string) int {
+	for i, value := range values {
+		if value == target {
+			return i
+		}
+	}
+	return -1
+}
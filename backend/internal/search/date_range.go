@@ -0,0 +1,67 @@
+package search
+
+import (
+	"fmt"
+	"kessler/internal/fugusdk"
+	"kessler/pkg/timestamp"
+	"time"
+)
+
+// dateRangeField is the normalised publish date written by the attachment indexer
+const dateRangeField = "metadata/date_iso"
+
+// RangeFilterInfo describes a range filter accepted by the search endpoints
+type RangeFilterInfo struct {
+	Name       string   `json:"name"`
+	FilterPath string   `json:"filter_path"`
+	Params     []string `json:"params"`
+	Format     string   `json:"format"`
+}
+
+// SupportedRangeFilters lists the range filters accepted alongside facet filters
+var SupportedRangeFilters = []RangeFilterInfo{
+	{
+		Name:       "date",
+		FilterPath: dateRangeField,
+		Params:     []string{"date_from", "date_to"},
+		Format:     "RFC3339",
+	},
+}
+
+// parseDateParam validates an RFC3339 date query parameter, an empty value is unbounded
+func parseDateParam(name, raw string) (timestamp.RFC3339Time, error) {
+	if raw == "" {
+		return timestamp.RFC3339Time{}, nil
+	}
+	parsed, err := timestamp.KessTimeFromString(raw)
+	if err != nil {
+		return timestamp.RFC3339Time{}, fmt.Errorf("%s must be an RFC3339 timestamp: %w", name, err)
+	}
+	return parsed, nil
+}
+
+// validateDateRange makes sure the bounds of a date range are not reversed
+func validateDateRange(from, to timestamp.RFC3339Time) error {
+	if from.IsZero() || to.IsZero() {
+		return nil
+	}
+	if time.Time(from).After(time.Time(to)) {
+		return fmt.Errorf("date_from (%s) is after date_to (%s)", from, to)
+	}
+	return nil
+}
+
+// dateRangeFilter converts the date bounds into a fugu range filter, returns "" when unbounded
+func dateRangeFilter(from, to timestamp.RFC3339Time) string {
+	if from.IsZero() && to.IsZero() {
+		return ""
+	}
+	var fromStr, toStr string
+	if !from.IsZero() {
+		fromStr = time.Time(from).UTC().Format(time.RFC3339)
+	}
+	if !to.IsZero() {
+		toStr = time.Time(to).UTC().Format(time.RFC3339)
+	}
+	return fugusdk.RangeFilter(dateRangeField, fromStr, toStr)
+}
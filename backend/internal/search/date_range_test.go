@@ -0,0 +1,129 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"kessler/internal/fugusdk"
+	"kessler/internal/fugusdk/fugutest"
+	"kessler/pkg/timestamp"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+)
+
+func mustDate(t *testing.T, raw string) timestamp.RFC3339Time {
+	t.Helper()
+	parsed, err := parseDateParam("date", raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return parsed
+}
+
+func TestParseDateParam(t *testing.T) {
+	if parsed, err := parseDateParam("date_from", ""); err != nil || !parsed.IsZero() {
+		t.Errorf("an empty date is unbounded, got %v, %v", parsed, err)
+	}
+	parsed := mustDate(t, "2024-03-01T12:00:00+02:00")
+	if want := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC); !time.Time(parsed).Equal(want) {
+		t.Errorf("parsed %v, want %v", time.Time(parsed), want)
+	}
+	for _, raw := range []string{"2024-03-01", "yesterday", "2024-13-01T00:00:00Z"} {
+		if _, err := parseDateParam("date_to", raw); err == nil {
+			t.Errorf("expected an error for %q", raw)
+		}
+	}
+}
+
+func TestValidateDateRange(t *testing.T) {
+	early, late := mustDate(t, "2024-01-01T00:00:00Z"), mustDate(t, "2024-06-01T00:00:00Z")
+	if err := validateDateRange(early, late); err != nil {
+		t.Error(err)
+	}
+	if err := validateDateRange(early, early); err != nil {
+		t.Errorf("a single instant is a valid range: %v", err)
+	}
+	if err := validateDateRange(late, timestamp.RFC3339Time{}); err != nil {
+		t.Errorf("an open range is valid: %v", err)
+	}
+	if err := validateDateRange(late, early); err == nil {
+		t.Error("expected an error for a reversed range")
+	}
+}
+
+func TestDateRangeFilter(t *testing.T) {
+	from := mustDate(t, "2024-03-01T02:00:00+02:00")
+	to := mustDate(t, "2024-03-31T00:00:00Z")
+	tests := []struct {
+		from, to timestamp.RFC3339Time
+		want     string
+	}{
+		{timestamp.RFC3339Time{}, timestamp.RFC3339Time{}, ""},
+		{from, to, "metadata/date_iso:[2024-03-01T00:00:00Z TO 2024-03-31T00:00:00Z]"},
+		{from, timestamp.RFC3339Time{}, "metadata/date_iso:[2024-03-01T00:00:00Z TO *]"},
+		{timestamp.RFC3339Time{}, to, "metadata/date_iso:[* TO 2024-03-31T00:00:00Z]"},
+	}
+	for _, test := range tests {
+		if got := dateRangeFilter(test.from, test.to); got != test.want {
+			t.Errorf("dateRangeFilter = %q, want %q", got, test.want)
+		}
+	}
+}
+
+// TestDateRangeBounds checks both bounds are inclusive, a document dated exactly on a bound matches and one
+// a second outside it does not
+func TestDateRangeBounds(t *testing.T) {
+	ctx := context.Background()
+	server := fugutest.NewServer(t)
+	client, err := server.Client(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dated := func(id, date string) fugusdk.ObjectRecord {
+		return fugusdk.ObjectRecord{ID: id, Text: "rate case", Metadata: map[string]interface{}{"date_iso": date}}
+	}
+	server.Seed(
+		dated("before", "2024-02-29T23:59:59Z"),
+		dated("on-from", "2024-03-01T00:00:00Z"),
+		dated("inside", "2024-03-15T00:00:00Z"),
+		dated("on-to", "2024-03-31T00:00:00Z"),
+		dated("after", "2024-03-31T00:00:01Z"),
+		fugusdk.ObjectRecord{ID: "undated", Text: "rate case"},
+	)
+	from, to := mustDate(t, "2024-03-01T00:00:00Z"), mustDate(t, "2024-03-31T00:00:00Z")
+
+	search := func(from, to timestamp.RFC3339Time) []string {
+		filters := []string{dateRangeFilter(from, to)}
+		page, perPage := 0, 20
+		resp, err := client.Search(ctx, fugusdk.FuguSearchQuery{Query: "rate", Filters: &filters, Page: &fugusdk.Pagination{Page: &page, PerPage: &perPage}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids := make([]string, 0, len(resp.Results))
+		for _, hit := range resp.Results {
+			ids = append(ids, hit.ID)
+		}
+		slices.Sort(ids)
+		return ids
+	}
+
+	if got, want := search(from, to), []string{"inside", "on-from", "on-to"}; !slices.Equal(got, want) {
+		t.Errorf("closed range matched %v, want %v", got, want)
+	}
+	if got, want := search(from, timestamp.RFC3339Time{}), []string{"after", "inside", "on-from", "on-to"}; !slices.Equal(got, want) {
+		t.Errorf("open ended range matched %v, want %v", got, want)
+	}
+	if got, want := search(timestamp.RFC3339Time{}, from), []string{"before", "on-from"}; !slices.Equal(got, want) {
+		t.Errorf("open started range matched %v, want %v", got, want)
+	}
+}
+
+func TestUnsupportedRangeIsNotImplemented(t *testing.T) {
+	rec := httptest.NewRecorder()
+	(&SearchServiceHandler{}).respondSearchError(rec, fmt.Errorf("fugu search failed: range filters: %w", fugusdk.ErrUnsupported))
+	if rec.Code != http.StatusNotImplemented {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusNotImplemented)
+	}
+}
@@ -0,0 +1,296 @@
+package search
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"kessler/internal/dbstore"
+	"kessler/pkg/logger"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+const (
+	// defaultExportMaxRows caps an export, SEARCH_EXPORT_MAX_ROWS overrides it
+	defaultExportMaxRows = 10000
+	exportPageSize       = 100
+)
+
+// ExportFormat selects how exported search hits are written
+type ExportFormat string
+
+const (
+	ExportCSV    ExportFormat = "csv"
+	ExportJSONL  ExportFormat = "jsonl"
+	ExportBibTeX ExportFormat = "bibtex"
+)
+
+// ParseExportFormat validates a format parameter, an empty value means csv
+func ParseExportFormat(raw string) (ExportFormat, error) {
+	switch format := ExportFormat(strings.ToLower(strings.TrimSpace(raw))); format {
+	case "":
+		return ExportCSV, nil
+	case ExportCSV, ExportJSONL, ExportBibTeX:
+		return format, nil
+	default:
+		return "", fmt.Errorf("unsupported export format %q, expected csv, jsonl or bibtex", raw)
+	}
+}
+
+// contentType returns the response content type and file extension for a format
+func (f ExportFormat) contentType() (string, string) {
+	switch f {
+	case ExportJSONL:
+		return "application/x-ndjson", "jsonl"
+	case ExportBibTeX:
+		return "application/x-bibtex", "bib"
+	default:
+		return "text/csv", "csv"
+	}
+}
+
+// exportMaxRowsFromEnv reads SEARCH_EXPORT_MAX_ROWS
+func exportMaxRowsFromEnv() int {
+	raw := os.Getenv("SEARCH_EXPORT_MAX_ROWS")
+	if raw == "" {
+		return defaultExportMaxRows
+	}
+	maxRows, err := strconv.Atoi(raw)
+	if err != nil || maxRows <= 0 {
+		logger.Warn(context.Background(), "invalid SEARCH_EXPORT_MAX_ROWS, using default",
+			zap.String("value", raw),
+			zap.Int("default", defaultExportMaxRows))
+		return defaultExportMaxRows
+	}
+	return maxRows
+}
+
+// ExportRow is a single exported document
+type ExportRow struct {
+	Name             string    `json:"name"`
+	DocketNumber     string    `json:"docket_number"`
+	ConversationName string    `json:"conversation_name"`
+	Authors          []string  `json:"authors"`
+	DatePublished    time.Time `json:"date_published,omitzero"`
+	FileUUID         uuid.UUID `json:"file_uuid"`
+	AttachmentUUID   uuid.UUID `json:"attachment_uuid"`
+	DownloadURL      string    `json:"download_url,omitempty"`
+}
+
+// datePublished formats the publish date as a plain date, empty when unknown
+func (row ExportRow) datePublished() string {
+	if row.DatePublished.IsZero() {
+		return ""
+	}
+	return row.DatePublished.Format(time.DateOnly)
+}
+
+// exportWriter writes export rows in one format, Close writes any trailer and flushes
+type exportWriter interface {
+	WriteRow(row ExportRow) error
+	Close() error
+}
+
+// newExportWriter creates the writer for a format and writes its header
+func newExportWriter(w io.Writer, format ExportFormat) (exportWriter, error) {
+	switch format {
+	case ExportJSONL:
+		return &jsonlExportWriter{enc: json.NewEncoder(w)}, nil
+	case ExportBibTeX:
+		return &bibtexExportWriter{w: w}, nil
+	default:
+		cw := csv.NewWriter(w)
+		err := cw.Write([]string{"name", "docket_number", "conversation_name", "authors", "date_published", "file_uuid", "attachment_uuid", "download_url"})
+		return &csvExportWriter{w: cw}, err
+	}
+}
+
+type csvExportWriter struct {
+	w *csv.Writer
+}
+
+func (c *csvExportWriter) WriteRow(row ExportRow) error {
+	return c.w.Write([]string{
+		row.Name,
+		row.DocketNumber,
+		row.ConversationName,
+		strings.Join(row.Authors, "; "),
+		row.datePublished(),
+		row.FileUUID.String(),
+		row.AttachmentUUID.String(),
+		row.DownloadURL,
+	})
+}
+
+func (c *csvExportWriter) Close() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+type jsonlExportWriter struct {
+	enc *json.Encoder
+}
+
+func (j *jsonlExportWriter) WriteRow(row ExportRow) error {
+	return j.enc.Encode(row)
+}
+
+func (j *jsonlExportWriter) Close() error {
+	return nil
+}
+
+type bibtexExportWriter struct {
+	w io.Writer
+}
+
+// bibtexEscaper escapes characters that would break a braced BibTeX value
+var bibtexEscaper = strings.NewReplacer(`\`, `\textbackslash{}`, "{", `\{`, "}", `\}`, "%", `\%`, "&", `\&`, "#", `\#`, "$", `\$`, "_", `\_`)
+
+func (b *bibtexExportWriter) WriteRow(row ExportRow) error {
+	var entry strings.Builder
+	fmt.Fprintf(&entry, "@misc{kessler_%s,\n", strings.ReplaceAll(row.AttachmentUUID.String(), "-", ""))
+	field := func(name, value string) {
+		if value != "" {
+			fmt.Fprintf(&entry, "  %s = {%s},\n", name, bibtexEscaper.Replace(value))
+		}
+	}
+	field("title", row.Name)
+	field("author", strings.Join(row.Authors, " and "))
+	if !row.DatePublished.IsZero() {
+		field("year", strconv.Itoa(row.DatePublished.Year()))
+		field("date", row.datePublished())
+	}
+	note := row.DocketNumber
+	if row.ConversationName != "" && row.ConversationName != row.DocketNumber {
+		note = strings.TrimPrefix(note+", "+row.ConversationName, ", ")
+	}
+	if note != "" {
+		field("note", "Docket "+note)
+	}
+	if row.DownloadURL != "" {
+		fmt.Fprintf(&entry, "  howpublished = {\\url{%s}},\n", row.DownloadURL)
+	}
+	field("file_uuid", row.FileUUID.String())
+	entry.WriteString("}\n\n")
+
+	_, err := io.WriteString(b.w, entry.String())
+	return err
+}
+
+func (b *bibtexExportWriter) Close() error {
+	return nil
+}
+
+// exportRowFromCard builds an export row from a hydrated document card
+func exportRowFromCard(doc DocumentCardData, hashes map[uuid.UUID]string) ExportRow {
+	row := ExportRow{
+		Name:             doc.Name,
+		DocketNumber:     doc.Conversation.ConvoNumber,
+		ConversationName: doc.Conversation.ConvoName,
+		DatePublished:    doc.DatePublished,
+		FileUUID:         doc.FileUUID,
+		AttachmentUUID:   doc.AttachmentUUID,
+	}
+	for _, author := range doc.Authors {
+		row.Authors = append(row.Authors, author.AuthorName)
+	}
+	if hash := hashes[doc.AttachmentUUID]; hash != "" {
+		row.DownloadURL = fmt.Sprintf("/public/raw_attachments/%s/raw", hash)
+	}
+	return row
+}
+
+// attachmentHashes looks up the raw file hashes of a page of attachments in one query.
+// A failed lookup only costs the download links, the rows are still exported.
+func (s *SearchService) attachmentHashes(ctx context.Context, ids []uuid.UUID) map[uuid.UUID]string {
+	if len(ids) == 0 {
+		return nil
+	}
+	rows, err := dbstore.New(s.db).AttachmentHashListByIds(ctx, ids)
+	if err != nil {
+		logger.Warn(ctx, "could not look up attachment hashes for export",
+			zap.Int("attachments", len(ids)),
+			zap.Error(err))
+		return nil
+	}
+	hashes := make(map[uuid.UUID]string, len(rows))
+	for _, row := range rows {
+		hashes[row.ID] = row.Hash
+	}
+	return hashes
+}
+
+// StreamExport walks every hit of a search by cursor and writes each document once, up to maxRows.
+// Each page is flushed before the next is fetched so large exports are never held in memory. start is
+// called once the first page has been fetched, before anything is written, so a search that fails
+// outright can still be answered with an error status.
+func (s *SearchService) StreamExport(ctx context.Context, w io.Writer, start func(), flush func(), format ExportFormat, query string, metadataFilters map[string]string, namespace string, opts SearchOptions, maxRows int) (int, error) {
+	ctx, span := serviceTracer.Start(ctx, "search-service:stream-export")
+	defer span.End()
+
+	// Cursor walking needs uncollapsed hits, segments of one attachment are deduplicated below
+	opts.Collapse = CollapseNone
+	opts.Facets = nil
+	opts.Cursor = nil
+
+	var writer exportWriter
+	seen := make(map[uuid.UUID]bool)
+	written := 0
+	pagination := PaginationParams{Page: 0, Limit: exportPageSize}
+
+	for written < maxRows {
+		response, err := s.ProcessSearch(ctx, query, metadataFilters, pagination, namespace, opts)
+		if err != nil {
+			return written, err
+		}
+		if writer == nil {
+			start()
+			if writer, err = newExportWriter(w, format); err != nil {
+				return written, err
+			}
+		}
+
+		var docs []DocumentCardData
+		var ids []uuid.UUID
+		for _, card := range response.Data {
+			doc, ok := card.(DocumentCardData)
+			if !ok || seen[doc.AttachmentUUID] || written+len(docs) >= maxRows {
+				continue
+			}
+			seen[doc.AttachmentUUID] = true
+			docs = append(docs, doc)
+			if doc.AttachmentUUID != uuid.Nil {
+				ids = append(ids, doc.AttachmentUUID)
+			}
+		}
+		hashes := s.attachmentHashes(ctx, ids)
+		for _, doc := range docs {
+			if err := writer.WriteRow(exportRowFromCard(doc, hashes)); err != nil {
+				return written, err
+			}
+			written++
+		}
+		if err := writer.Close(); err != nil {
+			return written, err
+		}
+		flush()
+
+		if response.NextCursor == "" {
+			break
+		}
+		if opts.Cursor, err = parseCursor(response.NextCursor); err != nil {
+			return written, err
+		}
+	}
+	if writer == nil {
+		return written, nil
+	}
+	return written, writer.Close()
+}
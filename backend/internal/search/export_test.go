@@ -0,0 +1,83 @@
+package search
+
+import (
+	"context"
+	"kessler/internal/fugusdk/fugutest"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestExportWriters(t *testing.T) {
+	row := ExportRow{
+		Name:             "Rate Case Filing, 50% Draft",
+		DocketNumber:     "23-E-0418",
+		ConversationName: "Electric Rates",
+		Authors:          []string{"Con Edison", "Staff"},
+		DatePublished:    time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+		FileUUID:         uuid.MustParse("11111111-1111-1111-1111-111111111111"),
+		AttachmentUUID:   uuid.MustParse("22222222-2222-2222-2222-222222222222"),
+		DownloadURL:      "/public/raw_attachments/abc/raw",
+	}
+
+	tests := []struct {
+		format ExportFormat
+		want   []string
+	}{
+		{ExportCSV, []string{"name,docket_number", `"Rate Case Filing, 50% Draft",23-E-0418,Electric Rates,Con Edison; Staff,2024-03-01`}},
+		{ExportJSONL, []string{`"docket_number":"23-E-0418"`, `"authors":["Con Edison","Staff"]`}},
+		{ExportBibTeX, []string{"@misc{kessler_22222222222222222222222222222222,", `title = {Rate Case Filing, 50\% Draft}`, "author = {Con Edison and Staff}", "year = {2024}", "note = {Docket 23-E-0418, Electric Rates}"}},
+	}
+	for _, tt := range tests {
+		var out strings.Builder
+		writer, err := newExportWriter(&out, tt.format)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.format, err)
+		}
+		if err := writer.WriteRow(row); err != nil {
+			t.Fatalf("%s: %v", tt.format, err)
+		}
+		if err := writer.Close(); err != nil {
+			t.Fatalf("%s: %v", tt.format, err)
+		}
+		for _, want := range tt.want {
+			if !strings.Contains(out.String(), want) {
+				t.Errorf("%s output missing %q:\n%s", tt.format, want, out.String())
+			}
+		}
+	}
+}
+
+func TestParseExportFormat(t *testing.T) {
+	if format, err := ParseExportFormat(""); err != nil || format != ExportCSV {
+		t.Errorf("expected csv default, got %q, %v", format, err)
+	}
+	if _, err := ParseExportFormat("xml"); err == nil {
+		t.Error("expected an error for an unsupported format")
+	}
+}
+
+// TestExportSearchFailsBeforeStreaming checks a search that fails on its first page is answered with an
+// error status rather than an empty download
+func TestExportSearchFailsBeforeStreaming(t *testing.T) {
+	server := fugutest.NewServer(t)
+	client, err := server.Client(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	server.Close()
+	handler := NewSearchHandler(&SearchService{client: client})
+
+	rec := httptest.NewRecorder()
+	handler.ExportSearch(rec, httptest.NewRequest(http.MethodGet, "/search/export?q=rate&format=jsonl", nil))
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("expected status %d, got %d", http.StatusInternalServerError, rec.Code)
+	}
+	if disposition := rec.Header().Get("Content-Disposition"); disposition != "" {
+		t.Errorf("a failed export must not be offered as a download, got %q", disposition)
+	}
+}
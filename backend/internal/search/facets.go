@@ -0,0 +1,177 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"kessler/internal/fugusdk"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// FacetName identifies a facet that can be counted over search hits
+type FacetName string
+
+const (
+	FacetDocket       FacetName = "docket"
+	FacetOrganization FacetName = "organization"
+	FacetDataType     FacetName = "data_type"
+	FacetExtension    FacetName = "extension"
+	FacetYear         FacetName = "year"
+)
+
+const (
+	// maxFacetValues caps the number of values returned for a single facet
+	maxFacetValues = 50
+	// facetScanLimit bounds the hits read to count facets. Fugu has no aggregations, so counts come from
+	// the top hits and are marked approximate when more match.
+	facetScanLimit = 400
+)
+
+// facetFilterKeys maps each facet to the metadata filter key that refines on one of its values
+var facetFilterKeys = map[FacetName]string{
+	FacetDocket:       "docket_gov_id",
+	FacetOrganization: "author_names",
+	FacetDataType:     "entity_type",
+	FacetExtension:    "file_extension",
+	FacetYear:         "date_year",
+}
+
+// SupportedFacets lists the facets accepted by the facets parameter
+var SupportedFacets = []FacetName{
+	FacetDocket,
+	FacetOrganization,
+	FacetDataType,
+	FacetExtension,
+	FacetYear,
+}
+
+// FacetValue is a single facet value and the number of matching results carrying it
+type FacetValue struct {
+	Value string `json:"value"`
+	Count int    `json:"count"`
+}
+
+// FacetCounts holds the value counts for one facet, FilterKey is the filter to send back to refine on a value
+type FacetCounts struct {
+	Name      FacetName    `json:"name"`
+	FilterKey string       `json:"filter_key"`
+	Values    []FacetValue `json:"values"`
+}
+
+// ParseFacets validates a comma separated facets parameter, an empty value requests no facets
+func ParseFacets(raw string) ([]FacetName, error) {
+	var facets []FacetName
+	seen := make(map[FacetName]bool)
+	for _, part := range strings.Split(raw, ",") {
+		name := FacetName(strings.ToLower(strings.TrimSpace(part)))
+		if name == "" || seen[name] {
+			continue
+		}
+		if _, ok := facetFilterKeys[name]; !ok {
+			return nil, fmt.Errorf("unsupported facet %q, expected one of docket, organization, data_type, extension, year", part)
+		}
+		seen[name] = true
+		facets = append(facets, name)
+	}
+	return facets, nil
+}
+
+// facetValues returns the values a hit contributes to a facet
+func facetValues(result fugusdk.FuguSearchResult, metadata fugusdk.AttachmentMetadata, name FacetName) []string {
+	switch name {
+	case FacetDocket:
+		return nonEmptyValues(metadata.DocketGovID)
+	case FacetOrganization:
+		if names := nonEmptyValues(metadata.AuthorNames...); len(names) > 0 {
+			return names
+		}
+		// Organizations are indexed with their own name rather than authors
+		organization, _ := fugusdk.DecodeResult[fugusdk.OrganizationMetadata](result)
+		return nonEmptyValues(organization.Metadata.OrganizationName)
+	case FacetDataType:
+		return nonEmptyValues(metadata.EntityType)
+	case FacetExtension:
+		return nonEmptyValues(metadata.FileExtension)
+	case FacetYear:
+		if !metadata.DateISO.IsZero() {
+			return []string{strconv.Itoa(metadata.DateISO.Year())}
+		}
+	}
+	return nil
+}
+
+// nonEmptyValues trims values and drops the blank ones
+func nonEmptyValues(values ...string) []string {
+	var kept []string
+	for _, value := range values {
+		if value = strings.TrimSpace(value); value != "" {
+			kept = append(kept, value)
+		}
+	}
+	return kept
+}
+
+// countFacets tallies facet values over hits, counting each segmented attachment once
+func countFacets(hits []fugusdk.FuguSearchResult, names []FacetName) []FacetCounts {
+	seenDocs := make(map[string]bool)
+	counts := make(map[FacetName]map[string]int, len(names))
+	for _, name := range names {
+		counts[name] = make(map[string]int)
+	}
+
+	for _, hit := range hits {
+		key := collapseKey(hit, CollapseAttachment)
+		if seenDocs[key] {
+			continue
+		}
+		seenDocs[key] = true
+
+		// Fields decode independently, one that does not fit only drops its own facet
+		typed, _ := fugusdk.DecodeResult[fugusdk.AttachmentMetadata](hit)
+		for _, name := range names {
+			for _, value := range facetValues(hit, typed.Metadata, name) {
+				counts[name][value]++
+			}
+		}
+	}
+
+	facets := make([]FacetCounts, 0, len(names))
+	for _, name := range names {
+		values := make([]FacetValue, 0, len(counts[name]))
+		for value, count := range counts[name] {
+			values = append(values, FacetValue{Value: value, Count: count})
+		}
+		sort.Slice(values, func(i, j int) bool {
+			if values[i].Count != values[j].Count {
+				return values[i].Count > values[j].Count
+			}
+			return values[i].Value < values[j].Value
+		})
+		if len(values) > maxFacetValues {
+			values = values[:maxFacetValues]
+		}
+		facets = append(facets, FacetCounts{
+			Name:      name,
+			FilterKey: facetFilterKeys[name],
+			Values:    values,
+		})
+	}
+	return facets
+}
+
+// executeFacetCounts scans the top hits matching the query and counts the requested facets.
+// The bool result is true when more hits matched than were scanned and counts are a lower bound.
+func (s *SearchService) executeFacetCounts(ctx context.Context, client *fugusdk.Client, query fugusdk.FuguSearchQuery, names []FacetName) ([]FacetCounts, bool, error) {
+	ctx, span := serviceTracer.Start(ctx, "search-service:execute-facet-counts")
+	defer span.End()
+
+	// Ordering and the cursor position do not affect counts
+	query.Sort = nil
+	query.SearchAfter = nil
+	hits, _, truncated, err := s.scanHits(ctx, client, query, facetScanLimit, nil)
+	if err != nil {
+		return nil, false, err
+	}
+	return countFacets(hits, names), truncated, nil
+}
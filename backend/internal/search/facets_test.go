@@ -0,0 +1,82 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"kessler/internal/fugusdk"
+	"kessler/internal/fugusdk/fugutest"
+	"reflect"
+	"testing"
+)
+
+func TestParseFacets(t *testing.T) {
+	facets, err := ParseFacets(" Docket, year,,docket ,organization")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []FacetName{FacetDocket, FacetYear, FacetOrganization}; !reflect.DeepEqual(facets, want) {
+		t.Errorf("ParseFacets = %v, want %v", facets, want)
+	}
+	if facets, err := ParseFacets(""); err != nil || len(facets) != 0 {
+		t.Errorf("an empty parameter requests no facets, got %v, %v", facets, err)
+	}
+	if _, err := ParseFacets("docket,color"); err == nil {
+		t.Error("expected an error for an unsupported facet")
+	}
+}
+
+func TestCountFacets(t *testing.T) {
+	hit := func(id string, metadata map[string]interface{}) fugusdk.FuguSearchResult {
+		return fugusdk.FuguSearchResult{ID: id, Metadata: metadata}
+	}
+	hits := []fugusdk.FuguSearchResult{
+		hit("a-segment-0", map[string]interface{}{"attachment_id": "a", "docket_gov_id": "22-E-0001", "author_names": []interface{}{"Con Edison", "Staff"}, "date_iso": "2023-05-01T00:00:00Z"}),
+		// A second segment of the same attachment is only counted once
+		hit("a-segment-1", map[string]interface{}{"attachment_id": "a", "docket_gov_id": "22-E-0001", "author_names": []interface{}{"Con Edison", "Staff"}, "date_iso": "2023-05-01T00:00:00Z"}),
+		hit("b", map[string]interface{}{"docket_gov_id": "22-E-0001", "organization_name": "Staff", "date_iso": "2024-01-02T00:00:00Z"}),
+		hit("c", map[string]interface{}{"docket_gov_id": " 23-G-0002 ", "author_names": []interface{}{"", "Con Edison"}, "date_iso": "20"}),
+		hit("d", nil),
+	}
+
+	facets := countFacets(hits, []FacetName{FacetDocket, FacetOrganization, FacetYear})
+	want := []FacetCounts{
+		{Name: FacetDocket, FilterKey: "docket_gov_id", Values: []FacetValue{{"22-E-0001", 2}, {"23-G-0002", 1}}},
+		{Name: FacetOrganization, FilterKey: "author_names", Values: []FacetValue{{"Con Edison", 2}, {"Staff", 2}}},
+		{Name: FacetYear, FilterKey: "date_year", Values: []FacetValue{{"2023", 1}, {"2024", 1}}},
+	}
+	if !reflect.DeepEqual(facets, want) {
+		t.Errorf("countFacets =\n%+v\nwant\n%+v", facets, want)
+	}
+
+	var many []fugusdk.FuguSearchResult
+	for i := 0; i < maxFacetValues+5; i++ {
+		many = append(many, hit(fmt.Sprintf("%d", i), map[string]interface{}{"file_extension": fmt.Sprintf("ext%02d", i)}))
+	}
+	if values := countFacets(many, []FacetName{FacetExtension})[0].Values; len(values) != maxFacetValues {
+		t.Errorf("expected values capped at %d, got %d", maxFacetValues, len(values))
+	}
+}
+
+func TestExecuteFacetCountsIsBounded(t *testing.T) {
+	ctx := context.Background()
+	server := fugutest.NewServer(t)
+	client, err := server.Client(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < facetScanLimit+50; i++ {
+		server.Seed(fugusdk.ObjectRecord{ID: fmt.Sprintf("doc-%04d", i), Text: "rate", Metadata: map[string]interface{}{"file_extension": "pdf"}})
+	}
+	s := &SearchService{client: client}
+
+	facets, truncated, err := s.executeFacetCounts(ctx, client, fugusdk.FuguSearchQuery{Query: "rate"}, []FacetName{FacetExtension})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !truncated || facets[0].Values[0].Count != facetScanLimit {
+		t.Errorf("expected %d counted hits marked approximate, got %+v, truncated %v", facetScanLimit, facets, truncated)
+	}
+	if searches := len(server.Searches()); searches != facetScanLimit/scanPageSize {
+		t.Errorf("expected %d searches, got %d", facetScanLimit/scanPageSize, searches)
+	}
+}
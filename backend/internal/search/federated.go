@@ -0,0 +1,383 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"kessler/internal/fugusdk"
+	"kessler/pkg/logger"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// ErrInvalidFederatedSearch is returned for federated search requests that cannot be run
+var ErrInvalidFederatedSearch = errors.New("invalid federated search")
+
+// BlendMode selects how results from several namespaces are merged into one list
+type BlendMode string
+
+const (
+	// BlendScore orders every hit by its score normalised within its namespace, times the namespace weight
+	BlendScore BlendMode = "score"
+	// BlendInterleave takes hits from each namespace in turn, in proportion to the weights, keeping each namespace's order
+	BlendInterleave BlendMode = "interleave"
+)
+
+const (
+	// maxFederatedNamespaces bounds how many namespaces one request fans out to
+	maxFederatedNamespaces = 8
+	// federatedFetchLimit is the most hits fetched from one namespace. The blended order is only complete
+	// that far, so it also bounds the results that can be paged through.
+	federatedFetchLimit = 100
+	// defaultFederatedTimeout applies to each namespace query separately
+	defaultFederatedTimeout = 5 * time.Second
+)
+
+// ParseBlendMode validates a blend parameter, an empty value means score
+func ParseBlendMode(raw string) (BlendMode, error) {
+	switch mode := BlendMode(strings.ToLower(strings.TrimSpace(raw))); mode {
+	case "":
+		return BlendScore, nil
+	case BlendScore, BlendInterleave:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("%w: unsupported blend %q, expected score or interleave", ErrInvalidFederatedSearch, raw)
+	}
+}
+
+// FederatedOptions selects the namespaces of a federated search and how their results are merged
+type FederatedOptions struct {
+	Namespaces []string           `json:"namespaces"`
+	Blend      BlendMode          `json:"blend"`
+	Weights    map[string]float64 `json:"weights,omitempty"`
+	// Timeout applies to each namespace query, zero uses SEARCH_FEDERATED_TIMEOUT
+	Timeout time.Duration `json:"-"`
+}
+
+// NamespaceResult reports how one namespace of a federated search went
+type NamespaceResult struct {
+	Namespace string `json:"namespace"`
+	Total     int    `json:"total"`
+	// Returned is how many of the page's hits came from this namespace, before hydration
+	Returned int    `json:"returned"`
+	Error    string `json:"error,omitempty"`
+	TimedOut bool   `json:"timed_out,omitempty"`
+}
+
+// ParseNamespaceList splits a comma separated namespace list, dropping blanks and duplicates
+func ParseNamespaceList(raw string) []string {
+	var namespaces []string
+	for _, ns := range strings.Split(raw, ",") {
+		if ns = strings.TrimSpace(ns); ns != "" && !slices.Contains(namespaces, ns) {
+			namespaces = append(namespaces, ns)
+		}
+	}
+	return namespaces
+}
+
+// ParseNamespaceWeights parses "NYPUC:2,CAPUC:0.5" into per namespace weights
+func ParseNamespaceWeights(raw string) (map[string]float64, error) {
+	weights := make(map[string]float64)
+	for _, pair := range strings.Split(raw, ",") {
+		if pair = strings.TrimSpace(pair); pair == "" {
+			continue
+		}
+		ns, rawWeight, ok := strings.Cut(pair, ":")
+		if !ok {
+			return nil, fmt.Errorf("%w: weight %q must look like namespace:weight", ErrInvalidFederatedSearch, pair)
+		}
+		weight, err := strconv.ParseFloat(strings.TrimSpace(rawWeight), 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: weight for %s is not a number", ErrInvalidFederatedSearch, ns)
+		}
+		weights[strings.TrimSpace(ns)] = weight
+	}
+	return weights, nil
+}
+
+// validate normalises the options and rejects those a federated search cannot honour
+func (fed *FederatedOptions) validate(opts SearchOptions) error {
+	namespaces := make([]string, 0, len(fed.Namespaces))
+	for _, ns := range fed.Namespaces {
+		if ns = strings.TrimSpace(ns); ns != "" && !slices.Contains(namespaces, ns) {
+			namespaces = append(namespaces, ns)
+		}
+	}
+	if len(namespaces) == 0 {
+		return fmt.Errorf("%w: at least one namespace is required", ErrInvalidFederatedSearch)
+	}
+	if len(namespaces) > maxFederatedNamespaces {
+		return fmt.Errorf("%w: at most %d namespaces can be searched together", ErrInvalidFederatedSearch, maxFederatedNamespaces)
+	}
+	sanitizer := fugusdk.NewInputSanitizer()
+	for _, ns := range namespaces {
+		if err := sanitizer.ValidateNamespace(ns); err != nil {
+			return fmt.Errorf("%w: namespace %q: %v", ErrInvalidFederatedSearch, ns, err)
+		}
+	}
+	for ns, weight := range fed.Weights {
+		if weight <= 0 {
+			return fmt.Errorf("%w: weight for %s must be positive", ErrInvalidFederatedSearch, ns)
+		}
+	}
+	fed.Namespaces = namespaces
+
+	if fed.Blend == "" {
+		fed.Blend = BlendScore
+	}
+	if fed.Timeout <= 0 {
+		fed.Timeout = federatedTimeoutFromEnv()
+	}
+
+	// Scores are only comparable after normalising, other orders and groupings do not merge across namespaces
+	if opts.Sort != "" && opts.Sort != SortRelevance {
+		return fmt.Errorf("%w: only relevance sort is supported", ErrInvalidFederatedSearch)
+	}
+	if opts.Cursor != nil {
+		return fmt.Errorf("%w: cursors are not supported, use page", ErrInvalidFederatedSearch)
+	}
+	if opts.Collapse != "" && opts.Collapse != CollapseNone {
+		return fmt.Errorf("%w: collapse is not supported", ErrInvalidFederatedSearch)
+	}
+	if len(opts.Facets) > 0 {
+		return fmt.Errorf("%w: facet counts are not supported", ErrInvalidFederatedSearch)
+	}
+	return nil
+}
+
+// validateFederatedPage rejects pages starting past the blended results
+func validateFederatedPage(pagination PaginationParams) error {
+	if pagination.Page*pagination.Limit >= federatedFetchLimit {
+		return fmt.Errorf("%w: only the first %d results can be paged through", ErrInvalidFederatedSearch, federatedFetchLimit)
+	}
+	return nil
+}
+
+func (fed FederatedOptions) weight(namespace string) float64 {
+	if weight, ok := fed.Weights[namespace]; ok {
+		return weight
+	}
+	return 1
+}
+
+// federatedTimeoutFromEnv reads SEARCH_FEDERATED_TIMEOUT as a Go duration
+func federatedTimeoutFromEnv() time.Duration {
+	raw := os.Getenv("SEARCH_FEDERATED_TIMEOUT")
+	if raw == "" {
+		return defaultFederatedTimeout
+	}
+	timeout, err := time.ParseDuration(raw)
+	if err != nil || timeout <= 0 {
+		logger.Warn(context.Background(), "invalid SEARCH_FEDERATED_TIMEOUT, using default",
+			zap.String("value", raw),
+			zap.Duration("default", defaultFederatedTimeout))
+		return defaultFederatedTimeout
+	}
+	return timeout
+}
+
+// federatedHit is a fugu hit tagged with where it came from
+type federatedHit struct {
+	result    fugusdk.FuguSearchResult
+	namespace string
+	rank      int
+	// normalized is the score relative to the best hit of the same namespace, in [0, 1]
+	normalized float64
+	blended    float64
+}
+
+// normalizeScores scales a namespace's scores by its best score, falling back to rank when fugu gave no scores
+func normalizeScores(hits []federatedHit) {
+	best := 0.0
+	for _, hit := range hits {
+		best = max(best, float64(hit.result.Score))
+	}
+	for i := range hits {
+		if best > 0 {
+			hits[i].normalized = max(float64(hits[i].result.Score), 0) / best
+		} else {
+			hits[i].normalized = 1 - float64(hits[i].rank)/float64(len(hits))
+		}
+	}
+}
+
+// blendHits merges the per namespace hit lists, each already in its namespace's order.
+// A hit found in several namespaces is kept once, where it first lands.
+func blendHits(perNamespace [][]federatedHit, fed FederatedOptions) []federatedHit {
+	var blended []federatedHit
+	switch fed.Blend {
+	case BlendInterleave:
+		blended = interleaveHits(perNamespace, fed)
+	default:
+		for i, hits := range perNamespace {
+			for _, hit := range hits {
+				hit.blended = hit.normalized * fed.weight(fed.Namespaces[i])
+				blended = append(blended, hit)
+			}
+		}
+		// Stable keeps namespace order then rank for equal scores
+		slices.SortStableFunc(blended, func(a, b federatedHit) int {
+			switch {
+			case a.blended > b.blended:
+				return -1
+			case a.blended < b.blended:
+				return 1
+			}
+			return 0
+		})
+	}
+
+	seen := make(map[string]bool, len(blended))
+	deduped := blended[:0]
+	for _, hit := range blended {
+		if !seen[hit.result.ID] {
+			seen[hit.result.ID] = true
+			deduped = append(deduped, hit)
+		}
+	}
+	return deduped
+}
+
+// interleaveHits runs a smooth weighted round robin over the namespaces, so a weight 2 namespace
+// contributes two hits for every one of a weight 1 namespace, spread evenly rather than in bursts
+func interleaveHits(perNamespace [][]federatedHit, fed FederatedOptions) []federatedHit {
+	next := make([]int, len(perNamespace))
+	current := make([]float64, len(perNamespace))
+	var blended []federatedHit
+	for {
+		total := 0.0
+		pick := -1
+		for i, hits := range perNamespace {
+			if next[i] >= len(hits) {
+				continue
+			}
+			weight := fed.weight(fed.Namespaces[i])
+			current[i] += weight
+			total += weight
+			if pick == -1 || current[i] > current[pick] {
+				pick = i
+			}
+		}
+		if pick == -1 {
+			return blended
+		}
+		current[pick] -= total
+		hit := perNamespace[pick][next[pick]]
+		hit.blended = hit.normalized * fed.weight(fed.Namespaces[pick])
+		blended = append(blended, hit)
+		next[pick]++
+	}
+}
+
+// ProcessFederatedSearch runs the same search in several namespaces concurrently and blends the results.
+// Namespaces that fail or time out are reported in the response while the others are still returned.
+func (s *SearchService) ProcessFederatedSearch(ctx context.Context, query string, metadataFilters map[string]string, pagination PaginationParams, fed FederatedOptions, opts SearchOptions) (*SearchResponse, error) {
+	ctx, span := serviceTracer.Start(ctx, "search-service:process-federated-search")
+	defer span.End()
+
+	startTime := time.Now()
+	if err := fed.validate(opts); err != nil {
+		return nil, err
+	}
+	if err := validateFederatedPage(pagination); err != nil {
+		return nil, err
+	}
+
+	parsedQuery, err := ParseQuery(query)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Info(ctx, "starting federated search",
+		zap.String("query", query),
+		zap.Strings("namespaces", fed.Namespaces),
+		zap.String("blend", string(fed.Blend)),
+		zap.Duration("namespace_timeout", fed.Timeout))
+
+	// Every namespace fetches the whole window up to the requested page so blending can reorder across it
+	fetch := PaginationParams{Page: 0, Limit: min((pagination.Page+1)*pagination.Limit, federatedFetchLimit)}
+
+	results := make([]NamespaceResult, len(fed.Namespaces))
+	perNamespace := make([][]federatedHit, len(fed.Namespaces))
+	var wg sync.WaitGroup
+	for i, namespace := range fed.Namespaces {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			nsCtx, cancel := context.WithTimeout(ctx, fed.Timeout)
+			defer cancel()
+
+			results[i].Namespace = namespace
+			searchNamespace, client := s.resolveNamespace(nsCtx, namespace)
+			fuguQuery := createFuguSearchQuery(parsedQuery.Text, buildBackendFilters(nsCtx, parsedQuery, metadataFilters, searchNamespace, opts), fetch, opts)
+			response, err := s.executeSearch(nsCtx, client, fuguQuery)
+			if err != nil {
+				results[i].Error = err.Error()
+				results[i].TimedOut = errors.Is(nsCtx.Err(), context.DeadlineExceeded)
+				logger.Warn(ctx, "federated namespace search failed",
+					zap.String("namespace", namespace),
+					zap.Bool("timed_out", results[i].TimedOut),
+					zap.Error(err))
+				return
+			}
+
+			results[i].Total = response.Total
+			hits := make([]federatedHit, len(response.Results))
+			for rank, result := range response.Results {
+				hits[rank] = federatedHit{result: result, namespace: namespace, rank: rank}
+			}
+			normalizeScores(hits)
+			perNamespace[i] = hits
+		}()
+	}
+	wg.Wait()
+
+	failed := 0
+	var errs []error
+	total := 0
+	for _, result := range results {
+		if result.Error != "" {
+			failed++
+			errs = append(errs, fmt.Errorf("%s: %s", result.Namespace, result.Error))
+		}
+		total += result.Total
+	}
+	if failed == len(results) {
+		return nil, fmt.Errorf("fugu search failed in every namespace: %w", errors.Join(errs...))
+	}
+
+	// Past the fetch limit a namespace may hold hits that would have blended in earlier
+	blended := blendHits(perNamespace, fed)
+	blended = blended[:min(len(blended), federatedFetchLimit)]
+	pageable := min(total, federatedFetchLimit)
+	start := min(pagination.Page*pagination.Limit, len(blended))
+	end := min(start+pagination.Limit, len(blended))
+	page := make([]fugusdk.FuguSearchResult, 0, end-start)
+	for _, hit := range blended[start:end] {
+		// Cards carry the blended score so it matches the order they are returned in
+		hit.result.Score = float32(hit.blended)
+		page = append(page, hit.result)
+		results[slices.Index(fed.Namespaces, hit.namespace)].Returned++
+	}
+
+	response, err := s.transformSearchResponse(ctx, &fugusdk.SanitizedResponse{Results: page, Total: pageable}, query, parsedQuery.Text, strings.Join(fed.Namespaces, ","), pagination, opts, time.Since(startTime))
+	if err != nil {
+		return nil, fmt.Errorf("failed to transform response: %w", err)
+	}
+	response.Total = pageable
+	response.TotalHits = total
+	response.NamespaceResults = results
+	response.Partial = failed > 0
+
+	logger.Info(ctx, "federated search completed",
+		zap.Int("result_count", len(response.Data)),
+		zap.Int("failed_namespaces", failed))
+
+	return response, nil
+}
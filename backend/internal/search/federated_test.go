@@ -0,0 +1,176 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"kessler/internal/fugusdk"
+	"kessler/internal/fugusdk/fugutest"
+	"net/http"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func namespaceHits(namespace string, scores ...float32) []federatedHit {
+	hits := make([]federatedHit, len(scores))
+	for i, score := range scores {
+		hits[i] = federatedHit{
+			result:    fugusdk.FuguSearchResult{ID: fmt.Sprintf("%s-%d", namespace, i), Score: score},
+			namespace: namespace,
+			rank:      i,
+		}
+	}
+	normalizeScores(hits)
+	return hits
+}
+
+func hitIDs(hits []federatedHit) []string {
+	ids := make([]string, len(hits))
+	for i, hit := range hits {
+		ids[i] = hit.result.ID
+	}
+	return ids
+}
+
+func TestBlendHits(t *testing.T) {
+	// Raw scores differ by an order of magnitude between namespaces, normalising makes them comparable
+	perNamespace := [][]federatedHit{
+		namespaceHits("NYPUC", 40, 20, 10),
+		namespaceHits("CAPUC", 4, 3),
+	}
+
+	t.Run("score", func(t *testing.T) {
+		fed := FederatedOptions{Namespaces: []string{"NYPUC", "CAPUC"}, Blend: BlendScore}
+		got := hitIDs(blendHits(perNamespace, fed))
+		want := []string{"NYPUC-0", "CAPUC-0", "CAPUC-1", "NYPUC-1", "NYPUC-2"}
+		if !slices.Equal(got, want) {
+			t.Errorf("got %v, want %v", got, want)
+		}
+	})
+
+	t.Run("weighted score", func(t *testing.T) {
+		fed := FederatedOptions{Namespaces: []string{"NYPUC", "CAPUC"}, Blend: BlendScore, Weights: map[string]float64{"NYPUC": 2}}
+		got := hitIDs(blendHits(perNamespace, fed))
+		want := []string{"NYPUC-0", "NYPUC-1", "CAPUC-0", "CAPUC-1", "NYPUC-2"}
+		if !slices.Equal(got, want) {
+			t.Errorf("got %v, want %v", got, want)
+		}
+	})
+
+	t.Run("weighted interleave", func(t *testing.T) {
+		fed := FederatedOptions{Namespaces: []string{"NYPUC", "CAPUC"}, Blend: BlendInterleave, Weights: map[string]float64{"NYPUC": 2}}
+		got := hitIDs(blendHits(perNamespace, fed))
+		want := []string{"NYPUC-0", "CAPUC-0", "NYPUC-1", "NYPUC-2", "CAPUC-1"}
+		if !slices.Equal(got, want) {
+			t.Errorf("got %v, want %v", got, want)
+		}
+	})
+
+	t.Run("duplicates kept once", func(t *testing.T) {
+		dup := namespaceHits("CAPUC", 1)
+		dup[0].result.ID = "NYPUC-0"
+		fed := FederatedOptions{Namespaces: []string{"NYPUC", "CAPUC"}, Blend: BlendScore}
+		got := blendHits([][]federatedHit{namespaceHits("NYPUC", 5), dup}, fed)
+		if len(got) != 1 || got[0].namespace != "NYPUC" {
+			t.Errorf("expected a single NYPUC hit, got %+v", got)
+		}
+	})
+}
+
+func TestFederatedOptionsValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		fed     FederatedOptions
+		opts    SearchOptions
+		wantErr bool
+	}{
+		{"valid", FederatedOptions{Namespaces: []string{"NYPUC", " CAPUC", "NYPUC"}}, SearchOptions{}, false},
+		{"no namespaces", FederatedOptions{Namespaces: []string{" "}}, SearchOptions{}, true},
+		{"bad namespace", FederatedOptions{Namespaces: []string{"NY PUC"}}, SearchOptions{}, true},
+		{"zero weight", FederatedOptions{Namespaces: []string{"NYPUC"}, Weights: map[string]float64{"NYPUC": 0}}, SearchOptions{}, true},
+		{"date sort", FederatedOptions{Namespaces: []string{"NYPUC"}}, SearchOptions{Sort: SortDatePublishedDesc}, true},
+		{"collapse", FederatedOptions{Namespaces: []string{"NYPUC"}}, SearchOptions{Collapse: CollapseFile}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.fed.validate(tt.opts)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if err != nil && !errors.Is(err, ErrInvalidFederatedSearch) {
+				t.Errorf("expected ErrInvalidFederatedSearch, got %v", err)
+			}
+			if err == nil && !slices.Equal(tt.fed.Namespaces, []string{"NYPUC", "CAPUC"}) {
+				t.Errorf("namespaces not normalised: %v", tt.fed.Namespaces)
+			}
+		})
+	}
+}
+
+// federatedServer seeds count matching attachments in each namespace
+func federatedServer(t *testing.T, count int, namespaces ...string) (*fugutest.Server, *SearchService) {
+	t.Helper()
+	server := fugutest.NewServer(t)
+	client, err := server.Client(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, namespace := range namespaces {
+		for i := 0; i < count; i++ {
+			server.Seed(fugusdk.ObjectRecord{ID: uuid.NewString(), Text: "rate case", Namespace: namespace})
+		}
+	}
+	return server, &SearchService{client: client}
+}
+
+func TestProcessFederatedSearchPartialFailure(t *testing.T) {
+	server, s := federatedServer(t, 3, "NYPUC", "CAPUC", "MAPUC")
+	server.FailNamespace("CAPUC", http.StatusInternalServerError)
+	server.StallNamespace("MAPUC")
+	fed := FederatedOptions{Namespaces: []string{"NYPUC", "CAPUC", "MAPUC"}, Timeout: 200 * time.Millisecond}
+
+	response, err := s.ProcessFederatedSearch(context.Background(), "rate", nil, PaginationParams{Limit: 10}, fed, SearchOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !response.Partial || len(response.Data) != 3 || response.Total != 3 {
+		t.Errorf("expected the 3 NYPUC hits in a partial response, got %d of %d, partial %v", len(response.Data), response.Total, response.Partial)
+	}
+	results := response.NamespaceResults
+	if results[0].Error != "" || results[0].Returned != 3 {
+		t.Errorf("NYPUC answered, got %+v", results[0])
+	}
+	if results[1].Error == "" || results[1].TimedOut {
+		t.Errorf("CAPUC failed without timing out, got %+v", results[1])
+	}
+	if results[2].Error == "" || !results[2].TimedOut {
+		t.Errorf("MAPUC timed out, got %+v", results[2])
+	}
+
+	server.FailNamespace("NYPUC", http.StatusInternalServerError)
+	if _, err := s.ProcessFederatedSearch(context.Background(), "rate", nil, PaginationParams{Limit: 10}, fed, SearchOptions{}); err == nil {
+		t.Error("expected an error when every namespace fails")
+	}
+}
+
+func TestProcessFederatedSearchPagesWithinFetchLimit(t *testing.T) {
+	_, s := federatedServer(t, 80, "NYPUC", "CAPUC")
+	fed := FederatedOptions{Namespaces: []string{"NYPUC", "CAPUC"}}
+
+	last := PaginationParams{Page: federatedFetchLimit/10 - 1, Limit: 10}
+	response, err := s.ProcessFederatedSearch(context.Background(), "rate", nil, last, fed, SearchOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(response.Data) != 10 || response.Total != federatedFetchLimit || response.TotalHits != 160 {
+		t.Errorf("expected a full last page of %d pageable out of 160 hits, got %d of %d (%d)", federatedFetchLimit, len(response.Data), response.Total, response.TotalHits)
+	}
+
+	past := PaginationParams{Page: federatedFetchLimit / 10, Limit: 10}
+	if _, err := s.ProcessFederatedSearch(context.Background(), "rate", nil, past, fed, SearchOptions{}); !errors.Is(err, ErrInvalidFederatedSearch) {
+		t.Errorf("expected pages past the fetch limit rejected, got %v", err)
+	}
+}
@@ -25,13 +25,13 @@ const (
 
 // Service handles filter operations
 type Service struct {
-	fuguServerURL string
-	cacheCtrl     cache.CacheController
-	cacheEnabled  bool
+	client       *fugusdk.Client
+	cacheCtrl    cache.CacheController
+	cacheEnabled bool
 }
 
-// NewService creates a new filter service
-func NewService(fuguServerURL string) *Service {
+// NewService creates a new filter service backed by the shared fugu client
+func NewService(client *fugusdk.Client) *Service {
 	cacheCtrl, err := cache.NewCacheController()
 	cacheEnabled := err == nil
 
@@ -40,9 +40,9 @@ func NewService(fuguServerURL string) *Service {
 	}
 
 	return &Service{
-		fuguServerURL: fuguServerURL,
-		cacheCtrl:     cacheCtrl,
-		cacheEnabled:  cacheEnabled,
+		client:       client,
+		cacheCtrl:    cacheCtrl,
+		cacheEnabled: cacheEnabled,
 	}
 }
 
@@ -65,12 +65,7 @@ func (s *Service) GetAllFilters(ctx context.Context) ([]fugusdk.Filter, error) {
 	// Fetch from Fugu
 	logger.Info(ctx, "fetching all filters from fugu backend")
 
-	client, err := fugusdk.NewClient(ctx, s.fuguServerURL)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create fugu client: %w", err)
-	}
-
-	filters, err := client.GetAllFilters(ctx)
+	filters, err := s.client.GetAllFilters(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get filters from fugu: %w", err)
 	}
@@ -110,12 +105,7 @@ func (s *Service) GetNamespaceFilters(ctx context.Context, namespace string) (ma
 	// Fetch from Fugu
 	logger.Info(ctx, "fetching namespace filters from fugu backend", zap.String("namespace", namespace))
 
-	client, err := fugusdk.NewClient(ctx, s.fuguServerURL)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create fugu client: %w", err)
-	}
-
-	response, err := client.GetNamespaceFilters(ctx, namespace)
+	response, err := s.client.GetNamespaceFilters(ctx, namespace)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get namespace filters from fugu: %w", err)
 	}
@@ -177,12 +167,7 @@ func (s *Service) GetFilterValues(ctx context.Context, filterPath string) ([]str
 	// Fetch from Fugu
 	logger.Info(ctx, "fetching filter values from fugu backend", zap.String("filter_path", filterPath))
 
-	client, err := fugusdk.NewClient(ctx, s.fuguServerURL)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create fugu client: %w", err)
-	}
-
-	response, err := client.GetFilterValues(ctx, filterPath)
+	response, err := s.client.GetFilterValues(ctx, filterPath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get filter values from fugu: %w", err)
 	}
@@ -4,8 +4,8 @@ package search
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"kessler/internal/dbstore"
 	"kessler/internal/fugusdk"
 	"kessler/internal/search/filter"
 	"kessler/pkg/logger"
@@ -14,6 +14,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/gorilla/mux"
 	"go.opentelemetry.io/otel"
 	"go.uber.org/zap"
@@ -21,22 +22,15 @@ import (
 
 var tracer = otel.Tracer("search-service")
 
-// RegisterSearchRoutes registers all search-related routes including filter configuration
-func RegisterSearchRoutes(router *mux.Router, db dbstore.DBTX) error {
-	fuguServerURL := "http://fugudb:3301"
-
-	// Create filter service and handler
-	filterService := filter.NewService(fuguServerURL)
-	filterHandler := filter.NewHandler(filterService)
-
-	// Create search service and handler with database
-	service, err := NewSearchService(fuguServerURL, filterService, db)
-	if err != nil {
-		return fmt.Errorf("failed to create search service: %w", err)
-	}
-
+// RegisterSearchRoutes registers all search-related routes including filter configuration. The service's
+// background work, such as re-running saved searches, is started separately by the server
+func RegisterSearchRoutes(router *mux.Router, service *SearchService) {
+	filterHandler := filter.NewHandler(service.filterService)
 	handler := NewSearchHandler(service)
 
+	// Start harvesting the spelling dictionary so early zero-hit searches can already be corrected
+	service.spelling.dictionary(context.Background())
+
 	fmt.Println("🔧 Registering search routes...")
 
 	// Main search endpoints
@@ -48,6 +42,23 @@ func RegisterSearchRoutes(router *mux.Router, db dbstore.DBTX) error {
 	router.HandleFunc("/organizations", handler.SearchOrganizations).Methods(http.MethodGet, http.MethodPost)
 	router.HandleFunc("/all", handler.SearchAll).Methods(http.MethodGet, http.MethodPost)
 
+	// "More like this" for an attachment or file
+	router.HandleFunc("/similar/{id}", handler.SearchSimilar).Methods(http.MethodGet)
+
+	// Streamed exports of every hit for a query
+	router.HandleFunc("/export", handler.ExportSearch).Methods(http.MethodGet)
+
+	// Saved searches and their new-result feeds
+	router.HandleFunc("/saved", handler.ListSavedSearches).Methods(http.MethodGet)
+	router.HandleFunc("/saved", handler.CreateSavedSearch).Methods(http.MethodPost)
+	router.HandleFunc("/saved/{id}", handler.GetSavedSearch).Methods(http.MethodGet)
+	router.HandleFunc("/saved/{id}", handler.UpdateSavedSearch).Methods(http.MethodPut)
+	router.HandleFunc("/saved/{id}", handler.DeleteSavedSearch).Methods(http.MethodDelete)
+	router.HandleFunc("/saved/{id}/feed", handler.GetSavedSearchFeed).Methods(http.MethodGet)
+
+	// Result clicks for search analytics
+	router.HandleFunc("/click", handler.RecordClick).Methods(http.MethodPost)
+
 	// Search info and health
 	router.HandleFunc("/info", handler.GetSearchInfo).Methods(http.MethodGet)
 	router.HandleFunc("/health", handler.HealthCheck).Methods(http.MethodGet)
@@ -60,7 +71,6 @@ func RegisterSearchRoutes(router *mux.Router, db dbstore.DBTX) error {
 	router.HandleFunc("/filters/invalidate", filterHandler.InvalidateCache).Methods(http.MethodPost)
 
 	fmt.Println("✅ Search and filter routes registered successfully")
-	return nil
 }
 
 // MarshalJSON for SearchResponse to handle the interface type
@@ -159,20 +169,30 @@ func (h *SearchServiceHandler) Search(w http.ResponseWriter, r *http.Request) {
 		Limit: searchReq.PerPage,
 	}
 
+	opts, err := searchOptionsFromRequest(searchReq)
+	if err != nil {
+		logger.Error(ctx, "invalid search options in search request", zap.Error(err))
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	logger.Info(ctx, "processing POST search request",
 		zap.String("query", searchReq.Query),
 		zap.String("namespace", searchReq.Namespace),
 		zap.Int("page", pagination.Page),
 		zap.Int("limit", pagination.Limit),
+		zap.String("sort", string(opts.Sort)),
 		zap.Int("filter_count", len(searchReq.Filters)))
 
 	// Process the search
-	response, err := h.service.ProcessSearch(ctx, searchReq.Query, searchReq.Filters, pagination, searchReq.Namespace)
+	started := time.Now()
+	response, err := h.service.ProcessSearch(ctx, searchReq.Query, searchReq.Filters, pagination, searchReq.Namespace, opts)
 	if err != nil {
 		logger.Error(ctx, "search processing failed", zap.Error(err))
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		h.respondSearchError(w, err)
 		return
 	}
+	h.recordSearch(ctx, response, searchRecordFor(response, searchReq.Query, searchReq.Filters, searchReq.Namespace, pagination, opts, time.Since(started)))
 
 	// Return response
 	w.Header().Set("Content-Type", "application/json")
@@ -213,20 +233,30 @@ func (h *SearchServiceHandler) SearchTextGet(w http.ResponseWriter, r *http.Requ
 	logger.Info(ctx, "extracting filters from query params")
 	filters := h.extractFilters(r)
 
+	opts, err := h.extractSearchOptions(r)
+	if err != nil {
+		logger.Error(ctx, "invalid search options in query params", zap.Error(err))
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	logger.Info(ctx, "processing GET search request",
 		zap.String("query", query),
 		zap.String("namespace", namespace),
 		zap.Int("page", pagination.Page),
 		zap.Int("limit", pagination.Limit),
+		zap.String("sort", string(opts.Sort)),
 		zap.Int("filter_count", len(filters)))
 
 	// Process the search
-	response, err := h.service.ProcessSearch(ctx, query, filters, pagination, namespace)
+	started := time.Now()
+	response, err := h.service.ProcessSearch(ctx, query, filters, pagination, namespace, opts)
 	if err != nil {
 		logger.Error(ctx, "search processing failed", zap.Error(err))
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		h.respondSearchError(w, err)
 		return
 	}
+	h.recordSearch(ctx, response, searchRecordFor(response, query, filters, namespace, pagination, opts, time.Since(started)))
 
 	// Return response
 	w.Header().Set("Content-Type", "application/json")
@@ -240,6 +270,109 @@ func (h *SearchServiceHandler) SearchTextGet(w http.ResponseWriter, r *http.Requ
 		zap.Int("result_count", len(response.Data)))
 }
 
+// SearchSimilar handles GET /search/similar/{id}, documents in other dockets resembling an attachment or file
+func (h *SearchServiceHandler) SearchSimilar(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	ctx, span := tracer.Start(ctx, "search-api:search-similar")
+	defer span.End()
+
+	rawID := mux.Vars(r)["id"]
+	id, err := uuid.Parse(rawID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error parsing uuid %v: %v", rawID, err), http.StatusBadRequest)
+		return
+	}
+	pagination := h.extractPagination(r)
+	sameDocket := r.URL.Query().Get("same_docket") == "true"
+
+	response, err := h.service.ProcessSimilarSearch(ctx, id, pagination, sameDocket)
+	if err != nil {
+		logger.Error(ctx, "similar search failed", zap.String("id", id.String()), zap.Error(err))
+		if errors.Is(err, ErrSimilarSourceNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		logger.Error(ctx, "failed to encode similar search response", zap.Error(err))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+}
+
+// ExportSearch handles GET /search/export, streaming every hit for a query as csv, jsonl or bibtex citations.
+// The number of rows is capped by SEARCH_EXPORT_MAX_ROWS, ?max_rows= can only lower it.
+func (h *SearchServiceHandler) ExportSearch(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	ctx, span := tracer.Start(ctx, "search-api:export")
+	defer span.End()
+
+	query := r.URL.Query().Get("q")
+	namespace := r.URL.Query().Get("namespace")
+	filters := h.extractFilters(r)
+
+	format, err := ParseExportFormat(r.URL.Query().Get("format"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	opts, err := h.extractSearchOptions(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	maxRows := exportMaxRowsFromEnv()
+	if raw := r.URL.Query().Get("max_rows"); raw != "" {
+		requested, err := strconv.Atoi(raw)
+		if err != nil || requested <= 0 {
+			http.Error(w, "max_rows must be a positive integer", http.StatusBadRequest)
+			return
+		}
+		maxRows = min(maxRows, requested)
+	}
+	// Query errors must surface before the response starts streaming
+	if _, err := ParseQuery(query); err != nil {
+		h.respondSearchError(w, err)
+		return
+	}
+
+	contentType, extension := format.contentType()
+	started := false
+	start := func() {
+		started = true
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="search-export.%s"`, extension))
+		w.WriteHeader(http.StatusOK)
+	}
+	flush := func() {
+		if flusher, ok := w.(http.Flusher); ok {
+			flusher.Flush()
+		}
+	}
+
+	logger.Info(ctx, "streaming search export",
+		zap.String("query", query),
+		zap.String("format", string(format)),
+		zap.Int("max_rows", maxRows))
+
+	written, err := h.service.StreamExport(ctx, w, start, flush, format, query, filters, namespace, opts, maxRows)
+	if err != nil && !started {
+		logger.Error(ctx, "search export failed before streaming", zap.Error(err))
+		h.respondSearchError(w, err)
+		return
+	}
+	if err != nil {
+		// The status line has already been sent, the truncated body is all that can signal the failure
+		logger.Error(ctx, "search export failed", zap.Int("rows_written", written), zap.Error(err))
+		return
+	}
+	logger.Info(ctx, "search export completed", zap.Int("rows_written", written))
+}
+
 // SearchConversations handles search requests specifically for conversations namespace
 func (h *SearchServiceHandler) SearchConversations(w http.ResponseWriter, r *http.Request) {
 	h.handleNamespaceSearch(w, r, "conversations")
@@ -250,7 +383,8 @@ func (h *SearchServiceHandler) SearchOrganizations(w http.ResponseWriter, r *htt
 	h.handleNamespaceSearch(w, r, "organizations")
 }
 
-// SearchAll handles search requests across all namespaces
+// SearchAll handles search requests across all namespaces. Given a namespaces list it runs a federated
+// search instead, querying each namespace separately and blending the results.
 func (h *SearchServiceHandler) SearchAll(w http.ResponseWriter, r *http.Request) {
 	h.handleNamespaceSearch(w, r, "")
 }
@@ -264,6 +398,8 @@ func (h *SearchServiceHandler) handleNamespaceSearch(w http.ResponseWriter, r *h
 	var query string
 	var metadataFilters map[string]string
 	var pagination PaginationParams
+	var opts SearchOptions
+	var fed FederatedOptions
 
 	if r.Method == http.MethodPost {
 		// Handle POST request
@@ -283,11 +419,44 @@ func (h *SearchServiceHandler) handleNamespaceSearch(w http.ResponseWriter, r *h
 		if pagination.Limit <= 0 {
 			pagination.Limit = 20
 		}
+
+		var err error
+		opts, err = searchOptionsFromRequest(searchReq)
+		if err != nil {
+			logger.Error(ctx, "invalid search options in search request", zap.Error(err))
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		fed.Namespaces = searchReq.Namespaces
+		fed.Weights = searchReq.NamespaceWeights
+		fed.Blend, err = ParseBlendMode(searchReq.Blend)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 	} else {
 		// Handle GET request
 		query = r.URL.Query().Get("q")
 		pagination = h.extractPagination(r)
 		metadataFilters = h.extractFilters(r)
+
+		var err error
+		opts, err = h.extractSearchOptions(r)
+		if err != nil {
+			logger.Error(ctx, "invalid search options in query params", zap.Error(err))
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		fed.Namespaces = ParseNamespaceList(r.URL.Query().Get("namespaces"))
+		if fed.Weights, err = ParseNamespaceWeights(r.URL.Query().Get("weights")); err == nil {
+			fed.Blend, err = ParseBlendMode(r.URL.Query().Get("blend"))
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 	}
 
 	if query == "" {
@@ -302,13 +471,23 @@ func (h *SearchServiceHandler) handleNamespaceSearch(w http.ResponseWriter, r *h
 		zap.Int("page", pagination.Page),
 		zap.Int("limit", pagination.Limit))
 
-	// Process the search with namespace
-	response, err := h.service.ProcessSearch(ctx, query, metadataFilters, pagination, namespace)
+	// Process the search with namespace, federated over the requested namespaces on the unscoped endpoint
+	var response *SearchResponse
+	var err error
+	started := time.Now()
+	loggedNamespace := namespace
+	if namespace == "" && len(fed.Namespaces) > 0 {
+		loggedNamespace = strings.Join(fed.Namespaces, ",")
+		response, err = h.service.ProcessFederatedSearch(ctx, query, metadataFilters, pagination, fed, opts)
+	} else {
+		response, err = h.service.ProcessSearch(ctx, query, metadataFilters, pagination, namespace, opts)
+	}
 	if err != nil {
 		logger.Error(ctx, "namespace search processing failed", zap.Error(err))
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		h.respondSearchError(w, err)
 		return
 	}
+	h.recordSearch(ctx, response, searchRecordFor(response, query, metadataFilters, loggedNamespace, pagination, opts, time.Since(started)))
 
 	// Return response
 	w.Header().Set("Content-Type", "application/json")
@@ -323,6 +502,12 @@ func (h *SearchServiceHandler) handleNamespaceSearch(w http.ResponseWriter, r *h
 		zap.Int("result_count", len(response.Data)))
 }
 
+// AvailableFiltersResponse is the fugu filter listing plus the range filters search accepts
+type AvailableFiltersResponse struct {
+	*fugusdk.SanitizedResponse
+	RangeFilters []RangeFilterInfo `json:"range_filters"`
+}
+
 // GetAvailableFilters returns legacy filter format for backwards compatibility
 func (h *SearchServiceHandler) GetAvailableFilters(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
@@ -331,18 +516,16 @@ func (h *SearchServiceHandler) GetAvailableFilters(w http.ResponseWriter, r *htt
 
 	logger.Info(ctx, "get available filters request received")
 
-	client, err := fugusdk.NewClient(ctx, h.service.fuguServerURL)
+	filters, err := h.service.client.ListFilters(ctx)
 	if err != nil {
-		logger.Error(ctx, "failed to create fugu client", zap.Error(err))
+		logger.Error(ctx, "failed to get filters from fugu", zap.Error(err))
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
 
-	response, err := client.ListFilters(ctx)
-	if err != nil {
-		logger.Error(ctx, "failed to get filters from fugu", zap.Error(err))
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
-		return
+	response := AvailableFiltersResponse{
+		SanitizedResponse: filters,
+		RangeFilters:      SupportedRangeFilters,
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -385,6 +568,51 @@ func (h *SearchServiceHandler) extractPagination(r *http.Request) PaginationPara
 	}
 }
 
+// extractSearchOptions extracts result shaping parameters from query string
+func (h *SearchServiceHandler) extractSearchOptions(r *http.Request) (SearchOptions, error) {
+	sortOption, err := ParseSortOption(r.URL.Query().Get("sort"))
+	if err != nil {
+		return SearchOptions{}, err
+	}
+
+	dateFrom, err := parseDateParam("date_from", r.URL.Query().Get("date_from"))
+	if err != nil {
+		return SearchOptions{}, err
+	}
+	dateTo, err := parseDateParam("date_to", r.URL.Query().Get("date_to"))
+	if err != nil {
+		return SearchOptions{}, err
+	}
+	if err := validateDateRange(dateFrom, dateTo); err != nil {
+		return SearchOptions{}, err
+	}
+
+	collapse, err := ParseCollapseMode(r.URL.Query().Get("collapse"))
+	if err != nil {
+		return SearchOptions{}, err
+	}
+
+	// facets may be comma separated or repeated
+	facets, err := ParseFacets(strings.Join(r.URL.Query()["facets"], ","))
+	if err != nil {
+		return SearchOptions{}, err
+	}
+
+	cursor, err := parseSearchCursor(r.URL.Query().Get("cursor"), collapse)
+	if err != nil {
+		return SearchOptions{}, err
+	}
+
+	return SearchOptions{
+		Sort:     sortOption,
+		DateFrom: dateFrom,
+		DateTo:   dateTo,
+		Collapse: collapse,
+		Facets:   facets,
+		Cursor:   cursor,
+	}, nil
+}
+
 // extractFilters extracts filter parameters from query string
 func (h *SearchServiceHandler) extractFilters(r *http.Request) map[string]string {
 	filters := make(map[string]string)
@@ -411,13 +639,7 @@ func (h *SearchServiceHandler) HealthCheck(w http.ResponseWriter, r *http.Reques
 
 	logger.Info(ctx, "search health request received")
 
-	// Create fugu client and test connection
-	client, err := fugusdk.NewClient(ctx, h.service.fuguServerURL)
-	if err != nil {
-		logger.Error(ctx, "failed to create fugu client for health check", zap.Error(err))
-		h.respondHealthError(w, "Failed to create fugu client", err.Error())
-		return
-	}
+	client := h.service.client
 
 	// Test fugu backend health
 	healthCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
@@ -425,30 +647,53 @@ func (h *SearchServiceHandler) HealthCheck(w http.ResponseWriter, r *http.Reques
 
 	if err := client.Health(healthCtx); err != nil {
 		logger.Error(ctx, "fugu server health check failed", zap.Error(err))
-		h.respondHealthError(w, "Fugu backend unavailable", err.Error())
+		h.respondHealthError(w, "Fugu backend unavailable", err.Error(), client.CircuitBreaker().Snapshot())
 		return
 	}
 
 	// All checks passed
-	h.respondHealthSuccess(w)
+	h.respondHealthSuccess(w, client.CircuitBreaker().Snapshot())
 	logger.Info(ctx, "search health check completed successfully")
 }
 
+// respondSearchError maps a ProcessSearch error to a response, problems with the request are a 400 and
+// sorts or date ranges the Fugu server cannot run are a 501
+func (h *SearchServiceHandler) respondSearchError(w http.ResponseWriter, err error) {
+	var parseErr *QueryParseError
+	switch {
+	case errors.As(err, &parseErr):
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"error":    "invalid query",
+			"message":  parseErr.Message,
+			"position": parseErr.Position,
+		})
+	case errors.Is(err, ErrInvalidCursor), errors.Is(err, ErrInvalidFederatedSearch):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, fugusdk.ErrUnsupported):
+		http.Error(w, err.Error(), http.StatusNotImplemented)
+	default:
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
+
 // respondHealthError responds with an unhealthy status
-func (h *SearchServiceHandler) respondHealthError(w http.ResponseWriter, errorMsg, details string) {
+func (h *SearchServiceHandler) respondHealthError(w http.ResponseWriter, errorMsg, details string, breaker fugusdk.BreakerSnapshot) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusServiceUnavailable)
 	json.NewEncoder(w).Encode(map[string]interface{}{
-		"status":    "unhealthy",
-		"error":     errorMsg,
-		"details":   details,
-		"timestamp": time.Now().Format(time.RFC3339),
-		"service":   "search",
+		"status":          "unhealthy",
+		"error":           errorMsg,
+		"details":         details,
+		"timestamp":       time.Now().Format(time.RFC3339),
+		"service":         "search",
+		"circuit_breaker": breaker,
 	})
 }
 
 // respondHealthSuccess responds with a healthy status and capabilities
-func (h *SearchServiceHandler) respondHealthSuccess(w http.ResponseWriter) {
+func (h *SearchServiceHandler) respondHealthSuccess(w http.ResponseWriter, breaker fugusdk.BreakerSnapshot) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 
@@ -458,9 +703,10 @@ func (h *SearchServiceHandler) respondHealthSuccess(w http.ResponseWriter) {
 		"service":   "search",
 		"version":   "1.1.0",
 		"backend": map[string]interface{}{
-			"name":   "fugu",
-			"url":    h.service.fuguServerURL,
-			"status": "healthy",
+			"name":            "fugu",
+			"url":             h.service.client.BaseURL(),
+			"status":          "healthy",
+			"circuit_breaker": breaker,
 		},
 		"capabilities": map[string]bool{
 			"search":          true,
@@ -489,6 +735,7 @@ func (h *SearchServiceHandler) respondHealthSuccess(w http.ResponseWriter) {
 			"namespace filters (conversations, organizations)",
 			"boolean filters (is_person=true)",
 			"numeric filters (total_documents=5)",
+			"date range filters (date_from, date_to as RFC3339)",
 		},
 	}
 
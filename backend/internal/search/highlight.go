@@ -0,0 +1,183 @@
+package search
+
+import (
+	"kessler/internal/fugusdk"
+	"slices"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+const (
+	// highlightContext is the number of characters kept on each side of a match
+	highlightContext = 80
+	// maxHighlightsPerCard caps the fragments returned for a single card
+	maxHighlightsPerCard = 3
+	// minHighlightTermLength skips very short terms that match almost everywhere
+	minHighlightTermLength = 2
+)
+
+// HighlightMatch is a matched term as character offsets into the full attachment text
+type HighlightMatch struct {
+	Start int `json:"start"`
+	End   int `json:"end"`
+}
+
+// Highlight is a snippet of attachment text around one or more query matches.
+// All offsets count characters (unicode code points) from the start of the full
+// attachment text, including for segmented attachments.
+type Highlight struct {
+	Fragment string           `json:"fragment"`
+	Start    int              `json:"start"`
+	End      int              `json:"end"`
+	Matches  []HighlightMatch `json:"matches"`
+}
+
+// highlightStopTerms are query syntax words that should never be highlighted
+var highlightStopTerms = map[string]bool{
+	"and": true,
+	"or":  true,
+	"not": true,
+}
+
+// highlightTerms breaks a query into lower cased terms worth highlighting
+func highlightTerms(query string) []string {
+	seen := make(map[string]bool)
+	var terms []string
+	for _, token := range strings.Fields(query) {
+		// Field prefixes like docket:123 only highlight the value
+		if idx := strings.LastIndex(token, ":"); idx >= 0 {
+			token = token[idx+1:]
+		}
+		words := strings.FieldsFunc(token, func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		})
+		for _, word := range words {
+			word = strings.ToLower(word)
+			if len([]rune(word)) < minHighlightTermLength || highlightStopTerms[word] || seen[word] {
+				continue
+			}
+			seen[word] = true
+			terms = append(terms, word)
+		}
+	}
+	return terms
+}
+
+// segmentOffset returns the character offset of a fugu result in its full attachment text.
+// The second value is false for later segments indexed before offsets were recorded, those read as 0.
+func segmentOffset(result fugusdk.FuguSearchResult) (int, bool) {
+	typed, _ := fugusdk.DecodeResult[fugusdk.AttachmentMetadata](result)
+	if typed.Err != nil && slices.Contains(typed.Err.Fields(), "segment_offset") {
+		return 0, false
+	}
+	metadata := typed.Metadata
+	if metadata.IsSegmented && metadata.SegmentIndex > 0 && metadata.SegmentOffset == 0 {
+		return 0, false
+	}
+	return metadata.SegmentOffset, true
+}
+
+// buildHighlights finds query terms in a result's text and returns fragments around them
+func buildHighlights(result fugusdk.FuguSearchResult, query string) []Highlight {
+	terms := highlightTerms(query)
+	if len(terms) == 0 || result.Text == "" {
+		return nil
+	}
+	offset, ok := segmentOffset(result)
+	if !ok {
+		return nil
+	}
+
+	text := []rune(result.Text)
+	lower := []rune(strings.ToLower(result.Text))
+	if len(lower) != len(text) {
+		// Lower casing changed the rune count, fall back to matching on the original text
+		lower = text
+	}
+
+	matches := findTermMatches(lower, terms)
+	if len(matches) == 0 {
+		return nil
+	}
+
+	var highlights []Highlight
+	for _, match := range matches {
+		start := max(match.Start-highlightContext, 0)
+		end := min(match.End+highlightContext, len(text))
+
+		// Merge matches whose context windows overlap into one fragment
+		if n := len(highlights); n > 0 && start <= highlights[n-1].End-offset {
+			last := &highlights[n-1]
+			last.End = end + offset
+			last.Matches = append(last.Matches, HighlightMatch{Start: match.Start + offset, End: match.End + offset})
+			continue
+		}
+		if len(highlights) == maxHighlightsPerCard {
+			break
+		}
+		highlights = append(highlights, Highlight{
+			Start:   start + offset,
+			End:     end + offset,
+			Matches: []HighlightMatch{{Start: match.Start + offset, End: match.End + offset}},
+		})
+	}
+
+	for i := range highlights {
+		highlights[i].Fragment = string(text[highlights[i].Start-offset : highlights[i].End-offset])
+	}
+	return highlights
+}
+
+// findTermMatches returns the non-overlapping positions of every term in text, in order
+func findTermMatches(text []rune, terms []string) []HighlightMatch {
+	var matches []HighlightMatch
+	for _, term := range terms {
+		needle := []rune(term)
+		for i := 0; i+len(needle) <= len(text); i++ {
+			if !runesHavePrefix(text[i:], needle) || !isWordBoundary(text, i-1) {
+				continue
+			}
+			matches = append(matches, HighlightMatch{Start: i, End: i + len(needle)})
+			i += len(needle) - 1
+		}
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].Start == matches[j].Start {
+			return matches[i].End > matches[j].End
+		}
+		return matches[i].Start < matches[j].Start
+	})
+
+	// Drop matches nested inside an earlier, longer one
+	deduped := matches[:0]
+	for _, match := range matches {
+		if n := len(deduped); n > 0 && match.Start < deduped[n-1].End {
+			continue
+		}
+		deduped = append(deduped, match)
+	}
+	return deduped
+}
+
+func runesHavePrefix(text, prefix []rune) bool {
+	if len(prefix) > len(text) {
+		return false
+	}
+	for i, r := range prefix {
+		if text[i] != r {
+			return false
+		}
+	}
+	return true
+}
+
+// isWordBoundary reports whether the rune at idx does not continue a word
+func isWordBoundary(text []rune, idx int) bool {
+	if idx < 0 || idx >= len(text) {
+		return true
+	}
+	r := text[idx]
+	return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+}
@@ -0,0 +1,70 @@
+package search
+
+import (
+	"kessler/internal/fugusdk"
+	"testing"
+)
+
+func TestBuildHighlightsSegmentOffset(t *testing.T) {
+	result := fugusdk.FuguSearchResult{
+		ID:   "00000000-0000-0000-0000-000000000000-segment-2",
+		Text: "The Rate Case schedule was amended by Con Edison.",
+		Metadata: map[string]interface{}{
+			"is_segmented":   true,
+			"segment_offset": float64(1000),
+		},
+	}
+
+	highlights := buildHighlights(result, `docket:"rate case" AND edison`)
+	if len(highlights) != 1 {
+		t.Fatalf("expected one merged highlight, got %d", len(highlights))
+	}
+
+	h := highlights[0]
+	if h.Start != 1000 || h.End != 1000+len([]rune(result.Text)) {
+		t.Errorf("unexpected fragment bounds %d-%d", h.Start, h.End)
+	}
+	want := []HighlightMatch{{Start: 1004, End: 1008}, {Start: 1009, End: 1013}, {Start: 1042, End: 1048}}
+	if len(h.Matches) != len(want) {
+		t.Fatalf("expected %d matches, got %v", len(want), h.Matches)
+	}
+	for i, match := range want {
+		if h.Matches[i] != match {
+			t.Errorf("match %d: expected %v, got %v", i, match, h.Matches[i])
+		}
+	}
+}
+
+func TestBuildHighlightsMissingSegmentOffset(t *testing.T) {
+	result := fugusdk.FuguSearchResult{
+		Text:     "rate case",
+		Metadata: map[string]interface{}{"is_segmented": true, "segment_index": float64(2)},
+	}
+	if highlights := buildHighlights(result, "rate"); highlights != nil {
+		t.Errorf("expected no highlights without a segment offset, got %v", highlights)
+	}
+	// The first segment starts the text, so its offset is known without being recorded
+	result.Metadata["segment_index"] = float64(0)
+	if highlights := buildHighlights(result, "rate"); len(highlights) != 1 || highlights[0].Start != 0 {
+		t.Errorf("expected a highlight at the start of the first segment, got %v", highlights)
+	}
+}
+
+func TestTruncateRunes(t *testing.T) {
+	tests := []struct {
+		text string
+		max  int
+		want string
+	}{
+		{"short", 10, "short"},
+		{"exactly", 7, "exactly"},
+		{"Zürich rate case", 2, "Zü"},
+		{"日本語のテキスト", 3, "日本語"},
+		{"", 3, ""},
+	}
+	for _, test := range tests {
+		if got := truncateRunes(test.text, test.max); got != test.want {
+			t.Errorf("truncateRunes(%q, %d) = %q, want %q", test.text, test.max, got, test.want)
+		}
+	}
+}
@@ -2,15 +2,15 @@ package search
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"kessler/internal/cache"
 	"kessler/internal/dbstore"
 	"kessler/internal/fugusdk"
+	"kessler/internal/objects/files"
 	"kessler/pkg/logger"
-	"kessler/pkg/util"
-	"reflect"
+	"slices"
 	"strings"
-	"time"
 
 	"github.com/google/uuid"
 
@@ -18,6 +18,24 @@ import (
 	"go.uber.org/zap"
 )
 
+// maxDescriptionLength is the number of characters of matched text shown on a card
+const maxDescriptionLength = 200
+
+// requiredAttachmentFields must decode for a search hit to become a document card
+var requiredAttachmentFields = []string{"file_id", "conversation_id", "author_ids"}
+
+// truncateRunes cuts text to at most maxRunes characters without splitting a multi-byte character
+func truncateRunes(text string, maxRunes int) string {
+	count := 0
+	for i := range text {
+		if count == maxRunes {
+			return text[:i]
+		}
+		count++
+	}
+	return text
+}
+
 func (s *SearchService) hydrateDocumentConvos(ctx context.Context, card *DocumentCardData, convoID uuid.UUID) error {
 	log := logger.FromContext(ctx)
 	queries := dbstore.New(s.db)
@@ -99,6 +117,51 @@ func (s *SearchService) hydrateDocumentAuthors(ctx context.Context, card *Docume
 	// }
 }
 
+// applyDocumentSummaries shows the generated summary as the description of every document card of a page,
+// read with one query. Summaries are applied after cards are loaded from the cache so a file summarized later
+// shows up at once, matched text stays available in the card's highlights.
+func (s *SearchService) applyDocumentSummaries(ctx context.Context, cards []CardData) {
+	if s.db == nil {
+		return
+	}
+	var fileIDs []uuid.UUID
+	for _, card := range cards {
+		if doc, ok := card.(DocumentCardData); ok && doc.FileUUID != uuid.Nil && !slices.Contains(fileIDs, doc.FileUUID) {
+			fileIDs = append(fileIDs, doc.FileUUID)
+		}
+	}
+	if len(fileIDs) == 0 {
+		return
+	}
+	log := logger.FromContext(ctx)
+	extras, err := dbstore.New(s.db).ExtrasFileListByIds(ctx, fileIDs)
+	if err != nil {
+		log.Warn("Failed to read file extras", zap.Int("files", len(fileIDs)), zap.Error(err))
+		return
+	}
+	summaries := make(map[uuid.UUID]string, len(extras))
+	for _, extra := range extras {
+		var generated files.FileGeneratedExtras
+		if err := json.Unmarshal(extra.ExtraObj, &generated); err != nil {
+			log.Warn("Failed to parse file extras", zap.String("file_id", extra.ID.String()), zap.Error(err))
+			continue
+		}
+		if description := generated.CardDescription(); description != "" {
+			summaries[extra.ID] = description
+		}
+	}
+	for i, card := range cards {
+		doc, ok := card.(DocumentCardData)
+		if !ok {
+			continue
+		}
+		if summary, ok := summaries[doc.FileUUID]; ok {
+			doc.Description = summary
+			cards[i] = doc
+		}
+	}
+}
+
 func (s *SearchService) HydrateDocument(ctx context.Context, result fugusdk.FuguSearchResult, index int) (CardData, error) {
 	log := logger.FromContext(ctx)
 	// Check cache first
@@ -112,9 +175,10 @@ func (s *SearchService) HydrateDocument(ctx context.Context, result fugusdk.Fugu
 	}
 
 	// validate and hydrate card data
+	description := truncateRunes(result.Text, maxDescriptionLength)
 	card := DocumentCardData{
 		Index:       index,
-		Description: result.Text[:200],
+		Description: description,
 		Type:        "document",
 	}
 
@@ -143,77 +207,57 @@ func (s *SearchService) HydrateDocument(ctx context.Context, result fugusdk.Fugu
 	}
 
 	if result.Metadata != nil {
-		// File name
-		if fileName, ok := result.Metadata["file_name"].(string); ok {
-			card.Name = fileName
+		typed, err := fugusdk.DecodeResult[fugusdk.AttachmentMetadata](result)
+		if err != nil {
+			// Identifiers are needed to hydrate the card, anything else is shown as far as it decoded
+			for _, field := range typed.Err.Fields() {
+				if slices.Contains(requiredAttachmentFields, field) {
+					return DocumentCardData{}, fmt.Errorf("Failed to decode attachment metadata field %q: %w", field, err)
+				}
+			}
+			log.Warn("Attachment metadata did not fully decode", zap.String("fugu_id", result.ID), zap.Strings("fields", typed.Err.Fields()), zap.Error(err))
 		}
+		metadata := typed.Metadata
 
-		// ObjectUUID
-		if fileIDString, ok := result.Metadata["file_id"].(string); ok {
-			fileID, err := uuid.Parse(fileIDString)
-			if err != nil {
-				return DocumentCardData{}, fmt.Errorf("Failed to parse file_id in metadata: %w", err)
-			}
-			card.ObjectUUID = fileID
-			card.FileUUID = fileID
+		card.Name = metadata.FileName
+		if metadata.FileID != uuid.Nil {
+			card.ObjectUUID = metadata.FileID
+			card.FileUUID = metadata.FileID
 		}
 
 		// Conversation
-		if convoIDString, ok := result.Metadata["conversation_id"].(string); ok {
-			convoID, err := uuid.Parse(convoIDString)
-			if err != nil {
-				return DocumentCardData{}, fmt.Errorf("Failed to parse conversation_id in metadata: %w", err)
-			}
-
-			err = s.hydrateDocumentConvos(ctx, &card, convoID)
+		if metadata.ConversationID != uuid.Nil {
+			err = s.hydrateDocumentConvos(ctx, &card, metadata.ConversationID)
 			if err != nil {
 				return DocumentCardData{}, fmt.Errorf("Failed to hydrate conversation: %w", err)
 			}
 		}
 
 		// Authors
-		if authorIDsRaw, ok := result.Metadata["author_ids"].([]interface{}); ok {
-			parseUUID := func(val interface{}) (uuid.UUID, error) {
-				valString := val.(string)
-				return uuid.Parse(valString)
-			}
-			authorIDs, err := util.MapErrorBubble(authorIDsRaw, parseUUID)
-			if err != nil {
-				return DocumentCardData{}, fmt.Errorf("Failed to parse author_ids in metadata: %w", err)
-			}
-			err = s.hydrateDocumentAuthors(ctx, &card, authorIDs)
+		if len(metadata.AuthorIDs) > 0 {
+			err = s.hydrateDocumentAuthors(ctx, &card, metadata.AuthorIDs)
 			if err != nil {
 				return DocumentCardData{}, fmt.Errorf("Failed to hydrate authors: %w", err)
 			}
-			if len(card.Authors) != len(authorIDsRaw) {
-				log.Error("Something went really wrong with author hydration, mismatch between ids provided and final author length", zap.Int("raw_author_ids_len", len(authorIDsRaw)), zap.Int("author_info_len", len(card.Authors)))
+			if len(card.Authors) != len(metadata.AuthorIDs) {
+				log.Error("Something went really wrong with author hydration, mismatch between ids provided and final author length", zap.Int("raw_author_ids_len", len(metadata.AuthorIDs)), zap.Int("author_info_len", len(card.Authors)))
 
 				return DocumentCardData{}, fmt.Errorf("Mismatch in raw_author_ids_len and author_info_len")
 			}
 		} else {
 			log.Warn("Authors were not detected",
 				zap.String("fugu_id", result.ID),
-				zap.Any("author_ids", result.Metadata["author_ids"]),
-				zap.Any("author_ids_type", reflect.TypeOf(result.Metadata["author_ids"])))
+				zap.Any("author_ids", result.Metadata["author_ids"]))
 		}
 
-		// Description
-		if desc, ok := result.Metadata["description"].(string); ok {
-			card.Description = desc
+		if metadata.Description != "" {
+			card.Description = metadata.Description
 		}
-
-		// Timestamp
-		if createdAt, ok := result.Metadata["created_at"].(string); ok {
-			if t, err := time.Parse(time.RFC3339, createdAt); err == nil {
-				card.Timestamp = t
-			} else {
-				logger.Warn(ctx, "Failed to parse created_at timestamp", zap.Error(err))
-			}
-		}
-
-		// Case number
-		if caseNumber, ok := result.Metadata["case_number"].(string); ok {
-			card.ExtraInfo = fmt.Sprintf("Case: %s", caseNumber)
+		card.Timestamp = metadata.CreatedAt
+		// Publish date, normalised by the indexer
+		card.DatePublished = metadata.DateISO
+		if metadata.CaseNumber != "" {
+			card.ExtraInfo = fmt.Sprintf("Case: %s", metadata.CaseNumber)
 		}
 	} else {
 		log.Error("Result had no metadata", zap.String("fugu_id", result.ID))
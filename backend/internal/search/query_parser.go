@@ -0,0 +1,426 @@
+package search
+
+import (
+	"fmt"
+	"kessler/pkg/timestamp"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// QueryParseError is a malformed search query, Position counts characters from the start of the query
+type QueryParseError struct {
+	Position int    `json:"position"`
+	Message  string `json:"message"`
+}
+
+func (e *QueryParseError) Error() string {
+	return fmt.Sprintf("query syntax error at position %d: %s", e.Position, e.Message)
+}
+
+// ParsedQuery is a search query compiled into Fugu query text plus the filters its field prefixes select
+type ParsedQuery struct {
+	Text     string
+	Filters  []string
+	DateFrom timestamp.RFC3339Time
+	DateTo   timestamp.RFC3339Time
+}
+
+// queryFields maps the field prefixes users can type to the metadata key they filter on.
+// before and after are handled separately as date bounds.
+var queryFields = map[string]string{
+	"docket": "docket_gov_id",
+	"author": "author_names",
+	"ext":    "file_extension",
+	"before": "",
+	"after":  "",
+}
+
+type queryTokenKind int
+
+const (
+	tokenWord queryTokenKind = iota
+	tokenPhrase
+	tokenField
+	tokenAnd
+	tokenOr
+	tokenNot
+	tokenLParen
+	tokenRParen
+	tokenEOF
+)
+
+type queryToken struct {
+	kind  queryTokenKind
+	text  string
+	pos   int
+	split bool // a field token whose value is separated from it by whitespace
+}
+
+// lexQuery splits a query into tokens, recording the character position of each
+func lexQuery(query string) ([]queryToken, error) {
+	runes := []rune(query)
+	var tokens []queryToken
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(':
+			tokens = append(tokens, queryToken{kind: tokenLParen, text: "(", pos: i})
+			i++
+		case r == ')':
+			tokens = append(tokens, queryToken{kind: tokenRParen, text: ")", pos: i})
+			i++
+		case r == '"':
+			end := i + 1
+			for end < len(runes) && runes[end] != '"' {
+				end++
+			}
+			if end == len(runes) {
+				return nil, &QueryParseError{Position: i, Message: "unterminated quoted phrase"}
+			}
+			tokens = append(tokens, queryToken{kind: tokenPhrase, text: string(runes[i+1 : end]), pos: i})
+			i = end + 1
+		default:
+			start := i
+			for i < len(runes) && !unicode.IsSpace(runes[i]) && runes[i] != '(' && runes[i] != ')' && runes[i] != '"' {
+				if runes[i] == ':' {
+					if _, ok := queryFields[strings.ToLower(string(runes[start:i]))]; ok {
+						break
+					}
+				}
+				i++
+			}
+			word := string(runes[start:i])
+			if i < len(runes) && runes[i] == ':' {
+				i++
+				tokens = append(tokens, queryToken{
+					kind:  tokenField,
+					text:  strings.ToLower(word),
+					pos:   start,
+					split: i == len(runes) || unicode.IsSpace(runes[i]),
+				})
+				continue
+			}
+			switch word {
+			case "AND":
+				tokens = append(tokens, queryToken{kind: tokenAnd, text: word, pos: start})
+			case "OR":
+				tokens = append(tokens, queryToken{kind: tokenOr, text: word, pos: start})
+			case "NOT":
+				tokens = append(tokens, queryToken{kind: tokenNot, text: word, pos: start})
+			default:
+				tokens = append(tokens, queryToken{kind: tokenWord, text: word, pos: start})
+			}
+		}
+	}
+
+	tokens = append(tokens, queryToken{kind: tokenEOF, pos: len(runes)})
+	return tokens, nil
+}
+
+// queryNode is a node of the parsed query tree
+type queryNode interface{}
+
+type termNode struct {
+	text   string
+	phrase bool
+}
+
+type fieldNode struct {
+	field    string
+	value    string
+	pos      int
+	valuePos int
+}
+
+type notNode struct {
+	child queryNode
+	pos   int
+}
+
+type groupNode struct {
+	child queryNode
+}
+
+// binaryNode joins two nodes, an empty op is an implicit conjunction of adjacent terms
+type binaryNode struct {
+	op          string
+	left, right queryNode
+}
+
+type queryParser struct {
+	tokens []queryToken
+	pos    int
+}
+
+func (p *queryParser) peek() queryToken {
+	return p.tokens[p.pos]
+}
+
+func (p *queryParser) next() queryToken {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokenEOF {
+		p.pos++
+	}
+	return tok
+}
+
+// parseOr handles the lowest precedence operator: or := and (OR and)*
+func (p *queryParser) parseOr() (queryNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().kind == tokenOr {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryNode{op: "OR", left: left, right: right}
+	}
+	return left, nil
+}
+
+// parseAnd handles explicit AND and implicit adjacency: and := unary ((AND)? unary)*
+func (p *queryParser) parseAnd() (queryNode, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op := ""
+		switch p.peek().kind {
+		case tokenAnd:
+			p.next()
+			op = "AND"
+		case tokenOr, tokenRParen, tokenEOF:
+			return left, nil
+		}
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryNode{op: op, left: left, right: right}
+	}
+}
+
+// parseUnary handles NOT: unary := NOT unary | primary
+func (p *queryParser) parseUnary() (queryNode, error) {
+	if tok := p.peek(); tok.kind == tokenNot {
+		p.next()
+		child, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &notNode{child: child, pos: tok.pos}, nil
+	}
+	return p.parsePrimary()
+}
+
+// parsePrimary handles terms, phrases, field prefixes and parenthesised groups
+func (p *queryParser) parsePrimary() (queryNode, error) {
+	tok := p.next()
+	switch tok.kind {
+	case tokenWord:
+		return &termNode{text: tok.text}, nil
+	case tokenPhrase:
+		return &termNode{text: tok.text, phrase: true}, nil
+	case tokenField:
+		value := p.peek()
+		if tok.split || (value.kind != tokenWord && value.kind != tokenPhrase) {
+			return nil, &QueryParseError{Position: tok.pos, Message: fmt.Sprintf("missing value for %s:", tok.text)}
+		}
+		p.next()
+		if strings.TrimSpace(value.text) == "" {
+			return nil, &QueryParseError{Position: value.pos, Message: fmt.Sprintf("empty value for %s:", tok.text)}
+		}
+		return &fieldNode{field: tok.text, value: strings.TrimSpace(value.text), pos: tok.pos, valuePos: value.pos}, nil
+	case tokenLParen:
+		child, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.next(); closing.kind != tokenRParen {
+			return nil, &QueryParseError{Position: tok.pos, Message: "unbalanced parenthesis"}
+		}
+		return &groupNode{child: child}, nil
+	case tokenRParen:
+		return nil, &QueryParseError{Position: tok.pos, Message: "unexpected closing parenthesis"}
+	case tokenEOF:
+		return nil, &QueryParseError{Position: tok.pos, Message: "unexpected end of query"}
+	default:
+		return nil, &QueryParseError{Position: tok.pos, Message: fmt.Sprintf("unexpected operator %s", tok.text)}
+	}
+}
+
+// ParseQuery compiles a search query into Fugu query text and filters.
+// Field prefixes become filters, so they may only be combined with the rest of the query by AND.
+func ParseQuery(query string) (ParsedQuery, error) {
+	if strings.TrimSpace(query) == "" {
+		return ParsedQuery{Text: query}, nil
+	}
+
+	tokens, err := lexQuery(query)
+	if err != nil {
+		return ParsedQuery{}, err
+	}
+	parser := &queryParser{tokens: tokens}
+	root, err := parser.parseOr()
+	if err != nil {
+		return ParsedQuery{}, err
+	}
+	if tok := parser.peek(); tok.kind != tokenEOF {
+		return ParsedQuery{}, &QueryParseError{Position: tok.pos, Message: "unexpected closing parenthesis"}
+	}
+
+	var parsed ParsedQuery
+	remaining, err := parsed.extractFields(root)
+	if err != nil {
+		return ParsedQuery{}, err
+	}
+	if remaining != nil {
+		parsed.Text = renderQueryNode(remaining, "")
+	}
+	return parsed, nil
+}
+
+// extractFields moves field nodes joined by AND into filters and returns what is left of the tree
+func (pq *ParsedQuery) extractFields(node queryNode) (queryNode, error) {
+	switch n := node.(type) {
+	case *fieldNode:
+		return nil, pq.addField(n)
+	case *groupNode:
+		child, err := pq.extractFields(n.child)
+		if err != nil || child == nil {
+			return nil, err
+		}
+		return &groupNode{child: child}, nil
+	case *binaryNode:
+		if n.op == "OR" {
+			return node, rejectNestedFields(node, "OR")
+		}
+		left, err := pq.extractFields(n.left)
+		if err != nil {
+			return nil, err
+		}
+		right, err := pq.extractFields(n.right)
+		if err != nil {
+			return nil, err
+		}
+		if left == nil {
+			return right, nil
+		}
+		if right == nil {
+			return left, nil
+		}
+		return &binaryNode{op: n.op, left: left, right: right}, nil
+	case *notNode:
+		return node, rejectNestedFields(n.child, "NOT")
+	}
+	return node, nil
+}
+
+// rejectNestedFields returns an error for the first field prefix under an operator filters cannot express
+func rejectNestedFields(node queryNode, op string) error {
+	switch n := node.(type) {
+	case *fieldNode:
+		return &QueryParseError{Position: n.pos, Message: fmt.Sprintf("%s: cannot be used with %s", n.field, op)}
+	case *groupNode:
+		return rejectNestedFields(n.child, op)
+	case *notNode:
+		return rejectNestedFields(n.child, op)
+	case *binaryNode:
+		if err := rejectNestedFields(n.left, op); err != nil {
+			return err
+		}
+		return rejectNestedFields(n.right, op)
+	}
+	return nil
+}
+
+// addField turns a field prefix into a filter or a date bound
+func (pq *ParsedQuery) addField(n *fieldNode) error {
+	switch n.field {
+	case "before", "after":
+		bound, err := parseQueryDate(n)
+		if err != nil {
+			return err
+		}
+		// before and after are exclusive, keep the tightest bound when repeated
+		if n.field == "before" {
+			bound = bound.Add(-time.Second)
+			if pq.DateTo.IsZero() || bound.Before(time.Time(pq.DateTo)) {
+				pq.DateTo = timestamp.RFC3339Time(bound)
+			}
+		} else if pq.DateFrom.IsZero() || bound.After(time.Time(pq.DateFrom)) {
+			pq.DateFrom = timestamp.RFC3339Time(bound)
+		}
+		return nil
+	case "ext":
+		n.value = strings.TrimPrefix(strings.ToLower(n.value), ".")
+	}
+	pq.Filters = append(pq.Filters, fmt.Sprintf("metadata/%s/%s", queryFields[n.field], n.value))
+	return nil
+}
+
+// parseQueryDate reads a before: or after: value, a bare date covers the whole day
+func parseQueryDate(n *fieldNode) (time.Time, error) {
+	if parsed, err := time.Parse(time.RFC3339, n.value); err == nil {
+		if n.field == "after" {
+			return parsed.Add(time.Second), nil
+		}
+		return parsed, nil
+	}
+	if parsed, err := time.Parse(time.DateOnly, n.value); err == nil {
+		if n.field == "after" {
+			return parsed.AddDate(0, 0, 1), nil
+		}
+		return parsed, nil
+	}
+	return time.Time{}, &QueryParseError{
+		Position: n.valuePos,
+		Message:  fmt.Sprintf("%s: expects a date like 2024-01-31 or an RFC3339 timestamp", n.field),
+	}
+}
+
+// renderQueryNode writes a tree back out as Fugu query text, parent is the operator of the enclosing node
+func renderQueryNode(node queryNode, parent string) string {
+	switch n := node.(type) {
+	case *termNode:
+		if n.phrase {
+			return `"` + n.text + `"`
+		}
+		return n.text
+	case *groupNode:
+		return "(" + renderQueryNode(n.child, "") + ")"
+	case *notNode:
+		return "NOT " + renderQueryNode(n.child, "NOT")
+	case *binaryNode:
+		sep := " "
+		if n.op != "" {
+			sep = " " + n.op + " "
+		}
+		text := renderQueryNode(n.left, n.op) + sep + renderQueryNode(n.right, n.op)
+		// Keep precedence when field extraction removed the parentheses around a subexpression
+		if parent != "" && parent != n.op {
+			return "(" + text + ")"
+		}
+		return text
+	}
+	return ""
+}
+
+// applyDateBounds narrows a date range with the bounds typed in the query
+func (pq ParsedQuery) applyDateBounds(from, to timestamp.RFC3339Time) (timestamp.RFC3339Time, timestamp.RFC3339Time) {
+	if !pq.DateFrom.IsZero() && (from.IsZero() || time.Time(pq.DateFrom).After(time.Time(from))) {
+		from = pq.DateFrom
+	}
+	if !pq.DateTo.IsZero() && (to.IsZero() || time.Time(pq.DateTo).Before(time.Time(to))) {
+		to = pq.DateTo
+	}
+	return from, to
+}
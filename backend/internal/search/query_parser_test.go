@@ -0,0 +1,90 @@
+package search
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseQuery(t *testing.T) {
+	tests := []struct {
+		query   string
+		text    string
+		filters []string
+	}{
+		{
+			query:   `author:"Con Edison" AND docket:23-E-0418`,
+			text:    "",
+			filters: []string{"metadata/author_names/Con Edison", "metadata/docket_gov_id/23-E-0418"},
+		},
+		{
+			query:   `"rate case" AND (gas OR electric) ext:.PDF`,
+			text:    `"rate case" AND (gas OR electric)`,
+			filters: []string{"metadata/file_extension/pdf"},
+		},
+		{
+			query: `solar NOT wind`,
+			text:  `solar NOT wind`,
+		},
+		{
+			query: `time:12 url:x`,
+			text:  `time:12 url:x`,
+		},
+	}
+
+	for _, tt := range tests {
+		parsed, err := ParseQuery(tt.query)
+		if err != nil {
+			t.Errorf("%q: unexpected error %v", tt.query, err)
+			continue
+		}
+		if parsed.Text != tt.text {
+			t.Errorf("%q: expected text %q, got %q", tt.query, tt.text, parsed.Text)
+		}
+		if !reflect.DeepEqual(parsed.Filters, tt.filters) {
+			t.Errorf("%q: expected filters %v, got %v", tt.query, tt.filters, parsed.Filters)
+		}
+	}
+}
+
+func TestParseQueryDates(t *testing.T) {
+	parsed, err := ParseQuery(`before:2024-01-01 after:2023-06-30 tariff`)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if want := time.Date(2023, 12, 31, 23, 59, 59, 0, time.UTC); !time.Time(parsed.DateTo).Equal(want) {
+		t.Errorf("expected date_to %v, got %v", want, parsed.DateTo)
+	}
+	if want := time.Date(2023, 7, 1, 0, 0, 0, 0, time.UTC); !time.Time(parsed.DateFrom).Equal(want) {
+		t.Errorf("expected date_from %v, got %v", want, parsed.DateFrom)
+	}
+}
+
+func TestParseQueryErrors(t *testing.T) {
+	tests := []struct {
+		query    string
+		position int
+	}{
+		{`"rate case`, 0},
+		{`(gas OR electric`, 0},
+		{`gas)`, 3},
+		{`gas AND`, 7},
+		{`docket: 23-E-0418`, 0},
+		{`gas OR author:x`, 7},
+		{`NOT (a docket:1)`, 7},
+		{`before:yesterday`, 7},
+	}
+
+	for _, tt := range tests {
+		_, err := ParseQuery(tt.query)
+		var parseErr *QueryParseError
+		if !errors.As(err, &parseErr) {
+			t.Errorf("%q: expected a QueryParseError, got %v", tt.query, err)
+			continue
+		}
+		if parseErr.Position != tt.position {
+			t.Errorf("%q: expected position %d, got %d (%s)", tt.query, tt.position, parseErr.Position, parseErr.Message)
+		}
+	}
+}
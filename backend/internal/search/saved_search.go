@@ -0,0 +1,394 @@
+package search
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"kessler/internal/dbstore"
+	"kessler/pkg/logger"
+	"maps"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"go.uber.org/zap"
+)
+
+const (
+	// defaultSavedSearchInterval is how often each saved search is re-run, SAVED_SEARCH_RUN_INTERVAL overrides it
+	defaultSavedSearchInterval = time.Hour
+	// savedSearchPollInterval is how often the runner looks for saved searches that are due
+	savedSearchPollInterval = time.Minute
+	// savedSearchMaxResults bounds how many hits a single run walks through
+	savedSearchMaxResults = 1000
+	savedSearchPageSize   = 100
+	// defaultSavedSearchFeedLimit and maxSavedSearchFeedLimit bound feed page sizes
+	defaultSavedSearchFeedLimit = 100
+	maxSavedSearchFeedLimit     = 500
+)
+
+// SavedSearchInput is the body accepted when creating or updating a saved search
+type SavedSearchInput struct {
+	Name string `json:"name"`
+	SearchRequest
+}
+
+// SavedSearch is a stored search request and the state of its periodic runs
+type SavedSearch struct {
+	ID           uuid.UUID     `json:"id"`
+	Name         string        `json:"name"`
+	Request      SearchRequest `json:"request"`
+	RunCount     int32         `json:"run_count"`
+	LastRunAt    *time.Time    `json:"last_run_at,omitempty"`
+	LastRunError string        `json:"last_run_error,omitempty"`
+	CreatedAt    time.Time     `json:"created_at"`
+	UpdatedAt    time.Time     `json:"updated_at"`
+}
+
+// SavedSearchFeedItem is a document that started matching a saved search after its first run
+type SavedSearchFeedItem struct {
+	SavedSearchID uuid.UUID `json:"saved_search_id"`
+	DocumentID    uuid.UUID `json:"document_id"`
+	FirstSeenAt   time.Time `json:"first_seen_at"`
+}
+
+// validate checks a saved search input can be run later, cursors only make sense for a single walk
+func (in *SavedSearchInput) validate() error {
+	in.Name = strings.TrimSpace(in.Name)
+	if in.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	in.Cursor = ""
+	if _, err := searchOptionsFromRequest(in.SearchRequest); err != nil {
+		return err
+	}
+	if _, err := ParseQuery(in.Query); err != nil {
+		return err
+	}
+	return nil
+}
+
+// savedSearchFromRow converts a database row into the API representation
+func savedSearchFromRow(row dbstore.SavedSearch) (*SavedSearch, error) {
+	var req SearchRequest
+	if err := json.Unmarshal(row.SearchRequest, &req); err != nil {
+		return nil, fmt.Errorf("decoding saved search %s: %w", row.ID, err)
+	}
+	saved := &SavedSearch{
+		ID:           row.ID,
+		Name:         row.Name,
+		Request:      req,
+		RunCount:     row.RunCount,
+		LastRunError: row.LastRunError,
+		CreatedAt:    row.CreatedAt.Time,
+		UpdatedAt:    row.UpdatedAt.Time,
+	}
+	if row.LastRunAt.Valid {
+		lastRun := row.LastRunAt.Time
+		saved.LastRunAt = &lastRun
+	}
+	return saved, nil
+}
+
+// savedSearchColumns encodes the stored columns of a saved search input
+func savedSearchColumns(in SavedSearchInput) (filters, request []byte, err error) {
+	if in.Filters == nil {
+		in.Filters = map[string]string{}
+	}
+	if filters, err = json.Marshal(in.Filters); err != nil {
+		return nil, nil, err
+	}
+	if request, err = json.Marshal(in.SearchRequest); err != nil {
+		return nil, nil, err
+	}
+	return filters, request, nil
+}
+
+// CreateSavedSearch stores a new saved search
+func (s *SearchService) CreateSavedSearch(ctx context.Context, in SavedSearchInput) (*SavedSearch, error) {
+	if err := in.validate(); err != nil {
+		return nil, err
+	}
+	filters, request, err := savedSearchColumns(in)
+	if err != nil {
+		return nil, err
+	}
+	row, err := dbstore.New(s.db).SavedSearchCreate(ctx, dbstore.SavedSearchCreateParams{
+		Name:          in.Name,
+		Query:         in.Query,
+		Namespace:     in.Namespace,
+		Filters:       filters,
+		SearchRequest: request,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("creating saved search: %w", err)
+	}
+	return savedSearchFromRow(row)
+}
+
+// UpdateSavedSearch replaces the name and request of a saved search. Its seen documents are kept while it
+// matches the same documents, a changed query, namespace, filters or date range starts a new baseline
+func (s *SearchService) UpdateSavedSearch(ctx context.Context, id uuid.UUID, in SavedSearchInput) (*SavedSearch, error) {
+	if err := in.validate(); err != nil {
+		return nil, err
+	}
+	filters, request, err := savedSearchColumns(in)
+	if err != nil {
+		return nil, err
+	}
+	q := dbstore.New(s.db)
+	current, err := q.SavedSearchRead(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("reading saved search: %w", err)
+	}
+	saved, err := savedSearchFromRow(current)
+	if err != nil {
+		return nil, err
+	}
+	reset := savedSearchMatchesChanged(saved.Request, in.SearchRequest)
+
+	row, err := q.SavedSearchUpdate(ctx, dbstore.SavedSearchUpdateParams{
+		ID:            id,
+		Name:          in.Name,
+		Query:         in.Query,
+		Namespace:     in.Namespace,
+		Filters:       filters,
+		SearchRequest: request,
+		ResetBaseline: reset,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("updating saved search: %w", err)
+	}
+	if reset {
+		if err := q.SavedSearchResultDeleteAll(ctx, id); err != nil {
+			return nil, fmt.Errorf("clearing saved search results: %w", err)
+		}
+	}
+	return savedSearchFromRow(row)
+}
+
+// savedSearchMatchesChanged reports whether an edit changes which documents a saved search matches, sort and
+// paging do not as runs always walk every result by date
+func savedSearchMatchesChanged(before, after SearchRequest) bool {
+	emptyIfNil := func(filters map[string]string) map[string]string {
+		if filters == nil {
+			return map[string]string{}
+		}
+		return filters
+	}
+	return before.Query != after.Query ||
+		before.Namespace != after.Namespace ||
+		!maps.Equal(emptyIfNil(before.Filters), emptyIfNil(after.Filters)) ||
+		!time.Time(before.DateFrom).Equal(time.Time(after.DateFrom)) ||
+		!time.Time(before.DateTo).Equal(time.Time(after.DateTo))
+}
+
+// GetSavedSearch reads a single saved search
+func (s *SearchService) GetSavedSearch(ctx context.Context, id uuid.UUID) (*SavedSearch, error) {
+	row, err := dbstore.New(s.db).SavedSearchRead(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("reading saved search: %w", err)
+	}
+	return savedSearchFromRow(row)
+}
+
+// ListSavedSearches returns every saved search, newest first
+func (s *SearchService) ListSavedSearches(ctx context.Context) ([]SavedSearch, error) {
+	rows, err := dbstore.New(s.db).SavedSearchList(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("listing saved searches: %w", err)
+	}
+	saved := make([]SavedSearch, 0, len(rows))
+	for _, row := range rows {
+		item, err := savedSearchFromRow(row)
+		if err != nil {
+			return nil, err
+		}
+		saved = append(saved, *item)
+	}
+	return saved, nil
+}
+
+// DeleteSavedSearch removes a saved search along with its recorded results
+func (s *SearchService) DeleteSavedSearch(ctx context.Context, id uuid.UUID) error {
+	if err := dbstore.New(s.db).SavedSearchDelete(ctx, id); err != nil {
+		return fmt.Errorf("deleting saved search: %w", err)
+	}
+	return nil
+}
+
+// SavedSearchFeed returns documents that newly matched a saved search after since, newest first
+func (s *SearchService) SavedSearchFeed(ctx context.Context, id uuid.UUID, since time.Time, limit int) ([]SavedSearchFeedItem, error) {
+	rows, err := dbstore.New(s.db).SavedSearchFeed(ctx, dbstore.SavedSearchFeedParams{
+		SavedSearchID: id,
+		FirstSeenAt:   pgtype.Timestamptz{Time: since, Valid: true},
+		Limit:         int32(limit),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("reading saved search feed: %w", err)
+	}
+	items := make([]SavedSearchFeedItem, 0, len(rows))
+	for _, row := range rows {
+		items = append(items, SavedSearchFeedItem{
+			SavedSearchID: row.SavedSearchID,
+			DocumentID:    row.DocumentID,
+			FirstSeenAt:   row.FirstSeenAt.Time,
+		})
+	}
+	return items, nil
+}
+
+// runSavedSearch re-executes a saved search and records documents it has not matched before.
+// Until a run has succeeded it only records a baseline so existing documents do not show up in the feed.
+func (s *SearchService) runSavedSearch(ctx context.Context, row dbstore.SavedSearch) ([]uuid.UUID, error) {
+	saved, err := savedSearchFromRow(row)
+	if err != nil {
+		return nil, err
+	}
+	documentIDs, err := s.savedSearchMatches(ctx, saved.Request)
+	if err != nil {
+		return nil, err
+	}
+	if len(documentIDs) == 0 {
+		return nil, nil
+	}
+	return dbstore.New(s.db).SavedSearchResultInsertNew(ctx, dbstore.SavedSearchResultInsertNewParams{
+		SavedSearchID: row.ID,
+		DocumentIds:   documentIDs,
+		Baseline:      !row.BaselineDone,
+		RunUpdatedAt:  row.UpdatedAt,
+	})
+}
+
+// savedSearchMatches walks the hits of a saved search, newest first so documents published since the last
+// run are inside the result cap, and returns the distinct documents they belong to
+func (s *SearchService) savedSearchMatches(ctx context.Context, req SearchRequest) ([]uuid.UUID, error) {
+	opts, err := searchOptionsFromRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	// Walk results by cursor so indexing during the run cannot skip documents
+	opts.Sort = SortDatePublishedDesc
+	opts.Collapse = CollapseNone
+	opts.Facets = nil
+
+	seen := make(map[uuid.UUID]bool)
+	var documentIDs []uuid.UUID
+	pagination := PaginationParams{Page: 0, Limit: savedSearchPageSize}
+	for scanned := 0; scanned < savedSearchMaxResults; scanned += savedSearchPageSize {
+		response, err := s.ProcessSearch(ctx, req.Query, req.Filters, pagination, req.Namespace, opts)
+		if err != nil {
+			return nil, err
+		}
+		for _, card := range response.Data {
+			doc, ok := card.(DocumentCardData)
+			if !ok {
+				continue
+			}
+			id := doc.AttachmentUUID
+			if id == uuid.Nil {
+				id = doc.ObjectUUID
+			}
+			if id != uuid.Nil && !seen[id] {
+				seen[id] = true
+				documentIDs = append(documentIDs, id)
+			}
+		}
+		if response.NextCursor == "" {
+			break
+		}
+		if opts.Cursor, err = parseCursor(response.NextCursor); err != nil {
+			return nil, err
+		}
+	}
+	return documentIDs, nil
+}
+
+// SavedSearchRunner periodically re-runs saved searches that are due
+type SavedSearchRunner struct {
+	service  *SearchService
+	interval time.Duration
+}
+
+// NewSavedSearchRunner creates a runner that re-runs each saved search once per interval
+func NewSavedSearchRunner(service *SearchService, interval time.Duration) *SavedSearchRunner {
+	return &SavedSearchRunner{
+		service:  service,
+		interval: interval,
+	}
+}
+
+// SavedSearchIntervalFromEnv reads SAVED_SEARCH_RUN_INTERVAL as a Go duration
+func SavedSearchIntervalFromEnv() time.Duration {
+	raw := os.Getenv("SAVED_SEARCH_RUN_INTERVAL")
+	if raw == "" {
+		return defaultSavedSearchInterval
+	}
+	interval, err := time.ParseDuration(raw)
+	if err != nil || interval <= 0 {
+		logger.Warn(context.Background(), "invalid SAVED_SEARCH_RUN_INTERVAL, using default",
+			zap.String("value", raw),
+			zap.Duration("default", defaultSavedSearchInterval))
+		return defaultSavedSearchInterval
+	}
+	return interval
+}
+
+// Run polls for due saved searches until the context is cancelled
+func (r *SavedSearchRunner) Run(ctx context.Context) {
+	ticker := time.NewTicker(savedSearchPollInterval)
+	defer ticker.Stop()
+
+	for {
+		r.runDue(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// runDue claims and runs saved searches one at a time until none are due
+func (r *SavedSearchRunner) runDue(ctx context.Context) {
+	q := dbstore.New(r.service.db)
+	for ctx.Err() == nil {
+		row, err := q.SavedSearchClaimDue(ctx, r.interval.Seconds())
+		if errors.Is(err, pgx.ErrNoRows) {
+			return
+		}
+		if err != nil {
+			logger.Error(ctx, "failed to claim due saved search", zap.Error(err))
+			return
+		}
+
+		runCtx, cancel := context.WithTimeout(ctx, 5*time.Minute)
+		newIDs, runErr := r.service.runSavedSearch(runCtx, row)
+		cancel()
+
+		runError := ""
+		if runErr != nil {
+			runError = runErr.Error()
+			logger.Error(ctx, "saved search run failed",
+				zap.String("saved_search_id", row.ID.String()),
+				zap.Error(runErr))
+		} else {
+			logger.Info(ctx, "saved search run completed",
+				zap.String("saved_search_id", row.ID.String()),
+				zap.Int("new_documents", len(newIDs)),
+				zap.Bool("baseline", !row.BaselineDone))
+		}
+		if err := q.SavedSearchFinishRun(ctx, dbstore.SavedSearchFinishRunParams{
+			ID:           row.ID,
+			LastRunError: runError,
+			Succeeded:    runErr == nil,
+			RunUpdatedAt: row.UpdatedAt,
+		}); err != nil {
+			logger.Error(ctx, "failed to record saved search run status", zap.Error(err))
+		}
+	}
+}
@@ -0,0 +1,191 @@
+package search
+
+import (
+	"encoding/json"
+	"errors"
+	"kessler/pkg/logger"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+)
+
+// savedSearchID parses the id path variable, writing a 400 when it is not a UUID
+func savedSearchID(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "saved search id must be a UUID", http.StatusBadRequest)
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+// respondSavedSearchError maps a saved search error to a status code
+func respondSavedSearchError(w http.ResponseWriter, err error) {
+	var parseErr *QueryParseError
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		http.Error(w, "saved search not found", http.StatusNotFound)
+	case errors.As(err, &parseErr):
+		http.Error(w, parseErr.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
+
+// writeJSON encodes a successful response body
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+// decodeSavedSearchInput reads and validates a create or update body, writing a 400 on failure
+func decodeSavedSearchInput(w http.ResponseWriter, r *http.Request) (SavedSearchInput, bool) {
+	var in SavedSearchInput
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return in, false
+	}
+	if err := in.validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return in, false
+	}
+	return in, true
+}
+
+// ListSavedSearches handles GET /search/saved
+func (h *SearchServiceHandler) ListSavedSearches(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracer.Start(r.Context(), "search-api:list-saved-searches")
+	defer span.End()
+
+	saved, err := h.service.ListSavedSearches(ctx)
+	if err != nil {
+		logger.Error(ctx, "failed to list saved searches", zap.Error(err))
+		respondSavedSearchError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, saved)
+}
+
+// CreateSavedSearch handles POST /search/saved
+func (h *SearchServiceHandler) CreateSavedSearch(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracer.Start(r.Context(), "search-api:create-saved-search")
+	defer span.End()
+
+	in, ok := decodeSavedSearchInput(w, r)
+	if !ok {
+		return
+	}
+	saved, err := h.service.CreateSavedSearch(ctx, in)
+	if err != nil {
+		logger.Error(ctx, "failed to create saved search", zap.Error(err))
+		respondSavedSearchError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, saved)
+}
+
+// GetSavedSearch handles GET /search/saved/{id}
+func (h *SearchServiceHandler) GetSavedSearch(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracer.Start(r.Context(), "search-api:get-saved-search")
+	defer span.End()
+
+	id, ok := savedSearchID(w, r)
+	if !ok {
+		return
+	}
+	saved, err := h.service.GetSavedSearch(ctx, id)
+	if err != nil {
+		logger.Error(ctx, "failed to read saved search", zap.String("saved_search_id", id.String()), zap.Error(err))
+		respondSavedSearchError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, saved)
+}
+
+// UpdateSavedSearch handles PUT /search/saved/{id}
+func (h *SearchServiceHandler) UpdateSavedSearch(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracer.Start(r.Context(), "search-api:update-saved-search")
+	defer span.End()
+
+	id, ok := savedSearchID(w, r)
+	if !ok {
+		return
+	}
+	in, ok := decodeSavedSearchInput(w, r)
+	if !ok {
+		return
+	}
+	saved, err := h.service.UpdateSavedSearch(ctx, id, in)
+	if err != nil {
+		logger.Error(ctx, "failed to update saved search", zap.String("saved_search_id", id.String()), zap.Error(err))
+		respondSavedSearchError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, saved)
+}
+
+// DeleteSavedSearch handles DELETE /search/saved/{id}
+func (h *SearchServiceHandler) DeleteSavedSearch(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracer.Start(r.Context(), "search-api:delete-saved-search")
+	defer span.End()
+
+	id, ok := savedSearchID(w, r)
+	if !ok {
+		return
+	}
+	if err := h.service.DeleteSavedSearch(ctx, id); err != nil {
+		logger.Error(ctx, "failed to delete saved search", zap.String("saved_search_id", id.String()), zap.Error(err))
+		respondSavedSearchError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetSavedSearchFeed handles GET /search/saved/{id}/feed, documents that newly matched after ?since=
+func (h *SearchServiceHandler) GetSavedSearchFeed(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracer.Start(r.Context(), "search-api:get-saved-search-feed")
+	defer span.End()
+
+	id, ok := savedSearchID(w, r)
+	if !ok {
+		return
+	}
+
+	since := time.Time{}
+	if raw := r.URL.Query().Get("since"); raw != "" {
+		parsed, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			http.Error(w, "since must be an RFC3339 timestamp", http.StatusBadRequest)
+			return
+		}
+		since = parsed
+	}
+	limit := defaultSavedSearchFeedLimit
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		if l, err := strconv.Atoi(raw); err == nil && l > 0 && l <= maxSavedSearchFeedLimit {
+			limit = l
+		}
+	}
+
+	// Distinguish an unknown search from one with nothing new
+	if _, err := h.service.GetSavedSearch(ctx, id); err != nil {
+		respondSavedSearchError(w, err)
+		return
+	}
+	items, err := h.service.SavedSearchFeed(ctx, id, since, limit)
+	if err != nil {
+		logger.Error(ctx, "failed to read saved search feed", zap.String("saved_search_id", id.String()), zap.Error(err))
+		respondSavedSearchError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"saved_search_id": id,
+		"items":           items,
+	})
+}
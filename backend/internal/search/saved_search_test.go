@@ -0,0 +1,92 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"kessler/internal/fugusdk"
+	"kessler/internal/fugusdk/fugutest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestSavedSearchMatchesChanged(t *testing.T) {
+	base := SearchRequest{Query: "rate case", Namespace: "ny", Filters: map[string]string{"docket_gov_id": "22-E-0001"}, DateFrom: mustDate(t, "2024-01-01T00:00:00Z")}
+	edit := func(change func(*SearchRequest)) SearchRequest {
+		req := base
+		req.Filters = map[string]string{"docket_gov_id": "22-E-0001"}
+		change(&req)
+		return req
+	}
+	tests := []struct {
+		name    string
+		after   SearchRequest
+		changed bool
+	}{
+		{"unchanged", edit(func(r *SearchRequest) {}), false},
+		{"sort and paging", edit(func(r *SearchRequest) { r.Sort = "docket_number"; r.PerPage = 50 }), false},
+		{"same instant in another zone", edit(func(r *SearchRequest) { r.DateFrom = mustDate(t, "2024-01-01T02:00:00+02:00") }), false},
+		{"query", edit(func(r *SearchRequest) { r.Query = "rate" }), true},
+		{"namespace", edit(func(r *SearchRequest) { r.Namespace = "" }), true},
+		{"filter value", edit(func(r *SearchRequest) { r.Filters["docket_gov_id"] = "23-G-0002" }), true},
+		{"filter removed", edit(func(r *SearchRequest) { r.Filters = nil }), true},
+		{"date to", edit(func(r *SearchRequest) { r.DateTo = mustDate(t, "2024-06-01T00:00:00Z") }), true},
+	}
+	for _, test := range tests {
+		if got := savedSearchMatchesChanged(base, test.after); got != test.changed {
+			t.Errorf("%s: savedSearchMatchesChanged = %v, want %v", test.name, got, test.changed)
+		}
+	}
+	if savedSearchMatchesChanged(SearchRequest{Query: "rate"}, SearchRequest{Query: "rate", Filters: map[string]string{}}) {
+		t.Error("missing and empty filters match the same documents")
+	}
+}
+
+// TestSavedSearchMatchesNewestFirst checks a run walks hits by publish date, so when more documents match
+// than a run takes the ones left out are the oldest rather than arbitrary low scoring ones
+func TestSavedSearchMatchesNewestFirst(t *testing.T) {
+	ctx := context.Background()
+	server := fugutest.NewServer(t)
+	client, err := server.Client(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const extra = 30
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	ids := make([]uuid.UUID, savedSearchMaxResults+extra)
+	for i := range ids {
+		ids[i] = uuid.NewSHA1(uuid.NameSpaceOID, []byte(fmt.Sprint(i)))
+		server.Seed(fugusdk.ObjectRecord{
+			ID:       ids[i].String(),
+			Text:     "rate case",
+			Metadata: map[string]interface{}{"date_iso": start.AddDate(0, 0, i).Format(time.RFC3339)},
+		})
+	}
+	s := &SearchService{client: client}
+
+	matched, err := s.savedSearchMatches(ctx, SearchRequest{Query: "rate", Sort: "docket_number"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(matched) != savedSearchMaxResults {
+		t.Fatalf("expected the run capped at %d documents, got %d", savedSearchMaxResults, len(matched))
+	}
+	found := make(map[uuid.UUID]bool, len(matched))
+	for _, id := range matched {
+		found[id] = true
+	}
+	for i, id := range ids {
+		if oldest := i < extra; found[id] == oldest {
+			t.Errorf("document %d published on day %d: matched %v", i, i, found[id])
+		}
+	}
+	for _, search := range server.Searches() {
+		if search.Sort == nil || (*search.Sort)[0] != SortDatePublishedDesc.fuguSortFields()[0] {
+			t.Fatalf("saved search runs must sort by publish date, searched with %+v", search.Sort)
+		}
+	}
+	if searches := len(server.Searches()); searches != savedSearchMaxResults/savedSearchPageSize {
+		t.Errorf("expected %d searches, got %d", savedSearchMaxResults/savedSearchPageSize, searches)
+	}
+}
@@ -11,7 +11,7 @@ import (
 
 // Update ProcessSearch to use the new transformer
 // Updated transformSearchResponse to return card data
-func (s *SearchService) transformSearchResponse(ctx context.Context, fuguResponse *fugusdk.SanitizedResponse, query, namespace string, pagination PaginationParams, processTime time.Duration) (*SearchResponse, error) {
+func (s *SearchService) transformSearchResponse(ctx context.Context, fuguResponse *fugusdk.SanitizedResponse, query, highlightQuery, namespace string, pagination PaginationParams, opts SearchOptions, processTime time.Duration) (*SearchResponse, error) {
 	log := logger.FromContext(ctx)
 	if fuguResponse == nil || len(fuguResponse.Results) == 0 {
 		return &SearchResponse{
@@ -21,12 +21,15 @@ func (s *SearchService) transformSearchResponse(ctx context.Context, fuguRespons
 			PerPage:     pagination.Limit,
 			Query:       query,
 			Namespace:   namespace,
+			Sort:        opts.Sort,
 			ProcessTime: processTime.String(),
 		}, nil
 	}
 	log.Info("Got result from fugu successfully", zap.Int("results_len", len(fuguResponse.Results)))
 
 	var cards []CardData
+	// sortKeys holds the sort key of the hit behind each card
+	var sortKeys []string
 
 	for i, result := range fuguResponse.Results {
 		resultType := s.getResultType(result.Facets)
@@ -48,6 +51,11 @@ func (s *SearchService) transformSearchResponse(ctx context.Context, fuguRespons
 			card, err = s.HydrateOrganization(ctx, result.ID, result.Score, i)
 		default:
 			card, err = s.HydrateDocument(ctx, result, i)
+			// Highlights depend on the query so they are added after the cached card is loaded
+			if doc, ok := card.(DocumentCardData); ok && err == nil {
+				doc.Highlights = buildHighlights(result, highlightQuery)
+				card = doc
+			}
 		}
 
 		if err != nil {
@@ -63,8 +71,12 @@ func (s *SearchService) transformSearchResponse(ctx context.Context, fuguRespons
 		// )
 
 		cards = append(cards, card)
+		sortKeys = append(sortKeys, hitSortKey(result, opts.Sort))
 	}
 
+	s.applyDocumentSummaries(ctx, cards)
+	sortCards(cards, sortKeys, opts.Sort)
+
 	return &SearchResponse{
 		Data:        cards,
 		Total:       fuguResponse.Total,
@@ -72,6 +84,7 @@ func (s *SearchService) transformSearchResponse(ctx context.Context, fuguRespons
 		PerPage:     pagination.Limit,
 		Query:       query,
 		Namespace:   namespace,
+		Sort:        opts.Sort,
 		ProcessTime: processTime.String(),
 	}, nil
 }
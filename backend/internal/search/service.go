@@ -7,8 +7,12 @@ import (
 	"kessler/internal/cache"
 	"kessler/internal/dbstore"
 	"kessler/internal/fugusdk"
+	"kessler/internal/indexalias"
 	"kessler/internal/search/filter"
+	"kessler/pkg/database"
 	"kessler/pkg/logger"
+	"kessler/pkg/timestamp"
+	"strings"
 	"time"
 
 	"go.opentelemetry.io/otel"
@@ -24,6 +28,20 @@ type SearchRequest struct {
 	Namespace string            `json:"namespace,omitempty"`
 	Page      int               `json:"page,omitempty"`
 	PerPage   int               `json:"per_page,omitempty"`
+	Sort      string            `json:"sort,omitempty"`
+	Collapse  string            `json:"collapse,omitempty"`
+	Facets    []string          `json:"facets,omitempty"`
+	// Cursor from a previous response's next_cursor, replaces page for deep paging
+	Cursor string `json:"cursor,omitempty"`
+
+	// Namespaces runs a federated search over several namespaces, only on /search/all
+	Namespaces       []string           `json:"namespaces,omitempty"`
+	Blend            string             `json:"blend,omitempty"`
+	NamespaceWeights map[string]float64 `json:"namespace_weights,omitempty"`
+
+	// Optional publish date bounds, inclusive
+	DateFrom timestamp.RFC3339Time `json:"date_from,omitempty"`
+	DateTo   timestamp.RFC3339Time `json:"date_to,omitempty"`
 }
 
 // Frontend response types
@@ -34,7 +52,29 @@ type SearchResponse struct {
 	PerPage     int        `json:"per_page,omitempty"`
 	Query       string     `json:"query,omitempty"`
 	Namespace   string     `json:"namespace,omitempty"`
+	Sort        SortOption `json:"sort,omitempty"`
 	ProcessTime string     `json:"process_time,omitempty"`
+	NextCursor  string     `json:"next_cursor,omitempty"`
+
+	// Identifies this search in the query log, sent back with POST /search/click
+	SearchID string `json:"search_id,omitempty"`
+
+	// Offered when the search found nothing and a corrected query finds something
+	Suggestion *SpellingSuggestion `json:"suggestion,omitempty"`
+
+	// Set when hits were collapsed, Total then counts groups rather than raw hits
+	Collapse         CollapseMode `json:"collapse,omitempty"`
+	TotalHits        int          `json:"total_hits,omitempty"`
+	TotalApproximate bool         `json:"total_approximate,omitempty"`
+
+	// Requested facet counts over every hit matching the query and filters
+	Facets            []FacetCounts `json:"facets,omitempty"`
+	FacetsApproximate bool          `json:"facets_approximate,omitempty"`
+
+	// Set for federated searches, Total then counts the blended results that can be paged through and
+	// TotalHits sums the namespaces that answered
+	NamespaceResults []NamespaceResult `json:"namespace_results,omitempty"`
+	Partial          bool              `json:"partial,omitempty"`
 }
 
 // SearchResultItem represents a single search result for the frontend
@@ -62,6 +102,60 @@ type PaginationParams struct {
 	Limit int `json:"limit"`
 }
 
+// SearchOptions holds the optional result shaping applied to a search
+type SearchOptions struct {
+	Sort     SortOption            `json:"sort"`
+	DateFrom timestamp.RFC3339Time `json:"date_from"`
+	DateTo   timestamp.RFC3339Time `json:"date_to"`
+	Collapse CollapseMode          `json:"collapse"`
+	Facets   []FacetName           `json:"facets"`
+	Cursor   *searchCursor         `json:"-"`
+}
+
+// searchOptionsFromRequest validates the result shaping fields of a request body
+func searchOptionsFromRequest(req SearchRequest) (SearchOptions, error) {
+	sortOption, err := ParseSortOption(req.Sort)
+	if err != nil {
+		return SearchOptions{}, err
+	}
+	if err := validateDateRange(req.DateFrom, req.DateTo); err != nil {
+		return SearchOptions{}, err
+	}
+	collapse, err := ParseCollapseMode(req.Collapse)
+	if err != nil {
+		return SearchOptions{}, err
+	}
+	facets, err := ParseFacets(strings.Join(req.Facets, ","))
+	if err != nil {
+		return SearchOptions{}, err
+	}
+	cursor, err := parseSearchCursor(req.Cursor, collapse)
+	if err != nil {
+		return SearchOptions{}, err
+	}
+
+	return SearchOptions{
+		Sort:     sortOption,
+		DateFrom: req.DateFrom,
+		DateTo:   req.DateTo,
+		Collapse: collapse,
+		Facets:   facets,
+		Cursor:   cursor,
+	}, nil
+}
+
+// parseSearchCursor decodes a cursor parameter, collapsed results are paged by offset only
+func parseSearchCursor(token string, collapse CollapseMode) (*searchCursor, error) {
+	cursor, err := parseCursor(token)
+	if err != nil {
+		return nil, err
+	}
+	if cursor != nil && collapse != "" && collapse != CollapseNone {
+		return nil, fmt.Errorf("%w: cursor cannot be combined with collapse", ErrInvalidCursor)
+	}
+	return cursor, nil
+}
+
 // SearchInfo represents search service information and capabilities
 type SearchInfo struct {
 	Status       string             `json:"status"`
@@ -80,6 +174,8 @@ type SearchCapabilities struct {
 	FacetSupport        bool     `json:"facet_support"`
 	NamespaceSupport    bool     `json:"namespace_support"`
 	SupportedQueries    []string `json:"supported_queries"`
+	SupportedSorts      []string `json:"supported_sorts"`
+	SupportedFacets     []string `json:"supported_facets"`
 	MaxQueryLength      int      `json:"max_query_length"`
 	MaxResultsPerPage   int      `json:"max_results_per_page"`
 	SupportedNamespaces []string `json:"supported_namespaces"`
@@ -97,15 +193,20 @@ type SearchStatistics struct {
 
 // SearchService handles the business logic for search operations
 type SearchService struct {
-	fuguServerURL string
+	client        *fugusdk.Client
 	filterService *filter.Service
 	db            dbstore.DBTX
 	cacheCtrl     cache.CacheController
 	cacheEnabled  bool
+	// aliases maps the default namespace to the version it was last switched to
+	aliases          *indexalias.Resolver
+	defaultNamespace string
+	spelling         *spellChecker
+	analytics        *AnalyticsRecorder
 }
 
-// NewSearchService creates a new search service
-func NewSearchService(fuguServerURL string, filterService *filter.Service, db dbstore.DBTX) (*SearchService, error) {
+// NewSearchService creates a new search service using the shared fugu client
+func NewSearchService(client *fugusdk.Client, filterService *filter.Service, db dbstore.DBTX, aliases *indexalias.Resolver, defaultNamespace string) (*SearchService, error) {
 	cacheCtrl, err := cache.NewCacheController()
 	cacheEnabled := err == nil
 
@@ -113,17 +214,43 @@ func NewSearchService(fuguServerURL string, filterService *filter.Service, db db
 		logger.Warn(context.Background(), "failed to initialize search cache controller", zap.Error(err))
 	}
 
+	var spelling *spellChecker
+	var analytics *AnalyticsRecorder
+	if db != nil {
+		spelling = newSpellChecker(db)
+		analytics = newAnalyticsRecorder(database.GetQueries(db), analyticsBuffer)
+	}
+
 	return &SearchService{
-		fuguServerURL: fuguServerURL,
+		client:        client,
 		filterService: filterService,
 		db:            db,
 		cacheCtrl:     cacheCtrl,
 		cacheEnabled:  cacheEnabled,
+
+		aliases:          aliases,
+		defaultNamespace: defaultNamespace,
+		spelling:         spelling,
+		analytics:        analytics,
 	}, nil
 }
 
+// resolveNamespace returns the namespace to filter on and the client to search it with. Once the default
+// namespace has been rebuilt into a version, searches of it, and unscoped searches, go to that version
+// through a client that strips the version's ID prefix from hits.
+func (s *SearchService) resolveNamespace(ctx context.Context, namespace string) (string, *fugusdk.Client) {
+	if s.aliases == nil || (namespace != "" && namespace != s.defaultNamespace) {
+		return namespace, s.client
+	}
+	target, client := s.aliases.FuguTarget(ctx, s.client, s.defaultNamespace)
+	if target == s.defaultNamespace {
+		return namespace, s.client
+	}
+	return target, client
+}
+
 // ProcessSearch processes a search request with namespace support
-func (s *SearchService) ProcessSearch(ctx context.Context, query string, metadataFilters map[string]string, pagination PaginationParams, namespace string) (*SearchResponse, error) {
+func (s *SearchService) ProcessSearch(ctx context.Context, query string, metadataFilters map[string]string, pagination PaginationParams, namespace string, opts SearchOptions) (*SearchResponse, error) {
 	ctx, span := serviceTracer.Start(ctx, "search-service:process-search")
 	defer span.End()
 
@@ -132,58 +259,118 @@ func (s *SearchService) ProcessSearch(ctx context.Context, query string, metadat
 	logger.Info(ctx, "starting search processing",
 		zap.String("query", query),
 		zap.String("namespace", namespace),
-		zap.String("fugu_url", s.fuguServerURL))
+		zap.String("sort", string(opts.Sort)),
+		zap.String("fugu_url", s.client.BaseURL()))
 
-	// Create fugu client with timeout
-	clientCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
-	defer cancel()
+	searchNamespace, client := s.resolveNamespace(ctx, namespace)
 
-	client, err := fugusdk.NewClient(clientCtx, s.fuguServerURL)
+	parsedQuery, err := ParseQuery(query)
 	if err != nil {
-		logger.Error(ctx, "failed to create fugu client", zap.Error(err))
-		return nil, fmt.Errorf("failed to create fugu client: %w", err)
+		return nil, err
 	}
 
-	logger.Info(ctx, "fugu client created successfully")
-
-	rawFilters := convertMetadataFiltersToRaw(metadataFilters)
+	backendFilters := buildBackendFilters(ctx, parsedQuery, metadataFilters, searchNamespace, opts)
 
-	// Convert filters to backend format with namespace
-	backendFilters, err := convertFiltersToBackend(ctx, rawFilters, namespace)
-	if err != nil {
-		logger.Warn(ctx, "failed to convert filters, proceeding with fallback", zap.Error(err))
-		backendFilters = fallbackFilterConversion(rawFilters, namespace)
+	fingerprint := searchFingerprint(query, namespace, metadataFilters, opts)
+	if opts.Cursor != nil && (opts.Cursor.Fingerprint != fingerprint || opts.Cursor.Sort != opts.Sort) {
+		return nil, fmt.Errorf("%w: cursor was issued for a different search", ErrInvalidCursor)
 	}
 
 	// Create fugu search query using SDK types
-	fuguQuery := createFuguSearchQuery(query, backendFilters, pagination)
+	fuguQuery := createFuguSearchQuery(parsedQuery.Text, backendFilters, pagination, opts)
 
 	// Execute search on fugu with timeout
 	searchCtx, searchCancel := context.WithTimeout(ctx, 15*time.Second)
 	defer searchCancel()
 
-	fuguResponse, err := s.executeSearch(searchCtx, client, fuguQuery)
-	if err != nil {
-		logger.Error(ctx, "fugu search execution failed", zap.Error(err))
-		return nil, fmt.Errorf("fugu search failed: %w", err)
+	var fuguResponse *fugusdk.SanitizedResponse
+	var collapsed *collapsedResults
+	if opts.Collapse != "" && opts.Collapse != CollapseNone {
+		collapsed, err = s.executeCollapsedSearch(searchCtx, client, fuguQuery, pagination, opts.Collapse)
+		if err != nil {
+			logger.Error(ctx, "fugu collapsed search execution failed", zap.Error(err))
+			return nil, fmt.Errorf("fugu search failed: %w", err)
+		}
+		fuguResponse = &fugusdk.SanitizedResponse{
+			Results: collapsed.page,
+			Total:   collapsed.groups,
+		}
+	} else {
+		fuguResponse, err = s.executeSearch(searchCtx, client, fuguQuery)
+		if err != nil {
+			logger.Error(ctx, "fugu search execution failed", zap.Error(err))
+			return nil, fmt.Errorf("fugu search failed: %w", err)
+		}
+	}
+
+	// The cursor is taken from the raw Fugu page, dropping seen hits or hydration failures must neither end
+	// the walk early nor move the position
+	next := ""
+	if collapsed == nil {
+		next = nextCursor(fuguResponse.Results, pagination.Limit, opts.Cursor, opts.Sort, fingerprint)
+		fuguResponse.Results = dropSeenHits(fuguResponse.Results, opts.Cursor)
 	}
 
 	logger.Info(ctx, "fugu search completed",
 		zap.Int("result_count", len(fuguResponse.Results)))
 
 	// Transform fugu response to frontend format
-	frontendResponse, err := s.transformSearchResponse(ctx, fuguResponse, query, namespace, pagination, time.Since(startTime))
+	frontendResponse, err := s.transformSearchResponse(ctx, fuguResponse, query, parsedQuery.Text, namespace, pagination, opts, time.Since(startTime))
 	if err != nil {
 		logger.Error(ctx, "failed to transform search response", zap.Error(err))
 		return nil, fmt.Errorf("failed to transform response: %w", err)
 	}
 
+	frontendResponse.NextCursor = next
+
+	// Nothing found is often a misspelling, offer a corrected query when one finds something
+	if len(fuguResponse.Results) == 0 && pagination.Page == 0 && opts.Cursor == nil {
+		frontendResponse.Suggestion = s.suggestSpelling(searchCtx, client, query, metadataFilters, searchNamespace, opts)
+	}
+	if collapsed != nil {
+		applyCollapseCounts(frontendResponse, collapsed, opts.Collapse)
+	}
+
+	if len(opts.Facets) > 0 {
+		if collapsed != nil {
+			frontendResponse.Facets = countFacets(collapsed.hits, opts.Facets)
+			frontendResponse.FacetsApproximate = collapsed.truncated
+		} else {
+			facets, truncated, err := s.executeFacetCounts(searchCtx, client, fuguQuery, opts.Facets)
+			if err != nil {
+				// Facets are a refinement aid, the results are still worth returning without them
+				logger.Warn(ctx, "fugu facet count failed, returning results without facets", zap.Error(err))
+			} else {
+				frontendResponse.Facets = facets
+				frontendResponse.FacetsApproximate = truncated
+			}
+		}
+	}
+
 	logger.Info(ctx, "search processing completed successfully",
 		zap.Int("final_result_count", len(frontendResponse.Data)))
 
 	return frontendResponse, nil
 }
 
+// buildBackendFilters combines request filters, query syntax filters and date bounds into fugu filters
+func buildBackendFilters(ctx context.Context, parsedQuery ParsedQuery, metadataFilters map[string]string, namespace string, opts SearchOptions) []string {
+	rawFilters := convertMetadataFiltersToRaw(metadataFilters)
+
+	// Convert filters to backend format with namespace
+	backendFilters, err := convertFiltersToBackend(ctx, rawFilters, namespace)
+	if err != nil {
+		logger.Warn(ctx, "failed to convert filters, proceeding with fallback", zap.Error(err))
+		backendFilters = fallbackFilterConversion(rawFilters, namespace)
+	}
+
+	backendFilters = append(backendFilters, parsedQuery.Filters...)
+	if rangeFilter := dateRangeFilter(parsedQuery.applyDateBounds(opts.DateFrom, opts.DateTo)); rangeFilter != "" {
+		backendFilters = append(backendFilters, rangeFilter)
+	}
+	return backendFilters
+}
+
 var metadataFilterRenameDict map[string]string = map[string]string{
 	"convo_id":   "conversation_id",
 	"author_id":  "author_ids",
@@ -265,7 +452,15 @@ func fallbackFilterConversion(filters map[string]string, namespace string) []str
 }
 
 // createFuguSearchQuery creates a Fugu search query from the request parameters
-func createFuguSearchQuery(query string, filters []string, pagination PaginationParams) fugusdk.FuguSearchQuery {
+func createFuguSearchQuery(query string, filters []string, pagination PaginationParams, opts SearchOptions) fugusdk.FuguSearchQuery {
+	// A cursor replaces the page offset, Fugu resumes after the cursor position instead
+	var searchAfterPtr *[]string
+	if opts.Cursor != nil {
+		searchAfter := opts.Cursor.searchAfter()
+		searchAfterPtr = &searchAfter
+		pagination.Page = 0
+	}
+
 	// Convert our internal pagination to SDK pagination
 	var fuguPagination *fugusdk.Pagination
 	if pagination.Page > 0 || pagination.Limit > 0 {
@@ -281,10 +476,17 @@ func createFuguSearchQuery(query string, filters []string, pagination Pagination
 		filtersPtr = &filters
 	}
 
+	var sortPtr *[]fugusdk.SortField
+	if sortFields := opts.Sort.fuguSortFields(); len(sortFields) > 0 {
+		sortPtr = &sortFields
+	}
+
 	return fugusdk.FuguSearchQuery{
-		Query:   query,
-		Filters: filtersPtr,
-		Page:    fuguPagination,
+		Query:       query,
+		Filters:     filtersPtr,
+		Page:        fuguPagination,
+		Sort:        sortPtr,
+		SearchAfter: searchAfterPtr,
 	}
 }
 
@@ -309,7 +511,8 @@ func (s *SearchService) executeSearch(ctx context.Context, client *fugusdk.Clien
 	logger.Info(ctx, "sending search query to fugu",
 		zap.String("query", query.Query),
 		zap.Any("filters", query.Filters),
-		zap.Any("page", query.Page))
+		zap.Any("page", query.Page),
+		zap.Any("sort", query.Sort))
 
 	// Make the search request using the SDK
 	response, err := client.Search(ctx, query)
@@ -326,49 +529,13 @@ func (s *SearchService) executeSearch(ctx context.Context, client *fugusdk.Clien
 	return response, nil
 }
 
-// extractTitle extracts a meaningful title from the search result
-func (s *SearchService) extractTitle(result fugusdk.FuguSearchResult) string {
-	// Try to extract title from metadata first
-	if result.Metadata != nil {
-		if title, ok := result.Metadata["title"].(string); ok && title != "" {
-			return title
-		}
-		if fileName, ok := result.Metadata["file_name"].(string); ok && fileName != "" {
-			return fileName
-		}
-		if subject, ok := result.Metadata["subject"].(string); ok && subject != "" {
-			return subject
-		}
-		if name, ok := result.Metadata["name"].(string); ok && name != "" {
-			return name
-		}
-	}
-
-	// Fallback to truncated text content
-	if len(result.Text) > 100 {
-		return result.Text[:100] + "..."
-	}
-
-	// Final fallback to ID
-	if result.Text != "" {
-		return result.Text
-	}
-
-	return fmt.Sprintf("Document %s", result.ID)
-}
-
 // GetSearchInfo provides information about search capabilities and status
 func (s *SearchService) GetSearchInfo(ctx context.Context) (*SearchInfo, error) {
 	ctx, span := serviceTracer.Start(ctx, "search-service:get-search-info")
 	defer span.End()
 
-	// Create fugu client to get backend status
-	client, err := fugusdk.NewClient(ctx, s.fuguServerURL)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create fugu client: %w", err)
-	}
-
 	// Check backend health
+	client := s.client
 	backendStatus := "healthy"
 	if err := client.Health(ctx); err != nil {
 		backendStatus = "unhealthy"
@@ -387,6 +554,15 @@ func (s *SearchService) GetSearchInfo(ctx context.Context) (*SearchInfo, error)
 		}
 	}
 
+	supportedSorts := make([]string, 0, len(SupportedSortOptions))
+	for _, option := range SupportedSortOptions {
+		supportedSorts = append(supportedSorts, string(option))
+	}
+	supportedFacets := make([]string, 0, len(SupportedFacets))
+	for _, facet := range SupportedFacets {
+		supportedFacets = append(supportedFacets, string(facet))
+	}
+
 	// Build search info response
 	info := &SearchInfo{
 		Status:      "operational",
@@ -395,8 +571,8 @@ func (s *SearchService) GetSearchInfo(ctx context.Context) (*SearchInfo, error)
 		Capabilities: SearchCapabilities{
 			FilterSupport:     true,
 			PaginationSupport: true,
-			SortingSupport:    false,
-			HighlightSupport:  false,
+			SortingSupport:    true,
+			HighlightSupport:  true,
 			FacetSupport:      true,
 			NamespaceSupport:  true,
 			SupportedQueries: []string{
@@ -407,6 +583,8 @@ func (s *SearchService) GetSearchInfo(ctx context.Context) (*SearchInfo, error)
 				"range_queries",
 				"wildcard_search",
 			},
+			SupportedSorts:    supportedSorts,
+			SupportedFacets:   supportedFacets,
 			MaxQueryLength:    10000,
 			MaxResultsPerPage: 100,
 			SupportedNamespaces: []string{
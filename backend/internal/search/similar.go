@@ -0,0 +1,244 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"kessler/internal/dbstore"
+	"kessler/internal/fugusdk"
+	"kessler/internal/objects/files"
+	"kessler/pkg/logger"
+	"math"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+const (
+	// similarQueryTerms is how many distinctive terms make up a "more like this" query
+	similarQueryTerms = 12
+	// similarMinTermLength skips short words that carry little meaning on their own
+	similarMinTermLength = 4
+	// similarMaxTextLength bounds how many characters of source text are analysed
+	similarMaxTextLength = 200_000
+	// similarOverfetch is how many hits are requested per result to leave room for exclusions
+	similarOverfetch = 4
+)
+
+// ErrSimilarSourceNotFound is returned when the id is neither an attachment nor a file with text
+var ErrSimilarSourceNotFound = errors.New("no attachment or file text found for similar search")
+
+// similarStopWords are common English and filing boilerplate words that never make a document distinctive
+var similarStopWords = map[string]bool{
+	"about": true, "above": true, "after": true, "again": true, "against": true, "also": true,
+	"among": true, "been": true, "before": true, "being": true, "below": true, "between": true,
+	"both": true, "could": true, "does": true, "doing": true, "down": true, "during": true,
+	"each": true, "either": true, "from": true, "further": true, "have": true, "having": true,
+	"here": true, "hereby": true, "herein": true, "however": true, "into": true, "itself": true,
+	"just": true, "more": true, "most": true, "must": true, "neither": true, "only": true,
+	"other": true, "ought": true, "over": true, "same": true, "shall": true, "should": true,
+	"some": true, "such": true, "than": true, "that": true, "their": true, "them": true,
+	"then": true, "there": true, "therefore": true, "these": true, "they": true, "this": true,
+	"those": true, "through": true, "under": true, "until": true, "upon": true, "very": true,
+	"were": true, "what": true, "when": true, "where": true, "whether": true, "which": true,
+	"while": true, "will": true, "with": true, "within": true, "without": true, "would": true,
+	"your": true, "page": true, "pages": true, "dated": true, "date": true, "filed": true,
+	"filing": true, "respectfully": true, "submitted": true, "sincerely": true, "dear": true,
+	"including": true, "pursuant": true, "section": true, "said": true,
+}
+
+// similarSource is the document a "more like this" search starts from
+type similarSource struct {
+	attachmentID uuid.UUID
+	fileID       uuid.UUID
+	conversation uuid.UUID
+	text         string
+}
+
+// distinctiveTerms picks the terms that best characterise a text.
+// Without corpus statistics, frequency is damped and longer words are favoured as they tend to be more specific.
+func distinctiveTerms(text string, limit int) []string {
+	counts := make(map[string]int)
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for _, word := range words {
+		if len([]rune(word)) < similarMinTermLength || similarStopWords[word] || !containsLetter(word) {
+			continue
+		}
+		counts[word]++
+	}
+
+	type scoredTerm struct {
+		term  string
+		score float64
+	}
+	scored := make([]scoredTerm, 0, len(counts))
+	for term, count := range counts {
+		// Terms seen once are as likely to be noise as signal in long filings
+		if count < 2 && len(words) > 200 {
+			continue
+		}
+		scored = append(scored, scoredTerm{
+			term:  term,
+			score: (1 + math.Log(float64(count))) * math.Log(float64(len([]rune(term)))),
+		})
+	}
+	sort.Slice(scored, func(i, j int) bool {
+		if scored[i].score != scored[j].score {
+			return scored[i].score > scored[j].score
+		}
+		return scored[i].term < scored[j].term
+	})
+
+	terms := make([]string, 0, limit)
+	for _, st := range scored {
+		if len(terms) == limit {
+			break
+		}
+		terms = append(terms, st.term)
+	}
+	return terms
+}
+
+func containsLetter(word string) bool {
+	for _, r := range word {
+		if unicode.IsLetter(r) {
+			return true
+		}
+	}
+	return false
+}
+
+// loadSimilarSource resolves an attachment or file id to its text and docket.
+// The attachment cache is tried first, then the attachment table, and finally the id is treated as a file.
+func (s *SearchService) loadSimilarSource(ctx context.Context, id uuid.UUID) (*similarSource, error) {
+	q := dbstore.New(s.db)
+	source := &similarSource{}
+
+	if cached, err := files.CachedAttachment(id); err == nil {
+		source.attachmentID = cached.ID
+		source.fileID = cached.FileID
+		for _, text := range cached.Texts {
+			if text.IsOriginalText || source.text == "" {
+				source.text = text.Text
+			}
+		}
+	} else if attachment, err := q.AttachmentGetById(ctx, id); err == nil {
+		source.attachmentID = attachment.ID
+		source.fileID = attachment.FileID
+	} else {
+		source.fileID = id
+	}
+
+	if source.text == "" {
+		texts, err := q.AttachmentTextListByFileId(ctx, source.fileID)
+		if err != nil {
+			return nil, fmt.Errorf("loading attachment text: %w", err)
+		}
+		var parts []string
+		for _, text := range texts {
+			// An attachment id narrows the text to that attachment, a file id uses all of them
+			if source.attachmentID != uuid.Nil && text.AttachmentID != source.attachmentID {
+				continue
+			}
+			if text.IsOriginalText {
+				parts = append(parts, text.Text)
+			}
+		}
+		source.text = strings.Join(parts, "\n")
+	}
+	if strings.TrimSpace(source.text) == "" {
+		return nil, ErrSimilarSourceNotFound
+	}
+	source.text = truncateRunes(source.text, similarMaxTextLength)
+
+	if convos, err := q.ConversationIDFetchFromFileID(ctx, source.fileID); err == nil && len(convos) > 0 {
+		source.conversation = convos[0].ConversationUuid
+	}
+	return source, nil
+}
+
+// isSimilarCandidate drops hits from the source file and, unless allowed, from the source docket
+func isSimilarCandidate(result fugusdk.FuguSearchResult, source *similarSource, sameDocket bool) bool {
+	typed, _ := fugusdk.DecodeResult[fugusdk.AttachmentMetadata](result)
+	if source.fileID != uuid.Nil && typed.Metadata.FileID == source.fileID {
+		return false
+	}
+	if !sameDocket && source.conversation != uuid.Nil && typed.Metadata.ConversationID == source.conversation {
+		return false
+	}
+	return true
+}
+
+// ProcessSimilarSearch finds documents resembling an attachment or file using its most distinctive terms
+func (s *SearchService) ProcessSimilarSearch(ctx context.Context, id uuid.UUID, pagination PaginationParams, sameDocket bool) (*SearchResponse, error) {
+	ctx, span := serviceTracer.Start(ctx, "search-service:process-similar-search")
+	defer span.End()
+
+	startTime := time.Now()
+
+	source, err := s.loadSimilarSource(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return s.searchSimilar(ctx, source, pagination, sameDocket, startTime)
+}
+
+// searchSimilar runs the "more like this" query for a loaded source and pages through the remaining hits
+func (s *SearchService) searchSimilar(ctx context.Context, source *similarSource, pagination PaginationParams, sameDocket bool, startTime time.Time) (*SearchResponse, error) {
+	terms := distinctiveTerms(source.text, similarQueryTerms)
+	if len(terms) == 0 {
+		return nil, ErrSimilarSourceNotFound
+	}
+	query := strings.Join(terms, " OR ")
+
+	logger.Info(ctx, "running similar document search",
+		zap.String("attachment_id", source.attachmentID.String()),
+		zap.String("file_id", source.fileID.String()),
+		zap.Strings("terms", terms))
+
+	searchNamespace, client := s.resolveNamespace(ctx, "")
+
+	// Only attachments are comparable, exclusions are applied locally since fugu filters cannot negate
+	filters := []string{"metadata/entity_type/attachment"}
+	if searchNamespace != "" {
+		filters = append(filters, "namespace/"+searchNamespace)
+	}
+	fetch := PaginationParams{Page: 0, Limit: min((pagination.Page+1)*pagination.Limit*similarOverfetch, 100)}
+	fuguQuery := createFuguSearchQuery(query, filters, fetch, SearchOptions{})
+
+	searchCtx, searchCancel := context.WithTimeout(ctx, 15*time.Second)
+	defer searchCancel()
+	fuguResponse, err := s.executeSearch(searchCtx, client, fuguQuery)
+	if err != nil {
+		return nil, fmt.Errorf("fugu search failed: %w", err)
+	}
+
+	var candidates []fugusdk.FuguSearchResult
+	for _, result := range fuguResponse.Results {
+		if isSimilarCandidate(result, source, sameDocket) {
+			candidates = append(candidates, result)
+		}
+	}
+	candidates, _ = collapseHits(candidates, CollapseAttachment)
+
+	start := min(pagination.Page*pagination.Limit, len(candidates))
+	end := min(start+pagination.Limit, len(candidates))
+	page := &fugusdk.SanitizedResponse{
+		Results: candidates[start:end],
+		Total:   fuguResponse.Total,
+	}
+
+	response, err := s.transformSearchResponse(ctx, page, query, query, "", pagination, SearchOptions{Sort: SortRelevance}, time.Since(startTime))
+	if err != nil {
+		return nil, err
+	}
+	// Fugu's total still counts the excluded and repeated hits beyond the fetched pool
+	response.TotalApproximate = true
+	return response, nil
+}
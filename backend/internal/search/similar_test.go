@@ -0,0 +1,70 @@
+package search
+
+import (
+	"context"
+	"kessler/internal/fugusdk"
+	"kessler/internal/fugusdk/fugutest"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestDistinctiveTerms(t *testing.T) {
+	text := "The petition concerns decoupling. Decoupling mechanisms and decoupling riders " +
+		"were filed with the commission, which shall review this petition. Page 2 of 10, 2024."
+
+	terms := distinctiveTerms(text, 3)
+	if len(terms) != 3 {
+		t.Fatalf("expected 3 terms, got %v", terms)
+	}
+	if terms[0] != "decoupling" {
+		t.Errorf("expected the most repeated term first, got %v", terms)
+	}
+	for _, stop := range []string{"shall", "which", "page", "2024", "the"} {
+		if slices.Contains(terms, stop) {
+			t.Errorf("stop word or number %q selected in %v", stop, terms)
+		}
+	}
+}
+
+func TestSearchSimilarExclusions(t *testing.T) {
+	ctx := context.Background()
+	server := fugutest.NewServer(t)
+	client, err := server.Client(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sourceFile, sourceDocket := uuid.New(), uuid.New()
+	attachment := func(metadata map[string]interface{}) fugusdk.FuguSearchResult {
+		id := uuid.New()
+		server.Seed(fugusdk.ObjectRecord{
+			ID:       id.String(),
+			Text:     "Decoupling riders and decoupling mechanisms for the utility.",
+			Metadata: metadata,
+			Facets:   []string{"metadata/entity_type/attachment"},
+		})
+		return fugusdk.FuguSearchResult{ID: id.String(), Metadata: metadata}
+	}
+	fromSource := attachment(map[string]interface{}{"file_id": sourceFile.String(), "conversation_id": sourceDocket.String()})
+	fromDocket := attachment(map[string]interface{}{"file_id": uuid.NewString(), "conversation_id": sourceDocket.String()})
+	other := attachment(map[string]interface{}{"file_id": uuid.NewString()})
+	s := &SearchService{client: client}
+	source := &similarSource{fileID: sourceFile, conversation: sourceDocket, text: "Decoupling mechanisms, decoupling riders."}
+
+	response, err := s.searchSimilar(ctx, source, PaginationParams{Limit: 10}, false, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(response.Data) != 1 || response.Data[0].(DocumentCardData).AttachmentUUID.String() != other.ID {
+		t.Errorf("expected only the attachment outside the source docket, got %+v", response.Data)
+	}
+	if response.Total != 3 || !response.TotalApproximate {
+		t.Errorf("expected Fugu's total of 3 marked approximate, got %d, %v", response.Total, response.TotalApproximate)
+	}
+
+	if isSimilarCandidate(fromSource, source, true) || !isSimilarCandidate(fromDocket, source, true) {
+		t.Error("same_docket keeps the source docket but never the source file")
+	}
+}
@@ -0,0 +1,125 @@
+package search
+
+import (
+	"fmt"
+	"kessler/internal/fugusdk"
+	"sort"
+	"strings"
+)
+
+// SortOption selects the order search results are returned in
+type SortOption string
+
+const (
+	SortRelevance         SortOption = "relevance"
+	SortDatePublishedDesc SortOption = "date_published_desc"
+	SortDatePublishedAsc  SortOption = "date_published_asc"
+	SortDocketNumber      SortOption = "docket_number"
+	SortAuthorName        SortOption = "author_name"
+)
+
+// SupportedSortOptions lists every sort accepted by the search endpoints
+var SupportedSortOptions = []SortOption{
+	SortRelevance,
+	SortDatePublishedDesc,
+	SortDatePublishedAsc,
+	SortDocketNumber,
+	SortAuthorName,
+}
+
+// ParseSortOption validates a sort parameter, an empty value means relevance
+func ParseSortOption(raw string) (SortOption, error) {
+	raw = strings.ToLower(strings.TrimSpace(raw))
+	if raw == "" {
+		return SortRelevance, nil
+	}
+	for _, option := range SupportedSortOptions {
+		if string(option) == raw {
+			return option, nil
+		}
+	}
+	return "", fmt.Errorf("unsupported sort %q", raw)
+}
+
+// idTiebreaker orders hits with equal sort values so cursor positions are stable
+var idTiebreaker = fugusdk.SortField{Field: "id", Order: fugusdk.SortAscending}
+
+// fuguSortFields converts a sort option into the sort keys sent to Fugu.
+// Relevance is Fugu's default order so no keys are sent for it.
+func (s SortOption) fuguSortFields() []fugusdk.SortField {
+	switch s {
+	case SortDatePublishedDesc:
+		return []fugusdk.SortField{{Field: "metadata/date_iso", Order: fugusdk.SortDescending}, idTiebreaker}
+	case SortDatePublishedAsc:
+		return []fugusdk.SortField{{Field: "metadata/date_iso", Order: fugusdk.SortAscending}, idTiebreaker}
+	case SortDocketNumber:
+		return []fugusdk.SortField{{Field: "metadata/docket_gov_id", Order: fugusdk.SortAscending}, idTiebreaker}
+	case SortAuthorName:
+		return []fugusdk.SortField{{Field: "metadata/author_names", Order: fugusdk.SortAscending}, idTiebreaker}
+	default:
+		return nil
+	}
+}
+
+// sortCards orders hydrated cards by the requested sort and renumbers their index. keys holds the sort key
+// of the hit each card was hydrated from, as hitSortKey gives it, so cards are ordered exactly as Fugu cut the
+// pages and as cursors compare. Hydration can drop or reorder results, so the order Fugu returned is not trusted.
+func sortCards(cards []CardData, keys []string, option SortOption) {
+	if option != SortRelevance && option != "" {
+		order := make([]int, len(cards))
+		for i := range order {
+			order[i] = i
+		}
+		sort.SliceStable(order, func(i, j int) bool {
+			a, b := order[i], order[j]
+			return cardLess(cards[a], cards[b], keys[a], keys[b], option)
+		})
+		sorted := make([]CardData, len(cards))
+		for i, from := range order {
+			sorted[i] = cards[from]
+		}
+		copy(cards, sorted)
+	}
+	for i, card := range cards {
+		cards[i] = withIndex(card, i)
+	}
+}
+
+// cardLess compares two cards by their sort keys, non-document cards sort after documents
+func cardLess(a, b CardData, keyA, keyB string, option SortOption) bool {
+	_, okA := a.(DocumentCardData)
+	_, okB := b.(DocumentCardData)
+	if !okA || !okB {
+		return okA && !okB
+	}
+	if option == SortDatePublishedDesc {
+		// Missing dates are empty keys and so already sort last
+		return keyA > keyB
+	}
+	return compareEmptyLast(keyA, keyB)
+}
+
+// compareEmptyLast orders strings with empty values last. Values compare case-sensitively, as Fugu sorts
+// them, so cards keep the order pages were cut in.
+func compareEmptyLast(a, b string) bool {
+	if (a == "") != (b == "") {
+		return b == ""
+	}
+	return a < b
+}
+
+// withIndex returns a copy of the card with its index set
+func withIndex(card CardData, index int) CardData {
+	switch c := card.(type) {
+	case DocumentCardData:
+		c.Index = index
+		return c
+	case AuthorCardData:
+		c.Index = index
+		return c
+	case DocketCardData:
+		c.Index = index
+		return c
+	}
+	return card
+}
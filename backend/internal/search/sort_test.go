@@ -0,0 +1,129 @@
+package search
+
+import (
+	"encoding/json"
+	"kessler/internal/fugusdk"
+	"strings"
+	"testing"
+)
+
+func TestParseSortOption(t *testing.T) {
+	tests := map[string]SortOption{
+		"":                     SortRelevance,
+		" Date_Published_Desc": SortDatePublishedDesc,
+		"docket_number":        SortDocketNumber,
+	}
+	for raw, want := range tests {
+		if got, err := ParseSortOption(raw); err != nil || got != want {
+			t.Errorf("ParseSortOption(%q) = %q, %v, want %q", raw, got, err, want)
+		}
+	}
+	if _, err := ParseSortOption("popularity"); err == nil {
+		t.Error("expected an error for an unsupported sort")
+	}
+}
+
+func TestFuguSortFields(t *testing.T) {
+	if fields := SortRelevance.fuguSortFields(); fields != nil {
+		t.Errorf("relevance sends no sort keys, got %+v", fields)
+	}
+	for _, option := range SupportedSortOptions[1:] {
+		fields := option.fuguSortFields()
+		if len(fields) != 2 || fields[1] != idTiebreaker {
+			t.Errorf("%s must end with the id tiebreaker, got %+v", option, fields)
+		}
+	}
+	if fields := SortDatePublishedDesc.fuguSortFields(); fields[0].Order != fugusdk.SortDescending {
+		t.Errorf("expected a descending date sort, got %+v", fields[0])
+	}
+}
+
+func TestSortCards(t *testing.T) {
+	hit := func(date, docket string, authors ...string) fugusdk.FuguSearchResult {
+		metadata := map[string]interface{}{"date_iso": date, "docket_gov_id": docket}
+		if len(authors) > 0 {
+			names := make([]interface{}, len(authors))
+			for i, author := range authors {
+				names[i] = author
+			}
+			metadata["author_names"] = names
+		}
+		return fugusdk.FuguSearchResult{Metadata: metadata}
+	}
+	doc := func(name string, primaryAuthor string) CardData {
+		card := DocumentCardData{Name: name, Type: "document"}
+		if primaryAuthor != "" {
+			card.Authors = []DocumentAuthor{{AuthorName: "secondary"}, {AuthorName: primaryAuthor, IsPrimaryAuthor: true}}
+		}
+		return card
+	}
+	names := func(cards []CardData) string {
+		out := make([]string, len(cards))
+		for i, card := range cards {
+			switch c := card.(type) {
+			case DocumentCardData:
+				out[i] = c.Name
+				if c.Index != i {
+					t.Errorf("card %s has index %d at position %d", c.Name, c.Index, i)
+				}
+			case DocketCardData:
+				out[i] = c.Name
+			}
+		}
+		return strings.Join(out, ",")
+	}
+	cards := func() []CardData {
+		return []CardData{
+			doc("a", "beta"),
+			DocketCardData{Name: "docket", Type: "docket"},
+			doc("b", ""),
+			// The primary author is not listed first, the first listed author is what Fugu sorts on
+			doc("c", "zeta"),
+			doc("d", "gamma"),
+		}
+	}
+	hits := []fugusdk.FuguSearchResult{
+		hit("2024-01-02", "22-002", "beta"),
+		{},
+		hit("", ""),
+		hit("2024-01-05", "22-001", "alpha", "zeta"),
+		hit("2024-01-01", "22-003", "gamma"),
+	}
+
+	tests := map[SortOption]string{
+		SortRelevance:         "a,docket,b,c,d",
+		SortDatePublishedDesc: "c,a,d,b,docket",
+		SortDatePublishedAsc:  "d,a,c,b,docket",
+		SortDocketNumber:      "c,a,d,b,docket",
+		SortAuthorName:        "c,a,d,b,docket",
+	}
+	for option, want := range tests {
+		sorted := cards()
+		keys := make([]string, len(hits))
+		for i, result := range hits {
+			keys[i] = hitSortKey(result, option)
+		}
+		sortCards(sorted, keys, option)
+		if got := names(sorted); got != want {
+			t.Errorf("sortCards(%s) = %s, want %s", option, got, want)
+		}
+	}
+
+	// The cursor on the first author sorted card places the cards after it in the same order
+	cursor := cursorFor(hits[3], SortAuthorName, "")
+	for _, i := range []int{0, 4} {
+		if !cursor.isAfter(hits[i]) {
+			t.Errorf("hit %d sorts after card c but the cursor does not place it after", i)
+		}
+	}
+}
+
+func TestDatePublishedOmittedWhenZero(t *testing.T) {
+	data, err := json.Marshal(DocumentCardData{Type: "document"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "date_published") {
+		t.Errorf("a card without a publish date must omit it, got %s", data)
+	}
+}
@@ -0,0 +1,278 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"kessler/internal/dbstore"
+	"kessler/internal/fugusdk"
+	"kessler/pkg/database"
+	"kessler/pkg/logger"
+	"kessler/pkg/util"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// spellingDictionaryTTL is how long a harvested dictionary is used before it is rebuilt in the background
+	spellingDictionaryTTL = 6 * time.Hour
+	// entityTermWeight makes organization and docket names win over words that only appear in filings
+	entityTermWeight = 1000
+	// Attachment words must appear in this many texts to count as spelled right
+	spellingMinDocuments = 3
+	spellingMaxTerms     = 50000
+	spellingMinWordRunes = 4
+)
+
+// SpellingSuggestion is a corrected query offered when a search found nothing
+type SpellingSuggestion struct {
+	Query string `json:"query"`
+	Hits  int    `json:"hits"`
+}
+
+// termDictionary holds known words with a frequency, bucketed by length for edit distance lookups
+type termDictionary struct {
+	freq     map[string]int64
+	byLength map[int][]string
+}
+
+func newTermDictionary() *termDictionary {
+	return &termDictionary{freq: make(map[string]int64), byLength: make(map[int][]string)}
+}
+
+// add records a known term, lower cased
+func (d *termDictionary) add(term string, weight int64) {
+	term = strings.ToLower(strings.TrimSpace(term))
+	if term == "" || weight <= 0 {
+		return
+	}
+	if _, known := d.freq[term]; !known {
+		n := utf8.RuneCountInString(term)
+		d.byLength[n] = append(d.byLength[n], term)
+	}
+	d.freq[term] += weight
+}
+
+// addText records every word of a name
+func (d *termDictionary) addText(text string, weight int64) {
+	for _, word := range strings.FieldsFunc(text, func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) }) {
+		if utf8.RuneCountInString(word) >= spellingMinWordRunes {
+			d.add(word, weight)
+		}
+	}
+}
+
+// spellingEdits is how many edits a word of this length may be away from its correction
+func spellingEdits(runes int) int {
+	if runes < 8 {
+		return 1
+	}
+	return 2
+}
+
+// correct returns the closest known term to word, preferring fewer edits then the more frequent term.
+// Known words, short words and numbers are left alone.
+func (d *termDictionary) correct(word string) (string, bool) {
+	lower := strings.ToLower(word)
+	n := utf8.RuneCountInString(lower)
+	if n < spellingMinWordRunes || d.freq[lower] > 0 || !strings.ContainsFunc(lower, unicode.IsLetter) {
+		return "", false
+	}
+
+	edits := spellingEdits(n)
+	best, bestDistance, bestFreq := "", edits+1, int64(0)
+	for length := n - edits; length <= n+edits; length++ {
+		for _, term := range d.byLength[length] {
+			distance := util.EditDistance(lower, term)
+			if distance < bestDistance || (distance == bestDistance && d.freq[term] > bestFreq) {
+				best, bestDistance, bestFreq = term, distance, d.freq[term]
+			}
+		}
+	}
+	if best == "" {
+		return "", false
+	}
+	return matchCase(word, best), true
+}
+
+// matchCase capitalises a correction the way the typed word was
+func matchCase(typed, correction string) string {
+	first, _ := utf8.DecodeRuneInString(typed)
+	switch {
+	case strings.ToUpper(typed) == typed && strings.ContainsFunc(typed, unicode.IsLetter):
+		return strings.ToUpper(correction)
+	case unicode.IsUpper(first):
+		r, size := utf8.DecodeRuneInString(correction)
+		return string(unicode.ToUpper(r)) + correction[size:]
+	default:
+		return correction
+	}
+}
+
+// queryWordPattern finds words in a query, hyphens are kept so docket numbers are corrected whole
+var queryWordPattern = regexp.MustCompile(`[\p{L}\p{N}][\p{L}\p{N}\-']*`)
+
+// correctQuery replaces misspelled free text words of a query, leaving quoted phrases, field names,
+// field values and operators as typed. It reports whether anything changed.
+func correctQuery(dict *termDictionary, query string) (string, bool) {
+	spans := queryWordPattern.FindAllStringIndex(query, -1)
+	changed := false
+	var b strings.Builder
+	last := 0
+	for _, span := range spans {
+		start, end := span[0], span[1]
+		word := strings.TrimRight(query[start:end], "-'")
+		end = start + len(word)
+
+		b.WriteString(query[last:start])
+		last = end
+		inQuotes := strings.Count(query[:start], `"`)%2 == 1
+		fieldName := end < len(query) && query[end] == ':'
+		fieldValue := start > 0 && query[start-1] == ':'
+		if inQuotes || fieldName || fieldValue || isQueryOperator(word) {
+			b.WriteString(word)
+			continue
+		}
+		if correction, ok := dict.correct(word); ok {
+			b.WriteString(correction)
+			changed = true
+			continue
+		}
+		b.WriteString(word)
+	}
+	b.WriteString(query[last:])
+	return b.String(), changed
+}
+
+func isQueryOperator(word string) bool {
+	return word == "AND" || word == "OR" || word == "NOT"
+}
+
+// spellChecker keeps a term dictionary harvested from Postgres, rebuilding it in the background when stale
+type spellChecker struct {
+	db dbstore.DBTX
+
+	mu       sync.Mutex
+	dict     *termDictionary
+	builtAt  time.Time
+	building bool
+}
+
+func newSpellChecker(db dbstore.DBTX) *spellChecker {
+	return &spellChecker{db: db}
+}
+
+// dictionary returns the current dictionary, nil until the first build finishes. A missing or stale
+// dictionary starts a rebuild so searches never wait for one.
+func (c *spellChecker) dictionary(ctx context.Context) *termDictionary {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.building && (c.dict == nil || time.Since(c.builtAt) > spellingDictionaryTTL) {
+		c.building = true
+		go c.rebuild(logger.WithLogger(context.Background()))
+	}
+	return c.dict
+}
+
+func (c *spellChecker) rebuild(ctx context.Context) {
+	dict, err := harvestTerms(ctx, c.db)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.building = false
+	if err != nil {
+		// Keep serving the previous dictionary, the next zero-hit search retries
+		logger.Warn(ctx, "failed to build spelling dictionary", zap.Error(err))
+		return
+	}
+	c.dict, c.builtAt = dict, time.Now()
+	logger.Info(ctx, "spelling dictionary built", zap.Int("terms", len(dict.freq)))
+}
+
+// harvestTerms builds a dictionary from organization names and aliases, docket names and numbers,
+// and words used across many attachment texts
+func harvestTerms(ctx context.Context, db dbstore.DBTX) (*termDictionary, error) {
+	q := database.GetQueries(db)
+	dict := newTermDictionary()
+
+	orgs, err := q.OrganizationList(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list organizations: %w", err)
+	}
+	for _, org := range orgs {
+		dict.addText(org.Name, entityTermWeight)
+		// The canonical name is kept when the aliases cannot be read
+		aliases, err := q.OrganizationGetAllAliases(ctx, org.ID)
+		if err != nil {
+			logger.Warn(ctx, "failed to read organization aliases for the spelling dictionary",
+				zap.String("organization_id", org.ID.String()),
+				zap.Error(err))
+			continue
+		}
+		for _, alias := range aliases {
+			dict.addText(alias.OrganizationAlias, entityTermWeight)
+		}
+	}
+
+	dockets, err := q.DocketConversationList(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list dockets: %w", err)
+	}
+	for _, docket := range dockets {
+		dict.addText(docket.Name, entityTermWeight)
+		dict.add(docket.DocketGovID, entityTermWeight)
+	}
+
+	terms, err := q.AttachmentTextTermsList(ctx, dbstore.AttachmentTextTermsListParams{
+		MinDocuments: spellingMinDocuments,
+		MaxTerms:     spellingMaxTerms,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("list attachment terms: %w", err)
+	}
+	for _, term := range terms {
+		dict.add(term.Word, term.Documents)
+	}
+	return dict, nil
+}
+
+// suggestSpelling offers a corrected query for a search that found nothing, only when the correction finds hits
+func (s *SearchService) suggestSpelling(ctx context.Context, client *fugusdk.Client, query string, metadataFilters map[string]string, namespace string, opts SearchOptions) *SpellingSuggestion {
+	dict := s.spelling.dictionary(ctx)
+	if dict == nil {
+		return nil
+	}
+	corrected, changed := correctQuery(dict, query)
+	if !changed {
+		return nil
+	}
+	parsed, err := ParseQuery(corrected)
+	if err != nil {
+		return nil
+	}
+
+	fuguQuery := createFuguSearchQuery(parsed.Text, buildBackendFilters(ctx, parsed, metadataFilters, namespace, opts), PaginationParams{Page: 0, Limit: 1}, SearchOptions{Sort: SortRelevance})
+	resp, err := s.executeSearch(ctx, client, fuguQuery)
+	if err != nil {
+		logger.Warn(ctx, "spelling suggestion search failed", zap.String("suggestion", corrected), zap.Error(err))
+		return nil
+	}
+	hits := max(resp.Total, len(resp.Results))
+	if hits == 0 {
+		return nil
+	}
+	logger.Info(ctx, "offering spelling suggestion",
+		zap.String("query", query),
+		zap.String("suggestion", corrected),
+		zap.Int("hits", hits))
+	return &SpellingSuggestion{Query: corrected, Hits: hits}
+}
@@ -0,0 +1,64 @@
+package search
+
+import "testing"
+
+func testDictionary() *termDictionary {
+	dict := newTermDictionary()
+	dict.addText("Consolidated Edison Company of New York", entityTermWeight)
+	dict.addText("National Grid", entityTermWeight)
+	dict.add("18-E-0138", entityTermWeight)
+	dict.add("interconnection", 40)
+	dict.add("tariff", 120)
+	dict.add("tariffs", 30)
+	dict.add("metering", 50)
+	return dict
+}
+
+func TestTermDictionaryCorrect(t *testing.T) {
+	dict := testDictionary()
+	tests := []struct {
+		word string
+		want string
+		ok   bool
+	}{
+		{"edisn", "edison", true},
+		{"Edisn", "Edison", true},
+		{"EDISN", "EDISON", true},
+		{"interconection", "interconnection", true},
+		{"consoldiated", "consolidated", true},
+		{"tarif", "tariff", true},
+		{"18-E-0183", "18-E-0138", true},
+		{"edison", "", false},
+		{"grd", "", false},
+		{"2024", "", false},
+		{"photosynthesis", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := dict.correct(tt.word)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("correct(%q) = %q, %v, want %q, %v", tt.word, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestCorrectQuery(t *testing.T) {
+	dict := testDictionary()
+	tests := []struct {
+		query   string
+		want    string
+		changed bool
+	}{
+		{"nationl grid tarif", "national grid tariff", true},
+		{"Edisn AND metring", "Edison AND metering", true},
+		{`"nationl grid" tarif`, `"nationl grid" tariff`, true},
+		{"author:Edisn interconection", "author:Edisn interconnection", true},
+		{"metring -tarif", "metering -tariff", true},
+		{"national grid", "national grid", false},
+	}
+	for _, tt := range tests {
+		got, changed := correctQuery(dict, tt.query)
+		if got != tt.want || changed != tt.changed {
+			t.Errorf("correctQuery(%q) = %q, %v, want %q, %v", tt.query, got, changed, tt.want, tt.changed)
+		}
+	}
+}
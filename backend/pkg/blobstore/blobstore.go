@@ -0,0 +1,84 @@
+// Package blobstore stores raw attachment bytes keyed by their KesslerHash, in S3 compatible object
+// storage or on the local filesystem, with an LRU bounded local cache in front.
+package blobstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"kessler/pkg/hashes"
+)
+
+var (
+	// ErrNotFound is returned when no blob is stored under a hash
+	ErrNotFound = errors.New("blob not found")
+	// ErrPresignUnsupported is returned by stores that cannot hand out a URL for a blob
+	ErrPresignUnsupported = errors.New("blob store cannot presign urls")
+)
+
+// BlobInfo describes a stored blob
+type BlobInfo struct {
+	Hash    hashes.KesslerHash
+	Size    int64
+	ModTime time.Time
+}
+
+// BlobStore is content addressed storage for raw attachments. Blobs are immutable, so Put of an existing
+// hash is expected to write the same bytes again.
+type BlobStore interface {
+	// Put stores everything read from r under hash, callers pass the hash of the content
+	Put(ctx context.Context, hash hashes.KesslerHash, r io.Reader) error
+	// Get opens a blob for reading, the caller closes it
+	Get(ctx context.Context, hash hashes.KesslerHash) (io.ReadCloser, error)
+	Stat(ctx context.Context, hash hashes.KesslerHash) (BlobInfo, error)
+	// Delete removes a blob, deleting a missing blob is not an error
+	Delete(ctx context.Context, hash hashes.KesslerHash) error
+	// List calls fn for every stored blob until fn returns an error
+	List(ctx context.Context, fn func(BlobInfo) error) error
+	// PresignGet returns a URL an external service can download the blob from until expiry passes
+	PresignGet(ctx context.Context, hash hashes.KesslerHash, expiry time.Duration) (string, error)
+}
+
+// PutFile hashes a file and stores it, returning its hash
+func PutFile(ctx context.Context, store BlobStore, path string) (hashes.KesslerHash, error) {
+	hash, err := hashes.HashFromFile(path)
+	if err != nil {
+		return hashes.KesslerHash{}, fmt.Errorf("hashing %s: %w", path, err)
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return hashes.KesslerHash{}, err
+	}
+	defer file.Close()
+	if err := store.Put(ctx, hash, file); err != nil {
+		return hashes.KesslerHash{}, fmt.Errorf("storing %s: %w", hash, err)
+	}
+	return hash, nil
+}
+
+// New builds the configured backend behind a local cache
+func New(cfg Config) (*CachedStore, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	var backend BlobStore
+	switch cfg.Backend {
+	case BackendS3:
+		s3Store, err := NewS3Store(cfg.S3)
+		if err != nil {
+			return nil, err
+		}
+		backend = s3Store
+	case BackendLocal:
+		localStore, err := NewLocalStore(cfg.LocalDir, cfg.LocalBaseURL)
+		if err != nil {
+			return nil, err
+		}
+		backend = localStore
+	}
+	return NewCachedStore(backend, cfg.CacheDir, cfg.CacheMaxBytes)
+}
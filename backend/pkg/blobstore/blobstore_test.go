@@ -0,0 +1,283 @@
+package blobstore
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"kessler/pkg/hashes"
+)
+
+func readAll(t *testing.T, r io.ReadCloser, err error) string {
+	t.Helper()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	data, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func put(t *testing.T, store BlobStore, content string) hashes.KesslerHash {
+	t.Helper()
+	hash := hashes.HashFromBytes([]byte(content))
+	if err := store.Put(context.Background(), hash, strings.NewReader(content)); err != nil {
+		t.Fatal(err)
+	}
+	return hash
+}
+
+func TestLocalStore(t *testing.T) {
+	ctx := context.Background()
+	store, err := NewLocalStore(t.TempDir(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hash := put(t, store, "testimony")
+	body, err := store.Get(ctx, hash)
+	if got := readAll(t, body, err); got != "testimony" {
+		t.Errorf("Get = %q", got)
+	}
+	if info, err := store.Stat(ctx, hash); err != nil || info.Size != int64(len("testimony")) {
+		t.Errorf("Stat = %+v, %v", info, err)
+	}
+
+	// Unfinished writes are not listed
+	os.WriteFile(filepath.Join(store.dir, ".tmp-123"), []byte("partial"), 0o644)
+	var listed []hashes.KesslerHash
+	if err := store.List(ctx, func(info BlobInfo) error {
+		listed = append(listed, info.Hash)
+		return nil
+	}); err != nil || len(listed) != 1 || listed[0] != hash {
+		t.Errorf("List = %v, %v", listed, err)
+	}
+
+	url, err := store.PresignGet(ctx, hash, time.Hour)
+	if err != nil || !strings.HasPrefix(url, "file://") {
+		t.Errorf("PresignGet = %q, %v", url, err)
+	}
+
+	if err := store.Delete(ctx, hash); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Get(ctx, hash); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get after Delete = %v, want ErrNotFound", err)
+	}
+	if err := store.Delete(ctx, hash); err != nil {
+		t.Errorf("deleting a missing blob should succeed, got %v", err)
+	}
+}
+
+func TestLocalStorePresignWithBaseURL(t *testing.T) {
+	store, err := NewLocalStore(t.TempDir(), "http://localhost:4041/public/raw_attachments/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	hash := put(t, store, "exhibit")
+	url, err := store.PresignGet(context.Background(), hash, time.Hour)
+	if err != nil || url != "http://localhost:4041/public/raw_attachments/"+hash.String() {
+		t.Errorf("PresignGet = %q, %v", url, err)
+	}
+}
+
+func TestPutFile(t *testing.T) {
+	store, err := NewLocalStore(t.TempDir(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "filing.pdf")
+	os.WriteFile(path, []byte("%PDF-1.7"), 0o644)
+
+	hash, err := PutFile(context.Background(), store, path)
+	if err != nil || hash != hashes.HashFromBytes([]byte("%PDF-1.7")) {
+		t.Fatalf("PutFile = %v, %v", hash, err)
+	}
+	if _, err := store.Stat(context.Background(), hash); err != nil {
+		t.Errorf("stored file missing: %v", err)
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	if err := DefaultConfig().Validate(); err != nil {
+		t.Errorf("default config invalid: %v", err)
+	}
+	cfg := DefaultConfig()
+	cfg.Backend = "gcs"
+	cfg.CacheMaxBytes = 0
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "gcs") || !strings.Contains(err.Error(), "cache size") {
+		t.Errorf("expected backend and cache size errors, got %v", err)
+	}
+}
+
+// countingStore counts Gets so tests can tell cache hits from downloads
+type countingStore struct {
+	BlobStore
+	gets  atomic.Int32
+	delay time.Duration
+}
+
+func (s *countingStore) Get(ctx context.Context, hash hashes.KesslerHash) (io.ReadCloser, error) {
+	s.gets.Add(1)
+	time.Sleep(s.delay)
+	return s.BlobStore.Get(ctx, hash)
+}
+
+func newCachedTestStore(t *testing.T, maxBytes int64) (*CachedStore, *countingStore) {
+	t.Helper()
+	local, err := NewLocalStore(t.TempDir(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	backend := &countingStore{BlobStore: local}
+	cache, err := NewCachedStore(backend, t.TempDir(), maxBytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cache, backend
+}
+
+func TestCachedStoreEvictsLeastRecentlyUsed(t *testing.T) {
+	ctx := context.Background()
+	cache, backend := newCachedTestStore(t, 20)
+	a := put(t, backend, "aaaaaaaaaa")
+	b := put(t, backend, "bbbbbbbbbb")
+	c := put(t, backend, "cccccccccc")
+
+	for _, hash := range []hashes.KesslerHash{a, b, a} {
+		if _, err := cache.Path(ctx, hash); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := backend.gets.Load(); got != 2 {
+		t.Fatalf("expected the second read of a to be a cache hit, got %d downloads", got)
+	}
+
+	// b is least recently used and makes room for c
+	if _, err := cache.Path(ctx, c); err != nil {
+		t.Fatal(err)
+	}
+	if cache.Size() != 20 {
+		t.Errorf("cache size = %d, want 20", cache.Size())
+	}
+	if _, err := os.Stat(cache.path(b)); !os.IsNotExist(err) {
+		t.Errorf("b should have been evicted, stat err = %v", err)
+	}
+	if _, err := os.Stat(cache.path(a)); err != nil {
+		t.Errorf("a should still be cached: %v", err)
+	}
+}
+
+func TestCachedStoreKeepsBlobLargerThanCache(t *testing.T) {
+	cache, backend := newCachedTestStore(t, 4)
+	hash := put(t, backend, "larger than the cache")
+	body, err := cache.Get(context.Background(), hash)
+	if got := readAll(t, body, err); got != "larger than the cache" {
+		t.Errorf("Get = %q", got)
+	}
+}
+
+func TestCachedStoreDownloadsOnce(t *testing.T) {
+	cache, backend := newCachedTestStore(t, 1<<20)
+	backend.delay = 20 * time.Millisecond
+	hash := put(t, backend, "rate case order")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			body, err := cache.Get(context.Background(), hash)
+			if got := readAll(t, body, err); got != "rate case order" {
+				t.Errorf("Get = %q", got)
+			}
+		}()
+	}
+	wg.Wait()
+	if got := backend.gets.Load(); got != 1 {
+		t.Errorf("concurrent reads downloaded %d times, want 1", got)
+	}
+}
+
+func TestCachedStoreAdoptsExistingFiles(t *testing.T) {
+	ctx := context.Background()
+	cache, backend := newCachedTestStore(t, 1<<20)
+	hash := put(t, backend, "already cached")
+	if _, err := cache.Path(ctx, hash); err != nil {
+		t.Fatal(err)
+	}
+
+	reopened, err := NewCachedStore(backend, cache.dir, 1<<20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := reopened.Path(ctx, hash); err != nil {
+		t.Fatal(err)
+	}
+	if got := backend.gets.Load(); got != 1 {
+		t.Errorf("reopened cache downloaded again, %d downloads", got)
+	}
+	if reopened.Size() != int64(len("already cached")) {
+		t.Errorf("reopened cache size = %d", reopened.Size())
+	}
+}
+
+func TestCachedStoreMissingBlob(t *testing.T) {
+	cache, _ := newCachedTestStore(t, 1<<20)
+	if _, err := cache.Get(context.Background(), hashes.HashFromBytes([]byte("nothing"))); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get = %v, want ErrNotFound", err)
+	}
+}
+
+// rangeRecorder records the spans requested from a local store
+type rangeRecorder struct {
+	*LocalStore
+	mu    sync.Mutex
+	spans [][2]int64
+}
+
+func (s *rangeRecorder) GetRange(ctx context.Context, hash hashes.KesslerHash, offset, end int64) (io.ReadCloser, error) {
+	s.mu.Lock()
+	s.spans = append(s.spans, [2]int64{offset, end})
+	s.mu.Unlock()
+	return s.LocalStore.GetRange(ctx, hash, offset, end)
+}
+
+func TestRangeSeekerBoundsFetches(t *testing.T) {
+	local, err := NewLocalStore(t.TempDir(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	content := "0123456789abcdefghij"
+	hash := put(t, local, content)
+	store := &rangeRecorder{LocalStore: local}
+	seeker := &rangeSeeker{ctx: context.Background(), store: store, hash: hash, size: int64(len(content))}
+
+	seeker.Bound(8)
+	if _, err := seeker.Seek(4, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	part := make([]byte, 4)
+	if _, err := io.ReadFull(seeker, part); err != nil || string(part) != "4567" {
+		t.Fatalf("bounded read = %q, %v", part, err)
+	}
+	// Reading past the bound fetches the rest instead of stopping early
+	rest, err := io.ReadAll(seeker)
+	if err != nil || string(rest) != content[8:] {
+		t.Fatalf("rest = %q, %v", rest, err)
+	}
+	want := [][2]int64{{4, 8}, {8, 20}}
+	if len(store.spans) != len(want) || store.spans[0] != want[0] || store.spans[1] != want[1] {
+		t.Errorf("fetched spans %v, want %v", store.spans, want)
+	}
+}
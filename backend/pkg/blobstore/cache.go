@@ -0,0 +1,261 @@
+package blobstore
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"kessler/pkg/hashes"
+	"kessler/pkg/logger"
+
+	"go.uber.org/zap"
+)
+
+// cacheEntry is a blob held in the cache directory
+type cacheEntry struct {
+	hash hashes.KesslerHash
+	size int64
+}
+
+// fetch is a download in progress, later callers for the same hash wait on done
+type fetch struct {
+	done chan struct{}
+	err  error
+}
+
+// CachedStore fronts a backend with a directory of downloaded blobs. Blobs are immutable so cached copies
+// never go stale, the least recently used ones are removed once the directory grows past maxBytes.
+type CachedStore struct {
+	backend  BlobStore
+	dir      string
+	maxBytes int64
+
+	mu       sync.Mutex
+	order    *list.List // front is most recently used
+	entries  map[hashes.KesslerHash]*list.Element
+	size     int64
+	fetching map[hashes.KesslerHash]*fetch
+}
+
+// NewCachedStore creates dir if needed and adopts blobs already in it, oldest first in line for eviction
+func NewCachedStore(backend BlobStore, dir string, maxBytes int64) (*CachedStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("creating blob cache directory %s: %w", dir, err)
+	}
+	c := &CachedStore{
+		backend:  backend,
+		dir:      dir,
+		maxBytes: maxBytes,
+		order:    list.New(),
+		entries:  make(map[hashes.KesslerHash]*list.Element),
+		fetching: make(map[hashes.KesslerHash]*fetch),
+	}
+
+	dirEntries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("reading blob cache directory %s: %w", dir, err)
+	}
+	var existing []BlobInfo
+	for _, entry := range dirEntries {
+		if strings.HasPrefix(entry.Name(), ".tmp-") {
+			// Left behind by a download that was interrupted
+			os.Remove(filepath.Join(dir, entry.Name()))
+			continue
+		}
+		hash, err := hashes.HashFromString(entry.Name())
+		if err != nil || !entry.Type().IsRegular() {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		existing = append(existing, BlobInfo{Hash: hash, Size: info.Size(), ModTime: info.ModTime()})
+	}
+	sort.Slice(existing, func(i, j int) bool { return existing[i].ModTime.Before(existing[j].ModTime) })
+	for _, info := range existing {
+		c.entries[info.Hash] = c.order.PushFront(&cacheEntry{hash: info.Hash, size: info.Size})
+		c.size += info.Size
+	}
+	c.mu.Lock()
+	c.evict(nil)
+	c.mu.Unlock()
+	return c, nil
+}
+
+// Backend returns the store behind the cache
+func (c *CachedStore) Backend() BlobStore {
+	return c.backend
+}
+
+func (c *CachedStore) path(hash hashes.KesslerHash) string {
+	return filepath.Join(c.dir, hash.String())
+}
+
+// Path returns a local file holding the blob, downloading it into the cache when missing. The file may be
+// evicted once other blobs are fetched, callers should open it right away rather than keep the path.
+func (c *CachedStore) Path(ctx context.Context, hash hashes.KesslerHash) (string, error) {
+	for {
+		c.mu.Lock()
+		if elem, ok := c.entries[hash]; ok {
+			if _, err := os.Stat(c.path(hash)); err == nil {
+				c.order.MoveToFront(elem)
+				c.mu.Unlock()
+				return c.path(hash), nil
+			}
+			// Removed from under the cache, forget it and download again
+			c.remove(elem)
+		}
+		if inFlight, ok := c.fetching[hash]; ok {
+			c.mu.Unlock()
+			select {
+			case <-inFlight.done:
+			case <-ctx.Done():
+				return "", ctx.Err()
+			}
+			if inFlight.err != nil {
+				return "", inFlight.err
+			}
+			continue
+		}
+		inFlight := &fetch{done: make(chan struct{})}
+		c.fetching[hash] = inFlight
+		c.mu.Unlock()
+
+		inFlight.err = c.download(ctx, hash)
+
+		c.mu.Lock()
+		delete(c.fetching, hash)
+		c.mu.Unlock()
+		close(inFlight.done)
+		if inFlight.err != nil {
+			return "", inFlight.err
+		}
+	}
+}
+
+// download copies a blob from the backend into the cache directory and records it
+func (c *CachedStore) download(ctx context.Context, hash hashes.KesslerHash) error {
+	body, err := c.backend.Get(ctx, hash)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	size, err := writeAtomically(c.dir, c.path(hash), body)
+	if err != nil {
+		return fmt.Errorf("caching blob %s: %w", hash, err)
+	}
+	logger.Info(ctx, "cached blob", zap.String("hash", hash.String()), zap.Int64("size", size))
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	elem := c.order.PushFront(&cacheEntry{hash: hash, size: size})
+	c.entries[hash] = elem
+	c.size += size
+	c.evict(elem)
+	return nil
+}
+
+// evict removes least recently used blobs until the cache fits, never the one just added. Callers hold mu.
+func (c *CachedStore) evict(keep *list.Element) {
+	for c.size > c.maxBytes {
+		oldest := c.order.Back()
+		if oldest == nil || oldest == keep {
+			return
+		}
+		entry := oldest.Value.(*cacheEntry)
+		if err := os.Remove(c.path(entry.hash)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			logger.Warn(context.Background(), "failed to evict cached blob", zap.String("hash", entry.hash.String()), zap.Error(err))
+		}
+		c.remove(oldest)
+	}
+}
+
+// remove forgets an entry without touching its file. Callers hold mu.
+func (c *CachedStore) remove(elem *list.Element) {
+	entry := elem.Value.(*cacheEntry)
+	c.order.Remove(elem)
+	delete(c.entries, entry.hash)
+	c.size -= entry.size
+}
+
+// Size returns the bytes currently cached
+func (c *CachedStore) Size() int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.size
+}
+
+func (c *CachedStore) Put(ctx context.Context, hash hashes.KesslerHash, r io.Reader) error {
+	return c.backend.Put(ctx, hash, r)
+}
+
+// Get opens the cached copy, downloading it first when missing
+func (c *CachedStore) Get(ctx context.Context, hash hashes.KesslerHash) (io.ReadCloser, error) {
+	return c.open(ctx, hash)
+}
+
+func (c *CachedStore) open(ctx context.Context, hash hashes.KesslerHash) (*os.File, error) {
+	// A second attempt covers the file being evicted between Path and Open
+	for attempt := 0; ; attempt++ {
+		path, err := c.Path(ctx, hash)
+		if err != nil {
+			return nil, err
+		}
+		file, err := os.Open(path)
+		if errors.Is(err, fs.ErrNotExist) && attempt == 0 {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		return file, nil
+	}
+}
+
+// Stat answers from the cache when the blob is there
+func (c *CachedStore) Stat(ctx context.Context, hash hashes.KesslerHash) (BlobInfo, error) {
+	c.mu.Lock()
+	_, cached := c.entries[hash]
+	c.mu.Unlock()
+	if cached {
+		if info, err := os.Stat(c.path(hash)); err == nil {
+			return BlobInfo{Hash: hash, Size: info.Size(), ModTime: info.ModTime()}, nil
+		}
+	}
+	return c.backend.Stat(ctx, hash)
+}
+
+// Delete removes the blob from the backend and the cache
+func (c *CachedStore) Delete(ctx context.Context, hash hashes.KesslerHash) error {
+	if err := c.backend.Delete(ctx, hash); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, ok := c.entries[hash]; ok {
+		c.remove(elem)
+	}
+	if err := os.Remove(c.path(hash)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func (c *CachedStore) List(ctx context.Context, fn func(BlobInfo) error) error {
+	return c.backend.List(ctx, fn)
+}
+
+func (c *CachedStore) PresignGet(ctx context.Context, hash hashes.KesslerHash, expiry time.Duration) (string, error) {
+	return c.backend.PresignGet(ctx, hash, expiry)
+}
@@ -0,0 +1,146 @@
+package blobstore
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// Storage backends selected by BLOB_STORE
+const (
+	BackendS3    = "s3"
+	BackendLocal = "local"
+)
+
+const (
+	// DefaultCacheMaxBytes bounds the local cache of downloaded blobs
+	DefaultCacheMaxBytes = 5 << 30
+	// DefaultPresignExpiry leaves Marker time to queue a document before fetching it
+	DefaultPresignExpiry = 6 * time.Hour
+)
+
+// S3Config locates an S3 compatible bucket
+type S3Config struct {
+	Endpoint  string
+	Region    string
+	Bucket    string
+	KeyPrefix string
+	AccessKey string
+	SecretKey string
+	// ForcePathStyle addresses the bucket in the path, which MinIO and most self hosted stores need
+	ForcePathStyle bool
+}
+
+// Config selects and configures a blob store
+type Config struct {
+	Backend string
+	S3      S3Config
+	// LocalDir holds blobs for the local backend, LocalBaseURL is where they are served from if anywhere
+	LocalDir     string
+	LocalBaseURL string
+	// CacheDir holds downloaded blobs, least recently used ones are removed past CacheMaxBytes
+	CacheDir      string
+	CacheMaxBytes int64
+	PresignExpiry time.Duration
+}
+
+// DefaultConfig returns the production DigitalOcean Spaces bucket NewKeFileManager always used
+func DefaultConfig() Config {
+	return Config{
+		Backend: BackendS3,
+		S3: S3Config{
+			Endpoint:  "https://sfo3.digitaloceanspaces.com",
+			Region:    "sfo3",
+			Bucket:    "kesslerproddocs",
+			KeyPrefix: "raw/",
+		},
+		LocalDir:      filepath.Join(os.TempDir(), "kessler-blobs"),
+		CacheDir:      filepath.Join(os.TempDir(), "raw"),
+		CacheMaxBytes: DefaultCacheMaxBytes,
+		PresignExpiry: DefaultPresignExpiry,
+	}
+}
+
+// LoadConfig builds the configuration from the defaults and the BLOB_* and S3_* environment variables
+func LoadConfig() (Config, error) {
+	cfg := DefaultConfig()
+
+	strs := map[string]*string{
+		"BLOB_STORE":          &cfg.Backend,
+		"BLOB_LOCAL_DIR":      &cfg.LocalDir,
+		"BLOB_LOCAL_BASE_URL": &cfg.LocalBaseURL,
+		"BLOB_CACHE_DIR":      &cfg.CacheDir,
+		"S3_ENDPOINT":         &cfg.S3.Endpoint,
+		"S3_REGION":           &cfg.S3.Region,
+		"S3_BUCKET":           &cfg.S3.Bucket,
+		"S3_KEY_PREFIX":       &cfg.S3.KeyPrefix,
+		"S3_ACCESS_KEY":       &cfg.S3.AccessKey,
+		"S3_SECRET_KEY":       &cfg.S3.SecretKey,
+	}
+	for key, field := range strs {
+		if v := os.Getenv(key); v != "" {
+			*field = v
+		}
+	}
+
+	if v := os.Getenv("S3_FORCE_PATH_STYLE"); v != "" {
+		parsed, err := strconv.ParseBool(v)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid S3_FORCE_PATH_STYLE %q: %w", v, err)
+		}
+		cfg.S3.ForcePathStyle = parsed
+	}
+	if v := os.Getenv("BLOB_CACHE_MAX_BYTES"); v != "" {
+		parsed, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid BLOB_CACHE_MAX_BYTES %q: %w", v, err)
+		}
+		cfg.CacheMaxBytes = parsed
+	}
+	if v := os.Getenv("BLOB_PRESIGN_EXPIRY"); v != "" {
+		parsed, err := time.ParseDuration(v)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid BLOB_PRESIGN_EXPIRY %q: %w", v, err)
+		}
+		cfg.PresignExpiry = parsed
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return Config{}, err
+	}
+	return cfg, nil
+}
+
+// Validate reports every invalid field at once
+func (cfg Config) Validate() error {
+	var errs []error
+	switch cfg.Backend {
+	case BackendS3:
+		if parsed, err := url.Parse(cfg.S3.Endpoint); err != nil || parsed.Scheme == "" || parsed.Host == "" {
+			errs = append(errs, fmt.Errorf("s3 endpoint %q must be an absolute URL", cfg.S3.Endpoint))
+		}
+		if cfg.S3.Bucket == "" {
+			errs = append(errs, errors.New("s3 bucket is required"))
+		}
+	case BackendLocal:
+		if cfg.LocalDir == "" {
+			errs = append(errs, errors.New("local blob directory is required"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("blob store %q must be %s or %s", cfg.Backend, BackendS3, BackendLocal))
+	}
+	if cfg.CacheDir == "" {
+		errs = append(errs, errors.New("blob cache directory is required"))
+	}
+	if cfg.CacheMaxBytes <= 0 {
+		errs = append(errs, errors.New("blob cache size must be positive"))
+	}
+	if cfg.PresignExpiry <= 0 {
+		errs = append(errs, errors.New("blob presign expiry must be positive"))
+	}
+	return errors.Join(errs...)
+}
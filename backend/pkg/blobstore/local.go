@@ -0,0 +1,128 @@
+package blobstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"kessler/pkg/hashes"
+)
+
+// LocalStore keeps blobs as files named by their hash in one directory, for development and tests
+type LocalStore struct {
+	dir     string
+	baseURL string
+}
+
+// NewLocalStore creates dir if needed. PresignGet returns baseURL followed by the hash when baseURL is set,
+// and a file URL otherwise.
+func NewLocalStore(dir, baseURL string) (*LocalStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("creating blob directory %s: %w", dir, err)
+	}
+	return &LocalStore{dir: dir, baseURL: strings.TrimSuffix(baseURL, "/")}, nil
+}
+
+func (s *LocalStore) path(hash hashes.KesslerHash) string {
+	return filepath.Join(s.dir, hash.String())
+}
+
+// writeAtomically copies r into a temporary file in dir and renames it to path, so readers never see
+// a partial blob
+func writeAtomically(dir, path string, r io.Reader) (int64, error) {
+	tmp, err := os.CreateTemp(dir, ".tmp-*")
+	if err != nil {
+		return 0, err
+	}
+	written, err := io.Copy(tmp, r)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return 0, err
+	}
+	return written, nil
+}
+
+func (s *LocalStore) Put(ctx context.Context, hash hashes.KesslerHash, r io.Reader) error {
+	_, err := writeAtomically(s.dir, s.path(hash), r)
+	return err
+}
+
+func (s *LocalStore) Get(ctx context.Context, hash hashes.KesslerHash) (io.ReadCloser, error) {
+	file, err := os.Open(s.path(hash))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, hash)
+	}
+	return file, err
+}
+
+func (s *LocalStore) Stat(ctx context.Context, hash hashes.KesslerHash) (BlobInfo, error) {
+	info, err := os.Stat(s.path(hash))
+	if errors.Is(err, fs.ErrNotExist) {
+		return BlobInfo{}, fmt.Errorf("%w: %s", ErrNotFound, hash)
+	}
+	if err != nil {
+		return BlobInfo{}, err
+	}
+	return BlobInfo{Hash: hash, Size: info.Size(), ModTime: info.ModTime()}, nil
+}
+
+func (s *LocalStore) Delete(ctx context.Context, hash hashes.KesslerHash) error {
+	err := os.Remove(s.path(hash))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// List walks the directory, files that are not named by a hash such as unfinished writes are skipped
+func (s *LocalStore) List(ctx context.Context, fn func(BlobInfo) error) error {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		hash, err := hashes.HashFromString(entry.Name())
+		if err != nil || !entry.Type().IsRegular() {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		if err := fn(BlobInfo{Hash: hash, Size: info.Size(), ModTime: info.ModTime()}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// PresignGet ignores expiry, local blobs are served for as long as they exist
+func (s *LocalStore) PresignGet(ctx context.Context, hash hashes.KesslerHash, expiry time.Duration) (string, error) {
+	if _, err := s.Stat(ctx, hash); err != nil {
+		return "", err
+	}
+	if s.baseURL != "" {
+		return s.baseURL + "/" + url.PathEscape(hash.String()), nil
+	}
+	abs, err := filepath.Abs(s.path(hash))
+	if err != nil {
+		return "", err
+	}
+	return (&url.URL{Scheme: "file", Path: abs}).String(), nil
+}
@@ -0,0 +1,144 @@
+package blobstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+
+	"kessler/pkg/hashes"
+)
+
+// RangeReader is implemented by stores that can read part of a blob without fetching all of it
+type RangeReader interface {
+	// GetRange opens the bytes of the blob from offset up to, but not including, end
+	GetRange(ctx context.Context, hash hashes.KesslerHash, offset, end int64) (io.ReadCloser, error)
+}
+
+// Bounded is implemented by seekers that fetch a blob remotely. Bound caps the next fetch at end so a
+// short byte range does not stream the rest of the blob, reads past end still fetch the remainder.
+type Bounded interface {
+	Bound(end int64)
+}
+
+func (s *LocalStore) GetRange(ctx context.Context, hash hashes.KesslerHash, offset, end int64) (io.ReadCloser, error) {
+	file, err := os.Open(s.path(hash))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, hash)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{io.LimitReader(file, end-offset), file}, nil
+}
+
+// rangeSeeker reads a blob through GetRange, reopening it at the new offset after a seek. Seeks that only
+// ask for the size, as http.ServeContent does, cost no request.
+type rangeSeeker struct {
+	ctx    context.Context
+	store  RangeReader
+	hash   hashes.KesslerHash
+	size   int64
+	offset int64
+	// end bounds the next fetch, zero fetches up to size
+	end  int64
+	body io.ReadCloser
+}
+
+func (r *rangeSeeker) Read(p []byte) (int, error) {
+	if r.offset >= r.size {
+		return 0, io.EOF
+	}
+	if r.body == nil {
+		end := r.size
+		if r.end > r.offset && r.end < r.size {
+			end = r.end
+		}
+		body, err := r.store.GetRange(r.ctx, r.hash, r.offset, end)
+		if err != nil {
+			return 0, err
+		}
+		r.body = body
+	}
+	n, err := r.body.Read(p)
+	r.offset += int64(n)
+	if err == io.EOF && r.offset < r.size {
+		// The bounded fetch is used up, the next read fetches the rest of the blob
+		r.body.Close()
+		r.body = nil
+		r.end = 0
+		err = nil
+	}
+	return n, err
+}
+
+func (r *rangeSeeker) Bound(end int64) {
+	r.end = end
+}
+
+func (r *rangeSeeker) Seek(offset int64, whence int) (int64, error) {
+	var next int64
+	switch whence {
+	case io.SeekStart:
+		next = offset
+	case io.SeekCurrent:
+		next = r.offset + offset
+	case io.SeekEnd:
+		next = r.size + offset
+	default:
+		return 0, errors.New("invalid whence")
+	}
+	if next < 0 {
+		return 0, errors.New("negative position")
+	}
+	if next != r.offset && r.body != nil {
+		r.body.Close()
+		r.body = nil
+	}
+	r.offset = next
+	return next, nil
+}
+
+func (r *rangeSeeker) Close() error {
+	if r.body == nil {
+		return nil
+	}
+	return r.body.Close()
+}
+
+// OpenSeeker opens a blob for random access. A cached copy is read from disk, otherwise a backend that
+// supports ranges is streamed from directly without filling the cache, and any other backend is downloaded
+// into the cache first.
+func (c *CachedStore) OpenSeeker(ctx context.Context, hash hashes.KesslerHash) (io.ReadSeekCloser, BlobInfo, error) {
+	c.mu.Lock()
+	_, cached := c.entries[hash]
+	c.mu.Unlock()
+
+	if ranged, ok := c.backend.(RangeReader); ok && !cached {
+		info, err := c.backend.Stat(ctx, hash)
+		if err != nil {
+			return nil, BlobInfo{}, err
+		}
+		return &rangeSeeker{ctx: ctx, store: ranged, hash: hash, size: info.Size}, info, nil
+	}
+
+	file, err := c.open(ctx, hash)
+	if err != nil {
+		return nil, BlobInfo{}, err
+	}
+	stat, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, BlobInfo{}, err
+	}
+	return file, BlobInfo{Hash: hash, Size: stat.Size(), ModTime: stat.ModTime()}, nil
+}
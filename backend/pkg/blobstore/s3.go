@@ -0,0 +1,161 @@
+package blobstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"kessler/pkg/hashes"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+)
+
+// S3Store keeps blobs in an S3 compatible bucket under KeyPrefix followed by the hash
+type S3Store struct {
+	client    *s3.S3
+	uploader  *s3manager.Uploader
+	bucket    string
+	keyPrefix string
+}
+
+// NewS3Store connects to the configured bucket
+func NewS3Store(cfg S3Config) (*S3Store, error) {
+	sess, err := session.NewSession(&aws.Config{
+		Region:           aws.String(cfg.Region),
+		Endpoint:         aws.String(cfg.Endpoint),
+		Credentials:      credentials.NewStaticCredentials(cfg.AccessKey, cfg.SecretKey, ""),
+		S3ForcePathStyle: aws.Bool(cfg.ForcePathStyle),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("creating s3 session: %w", err)
+	}
+	client := s3.New(sess)
+	return &S3Store{
+		client:    client,
+		uploader:  s3manager.NewUploaderWithClient(client),
+		bucket:    cfg.Bucket,
+		keyPrefix: cfg.KeyPrefix,
+	}, nil
+}
+
+func (s *S3Store) key(hash hashes.KesslerHash) string {
+	return s.keyPrefix + hash.String()
+}
+
+// notFound maps the S3 errors for a missing key to ErrNotFound
+func notFound(err error) error {
+	var reqErr awserr.RequestFailure
+	if errors.As(err, &reqErr) && reqErr.StatusCode() == http.StatusNotFound {
+		return fmt.Errorf("%w: %v", ErrNotFound, err)
+	}
+	var awsErr awserr.Error
+	if errors.As(err, &awsErr) && (awsErr.Code() == s3.ErrCodeNoSuchKey || awsErr.Code() == "NotFound") {
+		return fmt.Errorf("%w: %v", ErrNotFound, err)
+	}
+	return err
+}
+
+func (s *S3Store) Put(ctx context.Context, hash hashes.KesslerHash, r io.Reader) error {
+	_, err := s.uploader.UploadWithContext(ctx, &s3manager.UploadInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(s.key(hash)),
+		Body:   r,
+	})
+	return err
+}
+
+func (s *S3Store) Get(ctx context.Context, hash hashes.KesslerHash) (io.ReadCloser, error) {
+	out, err := s.client.GetObjectWithContext(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(s.key(hash)),
+	})
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return out.Body, nil
+}
+
+func (s *S3Store) GetRange(ctx context.Context, hash hashes.KesslerHash, offset, end int64) (io.ReadCloser, error) {
+	out, err := s.client.GetObjectWithContext(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(s.key(hash)),
+		Range:  aws.String(fmt.Sprintf("bytes=%d-%d", offset, end-1)),
+	})
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return out.Body, nil
+}
+
+func (s *S3Store) Stat(ctx context.Context, hash hashes.KesslerHash) (BlobInfo, error) {
+	out, err := s.client.HeadObjectWithContext(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(s.key(hash)),
+	})
+	if err != nil {
+		return BlobInfo{}, notFound(err)
+	}
+	return BlobInfo{
+		Hash:    hash,
+		Size:    aws.Int64Value(out.ContentLength),
+		ModTime: aws.TimeValue(out.LastModified),
+	}, nil
+}
+
+func (s *S3Store) Delete(ctx context.Context, hash hashes.KesslerHash) error {
+	_, err := s.client.DeleteObjectWithContext(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(s.key(hash)),
+	})
+	if errors.Is(notFound(err), ErrNotFound) {
+		return nil
+	}
+	return err
+}
+
+// List pages through the key prefix, keys that are not a hash are skipped
+func (s *S3Store) List(ctx context.Context, fn func(BlobInfo) error) error {
+	var fnErr error
+	err := s.client.ListObjectsV2PagesWithContext(ctx, &s3.ListObjectsV2Input{
+		Bucket: aws.String(s.bucket),
+		Prefix: aws.String(s.keyPrefix),
+	}, func(page *s3.ListObjectsV2Output, lastPage bool) bool {
+		for _, object := range page.Contents {
+			hash, err := hashes.HashFromString(strings.TrimPrefix(aws.StringValue(object.Key), s.keyPrefix))
+			if err != nil {
+				continue
+			}
+			fnErr = fn(BlobInfo{
+				Hash:    hash,
+				Size:    aws.Int64Value(object.Size),
+				ModTime: aws.TimeValue(object.LastModified),
+			})
+			if fnErr != nil {
+				return false
+			}
+		}
+		return true
+	})
+	if fnErr != nil {
+		return fnErr
+	}
+	return err
+}
+
+func (s *S3Store) PresignGet(ctx context.Context, hash hashes.KesslerHash, expiry time.Duration) (string, error) {
+	req, _ := s.client.GetObjectRequest(&s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(s.key(hash)),
+	})
+	req.SetContext(ctx)
+	return req.Presign(expiry)
+}
@@ -26,6 +26,8 @@ const loggerKey contextKey = "logger"
 var (
 	// Global logger instance for non-context scenarios
 	globalLogger *otelzap.Logger
+	// nopLogger is used before Init is called, e.g. in tests
+	nopLogger = otelzap.New(zap.NewNop())
 )
 
 // Config holds logger configuration
@@ -109,6 +111,9 @@ func FromContext(ctx context.Context) *otelzap.Logger {
 	if logger, ok := ctx.Value(loggerKey).(*otelzap.Logger); ok && logger != nil {
 		return logger
 	}
+	if globalLogger == nil {
+		return nopLogger
+	}
 	return globalLogger
 }
 
@@ -120,7 +125,7 @@ func Global() *otelzap.Logger {
 // Named returns a named logger
 func Named(name string) *otelzap.Logger {
 	if globalLogger == nil {
-		return nil
+		return nopLogger
 	}
 	// Get the underlying zap logger, create named version, then wrap with otelzap
 	namedZapLogger := globalLogger.Logger.Named(name)
@@ -1,22 +1,17 @@
 package s3utils
 
 import (
+	"context"
 	"crypto/md5"
 	"fmt"
 	"io"
+	"kessler/pkg/blobstore"
 	"kessler/pkg/hashes"
 	"net/http"
 	"os"
 	"path/filepath"
-	"strings"
+	"sync"
 	"time"
-
-	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/credentials"
-	"github.com/aws/aws-sdk-go/aws/session"
-	"github.com/aws/aws-sdk-go/service/s3"
-	"github.com/charmbracelet/log"
-	"go.uber.org/zap"
 )
 
 func DownloadFile(url, dir string) (string, error) {
@@ -47,129 +42,92 @@ func DownloadFile(url, dir string) (string, error) {
 	return filePath, nil
 }
 
-// Client structure for S3
+// KesslerFileManager reads and writes raw attachments through the configured blob store.
+// Every manager shares one store so the local cache is bounded for the whole process.
 type KesslerFileManager struct {
-	S3Client *s3.S3
-	S3Bucket string
-	S3RawDir string
-	RawDir   string
-	TmpDir   string
+	Blobs         *blobstore.CachedStore
+	PresignExpiry time.Duration
+	err           error
 }
 
-func NewKeFileManager() *KesslerFileManager {
-	CloudRegion := "sfo3" // Your region here. Change if needed
-	EndpointURL := "https://sfo3.digitaloceanspaces.com"
-	S3Bucket := "kesslerproddocs"
-	S3AccessKey := os.Getenv("S3_ACCESS_KEY")
-	S3SecretKey := os.Getenv("S3_SECRET_KEY")
-	S3RawDir := "raw/"
-	RawDir := "raw/"
-	TmpDir := os.TempDir()
-	sess := session.Must(session.NewSession(&aws.Config{
-		Region:      aws.String(CloudRegion),
-		Endpoint:    aws.String(EndpointURL),
-		Credentials: credentials.NewStaticCredentials(S3AccessKey, S3SecretKey, ""),
-	}))
-	return &KesslerFileManager{
-		S3Client: s3.New(sess),
-		S3Bucket: S3Bucket,
-		S3RawDir: S3RawDir,
-		RawDir:   RawDir,
-		TmpDir:   TmpDir,
-	}
-}
-
-func (manager *KesslerFileManager) GetURIFromHash(hash hashes.KesslerHash) (string, error) {
-	// Add validation logic to check if the file exists before sending it.
-	return manager.getURIFromHashUnsafe(hash), nil
-}
+var (
+	defaultManagerOnce sync.Once
+	defaultManager     *KesslerFileManager
+)
 
-func (manager *KesslerFileManager) getURIFromHashUnsafe(hash hashes.KesslerHash) string {
-	return manager.getURIFromS3Key(manager.getS3KeyFromHash(hash))
+// NewKeFileManager returns the shared manager configured from BLOB_STORE and the S3_* variables. When the
+// configuration is invalid every call on the manager returns the configuration error.
+func NewKeFileManager() *KesslerFileManager {
+	defaultManagerOnce.Do(func() {
+		cfg, err := blobstore.LoadConfig()
+		if err != nil {
+			defaultManager = &KesslerFileManager{err: fmt.Errorf("blob store configuration: %w", err)}
+			return
+		}
+		store, err := blobstore.New(cfg)
+		if err != nil {
+			defaultManager = &KesslerFileManager{err: fmt.Errorf("blob store: %w", err)}
+			return
+		}
+		defaultManager = NewKeFileManagerFromStore(store, cfg.PresignExpiry)
+	})
+	return defaultManager
 }
 
-// Hash computation
-func (manager *KesslerFileManager) getURIFromS3Key(key string) string {
-	endpoint := strings.TrimPrefix(manager.S3Client.Endpoint, "https://")
-	return fmt.Sprintf("https://%s.%s/%s", manager.S3Bucket, endpoint, key)
+// NewKeFileManagerFromStore wraps an existing store, for tests and tools with their own configuration
+func NewKeFileManagerFromStore(store *blobstore.CachedStore, presignExpiry time.Duration) *KesslerFileManager {
+	return &KesslerFileManager{Blobs: store, PresignExpiry: presignExpiry}
 }
 
-func (manager *KesslerFileManager) getS3KeyFromHash(hash hashes.KesslerHash) string {
-	return filepath.Join(manager.S3RawDir, hash.String())
+// Err reports why the blob store could not be configured, nil when it is usable
+func (manager *KesslerFileManager) Err() error {
+	return manager.err
 }
 
-func (manager *KesslerFileManager) getLocalPathFromHash(hash hashes.KesslerHash) string {
-	return filepath.Join("/tmp/", manager.RawDir, hash.String())
+// GetURIFromHash returns a URL external services such as Marker can download the attachment from
+func (manager *KesslerFileManager) GetURIFromHash(ctx context.Context, hash hashes.KesslerHash) (string, error) {
+	if manager.err != nil {
+		return "", manager.err
+	}
+	return manager.Blobs.PresignGet(ctx, hash, manager.PresignExpiry)
 }
 
-// Upload file to S3
-func (manager *KesslerFileManager) UploadFileToS3(filePath string) (hashes.KesslerHash, error) {
-	// F_ile opened twice, potential for optimisation.
-	hash_result, err := hashes.HashFromFile(filePath)
-	if err != nil {
-		return hashes.KesslerHash{}, fmt.Errorf("Error hashing file: %v", err)
+// UploadFile stores a file under its hash
+func (manager *KesslerFileManager) UploadFile(ctx context.Context, filePath string) (hashes.KesslerHash, error) {
+	if manager.err != nil {
+		return hashes.KesslerHash{}, manager.err
 	}
-	return hash_result, manager.pushFileToS3GivenHash(filePath, hash_result)
+	return blobstore.PutFile(ctx, manager.Blobs, filePath)
 }
 
-func (manager *KesslerFileManager) pushFileToS3GivenHash(filePath string, hash hashes.KesslerHash) error {
-	file, err := os.Open(filePath)
-	if err != nil {
-		return err
+// LocalPath returns a local copy of the attachment, downloading it into the cache when needed
+func (manager *KesslerFileManager) LocalPath(ctx context.Context, hash hashes.KesslerHash) (string, error) {
+	if manager.err != nil {
+		return "", manager.err
 	}
-	defer file.Close()
-
-	fileKey := manager.getS3KeyFromHash(hash)
-	_, err = manager.S3Client.PutObject(&s3.PutObjectInput{
-		Bucket: aws.String(manager.S3Bucket),
-		Key:    aws.String(fileKey),
-		Body:   file,
-	})
-	return err
+	return manager.Blobs.Path(ctx, hash)
 }
 
-func (manager *KesslerFileManager) DownloadFileFromS3(hash hashes.KesslerHash) (string, error) {
-	// listInput := s3.ListObjectsInput{Bucket: aws.String(manager.S3Bucket)}
-	// result, err := manager.S3Client.ListObjects(&listInput)
-	// log.Info(fmt.Sprintf("result: %v\nerror: %v\n", result, err))
-
-	localFilePath := manager.getLocalPathFromHash(hash)
-	if _, err := os.Stat(localFilePath); err == nil {
-		return localFilePath, nil
-	}
-	fileKey := manager.getS3KeyFromHash(hash)
-	log.Info("Attempting to download file from s3 bucket", zap.String("fileKey", fileKey), zap.String("bucket", manager.S3Bucket))
-	resultS3, err := manager.S3Client.GetObject(&s3.GetObjectInput{
-		Bucket: aws.String(manager.S3Bucket),
-		Key:    aws.String(fileKey),
-	})
-	if err != nil {
-		log.Warn("Failed to download file from s3.", zap.String("fileKey", fileKey), zap.String("bucket", manager.S3Bucket))
-		return "", err
+// Open reads the attachment through the cache
+func (manager *KesslerFileManager) Open(ctx context.Context, hash hashes.KesslerHash) (io.ReadCloser, error) {
+	if manager.err != nil {
+		return nil, manager.err
 	}
-	log.Info("Got file from s3 successfully", zap.String("fileKey", fileKey), zap.String("bucket", manager.S3Bucket))
-
-	var body io.ReadCloser = resultS3.Body
-	defer body.Close()
+	return manager.Blobs.Get(ctx, hash)
+}
 
-	// Create the file
-	// TODO: Move this directory code so that it only runs on startup or something.
-	// Ensure the directory structure exists
-	if err := os.MkdirAll(filepath.Dir(localFilePath), os.ModePerm); err != nil {
-		return "", fmt.Errorf("failed to create directories for %s: %v", localFilePath, err)
-	}
-	out, err := os.Create(localFilePath)
-	if err != nil {
-		return "", err
+// Stat reports the attachment's size and modification time without reading it
+func (manager *KesslerFileManager) Stat(ctx context.Context, hash hashes.KesslerHash) (blobstore.BlobInfo, error) {
+	if manager.err != nil {
+		return blobstore.BlobInfo{}, manager.err
 	}
-	log.Info("Created local file successfully", zap.String("path", localFilePath))
-	defer out.Close()
+	return manager.Blobs.Stat(ctx, hash)
+}
 
-	// Copy the body to the file
-	_, err = io.Copy(out, body)
-	if err != nil {
-		return "", err
+// OpenSeeker opens the attachment for random access, streaming byte ranges from the backend when it is not cached
+func (manager *KesslerFileManager) OpenSeeker(ctx context.Context, hash hashes.KesslerHash) (io.ReadSeekCloser, blobstore.BlobInfo, error) {
+	if manager.err != nil {
+		return nil, blobstore.BlobInfo{}, manager.err
 	}
-	log.Info("Copied file successfully", zap.String("path", localFilePath))
-	return localFilePath, nil
+	return manager.Blobs.OpenSeeker(ctx, hash)
 }
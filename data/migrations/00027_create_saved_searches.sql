@@ -0,0 +1,46 @@
+-- +goose Up
+-- +goose StatementBegin
+BEGIN;
+
+-- Saved searches are re-run periodically so new matching documents can be surfaced
+CREATE TABLE public.saved_search (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name TEXT NOT NULL,
+    query TEXT NOT NULL DEFAULT '',
+    namespace TEXT NOT NULL DEFAULT '',
+    filters JSONB NOT NULL DEFAULT '{}'::jsonb,
+    -- Full search request, including sort and date bounds, as submitted
+    search_request JSONB NOT NULL,
+    run_count INTEGER NOT NULL DEFAULT 0,
+    last_run_at TIMESTAMP WITH TIME ZONE,
+    last_run_error TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_saved_search_last_run_at ON public.saved_search (last_run_at);
+
+-- Every document a saved search has matched, baseline rows come from the first run and are not alerted on
+CREATE TABLE public.saved_search_result (
+    saved_search_id UUID NOT NULL REFERENCES public.saved_search(id) ON DELETE CASCADE,
+    document_id UUID NOT NULL,
+    baseline BOOLEAN NOT NULL DEFAULT false,
+    first_seen_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (saved_search_id, document_id)
+);
+
+CREATE INDEX idx_saved_search_result_feed ON public.saved_search_result (saved_search_id, first_seen_at DESC)
+WHERE NOT baseline;
+
+COMMIT;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+BEGIN;
+
+DROP TABLE IF EXISTS public.saved_search_result;
+DROP TABLE IF EXISTS public.saved_search;
+
+COMMIT;
+-- +goose StatementEnd
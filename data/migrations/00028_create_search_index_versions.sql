@@ -0,0 +1,50 @@
+-- +goose Up
+-- +goose StatementBegin
+BEGIN;
+
+-- Every full rebuild of a search index, written next to the live data under a versioned name
+CREATE TABLE public.search_index_version (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    -- 'fugu' or 'quickwit'
+    backend TEXT NOT NULL,
+    -- Name searches use, e.g. NYPUC or NY_Conversations
+    alias TEXT NOT NULL,
+    version INTEGER NOT NULL,
+    -- Namespace or index the version is built into, alias_v<version>
+    target TEXT NOT NULL,
+    -- building, ready, failed, live, retired or deleted
+    status TEXT NOT NULL DEFAULT 'building',
+    status_message TEXT NOT NULL DEFAULT '',
+    -- Per entity document counts from Postgres and from the build, compared before the version can go live
+    expected_counts JSONB NOT NULL DEFAULT '{}'::jsonb,
+    indexed_counts JSONB NOT NULL DEFAULT '{}'::jsonb,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    activated_at TIMESTAMP WITH TIME ZONE,
+    retired_at TIMESTAMP WITH TIME ZONE,
+    UNIQUE (backend, alias, version),
+    UNIQUE (backend, target)
+);
+
+-- The version each alias currently resolves to, switching is a single row update
+CREATE TABLE public.search_index_alias (
+    backend TEXT NOT NULL,
+    alias TEXT NOT NULL,
+    version_id UUID NOT NULL REFERENCES public.search_index_version(id),
+    target TEXT NOT NULL,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (backend, alias)
+);
+
+COMMIT;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+BEGIN;
+
+DROP TABLE IF EXISTS public.search_index_alias;
+DROP TABLE IF EXISTS public.search_index_version;
+
+COMMIT;
+-- +goose StatementEnd
@@ -0,0 +1,51 @@
+-- +goose Up
+-- +goose StatementBegin
+BEGIN;
+
+-- One row per search answered, written asynchronously by the search handlers
+CREATE TABLE public.search_query_log (
+    id UUID PRIMARY KEY,
+    query TEXT NOT NULL,
+    -- Lower cased with collapsed whitespace, what reports group by
+    normalized_query TEXT NOT NULL,
+    filters JSONB NOT NULL DEFAULT '{}'::jsonb,
+    namespace TEXT NOT NULL DEFAULT '',
+    result_count INTEGER NOT NULL,
+    -- Rank of the first card on the page and how many cards were shown, for click-through by position
+    result_offset INTEGER NOT NULL DEFAULT 0,
+    returned_count INTEGER NOT NULL DEFAULT 0,
+    latency_ms INTEGER NOT NULL,
+    -- Corrected query offered for a zero-result search, if any
+    suggestion TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX search_query_log_created_at_idx ON public.search_query_log (created_at);
+CREATE INDEX search_query_log_normalized_query_idx ON public.search_query_log (normalized_query, created_at);
+
+-- A card clicked in search results, search_id is not a foreign key as the search row may still be queued
+CREATE TABLE public.search_click (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    search_id UUID NOT NULL,
+    card_id TEXT NOT NULL,
+    card_type TEXT NOT NULL DEFAULT '',
+    -- 1-based rank of the card across pages
+    position INTEGER NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX search_click_search_id_idx ON public.search_click (search_id);
+CREATE INDEX search_click_created_at_idx ON public.search_click (created_at);
+
+COMMIT;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+BEGIN;
+
+DROP TABLE IF EXISTS public.search_click;
+DROP TABLE IF EXISTS public.search_query_log;
+
+COMMIT;
+-- +goose StatementEnd
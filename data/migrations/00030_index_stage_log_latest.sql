@@ -0,0 +1,18 @@
+-- +goose Up
+-- +goose StatementBegin
+BEGIN;
+
+-- Finding the latest stage of each file reads the log newest first per file
+CREATE INDEX IF NOT EXISTS idx_stage_log_file_created ON public.stage_log (file_id, created_at DESC);
+
+COMMIT;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+BEGIN;
+
+DROP INDEX IF EXISTS idx_stage_log_file_created;
+
+COMMIT;
+-- +goose StatementEnd
@@ -0,0 +1,20 @@
+-- +goose Up
+-- +goose StatementBegin
+BEGIN;
+
+-- Whether a run has recorded the baseline of existing matches, run_count cannot tell as it counts failed runs too
+ALTER TABLE public.saved_search ADD COLUMN baseline_done BOOLEAN NOT NULL DEFAULT false;
+
+UPDATE public.saved_search SET baseline_done = true WHERE run_count > 0 AND last_run_error = '';
+
+COMMIT;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+BEGIN;
+
+ALTER TABLE public.saved_search DROP COLUMN IF EXISTS baseline_done;
+
+COMMIT;
+-- +goose StatementEnd
@@ -62,4 +62,21 @@ FROM
     JOIN public.attachment a ON a.id = ats.attachment_id
 WHERE
     a.file_id = $1
-    AND ats.language = $2;
\ No newline at end of file
+    AND ats.language = $2;
+
+-- name: AttachmentTextTermsList :many
+-- Words of the most recent original texts and how many texts use them, for spelling suggestions
+SELECT
+    word :: VARCHAR AS word,
+    ndoc :: BIGINT AS documents
+FROM
+    ts_stat(
+        'SELECT to_tsvector(''simple'', left(text, 20000)) FROM public.attachment_text_source WHERE is_original_text ORDER BY created_at DESC LIMIT 5000'
+    )
+WHERE
+    ndoc >= @min_documents :: BIGINT
+    AND length(word) BETWEEN 4 AND 30
+ORDER BY
+    ndoc DESC
+LIMIT
+    @max_terms :: INTEGER;
@@ -56,11 +56,21 @@ WHERE
 ORDER BY
     created_at DESC;
 
+-- name: AttachmentHashListByIds :many
+SELECT
+    id,
+    hash
+FROM
+    public.attachment
+WHERE
+    id = ANY(@ids::uuid[]);
+
 -- name: GetAllSearchAttachments :many
 SELECT
 	a.id AS id,
   a.file_id as file_id,
 	a.name AS name,
+	a.extension AS extension,
 	a.created_at,
 	fm.mdata,
 	ats.text
@@ -79,6 +89,7 @@ SELECT
 	a.id AS id,
   a.file_id as file_id,
 	a.name AS name,
+	a.extension AS extension,
 	a.created_at,
 	fm.mdata,
 	ats.text
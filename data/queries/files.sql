@@ -127,6 +127,36 @@ ORDER BY
 LIMIT
     1;
 
+-- name: StageLogListLatestByStage :many
+-- files whose latest stage log is at a processing stage, optionally in one state, and older than a cutoff
+SELECT
+    latest.id,
+    latest.status,
+    latest.log,
+    latest.created_at,
+    latest.file_id
+FROM
+    (
+        SELECT
+            DISTINCT ON (file_id) *
+        FROM
+            public.stage_log
+        ORDER BY
+            file_id,
+            created_at DESC
+    ) latest
+WHERE
+    latest.log ->> 'docproc_stage' = @docproc_stage :: text
+    AND (
+        @status :: text = ''
+        OR latest.status :: text = @status :: text
+    )
+    AND latest.created_at < @updated_before
+ORDER BY
+    latest.created_at
+LIMIT
+    @max_rows;
+
 -- name: InsertMetadata :one
 INSERT INTO
     public.file_metadata (
@@ -201,4 +231,12 @@ SELECT
 FROM
     public.file_extras
 WHERE
-    id = $1;
\ No newline at end of file
+    id = $1;
+
+-- name: ExtrasFileListByIds :many
+SELECT
+    *
+FROM
+    public.file_extras
+WHERE
+    id = ANY(@ids::uuid[]);
\ No newline at end of file
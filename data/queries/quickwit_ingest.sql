@@ -52,4 +52,12 @@ GROUP BY
     docket_conversations.extra,
     docket_conversations.date_published,
     docket_conversations.created_at,
-    docket_conversations.updated_at;
\ No newline at end of file
+    docket_conversations.updated_at;
+
+-- name: ConversationTotalDocumentsGet :one
+SELECT
+    COUNT(public.docket_documents.file_id) AS total_documents
+FROM
+    public.docket_documents
+WHERE
+    public.docket_documents.conversation_uuid = $1;
@@ -0,0 +1,136 @@
+-- name: SavedSearchCreate :one
+INSERT INTO
+    public.saved_search (
+        name,
+        query,
+        namespace,
+        filters,
+        search_request,
+        created_at,
+        updated_at
+    )
+VALUES
+    ($1, $2, $3, $4, $5, NOW(), NOW())
+RETURNING
+    *;
+
+-- name: SavedSearchRead :one
+SELECT
+    *
+FROM
+    public.saved_search
+WHERE
+    id = $1;
+
+-- name: SavedSearchList :many
+SELECT
+    *
+FROM
+    public.saved_search
+ORDER BY
+    created_at DESC;
+
+-- name: SavedSearchUpdate :one
+-- Resetting the baseline makes the next run record existing matches of a changed query without alerting on them
+UPDATE
+    public.saved_search
+SET
+    name = @name,
+    query = @query,
+    namespace = @namespace,
+    filters = @filters,
+    search_request = @search_request,
+    baseline_done = baseline_done
+    AND NOT @reset_baseline::boolean,
+    updated_at = NOW()
+WHERE
+    id = @id
+RETURNING
+    *;
+
+-- name: SavedSearchDelete :exec
+DELETE FROM
+    public.saved_search
+WHERE
+    id = $1;
+
+-- name: SavedSearchClaimDue :one
+-- Claims one saved search that has not run within the interval, SKIP LOCKED keeps replicas from running the same search
+UPDATE
+    public.saved_search
+SET
+    last_run_at = NOW(),
+    run_count = run_count + 1
+WHERE
+    id = (
+        SELECT
+            ss.id
+        FROM
+            public.saved_search AS ss
+        WHERE
+            ss.last_run_at IS NULL
+            OR ss.last_run_at < NOW() - make_interval(secs => @interval_seconds::float8)
+        ORDER BY
+            ss.last_run_at ASC NULLS FIRST
+        LIMIT
+            1 FOR
+        UPDATE
+            SKIP LOCKED
+    )
+RETURNING
+    *;
+
+-- name: SavedSearchFinishRun :exec
+-- A successful run completes the baseline unless the search was edited while it ran
+UPDATE
+    public.saved_search
+SET
+    last_run_error = @last_run_error,
+    baseline_done = baseline_done
+    OR (
+        @succeeded::boolean
+        AND updated_at = @run_updated_at
+    )
+WHERE
+    id = @id;
+
+-- name: SavedSearchResultInsertNew :many
+-- Records matched documents and returns only the ones this search had not seen before, nothing is recorded
+-- if the search was edited since the run started
+INSERT INTO
+    public.saved_search_result (saved_search_id, document_id, baseline, first_seen_at)
+SELECT
+    ss.id,
+    unnest(@document_ids::uuid[]),
+    @baseline::boolean,
+    NOW()
+FROM
+    public.saved_search AS ss
+WHERE
+    ss.id = @saved_search_id
+    AND ss.updated_at = @run_updated_at
+ON CONFLICT (saved_search_id, document_id) DO NOTHING
+RETURNING
+    document_id;
+
+-- name: SavedSearchResultDeleteAll :exec
+DELETE FROM
+    public.saved_search_result
+WHERE
+    saved_search_id = $1;
+
+-- name: SavedSearchFeed :many
+SELECT
+    saved_search_id,
+    document_id,
+    first_seen_at
+FROM
+    public.saved_search_result
+WHERE
+    saved_search_id = $1
+    AND NOT baseline
+    AND first_seen_at > $2
+ORDER BY
+    first_seen_at DESC
+LIMIT
+    $3;
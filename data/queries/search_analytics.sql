@@ -0,0 +1,140 @@
+-- name: SearchQueryLogCreate :exec
+INSERT INTO
+    public.search_query_log (
+        id,
+        query,
+        normalized_query,
+        filters,
+        namespace,
+        result_count,
+        result_offset,
+        returned_count,
+        latency_ms,
+        suggestion,
+        created_at
+    )
+VALUES
+    ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11);
+
+-- name: SearchClickCreate :exec
+INSERT INTO
+    public.search_click (search_id, card_id, card_type, position, created_at)
+VALUES
+    ($1, $2, $3, $4, $5);
+
+-- name: SearchAnalyticsTopQueries :many
+-- Most frequent queries since a time, with how often they found nothing and how often a card was clicked
+SELECT
+    l.normalized_query,
+    COUNT(*) AS searches,
+    COUNT(*) FILTER (WHERE l.result_count = 0) AS zero_result_searches,
+    AVG(l.result_count) :: FLOAT8 AS avg_results,
+    AVG(l.latency_ms) :: FLOAT8 AS avg_latency_ms,
+    COUNT(*) FILTER (
+        WHERE
+            EXISTS (
+                SELECT
+                    1
+                FROM
+                    public.search_click c
+                WHERE
+                    c.search_id = l.id
+            )
+    ) AS clicked_searches
+FROM
+    public.search_query_log l
+WHERE
+    l.created_at >= @since
+GROUP BY
+    l.normalized_query
+ORDER BY
+    searches DESC,
+    l.normalized_query
+LIMIT
+    @max_rows :: INTEGER;
+
+-- name: SearchAnalyticsZeroResultQueries :many
+-- Queries that found nothing since a time, the dockets and names people cannot find
+SELECT
+    normalized_query,
+    COUNT(*) AS searches,
+    MAX(created_at) :: TIMESTAMPTZ AS last_searched_at,
+    MAX(suggestion) :: TEXT AS suggestion
+FROM
+    public.search_query_log
+WHERE
+    created_at >= @since
+    AND result_count = 0
+GROUP BY
+    normalized_query
+ORDER BY
+    searches DESC,
+    last_searched_at DESC
+LIMIT
+    @max_rows :: INTEGER;
+
+-- name: SearchAnalyticsSlowestQueries :many
+-- Queries by their 95th percentile latency since a time
+SELECT
+    normalized_query,
+    COUNT(*) AS searches,
+    AVG(latency_ms) :: FLOAT8 AS avg_latency_ms,
+    percentile_cont(0.95) WITHIN GROUP (
+        ORDER BY
+            latency_ms
+    ) :: FLOAT8 AS p95_latency_ms,
+    MAX(latency_ms) :: INTEGER AS max_latency_ms
+FROM
+    public.search_query_log
+WHERE
+    created_at >= @since
+GROUP BY
+    normalized_query
+ORDER BY
+    p95_latency_ms DESC
+LIMIT
+    @max_rows :: INTEGER;
+
+-- name: SearchAnalyticsClicksByPosition :many
+-- Times each rank was shown and clicked for searches since a time, clicks outside what a search showed are ignored
+WITH positions AS (
+    SELECT
+        generate_series(1, @max_position :: INTEGER) AS position
+),
+impressions AS (
+    SELECT
+        p.position,
+        COUNT(*) AS impressions
+    FROM
+        positions p
+        JOIN public.search_query_log l ON p.position > l.result_offset
+        AND p.position <= l.result_offset + l.returned_count
+    WHERE
+        l.created_at >= @since
+    GROUP BY
+        p.position
+),
+clicks AS (
+    SELECT
+        c.position,
+        COUNT(*) AS clicks
+    FROM
+        public.search_click c
+        JOIN public.search_query_log l ON l.id = c.search_id
+        AND c.position > l.result_offset
+        AND c.position <= l.result_offset + l.returned_count
+    WHERE
+        l.created_at >= @since
+        AND c.position <= @max_position :: INTEGER
+    GROUP BY
+        c.position
+)
+SELECT
+    i.position :: INTEGER AS position,
+    i.impressions,
+    COALESCE(c.clicks, 0) :: BIGINT AS clicks
+FROM
+    impressions i
+    LEFT JOIN clicks c ON c.position = i.position
+ORDER BY
+    i.position;
@@ -0,0 +1,171 @@
+-- name: SearchIndexVersionCreate :one
+-- Allocates the next version of an alias, the unique constraint rejects a concurrent build taking the same number
+INSERT INTO
+    public.search_index_version (
+        backend,
+        alias,
+        version,
+        target,
+        status,
+        created_at,
+        updated_at
+    )
+SELECT
+    @backend::text,
+    @alias::text,
+    next_version.version,
+    @alias::text || '_v' || next_version.version,
+    'building',
+    NOW(),
+    NOW()
+FROM
+    (
+        SELECT
+            COALESCE(MAX(v.version), 0) + 1 AS version
+        FROM
+            public.search_index_version AS v
+        WHERE
+            v.backend = @backend::text
+            AND v.alias = @alias::text
+    ) AS next_version
+RETURNING
+    *;
+
+-- name: SearchIndexVersionRead :one
+SELECT
+    *
+FROM
+    public.search_index_version
+WHERE
+    id = $1;
+
+-- name: SearchIndexVersionList :many
+SELECT
+    *
+FROM
+    public.search_index_version
+WHERE
+    backend = $1
+    AND alias = $2
+ORDER BY
+    version DESC;
+
+-- name: SearchIndexVersionSetStatus :exec
+UPDATE
+    public.search_index_version
+SET
+    status = $2,
+    status_message = $3,
+    updated_at = NOW()
+WHERE
+    id = $1;
+
+-- name: SearchIndexVersionSetCounts :exec
+UPDATE
+    public.search_index_version
+SET
+    expected_counts = $2,
+    indexed_counts = $3,
+    updated_at = NOW()
+WHERE
+    id = $1;
+
+-- name: SearchIndexAliasSwitch :one
+-- Points the alias at a ready or retired version and retires the version it pointed at. It is one statement
+-- so a reader sees either the old or the new target, never neither or both live.
+WITH switched_version AS (
+    SELECT
+        id,
+        backend,
+        alias,
+        target
+    FROM
+        public.search_index_version
+    WHERE
+        id = @version_id::uuid
+        AND status IN ('ready', 'retired')
+),
+retired AS (
+    UPDATE
+        public.search_index_version AS v
+    SET
+        status = 'retired',
+        retired_at = NOW(),
+        updated_at = NOW()
+    FROM
+        switched_version AS s
+    WHERE
+        v.backend = s.backend
+        AND v.alias = s.alias
+        AND v.status = 'live'
+        AND v.id <> s.id
+    RETURNING
+        v.id
+),
+activated AS (
+    UPDATE
+        public.search_index_version AS v
+    SET
+        status = 'live',
+        activated_at = NOW(),
+        retired_at = NULL,
+        updated_at = NOW()
+    FROM
+        switched_version AS s
+    WHERE
+        v.id = s.id
+    RETURNING
+        v.id
+)
+INSERT INTO
+    public.search_index_alias (backend, alias, version_id, target, updated_at)
+SELECT
+    backend,
+    alias,
+    id,
+    target,
+    NOW()
+FROM
+    switched_version
+ON CONFLICT (backend, alias) DO UPDATE
+SET
+    version_id = EXCLUDED.version_id,
+    target = EXCLUDED.target,
+    updated_at = NOW()
+RETURNING
+    *;
+
+-- name: SearchIndexAliasRead :one
+SELECT
+    *
+FROM
+    public.search_index_alias
+WHERE
+    backend = $1
+    AND alias = $2;
+
+-- name: GetSearchIndexSourceStats :one
+-- Rows each indexer reads from Postgres, a versioned build is only switched to when it covers them
+SELECT
+    (
+        SELECT
+            COUNT(*)
+        FROM
+            public.docket_conversations
+    ) AS conversation_count,
+    (
+        SELECT
+            COUNT(*)
+        FROM
+            public.organization
+    ) AS organization_count,
+    (
+        SELECT
+            COUNT(*)
+        FROM
+            public.attachment AS a
+            LEFT JOIN public.attachment_text_source AS ats ON ats.attachment_id = a.id
+        WHERE
+            ats.text IS NOT NULL
+            AND ats.text != ''
+    ) AS attachment_count;